@@ -0,0 +1,113 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// ErrNoMergeBase is returned by MergeBase when the refs have no common history.
+var ErrNoMergeBase = errors.New("no merge base")
+
+// IsAncestor returns true if the commit of ancestor is reachable from descendant,
+// e.g. to check that a release tag is contained in a branch. A ref is its own ancestor.
+func IsAncestor(ctx context.Context, client *server.Client, project, repo string, ancestor, descendant Ref) (bool, error) {
+	resp, err := client.GetCompareCommits(ctx, &server.GetCompareCommitsCommand{
+		ProjectKey: project,
+		RepoSlug:   repo,
+		From:       ancestor,
+		To:         descendant,
+		Limit:      1,
+	})
+	if server.IsNotFound(err) {
+		return false, fmt.Errorf("%w: %s or %s", ErrRefNotFound, ancestor, descendant)
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Commits) == 0, nil
+}
+
+// MergeBase returns the id of the best common ancestor of the commits of a and b,
+// as git merge-base does. It lists the commits on a that are not on b, use it
+// for refs that did not diverge too far.
+func MergeBase(ctx context.Context, client *server.Client, project, repo string, a, b Ref) (string, error) {
+	commits, err := compareCommits(ctx, client, project, repo, a, b)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		// a is an ancestor of b.
+		return ResolveRef(ctx, client, project, repo, a)
+	}
+
+	// The parents outside the list are on b, they are the common ancestors
+	// closest to a.
+	onlyA := map[string]bool{}
+	for _, c := range commits {
+		onlyA[c.ID] = true
+	}
+	var candidates []string
+	seen := map[string]bool{}
+	for _, c := range commits {
+		for _, p := range c.Parents {
+			if !onlyA[p] && !seen[p] {
+				seen[p] = true
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: %s and %s", ErrNoMergeBase, a, b)
+	}
+
+	// Drop the candidates that are ancestors of other candidates.
+	for _, c := range candidates {
+		best := true
+		for _, d := range candidates {
+			if c == d {
+				continue
+			}
+			ok, err := IsAncestor(ctx, client, project, repo, CommitRef(c), CommitRef(d))
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				best = false
+				break
+			}
+		}
+		if best {
+			return c, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+// compareCommits returns all commits reachable from from and not from to.
+func compareCommits(ctx context.Context, client *server.Client, project, repo string, from, to Ref) ([]*server.Commit, error) {
+	cmd := &server.GetCompareCommitsCommand{
+		ProjectKey: project,
+		RepoSlug:   repo,
+		From:       from,
+		To:         to,
+		Limit:      server.MaxLimit,
+	}
+	var res []*server.Commit
+	for {
+		resp, err := client.GetCompareCommits(ctx, cmd)
+		if server.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s or %s", ErrRefNotFound, from, to)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, resp.Commits...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
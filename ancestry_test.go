@@ -0,0 +1,71 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestAncestry(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	files := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	repo := fakeserver.NewRepo(files)
+	first := repo.Resolve("main")
+	repo.Tag("v1.0.0", first)
+	fork := repo.Commit("main", "fork point", files)
+	repo.Branches["feature"] = fork.ID
+	repo.Commit("feature", "feature 1", files)
+	repo.Commit("feature", "feature 2", files)
+	repo.Commit("main", "main 1", files)
+	srv.AddRepo("PRJ", "repo", repo)
+	client := &server.Client{BaseURL: srv.BaseURL()}
+	ctx := context.Background()
+
+	ancestors := []struct {
+		ancestor, descendant Ref
+		want                 bool
+	}{
+		{TagRef("v1.0.0"), BranchRef("main"), true},
+		{TagRef("v1.0.0"), BranchRef("feature"), true},
+		{BranchRef("feature"), BranchRef("main"), false},
+		{BranchRef("main"), BranchRef("main"), true},
+		{CommitRef(fork.ID), BranchRef("feature"), true},
+	}
+	for _, tt := range ancestors {
+		got, err := IsAncestor(ctx, client, "PRJ", "repo", tt.ancestor, tt.descendant)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("IsAncestor(%s, %s): expected %t, got %t", tt.ancestor, tt.descendant, tt.want, got)
+		}
+	}
+
+	bases := []struct {
+		a, b Ref
+		want string
+	}{
+		{BranchRef("feature"), BranchRef("main"), fork.ID},
+		{BranchRef("main"), BranchRef("feature"), fork.ID},
+		{TagRef("v1.0.0"), BranchRef("feature"), first.ID},
+		{BranchRef("main"), TagRef("v1.0.0"), first.ID},
+	}
+	for _, tt := range bases {
+		got, err := MergeBase(ctx, client, "PRJ", "repo", tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("MergeBase(%s, %s): expected %s, got %s", tt.a, tt.b, tt.want, got)
+		}
+	}
+
+	if _, err := IsAncestor(ctx, client, "PRJ", "repo", BranchRef("nope"), BranchRef("main")); !errors.Is(err, ErrRefNotFound) {
+		t.Errorf("expected ErrRefNotFound, got %v", err)
+	}
+}
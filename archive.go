@@ -0,0 +1,243 @@
+package bbfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// ArchiveOption is an option for WriteTar and WriteZip.
+type ArchiveOption func(*archiveConfig)
+
+type archiveConfig struct {
+	prefix  string
+	modTime time.Time
+	ignore  *Ignore
+}
+
+// WithArchivePrefix puts the files in the archive below the directory,
+// e.g. app-1.2.3 for app-1.2.3/README.md.
+func WithArchivePrefix(dir string) ArchiveOption {
+	return func(c *archiveConfig) {
+		c.prefix = strings.Trim(dir, "/")
+	}
+}
+
+// WithArchiveModTime sets the modification time of all entries.
+func WithArchiveModTime(t time.Time) ArchiveOption {
+	return func(c *archiveConfig) {
+		c.modTime = t
+	}
+}
+
+// WithArchiveIgnore leaves out the files and directories that the patterns,
+// relative to the root, ignore. See Ignore.
+func WithArchiveIgnore(ig *Ignore) ArchiveOption {
+	return func(c *archiveConfig) {
+		c.ignore = ig
+	}
+}
+
+// zipEpoch is the time of the entries without one, the earliest time of zip.
+var zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// archiveEntry is a file or directory of an archive.
+type archiveEntry struct {
+	name    string
+	isDir   bool
+	mode    fs.FileMode
+	modTime time.Time
+	// src is the name of a file in the file system.
+	src string
+}
+
+// WriteTar writes the files below root to w as a tar archive.
+//
+// The archive is reproducible: the entries are in lexical order, directories
+// before their files, with mode 0755 for directories and executables and
+// 0644 for other files, no owners, and the time of the commit of the FS of
+// bbfs as modification time. Other file systems keep the modification times
+// of their files, 1980-01-01 when they have none. Only directories and
+// regular files are written.
+func WriteTar(w io.Writer, fsys fs.FS, root string, opts ...ArchiveOption) error {
+	entries, err := archiveEntries(fsys, root, opts)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:    e.name,
+			Mode:    int64(e.mode),
+			ModTime: e.modTime,
+			Format:  tar.FormatPAX,
+		}
+		if e.isDir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.src)
+		if err != nil {
+			return err
+		}
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(len(data))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// WriteZip writes the files below root to w as a zip archive, reproducible
+// like WriteTar. The files are deflated.
+func WriteZip(w io.Writer, fsys fs.FS, root string, opts ...ArchiveOption) error {
+	entries, err := archiveEntries(fsys, root, opts)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{
+			Name:     e.name,
+			Method:   zip.Deflate,
+			Modified: e.modTime,
+		}
+		if e.isDir {
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+			hdr.SetMode(fs.ModeDir | e.mode)
+			if _, err := zw.CreateHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+		hdr.SetMode(e.mode)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		f, err := fsys.Open(e.src)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// archiveEntries returns the entries below root in lexical order.
+func archiveEntries(fsys fs.FS, root string, opts []ArchiveOption) ([]archiveEntry, error) {
+	cfg := &archiveConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	if root == "" {
+		root = "."
+	}
+	if cfg.modTime.IsZero() {
+		t, err := commitTime(fsys)
+		if err != nil {
+			return nil, err
+		}
+		cfg.modTime = t
+	}
+
+	var res []archiveEntry
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		rel := name
+		if root != "." {
+			rel = strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		}
+		// A root that is a file has the name of the file.
+		if rel == "" && !d.IsDir() {
+			rel = path.Base(name)
+		}
+		if cfg.ignore.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if rel == "." || rel == "" {
+			if cfg.prefix == "" {
+				return nil
+			}
+			rel = ""
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e := archiveEntry{
+			name:    path.Join(cfg.prefix, rel),
+			isDir:   d.IsDir(),
+			mode:    0o644,
+			modTime: cfg.modTime,
+			src:     name,
+		}
+		if d.IsDir() || fi.Mode()&0o111 != 0 {
+			e.mode = 0o755
+		}
+		if e.modTime.IsZero() {
+			e.modTime = fi.ModTime()
+		}
+		if e.modTime.IsZero() {
+			e.modTime = zipEpoch
+		}
+		e.modTime = e.modTime.UTC().Truncate(time.Second)
+		res = append(res, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// commitTime returns the time of the commit of an FS of bbfs, the zero time
+// for other file systems.
+func commitTime(fsys fs.FS) (time.Time, error) {
+	b, ok := fsys.(Bitbucket)
+	if !ok {
+		return time.Time{}, nil
+	}
+	resp, err := b.Client().GetCommits(context.Background(), &server.GetCommitsCommand{
+		ProjectKey: b.Project(),
+		RepoSlug:   b.Repo(),
+		Until:      b.Ref(),
+		Limit:      1,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(resp.Commits) == 0 {
+		return time.Time{}, fmt.Errorf("no commit for %s", b.Ref())
+	}
+	return resp.Commits[0].Timestamp, nil
+}
@@ -0,0 +1,104 @@
+package bbfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestWriteTar(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"README.md":        {Data: []byte("readme")},
+		"app/main.go":      {Data: []byte("package main")},
+		"app/conf/a.yaml":  {Data: []byte("a: 1")},
+		"app/conf/b.yaml":  {Data: []byte("b: 2")},
+		"other/ignored.md": {Data: []byte("x")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	var first, second bytes.Buffer
+	if err := WriteTar(&first, fsys, "app", WithArchivePrefix("app-1.0")); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := WriteTar(&second, fsys, "app", WithArchivePrefix("app-1.0")); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("the archives of the same commit differ")
+	}
+
+	var names []string
+	tr := tar.NewReader(&first)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		names = append(names, hdr.Name)
+		if !hdr.ModTime.Equal(repo.Commits[0].Timestamp) {
+			t.Errorf("%s: expected the commit time %s, got %s", hdr.Name, repo.Commits[0].Timestamp, hdr.ModTime)
+		}
+		if hdr.Name == "app-1.0/main.go" {
+			data, _ := io.ReadAll(tr)
+			if string(data) != "package main" || hdr.Mode != 0o644 {
+				t.Errorf("unexpected %s with mode %o", data, hdr.Mode)
+			}
+		}
+	}
+	want := []string{"app-1.0/", "app-1.0/conf/", "app-1.0/conf/a.yaml", "app-1.0/conf/b.yaml", "app-1.0/main.go"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"b.sh":     {Data: []byte("#!/bin/sh"), Mode: 0o755},
+		"a/c.txt":  {Data: []byte("c")},
+		"a/d/e.md": {Data: []byte("e")},
+	}
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, fsys, "", WithArchiveModTime(modTime)); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(modTime) {
+			t.Errorf("%s: expected %s, got %s", f.Name, modTime, f.Modified)
+		}
+		if f.Name == "b.sh" && f.Mode().Perm() != 0o755 {
+			t.Errorf("expected b.sh to be executable, got %s", f.Mode())
+		}
+	}
+	want := []string{"a/", "a/c.txt", "a/d/", "a/d/e.md", "b.sh"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+	rc, err := zr.Open("a/d/e.md")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	defer rc.Close()
+	if data, _ := io.ReadAll(rc); string(data) != "e" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
@@ -1,10 +1,108 @@
 package bbclient
 
-type Commit struct {
+import (
+	"context"
+	"io"
+)
+
+// File types of FileInfo.
+const (
+	TypeFile      = "FILE"
+	TypeDirectory = "DIRECTORY"
+)
+
+// BitbucketRepository gives access to the versions of the components in a
+// repository. The server and cloud packages both implement it, so code
+// written against it works with either backend.
+//
+// A version of a component is a ref in the repository, the files of the
+// component are in the directory with the name of the component.
+type BitbucketRepository interface {
+	// GetContent returns the content of the file of the version of the component.
+	GetContent(ctx context.Context, component string, version string, filePath string) ([]byte, error)
+	// GetTags returns all tags in the repository.
+	GetTags(ctx context.Context) ([]*Tag, error)
+	// ListFiles returns the entries of the directory of the version of the component.
+	ListFiles(ctx context.Context, component string, version string, dir string) ([]*FileInfo, error)
+	// OpenFile opens the file of the version of the component. Close it after use.
+	OpenFile(ctx context.Context, component string, version string, filePath string) (io.ReadCloser, error)
+}
 
+type Commit struct {
+	ID      string
+	Message string
 }
 
+// Tag is a tag in a repository.
 type Tag struct {
+	Name     string
+	CommitID string
+}
 
+// FileInfo is an entry in a directory.
+type FileInfo struct {
+	Name string
+	Size int64
+	// Type is TypeFile or TypeDirectory.
+	Type string
 }
 
+// The methods of BitbucketRepository, the Op of a Call.
+const (
+	OpGetContent = "GetContent"
+	OpGetTags    = "GetTags"
+	OpListFiles  = "ListFiles"
+	OpOpenFile   = "OpenFile"
+	// OpPutContent is the write of a file by the PutContent method of the
+	// server implementation, Path is the path in the repository and At the
+	// branch.
+	OpPutContent = "PutContent"
+)
+
+// Call is a call of a method of a BitbucketRepository, as the hooks see it.
+type Call struct {
+	// Op is the method, e.g. OpGetContent.
+	Op        string
+	Component string
+	Version   string
+	// Path is the file or directory in the directory of the component.
+	Path string
+	// At is the ref or commit that is read. Before may set it, e.g. to pin
+	// the reads to a commit, instead of the ref of the version of the
+	// component. After sees the ref or commit that was read.
+	At string
+}
+
+// Hooks are called around the calls of the BitbucketRepository
+// implementations, e.g. for an audit trail.
+type Hooks struct {
+	// Before is called before the call. It may change the call and return
+	// the context for the call. An error stops the call.
+	Before func(ctx context.Context, call *Call) (context.Context, error)
+	// After is called with the error of the call, or of Before. For OpenFile
+	// it is called when the file is opened, not when it is closed.
+	After func(ctx context.Context, call *Call, err error)
+}
+
+// Do calls fn between the hooks, with the context of Before.
+// A nil Hooks calls fn only.
+func (h *Hooks) Do(ctx context.Context, call *Call, fn func(ctx context.Context) error) error {
+	if h == nil {
+		return fn(ctx)
+	}
+	var err error
+	if h.Before != nil {
+		var hctx context.Context
+		hctx, err = h.Before(ctx, call)
+		if hctx != nil {
+			ctx = hctx
+		}
+	}
+	if err == nil {
+		err = fn(ctx)
+	}
+	if h.After != nil {
+		h.After(ctx, call, err)
+	}
+	return err
+}
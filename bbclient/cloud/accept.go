@@ -0,0 +1,26 @@
+package cloud
+
+import "net/http"
+
+// The Accept headers of the requests. Some proxies rewrite or transcode
+// the responses of requests without an Accept header.
+const (
+	// acceptJSON is the Accept header of the requests of the REST api.
+	acceptJSON = "application/json"
+	// acceptRaw is the Accept header of the requests of file content.
+	acceptRaw = "application/octet-stream"
+)
+
+// acceptCommand is a command with a response other than JSON.
+type acceptCommand interface {
+	accept() string
+}
+
+// setAccept sets the Accept header of the command.
+func setAccept(req *http.Request, cmd Command) {
+	accept := acceptJSON
+	if a, ok := cmd.(acceptCommand); ok {
+		accept = a.accept()
+	}
+	req.Header.Set("Accept", accept)
+}
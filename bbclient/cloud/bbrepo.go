@@ -2,38 +2,137 @@ package cloud
 
 import (
 	"context"
-	"net/url"
+	"errors"
+	"io"
+	"path"
+
+	"github.com/myhops/bbfs/bbclient"
 )
 
 type BitbucketRepo struct {
-	Client     *Client
-	ProjectKey string
-	RepoSlug   string
+	Client    *Client
+	Workspace string
+	RepoSlug  string
+	// Hooks, if not nil, are called around the calls of the methods of
+	// bbclient.BitbucketRepository.
+	Hooks *bbclient.Hooks
 }
 
-// GetContent implements server.BitbucketRepository.
+// commit returns the commit of the call, the At of the call when a hook
+// set it, otherwise the commit of the tag component/version. It sets the
+// At of the call for the After hook.
+func (r *BitbucketRepo) commit(ctx context.Context, call *bbclient.Call) (string, error) {
+	if call.At != "" {
+		return call.At, nil
+	}
+	commit, err := r.Client.ResolveRef(ctx, r.Workspace, r.RepoSlug, call.Component+"/"+call.Version)
+	if err != nil {
+		return "", err
+	}
+	call.At = commit
+	return commit, nil
+}
+
+// GetContent implements bbclient.BitbucketRepository.
 func (r *BitbucketRepo) GetContent(ctx context.Context, component string, version string, filePath string) ([]byte, error) {
-	path, err := url.JoinPath("", component, filePath)
+	call := &bbclient.Call{Op: bbclient.OpGetContent, Component: component, Version: version, Path: filePath}
+	var content []byte
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		commit, err := r.commit(ctx, call)
+		if err != nil {
+			return err
+		}
+		content, err = r.Client.GetFileContent(ctx, &GetFileContentCommand{
+			Workspace: r.Workspace,
+			RepoSlug:  r.RepoSlug,
+			FilePath:  path.Join(call.Component, call.Path),
+			At:        commit,
+		})
+		return err
+	})
+	return content, err
+}
+
+// GetTags implements bbclient.BitbucketRepository.
+func (r *BitbucketRepo) GetTags(ctx context.Context) ([]*bbclient.Tag, error) {
+	var tags []*bbclient.Tag
+	err := r.Hooks.Do(ctx, &bbclient.Call{Op: bbclient.OpGetTags}, func(ctx context.Context) error {
+		cmd := &GetTagsCommand{
+			Workspace: r.Workspace,
+			RepoSlug:  r.RepoSlug,
+			PageLen:   MaxPageLen,
+		}
+		for {
+			resp, err := r.Client.GetTags(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			for _, t := range resp.Tags {
+				tags = append(tags, &bbclient.Tag{Name: t.Name, CommitID: t.CommitID})
+			}
+			if resp.IsLastPage {
+				return nil
+			}
+			cmd.Page = resp.NextPage
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-	at := "refs/tags/" + component + "/" + version
-	cmd := &GetFileContentCommand{
-		ProjectKey: r.ProjectKey,
-		RepoSlug:   r.RepoSlug,
-		FilePath:   path,
-		At:         at,
-	}
-	return r.Client.GetFileContent(ctx, cmd)
+	return tags, nil
 }
 
-// GetTags implements server.BitbucketRepository.
-func (r *BitbucketRepo) GetTags(ctx context.Context) ([]string, error) {
-	cmd := &GetTagsCommand{
-		ProjectKey: r.ProjectKey,
-		RepoSlug:   r.RepoSlug,
+// ListFiles implements bbclient.BitbucketRepository.
+func (r *BitbucketRepo) ListFiles(ctx context.Context, component string, version string, dir string) ([]*bbclient.FileInfo, error) {
+	call := &bbclient.Call{Op: bbclient.OpListFiles, Component: component, Version: version, Path: dir}
+	var files []*bbclient.FileInfo
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		commit, err := r.commit(ctx, call)
+		if err != nil {
+			return err
+		}
+		it, err := r.Client.GetFilesIterator(ctx, &GetFilesCommand{
+			Workspace: r.Workspace,
+			RepoSlug:  r.RepoSlug,
+			FilePath:  path.Join(call.Component, call.Path),
+			At:        commit,
+			PageLen:   MaxPageLen,
+		})
+		if err != nil {
+			return err
+		}
+		for f := range it.Files() {
+			files = append(files, &bbclient.FileInfo{Name: f.Name, Size: f.Size, Type: f.Type})
+		}
+		if err := it.Err(); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return r.Client.GetTags(ctx, cmd)
+	return files, nil
+}
+
+// OpenFile implements bbclient.BitbucketRepository.
+func (r *BitbucketRepo) OpenFile(ctx context.Context, component string, version string, filePath string) (io.ReadCloser, error) {
+	call := &bbclient.Call{Op: bbclient.OpOpenFile, Component: component, Version: version, Path: filePath}
+	var body io.ReadCloser
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		commit, err := r.commit(ctx, call)
+		if err != nil {
+			return err
+		}
+		body, err = r.Client.OpenRawFile(ctx, &OpenRawFileCommand{
+			Workspace: r.Workspace,
+			RepoSlug:  r.RepoSlug,
+			FilePath:  path.Join(call.Component, call.Path),
+			At:        commit,
+		})
+		return err
+	})
+	return body, err
 }
 
-// var _ server.BitbucketRepository = &BitbucketRepo{}
+var _ bbclient.BitbucketRepository = &BitbucketRepo{}
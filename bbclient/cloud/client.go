@@ -1,41 +1,75 @@
 package cloud
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/myhops/bbfs/internal/buildinfo"
 	"github.com/myhops/bbfs/nulllog"
 )
 
+const (
+	// DefaultBaseURL is the url of the Bitbucket Cloud api.
+	DefaultBaseURL = "https://api.bitbucket.org/2.0"
+	// DefaultTokenURL is the url of the Bitbucket Cloud OAuth token endpoint.
+	DefaultTokenURL = "https://bitbucket.org/site/oauth2/access_token"
+)
+
+// Secret string masks the value for String to avoid accidental disclosure.
 type SecretString string
 
+// String returns a masked value.
 func (s SecretString) String() string {
 	return strings.Repeat("*", len(s))
 }
 
+// Secret returns the secret value.
 func (s SecretString) Secret() string {
 	return string(s)
 }
 
+// Client is a client for Bitbucket Cloud.
+//
+// Set Username and AppPassword to authenticate with an app password,
+// AccessKey for an OAuth or access token, or OAuthClientID and
+// OAuthClientSecret for an OAuth consumer. Without credentials only
+// public repositories are visible.
 type Client struct {
-	BaseURL   string
+	// BaseURL is the url of the api, defaults to DefaultBaseURL.
+	BaseURL string
+	// Username is the Bitbucket username for the app password.
+	Username string
+	// AppPassword is the app password of the user.
+	AppPassword SecretString
+	// AccessKey is an OAuth access token or a repository, project or
+	// workspace access token.
 	AccessKey SecretString
-	Logger    *slog.Logger
-}
-
-type GetFileContentCommand struct {
-	FilePath   string
-	ProjectKey string
-	RepoSlug   string
-	At         string
+	// OAuthClientID is the key of the OAuth consumer. The client gets
+	// access tokens with the client credentials grant.
+	OAuthClientID string
+	// OAuthClientSecret is the secret of the OAuth consumer.
+	OAuthClientSecret SecretString
+	// TokenURL is the url of the OAuth token endpoint, defaults to DefaultTokenURL.
+	TokenURL string
+	Logger   *slog.Logger
+	// UserAgent identifies the application in the User-Agent header of the
+	// requests, after bbfs/VERSION, e.g. docs-portal/2.1.
+	UserAgent string
+	// OnPage is called for each page of a paged listing the client fetches:
+	// FilesIterator, ListWorkspaces and ListRepos. Use it to trace slow
+	// walks or to report progress. Nil disables it.
+	OnPage func(ctx context.Context, page *PageInfo)
+
+	mu    sync.Mutex
+	token oauthToken
 }
 
 func (c *Client) initLogger() {
@@ -44,413 +78,308 @@ func (c *Client) initLogger() {
 	}
 }
 
-func (c *GetFileContentCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/raw/%s", baseURL, c.ProjectKey, c.RepoSlug, c.FilePath))
-	if err != nil {
-		return nil, err
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return DefaultBaseURL
 	}
-	if c.At != "" {
-		values := url.Values{}
-		values.Add("at", c.At)
-		u.RawQuery = values.Encode()
-	}
-	us := u.String()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
-	if err != nil {
-		return nil, err
-	}
-	return req, nil
+	return c.BaseURL
 }
 
-func (c *GetFileContentCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	if c.FilePath == "" {
-		return fmt.Errorf("FilePath is missing")
-	}
-	return nil
-}
+// ErrInvalidCommand is returned when a command does not validate.
+var ErrInvalidCommand = errors.New("command not valid")
 
-func (c *GetFileContentCommand) ParseResponse(data []byte) ([]byte, error) {
-	var resp struct {
-		Lines []struct {
-			Text string `json:"text"`
-		} `json:"lines"`
-	}
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, err
-	}
-	var b bytes.Buffer
-	for _, line := range resp.Lines {
-		if _, err := fmt.Fprintln(&b, line.Text); err != nil {
-			return nil, err
-		}
-	}
-	return b.Bytes(), nil
+// StatusError is returned when the server responds with a status other than 2xx.
+type StatusError struct {
+	StatusCode int
+	// Message is the error message from the response, if any.
+	Message string
 }
 
-func checkStatus(status int) error {
-	if status < 200 || status >= 300 {
-		return fmt.Errorf("bad status: %s", http.StatusText(status))
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("bad status: %s: %s", http.StatusText(e.StatusCode), e.Message)
 	}
-	return nil
+	return fmt.Sprintf("bad status: %s", http.StatusText(e.StatusCode))
 }
 
-func (c *Client) AuthorizeRequest(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+c.AccessKey.Secret())
+// IsNotFound returns true if err is a StatusError for http.StatusNotFound.
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
 }
 
-func (c *Client) GetFileContent(ctx context.Context, cmd *GetFileContentCommand) ([]byte, error) {
-	c.initLogger()
-	// Validate the request.
-	if err := cmd.Validate(); err != nil {
-		return nil, fmt.Errorf("command not valid: %w", err)
-	}
-
-	// Build a request.
-	req, err := cmd.newRequestWithContext(ctx, c.BaseURL)
-	if err != nil {
-		return nil, err
-	}
-	c.AuthorizeRequest(req)
-
-	us := req.URL.String()
-	c.Logger.Info("url build", slog.String("url", us))
+// IsConflict returns true if err is a StatusError for http.StatusConflict,
+// e.g. for a commit on a branch that moved past its parent.
+func IsConflict(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusConflict
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// IsUnauthorized returns true if err is a StatusError for
+// http.StatusUnauthorized or http.StatusForbidden.
+func IsUnauthorized(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) &&
+		(se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden)
+}
 
-	if err := checkStatus(resp.StatusCode); err != nil {
-		return nil, err
+// checkResponse returns a StatusError for a response with a status other than 2xx.
+// It reads the error message from the body.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
 	}
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	json.Unmarshal(data, &body)
+	return &StatusError{StatusCode: resp.StatusCode, Message: body.Error.Message}
+}
+
+// AuthorizeRequest adds the Authorization header for the credentials of the client.
+// For an OAuth consumer it gets an access token first.
+func (c *Client) AuthorizeRequest(req *http.Request) error {
+	switch {
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.AppPassword.Secret())
+	case c.AccessKey != "":
+		req.Header.Set("Authorization", "Bearer "+c.AccessKey.Secret())
+	case c.OAuthClientID != "":
+		token, err := c.accessToken(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
-	return cmd.ParseResponse(b)
+	return nil
 }
 
-type GetTagsCommand struct {
-	ProjectKey string
-	RepoSlug   string
-	OrderBy    string
+// GetFileContent returns the content of the file.
+//
+// Use OpenRawFile for large files.
+func (c *Client) GetFileContent(ctx context.Context, cmd *GetFileContentCommand) ([]byte, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-func (c *GetTagsCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/tags", baseURL, c.ProjectKey, c.RepoSlug))
-	if err != nil {
-		return nil, err
-	}
-	if c.OrderBy != "" {
-		values := url.Values{}
-		values.Add("orderBy", c.OrderBy)
-		u.RawQuery = values.Encode()
-	}
-	us := u.String()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
-	if err != nil {
-		return nil, err
-	}
-	return req, nil
+// OpenRawFile opens the file as specified in the cmd parameter.
+// The returned io.ReadCloser is the body of the response.
+// You need to close the io.ReadCloser after use.
+func (c *Client) OpenRawFile(ctx context.Context, cmd *OpenRawFileCommand) (io.ReadCloser, error) {
+	return DoCommandBody(ctx, c, cmd)
 }
 
-func (c *GetTagsCommand) ParseResponse(data []byte) ([]string, error) {
-	var resp struct {
-		Values []struct {
-			ID        string `json:"id"`
-			DisplayID string `json:"displayId"`
-		} `json:"values"`
-	}
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, err
-	}
-	var tags = make([]string, 0, len(resp.Values))
-	for _, tag := range resp.Values {
-		tags = append(tags, tag.DisplayID)
-	}
-	return tags, nil
+// GetFileMeta returns the type and size of the file or directory.
+func (c *Client) GetFileMeta(ctx context.Context, cmd *GetFileMetaCommand) (*FileInfo, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-func (c *Client) GetTags(ctx context.Context, cmd *GetTagsCommand) ([]string, error) {
-	// Validate the request.
-	if err := cmd.Validate(); err != nil {
-		return nil, fmt.Errorf("command not valid: %w", err)
-	}
-	// Build a request.
-	req, err := cmd.newRequestWithContext(ctx, c.BaseURL)
-	if err != nil {
-		return nil, err
-	}
-	c.AuthorizeRequest(req)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// GetFiles returns a page of the entries in the directory.
+func (c *Client) GetFiles(ctx context.Context, cmd *GetFilesCommand) (*GetFilesResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
 
-	if err := checkStatus(resp.StatusCode); err != nil {
-		return nil, err
-	}
-	b, err := io.ReadAll(resp.Body)
+// GetFilesIterator returns a file interator for the FilePath in GetFilesCommand.
+func (c *Client) GetFilesIterator(ctx context.Context, cmd *GetFilesCommand) (*FilesIterator, error) {
+	// Get the first result and pass it to the iterator.
+	start := time.Now()
+	res, err := c.GetFiles(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
-	return cmd.ParseResponse(b)
+	page := *cmd
+	c.observePage(ctx, &page, 0, len(res.Files), start)
+	return &FilesIterator{
+		client:      c,
+		lastResult:  res,
+		lastCommand: cmd,
+		ctx:         ctx,
+	}, nil
 }
 
-func (c *GetTagsCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	return nil
+// GetTags returns a page of the tags in the repository.
+func (c *Client) GetTags(ctx context.Context, cmd *GetTagsCommand) (*GetTagsResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-type GetFilesCommand struct {
-	FilePath   string
-	ProjectKey string
-	RepoSlug   string
-	At         string
-	Start      int
-	Limit      int
+// GetCommits returns a page of the commits reachable from Include.
+func (c *Client) GetCommits(ctx context.Context, cmd *GetCommitsCommand) (*GetCommitsResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-type GetFilesResponse struct {
-	Files     []*FileInfo
-	Start     int
-	NextStart int
-	LastPage  bool
-	Size      int
+// GetCommit returns a single commit.
+func (c *Client) GetCommit(ctx context.Context, cmd *GetCommitCommand) (*Commit, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-func (c *GetFilesCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	return nil
+// GetRepo returns the repository.
+func (c *Client) GetRepo(ctx context.Context, cmd *GetRepoCommand) (*Repository, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-func (c *GetFilesCommand) ParseResponse(data []byte) (*GetFilesResponse, error) {
-	var r struct {
-		Children struct {
-			Size          int  `json:"size"`
-			IsLastPage    bool `json:"isLastPage"`
-			NextPageStart int  `json:"nextPageStart"`
-			Start         int  `json:"start"`
-			Values        []struct {
-				Path struct {
-					Name string `json:"name"`
-					Components []string `json:"components"`
-				} `json:"path"`
-				Type string `json:"type"`
-				Size int64  `json:"size"`
-			} `json:"values"`
-		} `json:"children"`
-	}
-	if err := json.Unmarshal(data, &r); err != nil {
-		return nil, err
-	}
-	resp := &GetFilesResponse{
-		Start:     r.Children.Start,
-		Size:      r.Children.Size,
-		NextStart: r.Children.NextPageStart,
-		LastPage:  r.Children.IsLastPage,
-	}
-	for _, v := range r.Children.Values {
-		resp.Files = append(resp.Files, &FileInfo{
-			Name: v.Path.Components[0],
-			Size: v.Size,
-			Type: v.Type,
-		})
-	}
-	return resp, nil
+// GetWorkspaces returns a page of the workspaces of the user.
+func (c *Client) GetWorkspaces(ctx context.Context, cmd *GetWorkspacesCommand) (*GetWorkspacesResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-func addValue(v url.Values, name string, value string) {
-	if value != "" {
-		v.Add(name, value)
-	}
+// GetRepos returns a page of the repositories in the workspace.
+func (c *Client) GetRepos(ctx context.Context, cmd *GetReposCommand) (*GetReposResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
 }
 
-func (c *GetFilesCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	// https://bitbucket.belastingdienst.nl/rest/api/latest/projects/:projectKey/repos/:repoSlug/browse/:fileName
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/browse/%s", baseURL, c.ProjectKey, c.RepoSlug, c.FilePath))
-	if err != nil {
-		return nil, err
-	}
-	vals := url.Values{}
-	addValue(vals, "at", c.At)
-	addValue(vals, "start", strconv.Itoa(c.Start))
-	addValue(vals, "limit", strconv.Itoa(c.Limit))
-	u.RawQuery = vals.Encode()
-
-	us := u.String()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
-	if err != nil {
-		return nil, err
+// ListWorkspaces returns all workspaces of the user.
+func (c *Client) ListWorkspaces(ctx context.Context) ([]*Workspace, error) {
+	cmd := &GetWorkspacesCommand{PageLen: MaxPageLen}
+	var res []*Workspace
+	for index := 0; ; index++ {
+		start := time.Now()
+		resp, err := c.GetWorkspaces(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		page := *cmd
+		c.observePage(ctx, &page, index, len(resp.Workspaces), start)
+		res = append(res, resp.Workspaces...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Page = resp.NextPage
 	}
-	return req, nil
 }
 
-type FileInfo struct {
-	Name string `json:"name"`
-	Size int64  `json:"size"`
-	Type string `json:"type"`
+// ListRepos returns all repositories in the workspace.
+func (c *Client) ListRepos(ctx context.Context, workspace string) ([]*Repository, error) {
+	cmd := &GetReposCommand{Workspace: workspace, PageLen: MaxPageLen}
+	var res []*Repository
+	for index := 0; ; index++ {
+		start := time.Now()
+		resp, err := c.GetRepos(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		page := *cmd
+		c.observePage(ctx, &page, index, len(resp.Repos), start)
+		res = append(res, resp.Repos...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Page = resp.NextPage
+	}
 }
 
-func (c *Client) GetFiles(ctx context.Context, cmd *GetFilesCommand) (*GetFilesResponse, error) {
-	// Validate the request.
-	if err := cmd.Validate(); err != nil {
-		return nil, fmt.Errorf("command not valid: %w", err)
+// ResolveRef returns the commit hash for a branch, tag or commit.
+// The refs/heads/ and refs/tags/ prefixes are removed,
+// the empty ref is the main branch of the repository.
+//
+// The src endpoints need a commit without slashes,
+// resolve branch and tag names with ResolveRef first.
+func (c *Client) ResolveRef(ctx context.Context, workspace, repoSlug, ref string) (string, error) {
+	ref = refName(ref)
+	if isCommitHash(ref) {
+		return ref, nil
 	}
-	// Build a request.
-	req, err := cmd.newRequestWithContext(ctx, c.BaseURL)
+	if ref == "" {
+		repo, err := c.GetRepo(ctx, &GetRepoCommand{Workspace: workspace, RepoSlug: repoSlug})
+		if err != nil {
+			return "", err
+		}
+		ref = repo.MainBranch
+	}
+	resp, err := c.GetCommits(ctx, &GetCommitsCommand{
+		Workspace: workspace,
+		RepoSlug:  repoSlug,
+		Include:   ref,
+		PageLen:   1,
+	})
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
 	}
-	c.AuthorizeRequest(req)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	if len(resp.Commits) == 0 {
+		return "", fmt.Errorf("resolving %s: no commits", ref)
 	}
-	defer resp.Body.Close()
-
-	if err := checkStatus(resp.StatusCode); err != nil {
-		return nil, err
-	}
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	return cmd.ParseResponse(b)
-}
-
-type FilesIterator struct {
-	client      *Client
-	lastCommand *GetFilesCommand
-	lastResult  *GetFilesResponse
-	index       int
-	lastError   error
-	ctx         context.Context
+	return resp.Commits[0].Hash, nil
 }
 
-func (i *FilesIterator) Next() *FileInfo {
-	if i.lastError != nil {
-		return nil
-	}
-	if i.index >= len(i.lastResult.Files) {
-		if i.lastResult.LastPage {
-			i.lastError = io.EOF
-			return nil
-		}
-		// Get next page.
-		if err := i.loadPage(); err != nil {
-			i.lastError = err
-			return nil
-		}
-		i.index = 0
-	}
-	res := i.lastResult.Files[i.index]
-	i.index++
-	return res
+// Command is a request to the api, the commands of this package implement it.
+// Implement it for other endpoints, e.g. of a plugin, to send them with
+// DoCommandBody and DoCommandResponse with the authorization of the client.
+type Command interface {
+	slog.LogValuer
+	// Validate returns an error when the command can not be sent.
+	Validate() error
+	// NewRequestWithContext returns the request of the command for the
+	// api at baseURL, the BaseURL of the client, e.g. https://api.bitbucket.org/2.0.
+	NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error)
 }
 
-func (i *FilesIterator) Err() error {
-	return i.lastError
+// CommandResponse is a Command with a response to parse.
+type CommandResponse[T any] interface {
+	Command
+	// ParseResponse parses the body of the response.
+	ParseResponse([]byte) (T, error)
 }
 
-func (i *FilesIterator) loadPage() error {
-	i.lastCommand.Start = i.lastResult.NextStart
-	res, err := i.client.GetFiles(i.ctx, i.lastCommand)
+// DoCommandBody performs Do for the given command and returns the response body.
+// You need to close the io.ReadCloser after use.
+func DoCommandBody(ctx context.Context, client *Client, cmd Command) (io.ReadCloser, error) {
+	resp, err := doCommand(ctx, client, cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	i.lastResult = res
-	return nil
+	return resp.Body, nil
 }
 
-func (c *Client) GetFilesIterator(ctx context.Context, cmd *GetFilesCommand) (*FilesIterator, error) {
-	// Get the first result and pass it to the iterator.
-	res, err := c.GetFiles(ctx, cmd)
+// doCommand sends the command and returns the response with a 2xx status.
+func doCommand(ctx context.Context, client *Client, cmd Command) (*http.Response, error) {
+	client.initLogger()
+	client.Logger.Debug("executing command", slog.Any("command", cmd))
+	// Validate the request.
+	if err := cmd.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+	// Build a request.
+	req, err := cmd.NewRequestWithContext(ctx, client.baseURL())
 	if err != nil {
 		return nil, err
 	}
-	return &FilesIterator{
-		client:      c,
-		lastResult:  res,
-		lastCommand: cmd,
-		ctx:         ctx,
-	}, nil
-}
-
-type OpenRawFileCommand struct {
-		FilePath   string
-		ProjectKey string
-		RepoSlug   string
-		At         string
-}
-
-func (c *OpenRawFileCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/raw/%s", baseURL, c.ProjectKey, c.RepoSlug, c.FilePath))
-	if err != nil {
+	req.Header.Set("User-Agent", buildinfo.UserAgent(client.UserAgent))
+	setAccept(req, cmd)
+	if err := client.AuthorizeRequest(req); err != nil {
 		return nil, err
 	}
-	vals := url.Values{}
-	addValue(vals, "at", c.At)
-	u.RawQuery = vals.Encode()
-
-	us := u.String()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	return req, nil
-}
-
-func (c *OpenRawFileCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	if c.FilePath == "" {
-		return fmt.Errorf("FilePath is missing")
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
 	}
-	return nil
+	return resp, nil
 }
 
-func (c *Client) OpenRawFile(ctx context.Context, cmd *OpenRawFileCommand) (io.ReadCloser, error) {
-	// Validate the request.
-	if err := cmd.Validate(); err != nil {
-		return nil, fmt.Errorf("command not valid: %w", err)
+// DoCommandResponse performs do for the given command and returns the parsed body.
+func DoCommandResponse[C CommandResponse[T], T any](ctx context.Context, client *Client, cmd C) (T, error) {
+	var nullRes T
+	body, err := DoCommandBody(ctx, client, cmd)
+	if err != nil {
+		return nullRes, err
 	}
-	// Build a request.
-	req, err := cmd.newRequestWithContext(ctx, c.BaseURL)
+	defer body.Close()
+
+	b, err := io.ReadAll(body)
 	if err != nil {
-		return nil, err
+		return nullRes, err
 	}
-	c.AuthorizeRequest(req)
-	resp, err := http.DefaultClient.Do(req)
+	res, err := cmd.ParseResponse(b)
 	if err != nil {
-		return nil, err
+		return nullRes, err
 	}
-
-	if err := checkStatus(resp.StatusCode); err != nil {
-		resp.Body.Close()
-		return nil, err
+	// Only log responses that know how to summarize themselves.
+	if lv, ok := any(res).(slog.LogValuer); ok {
+		client.Logger.Debug("command response", slog.Any("command", cmd), slog.Any("response", lv))
 	}
-	return resp.Body, nil
-}
\ No newline at end of file
+	return res, nil
+}
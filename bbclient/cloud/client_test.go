@@ -2,103 +2,414 @@ package cloud
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
 	"testing"
+	"testing/fstest"
 
+	"github.com/myhops/bbfs/bbclient"
+	"github.com/myhops/bbfs/internal/fakecloud"
+	"github.com/myhops/bbfs/internal/fakeserver"
 	"github.com/myhops/bbfs/nulllog"
 )
 
-const (
-	accessKey = ""
-)
-
-func TestGetReadme(t *testing.T) {
-	c := &Client{
-		BaseURL:   "https://bitbucket.belastingdienst.nl/rest/api/latest",
-		AccessKey: accessKey,
-		Logger: nulllog.Logger(),
+// newTestServer returns a server with the repository ws/repo.
+func newTestServer(t *testing.T) (*fakecloud.Server, *fakeserver.Repo) {
+	t.Helper()
+	files := fstest.MapFS{
+		"README.md":          {Data: []byte("# readme\n")},
+		"comp/app.yaml":      {Data: []byte("name: app\n")},
+		"comp/conf/env.yaml": {Data: []byte("env: test\n")},
 	}
+	for i := range 5 {
+		files[fmt.Sprintf("many/file%d.txt", i)] = &fstest.MapFile{Data: []byte(fmt.Sprint(i))}
+	}
+	repo := fakeserver.NewRepo(files)
+	repo.Tag("comp/1.0.0", repo.Commits[0])
+	repo.Commit("main", "update readme", fstest.MapFS{"README.md": {Data: []byte("# updated\n")}})
+	repo.Tag("v2", repo.Commits[1])
+	srv := fakecloud.New()
+	t.Cleanup(srv.Close)
+	srv.AddRepo("ws", "repo", repo)
+	return srv, repo
+}
+
+func TestGetFileContent(t *testing.T) {
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL(), Logger: nulllog.Logger()}
 	content, err := c.GetFileContent(context.Background(), &GetFileContentCommand{
-		ProjectKey: "~zandp06",
-		RepoSlug:   "testraw",
-		FilePath:   "README.md",
-		At:         "olo-kor-eb-service/1.0.0.1",
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		FilePath:  "comp/app.yaml",
+		At:        repo.Commits[0].ID,
 	})
 	if err != nil {
 		t.Fatalf("error: %s", err.Error())
 	}
-	t.Logf("%s", string(content))
+	if string(content) != "name: app\n" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	_, err = c.GetFileContent(context.Background(), &GetFileContentCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		FilePath:  "comp/app.yaml",
+		At:        "comp/1.0.0",
+	})
+	if !errors.Is(err, ErrInvalidCommand) {
+		t.Errorf("expected ErrInvalidCommand for a ref with a slash, got %v", err)
+	}
 }
 
 func TestGetTags(t *testing.T) {
-	c := &Client{
-		BaseURL:   "https://bitbucket.belastingdienst.nl/rest/api/latest",
-		AccessKey: accessKey,
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL()}
+	cmd := &GetTagsCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		PageLen:   1,
+	}
+	var names []string
+	for {
+		resp, err := c.GetTags(context.Background(), cmd)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		for _, tag := range resp.Tags {
+			names = append(names, tag.Name)
+		}
+		if resp.IsLastPage {
+			break
+		}
+		cmd.Page = resp.NextPage
 	}
-	tags, err := c.GetTags(context.Background(), &GetTagsCommand{
-		ProjectKey: "~zandp06",
-		RepoSlug:   "testraw",
-		OrderBy:    "ALPHABETICAL",
+	if !slices.Equal(names, []string{"comp/1.0.0", "v2"}) {
+		t.Errorf("unexpected tags %v", names)
+	}
+
+	resp, err := c.GetTags(context.Background(), &GetTagsCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		Query:     NameQuery("v2"),
 	})
 	if err != nil {
 		t.Fatalf("error: %s", err.Error())
 	}
-	t.Logf("%s", tags)
+	if len(resp.Tags) != 1 || resp.Tags[0].CommitID != repo.Commits[1].ID || resp.Tags[0].Message != "update readme" {
+		t.Errorf("unexpected tags %v", resp.Tags)
+	}
 }
 
-func TestGetFiles(t *testing.T) {
-	c := &Client{
-		BaseURL:   "https://bitbucket.belastingdienst.nl/rest/api/latest",
-		AccessKey: accessKey,
-	}
-	files, err := c.GetFiles(context.Background(), &GetFilesCommand{
-		ProjectKey: "~zandp06",
-		RepoSlug:   "testraw",
-		FilePath: "",
-		Limit: 100,
+func TestGetCommits(t *testing.T) {
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL()}
+	resp, err := c.GetCommits(context.Background(), &GetCommitsCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		Include:   "refs/heads/main",
+	})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(resp.Commits) != 2 || resp.Commits[0].Hash != repo.Commits[1].ID {
+		t.Fatalf("unexpected commits %v", resp.Commits)
+	}
+	if got := resp.Commits[0].Parents; !slices.Equal(got, []string{repo.Commits[0].ID}) {
+		t.Errorf("unexpected parents %v", got)
+	}
+	if !resp.Commits[1].Date.Equal(fakeserver.Epoch) {
+		t.Errorf("unexpected date %s", resp.Commits[1].Date)
+	}
+
+	commit, err := c.GetCommit(context.Background(), &GetCommitCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		Commit:    repo.Commits[0].ID[:12],
 	})
 	if err != nil {
 		t.Fatalf("error: %s", err.Error())
 	}
-	t.Logf("%v", files)
+	if commit.Message != "initial commit" {
+		t.Errorf("unexpected commit %v", commit)
+	}
 }
 
-func TestGetAllFiles(t *testing.T) {
-	c := &Client{
-		BaseURL:   "https://bitbucket.belastingdienst.nl/rest/api/latest",
-		AccessKey: accessKey,
-	}
-	files, err := c.GetFiles(context.Background(), &GetFilesCommand{
-		ProjectKey: "~zandp06",
-		RepoSlug:   "testraw",
-		FilePath: "",
-		Limit: 100,
+func TestFilesIterator(t *testing.T) {
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL()}
+	iter, err := c.GetFilesIterator(context.Background(), &GetFilesCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		At:        repo.Commits[0].ID,
+		FilePath:  "many",
+		PageLen:   2,
 	})
 	if err != nil {
 		t.Fatalf("error: %s", err.Error())
 	}
-	t.Logf("%v", files)
+	var names []string
+	for f := range iter.Files() {
+		names = append(names, f.Name)
+	}
+	if err := iter.Err(); !errors.Is(err, io.EOF) {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := []string{"file0.txt", "file1.txt", "file2.txt", "file3.txt", "file4.txt"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
 }
 
-func TestIterator(t *testing.T) {
+func TestOnPage(t *testing.T) {
+	srv, repo := newTestServer(t)
+	var pages []string
 	c := &Client{
-		BaseURL:   "https://bitbucket.belastingdienst.nl/rest/api/latest",
-		AccessKey: accessKey,
+		BaseURL: srv.BaseURL(),
+		OnPage: func(ctx context.Context, p *PageInfo) {
+			pages = append(pages, fmt.Sprintf("%T %d:%d", p.Command, p.Index, p.Size))
+		},
 	}
 	iter, err := c.GetFilesIterator(context.Background(), &GetFilesCommand{
-		ProjectKey: "~zandp06",
-		RepoSlug:   "testraw",
-		FilePath: "server",
-		Limit: 7,
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		At:        repo.Commits[0].ID,
+		FilePath:  "many",
+		PageLen:   2,
 	})
 	if err != nil {
 		t.Fatalf("error: %s", err.Error())
 	}
-	var n int
-	for f := iter.Next(); f != nil; f = iter.Next() {
-		n++
-		t.Logf("%v", f)
+	for range iter.Files() {
+	}
+	if _, err := c.ListRepos(context.Background(), "ws"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := []string{
+		"*cloud.GetFilesCommand 0:2",
+		"*cloud.GetFilesCommand 1:2",
+		"*cloud.GetFilesCommand 2:1",
+		"*cloud.GetReposCommand 0:1",
+	}
+	if !slices.Equal(pages, want) {
+		t.Errorf("expected pages %q, got %q", want, pages)
 	}
-	t.Logf("%d", n)
 }
 
+func TestResolveRef(t *testing.T) {
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL()}
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"", repo.Commits[1].ID},
+		{"main", repo.Commits[1].ID},
+		{"refs/tags/comp/1.0.0", repo.Commits[0].ID},
+		{"comp/1.0.0", repo.Commits[0].ID},
+		{repo.Commits[0].ID[:8], repo.Commits[0].ID},
+		{repo.Commits[0].ID, repo.Commits[0].ID},
+	}
+	for _, tt := range tests {
+		got, err := c.ResolveRef(context.Background(), "ws", "repo", tt.ref)
+		if err != nil {
+			t.Fatalf("%q: error: %s", tt.ref, err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.ref, tt.want, got)
+		}
+	}
+	if _, err := c.ResolveRef(context.Background(), "ws", "repo", "missing"); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
 
+func TestAuth(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.SetAppPassword("user", "app-password")
+	srv.SetAccessToken("access-token")
+	srv.SetOAuthConsumer("key", "secret")
+
+	tests := []struct {
+		name   string
+		client *Client
+		ok     bool
+	}{
+		{"none", &Client{}, false},
+		{"app password", &Client{Username: "user", AppPassword: "app-password"}, true},
+		{"bad app password", &Client{Username: "user", AppPassword: "wrong"}, false},
+		{"access token", &Client{AccessKey: "access-token"}, true},
+		{"oauth", &Client{OAuthClientID: "key", OAuthClientSecret: "secret", TokenURL: srv.TokenURL()}, true},
+		{"bad oauth", &Client{OAuthClientID: "key", OAuthClientSecret: "wrong", TokenURL: srv.TokenURL()}, false},
+	}
+	for _, tt := range tests {
+		tt.client.BaseURL = srv.BaseURL()
+		for range 2 {
+			_, err := tt.client.GetRepo(context.Background(), &GetRepoCommand{Workspace: "ws", RepoSlug: "repo"})
+			if tt.ok && err != nil {
+				t.Fatalf("%s: error: %s", tt.name, err.Error())
+			}
+			if !tt.ok && !IsUnauthorized(err) {
+				t.Errorf("%s: expected unauthorized, got %v", tt.name, err)
+			}
+		}
+	}
+	// The access token is reused.
+	if n := srv.TokensIssued(); n != 1 {
+		t.Errorf("expected 1 token, got %d", n)
+	}
+}
+
+func TestBitbucketRepo(t *testing.T) {
+	srv, repo := newTestServer(t)
+	r := &BitbucketRepo{Client: &Client{BaseURL: srv.BaseURL()}, Workspace: "ws", RepoSlug: "repo"}
+	ctx := context.Background()
+
+	tags, err := r.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(tags) != 2 || tags[0].CommitID != repo.Commits[0].ID {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	data, err := r.GetContent(ctx, "comp", "1.0.0", "conf/env.yaml")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "env: test\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+	files, err := r.ListFiles(ctx, "comp", "1.0.0", "")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(files) != 2 || files[0].Name != "app.yaml" || files[1].Name != "conf" {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestBitbucketRepoHooks(t *testing.T) {
+	srv, repo := newTestServer(t)
+	var ats []string
+	r := &BitbucketRepo{
+		Client:    &Client{BaseURL: srv.BaseURL()},
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		Hooks: &bbclient.Hooks{
+			Before: func(ctx context.Context, call *bbclient.Call) (context.Context, error) {
+				if call.Version == "latest" {
+					call.At = repo.Commits[1].ID
+				}
+				return ctx, nil
+			},
+			After: func(ctx context.Context, call *bbclient.Call, err error) {
+				ats = append(ats, call.At)
+			},
+		},
+	}
+	tests := []struct{ component, version, file, want string }{
+		{"comp", "1.0.0", "app.yaml", "name: app\n"},
+		{"", "latest", "README.md", "# updated\n"},
+	}
+	for _, tt := range tests {
+		data, err := r.GetContent(context.Background(), tt.component, tt.version, tt.file)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.version, tt.want, data)
+		}
+	}
+	if len(ats) != 2 || ats[0] != repo.Commits[0].ID || ats[1] != repo.Commits[1].ID {
+		t.Errorf("unexpected refs %q", ats)
+	}
+}
+
+func TestCommitBuilder(t *testing.T) {
+	srv, repo := newTestServer(t)
+	r := &BitbucketRepo{Client: &Client{BaseURL: srv.BaseURL()}, Workspace: "ws", RepoSlug: "repo"}
+	ctx := context.Background()
+
+	commit, err := r.NewCommitBuilder("main", "nothing").Commit(ctx)
+	if err != nil || commit != nil {
+		t.Fatalf("expected no commit, got %v, %v", commit, err)
+	}
+
+	b := r.NewCommitBuilder("main", "update app").
+		Put("comp/app.yaml", []byte("name: app2\n")).
+		Put("comp/new.yaml", []byte("new\n")).
+		Delete("README.md")
+	commit, err = b.Commit(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	head := repo.Resolve("main")
+	if commit.Hash != head.ID || commit.Message != "update app" {
+		t.Errorf("unexpected commit %v", commit)
+	}
+	if string(head.Files["comp/app.yaml"].Data) != "name: app2\n" || string(head.Files["comp/new.yaml"].Data) != "new\n" {
+		t.Errorf("unexpected files %v", slices.Sorted(maps.Keys(head.Files)))
+	}
+	if _, ok := head.Files["README.md"]; ok {
+		t.Errorf("README.md is not deleted")
+	}
+
+	// A commit on a parent that is not the head is refused.
+	_, err = r.Client.CommitFiles(ctx, &CommitFilesCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		Branch:    "main",
+		Parent:    repo.Commits[0].ID,
+		Files:     map[string][]byte{"comp/app.yaml": []byte("stale\n")},
+	})
+	if !IsConflict(err) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	if repo.Resolve("main") != head {
+		t.Errorf("the branch moved")
+	}
+}
+
+func TestDeleteFile(t *testing.T) {
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL()}
+	ctx := context.Background()
+
+	hash, err := c.DeleteFile(ctx, &DeleteFileCommand{
+		Workspace: "ws",
+		RepoSlug:  "repo",
+		FilePath:  "README.md",
+		Message:   "remove readme",
+		Parent:    repo.Commits[1].ID,
+	})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	head := repo.Resolve("main")
+	if hash != head.ID || head.Message != "remove readme" {
+		t.Errorf("unexpected commit %s", hash)
+	}
+	if _, ok := head.Files["README.md"]; ok {
+		t.Errorf("README.md is not deleted")
+	}
+
+	tests := []struct {
+		name string
+		cmd  *DeleteFileCommand
+		want func(error) bool
+	}{
+		{"stale parent", &DeleteFileCommand{Workspace: "ws", RepoSlug: "repo", FilePath: "README.md", Parent: repo.Commits[1].ID}, IsConflict},
+		{"missing file", &DeleteFileCommand{Workspace: "ws", RepoSlug: "repo", FilePath: "README.md"}, IsNotFound},
+		{"missing path", &DeleteFileCommand{Workspace: "ws", RepoSlug: "repo"}, func(err error) bool { return err != nil }},
+	}
+	for _, tt := range tests {
+		if _, err := c.DeleteFile(ctx, tt.cmd); !tt.want(err) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+	if repo.Resolve("main") != head {
+		t.Errorf("the branch moved")
+	}
+}
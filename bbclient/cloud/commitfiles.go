@@ -0,0 +1,204 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// CommitFilesCommand commits the changes of several files on a branch in a
+// single commit, with the form of the src endpoint.
+type CommitFilesCommand struct {
+	Workspace string
+	RepoSlug  string
+	// Branch is the branch to commit on, empty for the main branch.
+	Branch  string
+	Message string
+	// Parent is the commit the changes are based on, optional. The api
+	// refuses the commit with 409 Conflict when it is not the head of the
+	// branch, see IsConflict.
+	Parent string
+	// Files are the contents of the added and updated files by path.
+	Files map[string][]byte
+	// Deletes are the paths of the deleted files.
+	Deletes []string
+}
+
+func (c *CommitFilesCommand) Validate() error {
+	errs := []error{
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+	}
+	if len(c.Files)+len(c.Deletes) == 0 {
+		errs = append(errs, errors.New("Files or Deletes is missing"))
+	}
+	for p := range c.Files {
+		errs = append(errs, required("Files path", p), validatePath("Files path", p))
+	}
+	for _, p := range c.Deletes {
+		errs = append(errs, required("Deletes path", p), validatePath("Deletes path", p))
+		if _, ok := c.Files[p]; ok {
+			errs = append(errs, fmt.Errorf("%s is in Files and in Deletes", p))
+		}
+	}
+	if c.Parent != "" {
+		errs = append(errs, validateCommit("Parent", c.Parent))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CommitFilesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "src")
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"message", c.Message},
+		{"branch", refName(c.Branch)},
+		{"parents", c.Parent},
+	}
+	for _, p := range c.Deletes {
+		fields = append(fields, [2]string{"files", p})
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return nil, err
+		}
+	}
+	// The form field of a file is its path.
+	for _, p := range slices.Sorted(maps.Keys(c.Files)) {
+		fw, err := mw.CreateFormFile(p, path.Base(p))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(c.Files[p]); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *CommitFilesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "CommitFiles"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("branch", c.Branch),
+		slog.String("parent", c.Parent),
+		slog.Int("files", len(c.Files)),
+		slog.Int("deletes", len(c.Deletes)),
+	)
+}
+
+// CommitFiles commits the changes of the command and returns the hash of
+// the new commit.
+func (c *Client) CommitFiles(ctx context.Context, cmd *CommitFilesCommand) (string, error) {
+	resp, err := doCommand(ctx, c, cmd)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return locationCommit(resp)
+}
+
+// locationCommit returns the hash of the commit of a response of the src
+// endpoint, the Location of the response is the url of the commit.
+func locationCommit(resp *http.Response) (string, error) {
+	loc := resp.Header.Get("Location")
+	i := strings.LastIndex(loc, "/commit/")
+	if i < 0 {
+		return "", fmt.Errorf("no commit in the location %q of the response", loc)
+	}
+	return loc[i+len("/commit/"):], nil
+}
+
+// CommitBuilder stages the changes of several files on a branch and
+// commits them in a single commit. Create it with BitbucketRepo.NewCommitBuilder.
+type CommitBuilder struct {
+	repo    *BitbucketRepo
+	branch  string
+	message string
+	puts    map[string][]byte
+	deletes map[string]bool
+}
+
+// NewCommitBuilder returns a CommitBuilder for the branch, the message is
+// the message of the commit.
+func (r *BitbucketRepo) NewCommitBuilder(branch, message string) *CommitBuilder {
+	return &CommitBuilder{
+		repo:    r,
+		branch:  branch,
+		message: message,
+		puts:    map[string][]byte{},
+		deletes: map[string]bool{},
+	}
+}
+
+// Put stages the content of the file, relative to the root of the
+// repository, as an add or an update. It replaces an earlier change of the file.
+func (b *CommitBuilder) Put(filePath string, content []byte) *CommitBuilder {
+	delete(b.deletes, filePath)
+	b.puts[filePath] = content
+	return b
+}
+
+// Delete stages the delete of the file. It replaces an earlier change of the file.
+func (b *CommitBuilder) Delete(filePath string) *CommitBuilder {
+	delete(b.puts, filePath)
+	b.deletes[filePath] = true
+	return b
+}
+
+// Len returns the number of staged changes.
+func (b *CommitBuilder) Len() int {
+	return len(b.puts) + len(b.deletes)
+}
+
+// Commit commits the staged changes on the head of the branch when Commit
+// starts and returns the commit, nil when nothing is staged. It fails with
+// a conflict, see IsConflict, when the branch moved in the meantime.
+func (b *CommitBuilder) Commit(ctx context.Context) (*Commit, error) {
+	if b.Len() == 0 {
+		return nil, nil
+	}
+	r := b.repo
+	head, err := r.Client.ResolveRef(ctx, r.Workspace, r.RepoSlug, b.branch)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := r.Client.CommitFiles(ctx, &CommitFilesCommand{
+		Workspace: r.Workspace,
+		RepoSlug:  r.RepoSlug,
+		Branch:    b.branch,
+		Message:   b.message,
+		Parent:    head,
+		Files:     b.puts,
+		Deletes:   slices.Sorted(maps.Keys(b.deletes)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.Client.GetCommit(ctx, &GetCommitCommand{Workspace: r.Workspace, RepoSlug: r.RepoSlug, Commit: hash})
+}
@@ -0,0 +1,71 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// DeleteFileCommand deletes a file on a branch in a new commit, with the
+// form of the src endpoint.
+type DeleteFileCommand struct {
+	Workspace string
+	RepoSlug  string
+	FilePath  string
+	// Branch is the branch to commit on, empty for the main branch.
+	Branch  string
+	Message string
+	// Parent is the commit the delete is based on, optional. The api
+	// refuses the commit with 409 Conflict when it is not the head of the
+	// branch, see IsConflict.
+	Parent string
+}
+
+func (c *DeleteFileCommand) Validate() error {
+	errs := []error{
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+	}
+	if c.Parent != "" {
+		errs = append(errs, validateCommit("Parent", c.Parent))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *DeleteFileCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	cmd := &CommitFilesCommand{
+		Workspace: c.Workspace,
+		RepoSlug:  c.RepoSlug,
+		Branch:    c.Branch,
+		Message:   c.Message,
+		Parent:    c.Parent,
+		Deletes:   []string{c.FilePath},
+	}
+	return cmd.NewRequestWithContext(ctx, baseURL)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *DeleteFileCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "DeleteFile"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("branch", c.Branch),
+		slog.String("parent", c.Parent),
+	)
+}
+
+// DeleteFile deletes the file of the command and returns the hash of the
+// new commit.
+func (c *Client) DeleteFile(ctx context.Context, cmd *DeleteFileCommand) (string, error) {
+	resp, err := doCommand(ctx, c, cmd)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return locationCommit(resp)
+}
@@ -1,4 +1,11 @@
 /*
-cloud does not work yet, do not use
+Package cloud is a client for the Bitbucket Cloud 2.0 api.
+
+It lists and reads files, tags and commits, and NewFS serves a repository
+as fs.FS. The client authenticates with an app password, an access token
+or the client credentials of an OAuth consumer.
+
+The src endpoints of the api take a commit, use Client.ResolveRef to get the
+commit of a branch or tag with a slash in the name.
 */
-package cloud
\ No newline at end of file
+package cloud
@@ -0,0 +1,73 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"iter"
+	"time"
+)
+
+// FilesIterator is an iterator for the files in a directory in the repository.
+type FilesIterator struct {
+	client      *Client
+	lastCommand *GetFilesCommand
+	lastResult  *GetFilesResponse
+	index       int
+	lastError   error
+	ctx         context.Context
+	// page is the index of lastResult.
+	page int
+}
+
+// Next returns the next FileInfo in the directory, or nil if all entries have been read.
+func (i *FilesIterator) Next() *FileInfo {
+	if i.lastError != nil {
+		return nil
+	}
+	for i.index >= len(i.lastResult.Files) {
+		if i.lastResult.IsLastPage {
+			i.lastError = io.EOF
+			return nil
+		}
+		// Get next page.
+		if err := i.loadPage(); err != nil {
+			i.lastError = err
+			return nil
+		}
+		i.index = 0
+	}
+	res := i.lastResult.Files[i.index]
+	i.index++
+	return res
+}
+
+// Err returns the last occured error.
+func (i *FilesIterator) Err() error {
+	return i.lastError
+}
+
+// loadPage loads the next page from the directory.
+func (i *FilesIterator) loadPage() error {
+	i.lastCommand.Page = i.lastResult.NextPage
+	start := time.Now()
+	res, err := i.client.GetFiles(i.ctx, i.lastCommand)
+	if err != nil {
+		return err
+	}
+	i.lastResult = res
+	i.page++
+	page := *i.lastCommand
+	i.client.observePage(i.ctx, &page, i.page, len(res.Files), start)
+	return nil
+}
+
+// Files returns a new iter iterator
+func (i *FilesIterator) Files() iter.Seq[*FileInfo] {
+	return func(yield func(v *FileInfo) bool) {
+		for f := i.Next(); f != nil; f = i.Next() {
+			if !yield(f) {
+				return
+			}
+		}
+	}
+}
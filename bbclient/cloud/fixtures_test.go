@@ -0,0 +1,105 @@
+package cloud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient"
+	"github.com/myhops/bbfs/internal/fixtures"
+)
+
+func TestParseFixtures(t *testing.T) {
+	// Parse the dates like the parsers, a zero offset may get time.Local.
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		return d
+	}
+	head := &Commit{
+		Hash:    "8d51122def5632836d1cb1026e879069e10a1e13",
+		Date:    date("2019-01-29T00:00:07+00:00"),
+		Message: "Add the docs\n",
+		Author:  "Charlie <charlie@example.com>",
+		Parents: []string{"abcdef0123abcdef4567abcdef8987abcdef6543"},
+	}
+	first := &Commit{
+		Hash:    "abcdef0123abcdef4567abcdef8987abcdef6543",
+		Date:    date("2019-01-28T00:00:07+00:00"),
+		Message: "Initial commit\n",
+		Author:  "Charlie <charlie@example.com>",
+	}
+	tests := []struct {
+		file  string
+		parse func(data []byte) (any, error)
+		want  any
+	}{
+		{
+			file:  "cloud/2.0/src-dir.json",
+			parse: func(data []byte) (any, error) { return (&GetFilesCommand{}).ParseResponse(data) },
+			want: &GetFilesResponse{
+				Files: []*FileInfo{
+					{Name: "README.md", Path: "README.md", Size: 9, Type: bbclient.TypeFile},
+					{Name: "docs", Path: "docs", Type: bbclient.TypeDirectory},
+				},
+				PageLen:  10,
+				NextPage: "Mnx8ZG9jcw==",
+			},
+		},
+		{
+			file:  "cloud/2.0/src-meta.json",
+			parse: func(data []byte) (any, error) { return (&GetFileMetaCommand{}).ParseResponse(data) },
+			want:  &FileInfo{Name: "README.md", Path: "README.md", Size: 9, Type: bbclient.TypeFile},
+		},
+		{
+			file:  "cloud/2.0/tags.json",
+			parse: func(data []byte) (any, error) { return (&GetTagsCommand{}).ParseResponse(data) },
+			want: &GetTagsResponse{
+				Tags:       []*Tag{{Name: "v1.0.0", CommitID: head.Hash, Date: head.Date, Message: head.Message}},
+				Size:       1,
+				PageLen:    10,
+				IsLastPage: true,
+			},
+		},
+		{
+			file:  "cloud/2.0/commits.json",
+			parse: func(data []byte) (any, error) { return (&GetCommitsCommand{}).ParseResponse(data) },
+			want:  &GetCommitsResponse{Commits: []*Commit{head, first}, PageLen: 30, NextPage: "2"},
+		},
+		{
+			file:  "cloud/2.0/commit.json",
+			parse: func(data []byte) (any, error) { return (&GetCommitCommand{}).ParseResponse(data) },
+			want:  head,
+		},
+		{
+			file:  "cloud/2.0/repositories.json",
+			parse: func(data []byte) (any, error) { return (&GetReposCommand{}).ParseResponse(data) },
+			want: &GetReposResponse{
+				Repos: []*Repository{{
+					Slug:       "repo",
+					Name:       "repo",
+					FullName:   "team/repo",
+					Workspace:  "team",
+					MainBranch: "main",
+					IsPrivate:  true,
+				}},
+				Size:       1,
+				PageLen:    10,
+				IsLastPage: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := tt.parse(fixtures.Read(tt.file))
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewFS returns a read only file system for the repository at the ref.
+//
+// The ref is a branch, tag or commit, the empty ref is the main branch.
+// It is resolved to a commit by the first call, so the FS serves the same
+// commit for its lifetime.
+func NewFS(client *Client, workspace, repoSlug, ref string) fs.FS {
+	return &cloudFS{
+		client:    client,
+		workspace: workspace,
+		repoSlug:  repoSlug,
+		ref:       ref,
+		resolve:   new(sync.Once),
+	}
+}
+
+// cloudFS implements fs.FS on Bitbucket Cloud.
+type cloudFS struct {
+	client    *Client
+	workspace string
+	repoSlug  string
+	ref       string
+
+	resolve *sync.Once
+	commit  string
+	err     error
+}
+
+var (
+	_ fs.ReadDirFS  = &cloudFS{}
+	_ fs.ReadFileFS = &cloudFS{}
+	_ fs.StatFS     = &cloudFS{}
+)
+
+// resolveCommit returns the hash of the commit served by the FS.
+func (c *cloudFS) resolveCommit() (string, error) {
+	c.resolve.Do(func() {
+		c.commit, c.err = c.client.ResolveRef(context.Background(), c.workspace, c.repoSlug, c.ref)
+	})
+	return c.commit, c.err
+}
+
+// pathError returns a PathError for err, not found errors become fs.ErrNotExist.
+func pathError(op, name string, err error) error {
+	if IsNotFound(err) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// stat returns the info for the file or directory.
+func (c *cloudFS) stat(op, name string) (*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	commit, err := c.resolveCommit()
+	if err != nil {
+		return nil, pathError(op, name, err)
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+	fi, err := c.client.GetFileMeta(context.Background(), &GetFileMetaCommand{
+		Workspace: c.workspace,
+		RepoSlug:  c.repoSlug,
+		At:        commit,
+		FilePath:  name,
+	})
+	if err != nil {
+		return nil, pathError(op, name, err)
+	}
+	return newFileInfo(fi), nil
+}
+
+// Open opens the file in the repository.
+func (c *cloudFS) Open(name string) (fs.File, error) {
+	fi, err := c.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{fsys: c, name: name, path: name, fi: fi}, nil
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+func (c *cloudFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := f.(*file).ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (c *cloudFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	commit, err := c.resolveCommit()
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	data, err := c.client.GetFileContent(context.Background(), &GetFileContentCommand{
+		Workspace: c.workspace,
+		RepoSlug:  c.repoSlug,
+		At:        commit,
+		FilePath:  name,
+	})
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	return data, nil
+}
+
+// Stat returns a FileInfo for the named file.
+func (c *cloudFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := c.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
+
+// file implements fs.File and fs.ReadDirFile.
+type file struct {
+	fsys *cloudFS
+	// name is the name used to open the file, path the path in the repository.
+	name string
+	path string
+	fi   *fileInfo
+
+	data    io.ReadCloser
+	dirIter *FilesIterator
+	lastErr error
+}
+
+// Read reads from the file, the first call opens the file on the server.
+func (f *file) Read(b []byte) (int, error) {
+	if f.fi.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	if f.data == nil {
+		r, err := f.fsys.client.OpenRawFile(context.Background(), &OpenRawFileCommand{
+			Workspace: f.fsys.workspace,
+			RepoSlug:  f.fsys.repoSlug,
+			At:        f.fsys.commit,
+			FilePath:  f.path,
+		})
+		if err != nil {
+			return 0, pathError("read", f.name, err)
+		}
+		f.data = r
+	}
+	return f.data.Read(b)
+}
+
+// Stat returns a FileInfo.
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *file) Close() error {
+	if f.data == nil {
+		return nil
+	}
+	tmp := f.data
+	f.data = nil
+	return tmp.Close()
+}
+
+// ReadDir returns the entries of the directory.
+func (f *file) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.fi.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if f.lastErr != nil {
+		return nil, f.lastErr
+	}
+	if f.dirIter == nil {
+		dir := f.path
+		if dir == "." {
+			dir = ""
+		}
+		iter, err := f.fsys.client.GetFilesIterator(context.Background(), &GetFilesCommand{
+			Workspace: f.fsys.workspace,
+			RepoSlug:  f.fsys.repoSlug,
+			At:        f.fsys.commit,
+			FilePath:  dir,
+			PageLen:   MaxPageLen,
+		})
+		if err != nil {
+			f.lastErr = pathError("readdir", f.name, err)
+			return nil, f.lastErr
+		}
+		f.dirIter = iter
+	}
+
+	res := []fs.DirEntry{}
+	for n <= 0 || len(res) < n {
+		ff := f.dirIter.Next()
+		if ff == nil {
+			if err := f.dirIter.Err(); !errors.Is(err, io.EOF) {
+				f.lastErr = pathError("readdir", f.name, err)
+				return res, f.lastErr
+			}
+			break
+		}
+		res = append(res, newFileInfo(ff))
+	}
+	// Only signal the end of the directory when asked for a limited number of entries.
+	if n > 0 && len(res) == 0 {
+		return res, io.EOF
+	}
+	return res, nil
+}
+
+// fileInfo implements fs.FileInfo and fs.DirEntry.
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+	exec bool
+}
+
+func newFileInfo(fi *FileInfo) *fileInfo {
+	return &fileInfo{name: fi.Name, size: fi.Size, dir: fi.IsDir(), exec: fi.Executable}
+}
+
+func (fi *fileInfo) Name() string { return fi.name }
+func (fi *fileInfo) Size() int64  { return fi.size }
+
+// Mode returns the mode in the convention of bbfs: the permission bits are
+// 0, except the executable bits of an executable file.
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir
+	}
+	if fi.exec {
+		return 0o111
+	}
+	return 0
+}
+func (fi *fileInfo) ModTime() time.Time         { return time.Time{} }
+func (fi *fileInfo) IsDir() bool                { return fi.dir }
+func (fi *fileInfo) Sys() any                   { return nil }
+func (fi *fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
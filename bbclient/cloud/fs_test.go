@@ -0,0 +1,85 @@
+package cloud
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	srv, repo := newTestServer(t)
+	c := &Client{BaseURL: srv.BaseURL()}
+
+	fsys := NewFS(c, "ws", "repo", "comp/1.0.0")
+	if err := fstest.TestFS(fsys, "README.md", "comp/app.yaml", "comp/conf/env.yaml", "many/file4.txt"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	// The main branch has the updated readme.
+	data, err := fs.ReadFile(NewFS(c, "ws", "repo", ""), "README.md")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "# updated\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	// The FS keeps serving the commit the ref resolved to.
+	fsys = NewFS(c, "ws", "repo", "main")
+	if _, err := fs.Stat(fsys, "."); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	srv.Update(func() {
+		repo.Commit("main", "remove readme", fstest.MapFS{})
+	})
+	if _, err := fs.Stat(fsys, "README.md"); err != nil {
+		t.Errorf("expected the readme at the resolved commit, got %v", err)
+	}
+
+	if _, err := fs.Stat(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+	if _, err := fs.ReadFile(NewFS(c, "ws", "repo", "missing"), "README.md"); err == nil {
+		t.Errorf("expected an error for a missing ref")
+	}
+}
+
+func TestFSExecutable(t *testing.T) {
+	srv, repo := newTestServer(t)
+	srv.Update(func() {
+		repo.Commit("main", "add script", fstest.MapFS{
+			"README.md":  {Data: []byte("# readme\n")},
+			"bin/run.sh": {Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+		})
+	})
+	fsys := NewFS(&Client{BaseURL: srv.BaseURL()}, "ws", "repo", "main")
+
+	entries, err := fs.ReadDir(fsys, "bin")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	listed, err := entries[0].Info()
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if listed.Mode() != 0o111 {
+		t.Errorf("listing: expected mode %s, got %s", fs.FileMode(0o111), listed.Mode())
+	}
+	tests := []struct {
+		name string
+		want fs.FileMode
+	}{
+		{"bin/run.sh", 0o111},
+		{"README.md", 0},
+	}
+	for _, tt := range tests {
+		fi, err := fs.Stat(fsys, tt.name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if fi.Mode() != tt.want {
+			t.Errorf("%s: expected mode %s, got %s", tt.name, tt.want, fi.Mode())
+		}
+	}
+}
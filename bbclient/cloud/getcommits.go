@@ -0,0 +1,176 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Commit is a commit in the repository.
+type Commit struct {
+	Hash    string
+	Date    time.Time
+	Message string
+	// Author is the raw author, e.g. "Name <email>".
+	Author  string
+	Parents []string
+}
+
+// commitJSON is a commit in the responses of the api.
+type commitJSON struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+	Author  struct {
+		Raw string `json:"raw"`
+	} `json:"author"`
+	Parents []struct {
+		Hash string `json:"hash"`
+	} `json:"parents"`
+}
+
+func (c *commitJSON) commit() *Commit {
+	res := &Commit{
+		Hash:    c.Hash,
+		Date:    c.Date,
+		Message: c.Message,
+		Author:  c.Author.Raw,
+	}
+	for _, p := range c.Parents {
+		res.Parents = append(res.Parents, p.Hash)
+	}
+	return res
+}
+
+// GetCommitsCommand lists the commits reachable from Include and not from Exclude,
+// newest first.
+type GetCommitsCommand struct {
+	Workspace string
+	RepoSlug  string
+	// Include is a branch, tag or commit, empty for the main branch.
+	Include string
+	// Exclude is a branch, tag or commit.
+	Exclude string
+	// Path limits the commits to the ones that change the path.
+	Path    string
+	Page    string
+	PageLen int
+}
+
+type GetCommitsResponse struct {
+	Commits    []*Commit
+	PageLen    int
+	NextPage   string
+	IsLastPage bool
+}
+
+func (c *GetCommitsCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		validatePath("Path", c.Path),
+		validatePageLen(c.PageLen),
+	)
+}
+
+func (c *GetCommitsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "commits")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "include", refName(c.Include))
+	addValue(vals, "exclude", refName(c.Exclude))
+	addValue(vals, "path", c.Path)
+	addPaging(vals, c.Page, c.PageLen)
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetCommitsCommand) ParseResponse(data []byte) (*GetCommitsResponse, error) {
+	var r struct {
+		pageJSON
+		Values []*commitJSON `json:"values"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	resp := &GetCommitsResponse{
+		PageLen:    r.PageLen,
+		NextPage:   r.nextPage(),
+		IsLastPage: r.nextPage() == "",
+	}
+	for _, v := range r.Values {
+		resp.Commits = append(resp.Commits, v.commit())
+	}
+	return resp, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetCommitsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetCommits"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("include", c.Include),
+		slog.String("exclude", c.Exclude),
+		slog.String("path", c.Path),
+		slog.String("page", c.Page),
+		slog.Int("pagelen", c.PageLen),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetCommitsResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("commits", len(r.Commits)),
+		slog.String("nextPage", r.NextPage),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
+
+// GetCommitCommand gets a single commit.
+type GetCommitCommand struct {
+	Workspace string
+	RepoSlug  string
+	// Commit is the (abbreviated) hash of the commit.
+	Commit string
+}
+
+func (c *GetCommitCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		validateCommit("Commit", c.Commit),
+	)
+}
+
+func (c *GetCommitCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "commit", c.Commit)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetCommitCommand) ParseResponse(data []byte) (*Commit, error) {
+	var r commitJSON
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r.commit(), nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetCommitCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetCommit"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("commit", c.Commit),
+	)
+}
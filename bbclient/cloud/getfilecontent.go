@@ -0,0 +1,54 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// GetFileContentCommand gets the content of a file.
+type GetFileContentCommand struct {
+	Workspace string
+	RepoSlug  string
+	// At is the commit, a branch or tag name without slashes works too.
+	At       string
+	FilePath string
+}
+
+func (c *GetFileContentCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "src", c.At, c.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetFileContentCommand) accept() string {
+	return acceptRaw
+}
+
+func (c *GetFileContentCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		validateCommit("At", c.At),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+	)
+}
+
+func (c *GetFileContentCommand) ParseResponse(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetFileContentCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFileContent"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("at", c.At),
+		slog.String("path", c.FilePath),
+	)
+}
@@ -0,0 +1,193 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+
+	"github.com/myhops/bbfs/bbclient"
+)
+
+// Types of the entries in the src responses.
+const (
+	srcTypeFile      = "commit_file"
+	srcTypeDirectory = "commit_directory"
+)
+
+// FileInfo is a file or directory in the repository.
+type FileInfo struct {
+	Name string `json:"name"`
+	// Path is the path of the entry in the repository.
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Type is bbclient.TypeFile or bbclient.TypeDirectory.
+	Type string `json:"type"`
+	// Executable is true for a file with the executable bit.
+	Executable bool `json:"executable,omitempty"`
+}
+
+// IsDir returns true for a directory.
+func (f *FileInfo) IsDir() bool {
+	return f.Type == bbclient.TypeDirectory
+}
+
+// fileJSON is an entry in the src responses.
+type fileJSON struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+	// Attributes of a file, like executable, link or lfs.
+	Attributes []string `json:"attributes"`
+}
+
+func (f *fileJSON) fileInfo() *FileInfo {
+	typ := bbclient.TypeFile
+	if f.Type == srcTypeDirectory {
+		typ = bbclient.TypeDirectory
+	}
+	return &FileInfo{
+		Name:       path.Base(f.Path),
+		Path:       f.Path,
+		Size:       f.Size,
+		Type:       typ,
+		Executable: slices.Contains(f.Attributes, "executable"),
+	}
+}
+
+// GetFilesCommand lists a page of the entries of a directory.
+type GetFilesCommand struct {
+	Workspace string
+	RepoSlug  string
+	// At is the commit, a branch or tag name without slashes works too.
+	At       string
+	FilePath string
+	// Page is the NextPage of the previous response, empty for the first page.
+	Page    string
+	PageLen int
+}
+
+type GetFilesResponse struct {
+	Files []*FileInfo
+	// Size is the total number of entries.
+	Size    int
+	PageLen int
+	// NextPage is the Page for the next request.
+	NextPage   string
+	IsLastPage bool
+}
+
+func (c *GetFilesCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		validateCommit("At", c.At),
+		validatePath("FilePath", c.FilePath),
+		validatePageLen(c.PageLen),
+	)
+}
+
+func (c *GetFilesCommand) ParseResponse(data []byte) (*GetFilesResponse, error) {
+	var r struct {
+		pageJSON
+		Values []*fileJSON `json:"values"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	resp := &GetFilesResponse{
+		Size:       r.Size,
+		PageLen:    r.PageLen,
+		NextPage:   r.nextPage(),
+		IsLastPage: r.nextPage() == "",
+	}
+	for _, v := range r.Values {
+		resp.Files = append(resp.Files, v.fileInfo())
+	}
+	return resp, nil
+}
+
+func (c *GetFilesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "src", c.At, c.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	// The trailing slash asks for the listing of a directory.
+	u.Path += "/"
+	vals := url.Values{}
+	addPaging(vals, c.Page, c.PageLen)
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetFilesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFiles"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("at", c.At),
+		slog.String("path", c.FilePath),
+		slog.String("page", c.Page),
+		slog.Int("pagelen", c.PageLen),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetFilesResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("files", len(r.Files)),
+		slog.String("nextPage", r.NextPage),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
+
+// GetFileMetaCommand gets the type and size of a file or directory.
+type GetFileMetaCommand struct {
+	Workspace string
+	RepoSlug  string
+	// At is the commit, a branch or tag name without slashes works too.
+	At       string
+	FilePath string
+}
+
+func (c *GetFileMetaCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		validateCommit("At", c.At),
+		validatePath("FilePath", c.FilePath),
+	)
+}
+
+func (c *GetFileMetaCommand) ParseResponse(data []byte) (*FileInfo, error) {
+	var r fileJSON
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r.fileInfo(), nil
+}
+
+func (c *GetFileMetaCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "src", c.At, c.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = url.Values{"format": {"meta"}}.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetFileMetaCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFileMeta"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("at", c.At),
+		slog.String("path", c.FilePath),
+	)
+}
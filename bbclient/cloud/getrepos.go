@@ -0,0 +1,165 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// Repository is a repository in a workspace.
+type Repository struct {
+	Slug      string
+	Name      string
+	FullName  string
+	Workspace string
+	// MainBranch is the name of the main branch.
+	MainBranch string
+	IsPrivate  bool
+}
+
+// repositoryJSON is a repository in the responses of the api.
+type repositoryJSON struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	FullName  string `json:"full_name"`
+	IsPrivate bool   `json:"is_private"`
+	Workspace struct {
+		Slug string `json:"slug"`
+	} `json:"workspace"`
+	MainBranch struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+func (r *repositoryJSON) repository() *Repository {
+	return &Repository{
+		Slug:       r.Slug,
+		Name:       r.Name,
+		FullName:   r.FullName,
+		Workspace:  r.Workspace.Slug,
+		MainBranch: r.MainBranch.Name,
+		IsPrivate:  r.IsPrivate,
+	}
+}
+
+// GetRepoCommand gets a single repository.
+type GetRepoCommand struct {
+	Workspace string
+	RepoSlug  string
+}
+
+func (c *GetRepoCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+	)
+}
+
+func (c *GetRepoCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetRepoCommand) ParseResponse(data []byte) (*Repository, error) {
+	var r repositoryJSON
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r.repository(), nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetRepoCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetRepo"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+	)
+}
+
+// GetReposCommand lists a page of the repositories in a workspace.
+type GetReposCommand struct {
+	Workspace string
+	// Query is a query in the Bitbucket query language, e.g. NameQuery(name).
+	Query string
+	// Sort is the field to sort on, prefix it with - for descending order.
+	Sort    string
+	Page    string
+	PageLen int
+}
+
+type GetReposResponse struct {
+	Repos []*Repository
+	// Size is the total number of repositories.
+	Size       int
+	PageLen    int
+	NextPage   string
+	IsLastPage bool
+}
+
+func (c *GetReposCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		validatePageLen(c.PageLen),
+	)
+}
+
+func (c *GetReposCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := apiURL(baseURL, "repositories", c.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "q", c.Query)
+	addValue(vals, "sort", c.Sort)
+	addPaging(vals, c.Page, c.PageLen)
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetReposCommand) ParseResponse(data []byte) (*GetReposResponse, error) {
+	var r struct {
+		pageJSON
+		Values []*repositoryJSON `json:"values"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	resp := &GetReposResponse{
+		Size:       r.Size,
+		PageLen:    r.PageLen,
+		NextPage:   r.nextPage(),
+		IsLastPage: r.nextPage() == "",
+	}
+	for _, v := range r.Values {
+		resp.Repos = append(resp.Repos, v.repository())
+	}
+	return resp, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetReposCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetRepos"),
+		slog.String("workspace", c.Workspace),
+		slog.String("query", c.Query),
+		slog.String("sort", c.Sort),
+		slog.String("page", c.Page),
+		slog.Int("pagelen", c.PageLen),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetReposResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("repos", len(r.Repos)),
+		slog.String("nextPage", r.NextPage),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
@@ -0,0 +1,121 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Tag is a tag in the repository.
+type Tag struct {
+	Name string
+	// CommitID is the hash of the tagged commit.
+	CommitID string
+	// Date and Message are those of the tagged commit.
+	Date    time.Time
+	Message string
+}
+
+// GetTagsCommand lists a page of the tags in the repository.
+type GetTagsCommand struct {
+	Workspace string
+	RepoSlug  string
+	// Query is a query in the Bitbucket query language, e.g. NameQuery(name).
+	Query string
+	// Sort is the field to sort on, prefix it with - for descending order.
+	// E.g. "-target.date" for the most recent tags first.
+	Sort    string
+	Page    string
+	PageLen int
+}
+
+// NameQuery returns the query for the tag or branch with the name.
+func NameQuery(name string) string {
+	return fmt.Sprintf("name = %s", strconv.Quote(name))
+}
+
+type GetTagsResponse struct {
+	Tags []*Tag
+	// Size is the total number of tags.
+	Size       int
+	PageLen    int
+	NextPage   string
+	IsLastPage bool
+}
+
+func (c *GetTagsCommand) Validate() error {
+	return errors.Join(
+		required("Workspace", c.Workspace),
+		required("RepoSlug", c.RepoSlug),
+		validatePageLen(c.PageLen),
+	)
+}
+
+func (c *GetTagsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.Workspace, c.RepoSlug, "refs", "tags")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "q", c.Query)
+	addValue(vals, "sort", c.Sort)
+	addPaging(vals, c.Page, c.PageLen)
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetTagsCommand) ParseResponse(data []byte) (*GetTagsResponse, error) {
+	var r struct {
+		pageJSON
+		Values []struct {
+			Name   string     `json:"name"`
+			Target commitJSON `json:"target"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	resp := &GetTagsResponse{
+		Size:       r.Size,
+		PageLen:    r.PageLen,
+		NextPage:   r.nextPage(),
+		IsLastPage: r.nextPage() == "",
+	}
+	for _, v := range r.Values {
+		resp.Tags = append(resp.Tags, &Tag{
+			Name:     v.Name,
+			CommitID: v.Target.Hash,
+			Date:     v.Target.Date,
+			Message:  v.Target.Message,
+		})
+	}
+	return resp, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetTagsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetTags"),
+		slog.String("workspace", c.Workspace),
+		slog.String("repo", c.RepoSlug),
+		slog.String("query", c.Query),
+		slog.String("sort", c.Sort),
+		slog.String("page", c.Page),
+		slog.Int("pagelen", c.PageLen),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetTagsResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("tags", len(r.Tags)),
+		slog.String("nextPage", r.NextPage),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
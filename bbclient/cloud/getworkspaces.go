@@ -0,0 +1,96 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// Workspace is a workspace the user has access to.
+type Workspace struct {
+	Slug      string
+	Name      string
+	IsPrivate bool
+}
+
+// GetWorkspacesCommand lists a page of the workspaces of the user.
+type GetWorkspacesCommand struct {
+	// Query is a query in the Bitbucket query language.
+	Query   string
+	Page    string
+	PageLen int
+}
+
+type GetWorkspacesResponse struct {
+	Workspaces []*Workspace
+	// Size is the total number of workspaces.
+	Size       int
+	PageLen    int
+	NextPage   string
+	IsLastPage bool
+}
+
+func (c *GetWorkspacesCommand) Validate() error {
+	return validatePageLen(c.PageLen)
+}
+
+func (c *GetWorkspacesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := apiURL(baseURL, "workspaces")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "q", c.Query)
+	addPaging(vals, c.Page, c.PageLen)
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetWorkspacesCommand) ParseResponse(data []byte) (*GetWorkspacesResponse, error) {
+	var r struct {
+		pageJSON
+		Values []struct {
+			Slug      string `json:"slug"`
+			Name      string `json:"name"`
+			IsPrivate bool   `json:"is_private"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	resp := &GetWorkspacesResponse{
+		Size:       r.Size,
+		PageLen:    r.PageLen,
+		NextPage:   r.nextPage(),
+		IsLastPage: r.nextPage() == "",
+	}
+	for _, v := range r.Values {
+		resp.Workspaces = append(resp.Workspaces, &Workspace{
+			Slug:      v.Slug,
+			Name:      v.Name,
+			IsPrivate: v.IsPrivate,
+		})
+	}
+	return resp, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetWorkspacesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetWorkspaces"),
+		slog.String("query", c.Query),
+		slog.String("page", c.Page),
+		slog.Int("pagelen", c.PageLen),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetWorkspacesResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("workspaces", len(r.Workspaces)),
+		slog.String("nextPage", r.NextPage),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
@@ -0,0 +1,68 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/internal/buildinfo"
+)
+
+// oauthToken is an access token from the token endpoint.
+type oauthToken struct {
+	value   string
+	expires time.Time
+}
+
+// tokenExpiryMargin renews tokens this long before they expire.
+const tokenExpiryMargin = time.Minute
+
+// accessToken returns a valid access token for the OAuth consumer,
+// it requests a new token with the client credentials grant when needed.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token.value != "" && time.Now().Add(tokenExpiryMargin).Before(c.token.expires) {
+		return c.token.value, nil
+	}
+
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", buildinfo.UserAgent(c.UserAgent))
+	req.SetBasicAuth(c.OAuthClientID, c.OAuthClientSecret.Secret())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("getting access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return "", fmt.Errorf("getting access token: %w", err)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("getting access token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("getting access token: empty token")
+	}
+	c.token = oauthToken{
+		value:   body.AccessToken,
+		expires: time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}
+	return c.token.value, nil
+}
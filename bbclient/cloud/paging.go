@@ -0,0 +1,68 @@
+package cloud
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PageInfo is a page of a paged listing, see Client.OnPage.
+type PageInfo struct {
+	// Command is the command of the page, e.g. a *GetFilesCommand with the
+	// token of the page.
+	Command Command
+	// Index is the index of the page in the listing, 0 for the first page.
+	Index int
+	// Size is the number of values on the page.
+	Size int
+	// Elapsed is the time the request of the page took.
+	Elapsed time.Duration
+}
+
+// LogValue implements slog.LogValuer.
+func (p *PageInfo) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("command", p.Command),
+		slog.Int("index", p.Index),
+		slog.Int("size", p.Size),
+		slog.Duration("elapsed", p.Elapsed),
+	)
+}
+
+// observePage calls OnPage for the page of the command, which was fetched
+// since start.
+func (c *Client) observePage(ctx context.Context, cmd Command, index, size int, start time.Time) {
+	if c.OnPage == nil {
+		return
+	}
+	c.OnPage(ctx, &PageInfo{Command: cmd, Index: index, Size: size, Elapsed: time.Since(start)})
+}
+
+// pageJSON is the paging information in the responses of the api.
+type pageJSON struct {
+	Size    int    `json:"size"`
+	Page    int    `json:"page"`
+	PageLen int    `json:"pagelen"`
+	Next    string `json:"next"`
+}
+
+// nextPage returns the page parameter of the next url,
+// the empty string on the last page.
+func (p *pageJSON) nextPage() string {
+	if p.Next == "" {
+		return ""
+	}
+	u, err := url.Parse(p.Next)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("page")
+}
+
+// addPaging adds the page and pagelen parameters.
+func addPaging(vals url.Values, page string, pageLen int) {
+	addValue(vals, "page", page)
+	addValue(vals, "pagelen", strconv.Itoa(pageLen))
+}
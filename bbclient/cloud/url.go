@@ -0,0 +1,32 @@
+package cloud
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+)
+
+// repoURL returns the api url for the elements below the repository.
+//
+// The workspace, repo and elements are not escaped, the escaping is done when
+// the url is encoded. Elements may contain slashes.
+func repoURL(baseURL, workspace, repo string, elem ...string) (*url.URL, error) {
+	return apiURL(baseURL, append([]string{"repositories", workspace, repo}, elem...)...)
+}
+
+// apiURL returns the api url for the elements below the base url.
+func apiURL(baseURL string, elem ...string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base url: %w", err)
+	}
+	u.Path = path.Join(append([]string{u.Path}, elem...)...)
+	u.RawPath = ""
+	return u, nil
+}
+
+func addValue(v url.Values, name string, value string) {
+	if value != "" && value != "0" {
+		v.Add(name, value)
+	}
+}
@@ -0,0 +1,72 @@
+package cloud
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MaxPageLen is the maximum page length the api accepts.
+const MaxPageLen = 100
+
+// required returns an error if value is empty.
+func required(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is missing", name)
+	}
+	return nil
+}
+
+// validatePageLen checks the page length of a paged request.
+func validatePageLen(pageLen int) error {
+	if pageLen < 0 || pageLen > MaxPageLen {
+		return fmt.Errorf("PageLen must be between 0 and %d, got %d", MaxPageLen, pageLen)
+	}
+	return nil
+}
+
+// validatePath checks that p is a clean relative path.
+// The empty path denotes the root of the repository.
+func validatePath(name, p string) error {
+	if p == "" {
+		return nil
+	}
+	if strings.HasPrefix(p, "/") {
+		return fmt.Errorf("%s must be relative, got %q", name, p)
+	}
+	if path.Clean(p) != p || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return fmt.Errorf("%s is not a normalized path, got %q", name, p)
+	}
+	return nil
+}
+
+// validateCommit checks the commit of a src request.
+// The api can not tell a commit with slashes from the path that follows it.
+func validateCommit(name, commit string) error {
+	if err := required(name, commit); err != nil {
+		return err
+	}
+	if strings.Contains(commit, "/") {
+		return fmt.Errorf("%s must not contain a slash, resolve %q with ResolveRef", name, commit)
+	}
+	return nil
+}
+
+// refName returns the ref without the refs/heads/ or refs/tags/ prefix.
+func refName(ref string) string {
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	return strings.TrimPrefix(ref, "refs/tags/")
+}
+
+// isCommitHash returns true if s is a complete commit hash.
+func isCommitHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// NewWorkspaceFS returns a read only file system with a directory for each
+// repository in the workspace. The directories serve the repositories at
+// the ref, the empty ref is the main branch of each repository.
+//
+// The repositories are listed when the root is read, a repository is
+// resolved when it is first opened.
+func NewWorkspaceFS(client *Client, workspace, ref string) fs.FS {
+	return &workspaceFS{
+		client:    client,
+		workspace: workspace,
+		ref:       ref,
+		repos:     map[string]*cloudFS{},
+	}
+}
+
+// workspaceFS implements fs.FS for the repositories in a workspace.
+type workspaceFS struct {
+	client    *Client
+	workspace string
+	ref       string
+
+	mu    sync.Mutex
+	repos map[string]*cloudFS
+}
+
+// repo returns the FS for the repository.
+func (w *workspaceFS) repo(slug string) *cloudFS {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, ok := w.repos[slug]
+	if !ok {
+		r = NewFS(w.client, w.workspace, slug, w.ref).(*cloudFS)
+		w.repos[slug] = r
+	}
+	return r
+}
+
+// Open opens the file, the first element of the name is the repository.
+func (w *workspaceFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &workspaceDir{fsys: w}, nil
+	}
+	slug, rest, _ := strings.Cut(name, "/")
+	if rest == "" {
+		rest = "."
+	}
+	f, err := w.repo(slug).Open(rest)
+	if err != nil {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			pe.Path = name
+		}
+		return nil, err
+	}
+	ff := f.(*file)
+	ff.name = path.Join(slug, ff.name)
+	if rest == "." {
+		ff.fi = &fileInfo{name: slug, dir: true}
+	}
+	return ff, nil
+}
+
+// workspaceDir is the root of a workspaceFS, it lists the repositories.
+type workspaceDir struct {
+	fsys    *workspaceFS
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *workspaceDir) Stat() (fs.FileInfo, error) {
+	return &fileInfo{name: ".", dir: true}, nil
+}
+
+func (d *workspaceDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (d *workspaceDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the repositories in the workspace, sorted by slug.
+func (d *workspaceDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		repos, err := d.fsys.client.ListRepos(context.Background(), d.fsys.workspace)
+		if err != nil {
+			return nil, pathError("readdir", ".", err)
+		}
+		for _, r := range repos {
+			d.entries = append(d.entries, &fileInfo{name: r.Slug, dir: true})
+		}
+		slices.SortFunc(d.entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+		d.read = true
+	}
+	if n <= 0 {
+		res := d.entries
+		d.entries = nil
+		return res, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	res := d.entries[:n]
+	d.entries = d.entries[n:]
+	return res, nil
+}
@@ -0,0 +1,123 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakecloud"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+// newWorkspaceServer returns a server with the workspaces ws and other.
+// Workspace ws has n repositories.
+func newWorkspaceServer(t *testing.T, n int) *fakecloud.Server {
+	t.Helper()
+	srv := fakecloud.New()
+	t.Cleanup(srv.Close)
+	for i := range n {
+		slug := fmt.Sprintf("repo%d", i)
+		srv.AddRepo("ws", slug, fakeserver.NewRepo(fstest.MapFS{
+			"README.md":     {Data: []byte("# " + slug + "\n")},
+			"conf/app.yaml": {Data: []byte("name: " + slug + "\n")},
+		}))
+	}
+	srv.AddRepo("other", "repo", fakeserver.NewRepo(fstest.MapFS{"README.md": {}}))
+	return srv
+}
+
+func TestListWorkspacesAndRepos(t *testing.T) {
+	srv := newWorkspaceServer(t, 3)
+	c := &Client{BaseURL: srv.BaseURL()}
+
+	workspaces, err := c.ListWorkspaces(context.Background())
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var slugs []string
+	for _, w := range workspaces {
+		slugs = append(slugs, w.Slug)
+	}
+	if !slices.Equal(slugs, []string{"other", "ws"}) {
+		t.Errorf("unexpected workspaces %v", slugs)
+	}
+
+	// Page through the repositories.
+	cmd := &GetReposCommand{Workspace: "ws", PageLen: 2}
+	var repos []string
+	for {
+		resp, err := c.GetRepos(context.Background(), cmd)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if resp.Size != 3 {
+			t.Errorf("expected size 3, got %d", resp.Size)
+		}
+		for _, r := range resp.Repos {
+			repos = append(repos, r.Slug)
+		}
+		if resp.IsLastPage {
+			break
+		}
+		cmd.Page = resp.NextPage
+	}
+	if !slices.Equal(repos, []string{"repo0", "repo1", "repo2"}) {
+		t.Errorf("unexpected repositories %v", repos)
+	}
+
+	all, err := c.ListRepos(context.Background(), "ws")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(all) != 3 || all[0].MainBranch != "main" || all[0].FullName != "ws/repo0" {
+		t.Errorf("unexpected repositories %v", all)
+	}
+
+	if _, err := c.GetRepos(context.Background(), &GetReposCommand{Workspace: "ws", PageLen: MaxPageLen + 1}); !errors.Is(err, ErrInvalidCommand) {
+		t.Errorf("expected ErrInvalidCommand, got %v", err)
+	}
+}
+
+func TestWorkspaceFS(t *testing.T) {
+	// More repositories than fit on a page.
+	srv := newWorkspaceServer(t, MaxPageLen+2)
+	c := &Client{BaseURL: srv.BaseURL()}
+	fsys := NewWorkspaceFS(c, "ws", "")
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(entries) != MaxPageLen+2 || !entries[0].IsDir() {
+		t.Fatalf("expected %d repositories, got %d", MaxPageLen+2, len(entries))
+	}
+
+	data, err := fs.ReadFile(fsys, "repo7/conf/app.yaml")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "name: repo7\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	if _, err := fs.Stat(fsys, "missing/README.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+
+	sub, err := fs.Sub(fsys, "repo1")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := fstest.TestFS(sub, "README.md", "conf/app.yaml"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	small := NewWorkspaceFS(&Client{BaseURL: newWorkspaceServer(t, 2).BaseURL()}, "ws", "main")
+	if err := fstest.TestFS(small, "repo0/README.md", "repo1/conf/app.yaml"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+}
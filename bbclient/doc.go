@@ -2,6 +2,8 @@
 bbclient exposes services to interact with a Bitbucket repository.
 It's main purpose is to deliver the functions to implement a fs.FS, 
 but also provide services for finding tags and getting files in a commit.
+
+BitbucketRepository is implemented by the server and the cloud packages,
+use it to write code that works with both.
 */
 package bbclient
-
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// The Accept headers of the requests. Some proxies rewrite or transcode
+// the responses of requests without an Accept header, e.g. into an html
+// error page or a text response with other line endings.
+const (
+	// acceptJSON is the Accept header of the requests of the REST api.
+	acceptJSON = "application/json"
+	// acceptRaw is the Accept header of the requests of file content and archives.
+	acceptRaw = "application/octet-stream"
+)
+
+// acceptCommand is a command with a response other than JSON.
+type acceptCommand interface {
+	accept() string
+}
+
+// newRequest returns the request of the command with the Accept header of
+// the command. A header of the client or the context replaces it.
+func (c *Client) newRequest(ctx context.Context, cmd Command) (*http.Request, error) {
+	req, err := cmd.NewRequestWithContext(ctx, c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	accept := acceptJSON
+	if a, ok := cmd.(acceptCommand); ok {
+		accept = a.accept()
+	}
+	req.Header.Set("Accept", accept)
+	return req, nil
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// Archive formats of the server.
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTar   = "tar"
+	ArchiveFormatTarGz = "tar.gz"
+	ArchiveFormatTgz   = "tgz"
+)
+
+// OpenArchiveCommand is the command to stream an archive of the repository at a ref.
+type OpenArchiveCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	At         Ref
+	// Format is one of the ArchiveFormat constants, the server defaults to zip.
+	Format string
+	// Path limits the archive to a file or directory, optional.
+	// The entries keep their path in the repository.
+	Path string
+	// Prefix is put before the path of the entries, optional, e.g. "name/".
+	Prefix string
+}
+
+func (c *OpenArchiveCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "archive")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "at", c.At.String())
+	addValue(vals, "format", c.Format)
+	addValue(vals, "path", c.Path)
+	addValue(vals, "prefix", c.Prefix)
+	u.RawQuery = vals.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (c *OpenArchiveCommand) Validate() error {
+	var formatErr error
+	if c.Format != "" && !slices.Contains([]string{ArchiveFormatZip, ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTgz}, c.Format) {
+		formatErr = fmt.Errorf("Format %q is not supported", c.Format)
+	}
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		validatePath("Path", c.Path),
+		c.At.Validate(),
+		formatErr,
+	)
+}
+
+func (c *OpenArchiveCommand) accept() string {
+	return acceptRaw
+}
+
+// LogValue implements slog.LogValuer.
+func (c *OpenArchiveCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "OpenArchive"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("at", c.At.String()),
+		slog.String("format", c.Format),
+		slog.String("path", c.Path),
+		slog.String("prefix", c.Prefix),
+	)
+}
@@ -2,43 +2,265 @@ package server
 
 import (
 	"context"
-	"net/url"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient"
 )
 
+// DefaultRefTemplate is the ref of a component version when
+// BitbucketRepo.RefTemplate is empty.
+const DefaultRefTemplate = "refs/tags/{component}/{version}"
+
 type BitbucketRepo struct {
 	Client     *Client
 	ProjectKey string
 	RepoSlug   string
+	// RefTemplate is the ref of a version of a component, with the
+	// placeholders {component} and {version}. Defaults to DefaultRefTemplate.
+	// Use refs/heads/ for branches, e.g. refs/heads/release/{component}-{version}.
+	RefTemplate string
+	// Hooks, if not nil, are called around the calls of the methods of
+	// bbclient.BitbucketRepository.
+	Hooks *bbclient.Hooks
+}
+
+func (r *BitbucketRepo) refTemplate() string {
+	if r.RefTemplate == "" {
+		return DefaultRefTemplate
+	}
+	return r.RefTemplate
 }
 
-// GetContent implements server.BitbucketRepository.
+// Ref returns the ref of the version of the component.
+func (r *BitbucketRepo) Ref(component, version string) Ref {
+	return Ref(strings.NewReplacer("{component}", component, "{version}", version).Replace(r.refTemplate()))
+}
+
+// GetContent implements bbclient.BitbucketRepository.
 func (r *BitbucketRepo) GetContent(ctx context.Context, component string, version string, filePath string) ([]byte, error) {
-	path, err := url.JoinPath("", component, filePath)
+	call := &bbclient.Call{Op: bbclient.OpGetContent, Component: component, Version: version, Path: filePath}
+	var content []byte
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		var err error
+		content, err = r.Client.GetFileContent(ctx, &GetFileContentCommand{
+			ProjectKey: r.ProjectKey,
+			RepoSlug:   r.RepoSlug,
+			FilePath:   path.Join(call.Component, call.Path),
+			At:         r.at(call),
+		})
+		return err
+	})
+	return content, err
+}
+
+// GetTags implements bbclient.BitbucketRepository.
+func (r *BitbucketRepo) GetTags(ctx context.Context) ([]*bbclient.Tag, error) {
+	var tags []*bbclient.Tag
+	err := r.Hooks.Do(ctx, &bbclient.Call{Op: bbclient.OpGetTags}, func(ctx context.Context) error {
+		cmd := &GetTagsCommand{
+			ProjectKey: r.ProjectKey,
+			RepoSlug:   r.RepoSlug,
+			Limit:      MaxLimit,
+		}
+		for index := 0; ; index++ {
+			start := time.Now()
+			resp, err := r.Client.GetTags(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			page := *cmd
+			r.Client.observePage(ctx, &page, index, len(resp.Tags), start)
+			for _, t := range resp.Tags {
+				tags = append(tags, &bbclient.Tag{Name: t.Name, CommitID: t.CommitID})
+			}
+			if resp.IsLastPage {
+				return nil
+			}
+			cmd.Start = resp.NextPageStart
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-	at := "refs/tags/" + component + "/" + version
-	cmd := &GetFileContentCommand{
-		ProjectKey: r.ProjectKey,
-		RepoSlug:   r.RepoSlug,
-		FilePath:   path,
-		At:         at,
+	return tags, nil
+}
+
+// ListFiles implements bbclient.BitbucketRepository.
+func (r *BitbucketRepo) ListFiles(ctx context.Context, component string, version string, dir string) ([]*bbclient.FileInfo, error) {
+	call := &bbclient.Call{Op: bbclient.OpListFiles, Component: component, Version: version, Path: dir}
+	var files []*bbclient.FileInfo
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		it, err := r.Client.GetFilesIterator(ctx, &GetFilesCommand{
+			ProjectKey: r.ProjectKey,
+			RepoSlug:   r.RepoSlug,
+			FilePath:   path.Join(call.Component, call.Path),
+			At:         r.at(call),
+			Limit:      MaxLimit,
+		})
+		if err != nil {
+			return err
+		}
+		for f := range it.Files() {
+			files = append(files, &bbclient.FileInfo{Name: f.Name, Size: f.Size, Type: f.Type})
+		}
+		if err := it.Err(); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return r.Client.GetFileContent(ctx, cmd)
+	return files, nil
 }
 
-// GetTags implements server.BitbucketRepository.
-func (r *BitbucketRepo) GetTags(ctx context.Context) ([]*Tag, error) {
+// OpenFile implements bbclient.BitbucketRepository.
+func (r *BitbucketRepo) OpenFile(ctx context.Context, component string, version string, filePath string) (io.ReadCloser, error) {
+	call := &bbclient.Call{Op: bbclient.OpOpenFile, Component: component, Version: version, Path: filePath}
+	var body io.ReadCloser
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		var err error
+		body, err = r.Client.OpenRawFile(ctx, &OpenRawFileCommand{
+			ProjectKey: r.ProjectKey,
+			RepoSlug:   r.RepoSlug,
+			FilePath:   path.Join(call.Component, call.Path),
+			At:         r.at(call),
+		})
+		return err
+	})
+	return body, err
+}
+
+// PutContent commits the content of the file at filePath, relative to the
+// root of the repository, on the branch and returns the new commit.
+// sourceCommit is the commit the change is based on, required to update an
+// existing file and empty for a new one; when the file changed on the
+// branch since then the error is a conflict, see IsConflict.
+func (r *BitbucketRepo) PutContent(ctx context.Context, filePath string, content []byte, branch, message, sourceCommit string) (*Commit, error) {
+	call := &bbclient.Call{Op: bbclient.OpPutContent, Path: filePath, At: branch}
+	var commit *Commit
+	err := r.Hooks.Do(ctx, call, func(ctx context.Context) error {
+		var err error
+		commit, err = r.Client.PutFileContent(ctx, &PutFileContentCommand{
+			ProjectKey:     r.ProjectKey,
+			RepoSlug:       r.RepoSlug,
+			FilePath:       call.Path,
+			Content:        content,
+			Branch:         call.At,
+			Message:        message,
+			SourceCommitID: sourceCommit,
+		})
+		return err
+	})
+	return commit, err
+}
+
+// at returns the ref of the call, the At of the call when a hook set it,
+// otherwise the ref of the version of the component. It sets the At of
+// the call for the After hook.
+func (r *BitbucketRepo) at(call *bbclient.Call) Ref {
+	if call.At == "" {
+		call.At = string(r.Ref(call.Component, call.Version))
+	}
+	return Ref(call.At)
+}
+
+// ListVersions returns the versions of the component, in alphabetical order.
+// The versions are the parts of the tag or branch names in the place of
+// {version} in the ref template.
+func (r *BitbucketRepo) ListVersions(ctx context.Context, component string) ([]string, error) {
+	tmpl := strings.ReplaceAll(r.refTemplate(), "{component}", component)
+	name := Ref(tmpl).Name()
+	prefix, suffix, ok := strings.Cut(name, "{version}")
+	if !ok || strings.Contains(suffix, "{version}") {
+		return nil, fmt.Errorf("ref template %q must contain {version} once", r.refTemplate())
+	}
+
+	var names []string
+	var err error
+	if Ref(tmpl).IsBranch() {
+		names, err = r.branchNames(ctx, prefix)
+	} else {
+		names, err = r.tagNames(ctx, prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, n := range names {
+		v, ok := strings.CutPrefix(n, prefix)
+		if !ok {
+			continue
+		}
+		v, ok = strings.CutSuffix(v, suffix)
+		if !ok || v == "" {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// tagNames returns the names of the tags containing filter.
+func (r *BitbucketRepo) tagNames(ctx context.Context, filter string) ([]string, error) {
 	cmd := &GetTagsCommand{
 		ProjectKey: r.ProjectKey,
 		RepoSlug:   r.RepoSlug,
+		OrderBy:    OrderByAlphabetical,
+		FilterText: filter,
+		Limit:      MaxLimit,
 	}
-	tags, err := r.Client.GetTags(ctx, cmd)
-	if err != nil {
-		return nil, err
+	var names []string
+	for index := 0; ; index++ {
+		start := time.Now()
+		resp, err := r.Client.GetTags(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		page := *cmd
+		r.Client.observePage(ctx, &page, index, len(resp.Tags), start)
+		for _, t := range resp.Tags {
+			names = append(names, t.Name)
+		}
+		if resp.IsLastPage {
+			return names, nil
+		}
+		cmd.Start = resp.NextPageStart
 	}
+}
 
-	return tags.Tags, nil
+// branchNames returns the names of the branches containing filter.
+func (r *BitbucketRepo) branchNames(ctx context.Context, filter string) ([]string, error) {
+	cmd := &GetBranchesCommand{
+		ProjectKey: r.ProjectKey,
+		RepoSlug:   r.RepoSlug,
+		OrderBy:    OrderByAlphabetical,
+		FilterText: filter,
+		Limit:      MaxLimit,
+	}
+	var names []string
+	for index := 0; ; index++ {
+		start := time.Now()
+		resp, err := r.Client.GetBranches(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		page := *cmd
+		r.Client.observePage(ctx, &page, index, len(resp.Branches), start)
+		for _, b := range resp.Branches {
+			names = append(names, b.Name)
+		}
+		if resp.IsLastPage {
+			return names, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
 }
 
-// var _ server.BitbucketRepository = &BitbucketRepo{}
+var _ bbclient.BitbucketRepository = &BitbucketRepo{}
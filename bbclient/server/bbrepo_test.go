@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestBitbucketRepoVersions(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"comp/app.yaml": {Data: []byte("v1")},
+	})
+	first := repo.Commits[0]
+	repo.Tag("comp/1.0.0", first)
+	repo.Tag("comp/1.1.0", first)
+	repo.Tag("other/2.0.0", first)
+	repo.Tag("release-comp-3.0.0", first)
+	repo.Commit("release/comp-4.0.0", "release", fstest.MapFS{"comp/app.yaml": {Data: []byte("v4")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	client := &Client{BaseURL: srv.BaseURL()}
+
+	tests := []struct {
+		template string
+		want     []string
+		ref      Ref
+	}{
+		{"", []string{"1.0.0", "1.1.0"}, "refs/tags/comp/1.0.0"},
+		{"refs/tags/release-{component}-{version}", []string{"3.0.0"}, "refs/tags/release-comp-3.0.0"},
+		{"refs/heads/release/{component}-{version}", []string{"4.0.0"}, "refs/heads/release/comp-4.0.0"},
+	}
+	for _, tt := range tests {
+		r := &BitbucketRepo{Client: client, ProjectKey: "PRJ", RepoSlug: "repo", RefTemplate: tt.template}
+		versions, err := r.ListVersions(context.Background(), "comp")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if !slices.Equal(versions, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.template, tt.want, versions)
+		}
+		if ref := r.Ref("comp", tt.want[0]); ref != tt.ref {
+			t.Errorf("%q: expected ref %s, got %s", tt.template, tt.ref, ref)
+		}
+	}
+
+	r := &BitbucketRepo{Client: client, ProjectKey: "PRJ", RepoSlug: "repo", RefTemplate: "refs/tags/{component}"}
+	if _, err := r.ListVersions(context.Background(), "comp"); err == nil {
+		t.Errorf("expected an error for a template without {version}")
+	}
+}
+
+func TestBitbucketRepository(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"comp/app.yaml":      {Data: []byte("name: app\n")},
+		"comp/conf/env.yaml": {Data: []byte("env: test\n")},
+		"other/readme.md":    {Data: []byte("other\n")},
+	})
+	repo.Tag("comp/1.0.0", repo.Commits[0])
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	var r bbclient.BitbucketRepository = &BitbucketRepo{
+		Client:     &Client{BaseURL: srv.BaseURL()},
+		ProjectKey: "PRJ",
+		RepoSlug:   "repo",
+	}
+	ctx := context.Background()
+
+	tags, err := r.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(tags) != 1 || tags[0].Name != "comp/1.0.0" || tags[0].CommitID != repo.Commits[0].ID {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	files, err := r.ListFiles(ctx, "comp", "1.0.0", "")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name+" "+f.Type)
+	}
+	want := []string{"app.yaml " + bbclient.TypeFile, "conf " + bbclient.TypeDirectory}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	rc, err := r.OpenFile(ctx, "comp", "1.0.0", "conf/env.yaml")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "env: test\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	if _, err := r.OpenFile(ctx, "comp", "2.0.0", "app.yaml"); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestBitbucketRepoHooks(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"comp/app.yaml": {Data: []byte("v1")}})
+	repo.Tag("comp/1.0.0", repo.Commits[0])
+	repo.Commit("main", "update", fstest.MapFS{"comp/app.yaml": {Data: []byte("v2")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	errDenied := errors.New("denied")
+	var audit []string
+	r := &BitbucketRepo{
+		Client:     &Client{BaseURL: srv.BaseURL()},
+		ProjectKey: "PRJ",
+		RepoSlug:   "repo",
+		Hooks: &bbclient.Hooks{
+			Before: func(ctx context.Context, call *bbclient.Call) (context.Context, error) {
+				if call.Op == bbclient.OpListFiles {
+					return nil, errDenied
+				}
+				if call.Version == "latest" {
+					call.At = repo.Commits[1].ID
+				}
+				return ctx, nil
+			},
+			After: func(ctx context.Context, call *bbclient.Call, err error) {
+				audit = append(audit, fmt.Sprintf("%s %s %s %v", call.Op, call.Path, call.At, err))
+			},
+		},
+	}
+	ctx := context.Background()
+
+	for _, tt := range []struct{ version, want string }{{"1.0.0", "v1"}, {"latest", "v2"}} {
+		f, err := r.OpenFile(ctx, "comp", tt.version, "app.yaml")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.version, tt.want, data)
+		}
+	}
+	if _, err := r.ListFiles(ctx, "comp", "1.0.0", ""); !errors.Is(err, errDenied) {
+		t.Errorf("expected errDenied, got %v", err)
+	}
+	want := []string{
+		"OpenFile app.yaml refs/tags/comp/1.0.0 <nil>",
+		"OpenFile app.yaml " + repo.Commits[1].ID + " <nil>",
+		"ListFiles   denied",
+	}
+	if !slices.Equal(audit, want) {
+		t.Errorf("expected %q, got %q", want, audit)
+	}
+}
+
+func TestBitbucketRepoPutContent(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"conf/app.yaml": {Data: []byte("v1\n")}})
+	first := repo.Commits[0].ID
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	var calls []string
+	r := &BitbucketRepo{
+		Client:     &Client{BaseURL: srv.BaseURL()},
+		ProjectKey: "PRJ",
+		RepoSlug:   "repo",
+		Hooks: &bbclient.Hooks{After: func(ctx context.Context, call *bbclient.Call, err error) {
+			calls = append(calls, fmt.Sprintf("%s %s@%s", call.Op, call.Path, call.At))
+		}},
+	}
+	ctx := context.Background()
+	read := func() string {
+		body, err := r.Client.OpenRawFile(ctx, &OpenRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "conf/app.yaml", At: "main"})
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		defer body.Close()
+		content, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		return string(content)
+	}
+	// In the cache before the update.
+	if got := read(); got != "v1\n" {
+		t.Fatalf("expected v1, got %q", got)
+	}
+
+	commit, err := r.PutContent(ctx, "conf/app.yaml", []byte("v2\n"), "main", "Update app", first)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if commit.Message != "Update app" || !slices.Equal(commit.Parents, []string{first}) {
+		t.Errorf("unexpected commit %+v", commit)
+	}
+	if got := read(); got != "v2\n" {
+		t.Errorf("expected v2 after the update, got %q", got)
+	}
+
+	// Based on the first commit the update is a conflict now.
+	_, err = r.PutContent(ctx, "conf/app.yaml", []byte("v3\n"), "main", "Update app", first)
+	if !IsConflict(err) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	// A new file needs no source commit.
+	if _, err := r.PutContent(ctx, "conf/new.yaml", []byte("new\n"), "main", "Add new", ""); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if n := len(repo.Commits); n != 3 {
+		t.Errorf("expected 3 commits, got %d", n)
+	}
+	want := []string{"PutContent conf/app.yaml@main", "PutContent conf/app.yaml@main", "PutContent conf/new.yaml@main"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("expected %q, got %q", want, calls)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned when the circuit breaker rejects a request.
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+const (
+	// DefaultBreakerWindow is the default period over which the error rate is computed.
+	DefaultBreakerWindow = time.Minute
+	// DefaultBreakerCoolDown is the default time the breaker stays open.
+	DefaultBreakerCoolDown = 30 * time.Second
+	// DefaultBreakerMinRequests is the default number of requests needed before the breaker can open.
+	DefaultBreakerMinRequests = 10
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker stops sending requests to the server when too many of them fail.
+//
+// The breaker opens when the error rate in the current window reaches Threshold.
+// After CoolDown a single trial request is let through,
+// the breaker closes again when that request succeeds.
+type CircuitBreaker struct {
+	// Threshold is the error rate between 0 and 1 that opens the breaker.
+	Threshold float64
+	// MinRequests is the number of requests in the window
+	// before the error rate is evaluated.
+	MinRequests int
+	// Window is the period over which the error rate is computed.
+	Window time.Duration
+	// CoolDown is the time the breaker stays open.
+	CoolDown time.Duration
+
+	mu          sync.Mutex
+	state       breakerState
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	now         func() time.Time
+}
+
+// NewCircuitBreaker returns a breaker that opens at the given error rate
+// and stays open for coolDown.
+func NewCircuitBreaker(threshold float64, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold:   threshold,
+		MinRequests: DefaultBreakerMinRequests,
+		Window:      DefaultBreakerWindow,
+		CoolDown:    coolDown,
+	}
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) coolDown() time.Duration {
+	if b.CoolDown <= 0 {
+		return DefaultBreakerCoolDown
+	}
+	return b.CoolDown
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window <= 0 {
+		return DefaultBreakerWindow
+	}
+	return b.Window
+}
+
+// Allow returns ErrCircuitOpen when no request should be sent.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.clock().Sub(b.openedAt) < b.coolDown() {
+			return ErrCircuitOpen
+		}
+		// Let one trial request through.
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// The trial request is still running.
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Record registers the outcome of a request.
+func (b *CircuitBreaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock()
+	switch b.state {
+	case breakerHalfOpen:
+		if failed {
+			b.open(now)
+			return
+		}
+		b.reset(now)
+		b.state = breakerClosed
+		return
+	case breakerOpen:
+		return
+	}
+
+	if now.Sub(b.windowStart) > b.window() {
+		b.reset(now)
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests < b.MinRequests {
+		return
+	}
+	if float64(b.failures)/float64(b.requests) >= b.Threshold {
+		b.open(now)
+	}
+}
+
+// Release returns a request that Allow let through without an outcome,
+// e.g. one canceled by the caller. A trial request in the half-open state
+// reopens the breaker with the old opening time, so the next request is
+// the new trial.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// State returns "closed", "open" or "half-open".
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.state = breakerOpen
+	b.openedAt = now
+}
+
+func (b *CircuitBreaker) reset(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+// isServerFailure returns true if the response indicates a degraded server.
+func isServerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(0.5, time.Minute)
+	b.MinRequests = 4
+	b.now = func() time.Time { return now }
+
+	for _, failed := range []bool{false, true, false, true} {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		b.Record(failed)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+
+	// After the cool down one trial request is allowed.
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected only one trial request, got %v", err)
+	}
+	b.Record(false)
+	if s := b.State(); s != "closed" {
+		t.Fatalf("expected closed breaker, got %s", s)
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		BaseURL:        srv.URL,
+		CircuitBreaker: NewCircuitBreaker(0.5, time.Minute),
+	}
+	c.CircuitBreaker.MinRequests = 2
+	cmd := &GetTagsCommand{ProjectKey: "PRJ", RepoSlug: "repo"}
+	for range 5 {
+		c.GetTags(context.Background(), cmd)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls to the server, got %d", calls)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"isLastPage":true,"values":[]}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		BaseURL:        srv.URL,
+		MaxBodyInCache: -1,
+	}
+	b := NewBudget(2)
+	ctx := ContextWithBudget(context.Background(), b)
+	for i := range 2 {
+		cmd := &GetTagsCommand{ProjectKey: "PRJ", RepoSlug: "repo", Start: i}
+		if _, err := c.GetTags(ctx, cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	_, err := c.GetTags(ctx, &GetTagsCommand{ProjectKey: "PRJ", RepoSlug: "repo", Start: 3})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected budget exceeded, got %v", err)
+	}
+	if n := b.Requests(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestClientCircuitBreakerCanceledTrial(t *testing.T) {
+	var mode atomic.Value
+	mode.Store("fail")
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch mode.Load() {
+		case "fail":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "block":
+			started <- struct{}{}
+			<-r.Context().Done()
+		default:
+			w.Write([]byte(`{"isLastPage":true,"values":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	c := &Client{
+		BaseURL:        srv.URL,
+		MaxBodyInCache: -1,
+		CircuitBreaker: NewCircuitBreaker(0.5, time.Minute),
+	}
+	c.CircuitBreaker.MinRequests = 2
+	c.CircuitBreaker.now = func() time.Time { return now }
+	cmd := &GetTagsCommand{ProjectKey: "PRJ", RepoSlug: "repo"}
+	for range 2 {
+		c.GetTags(context.Background(), cmd)
+	}
+	if s := c.CircuitBreaker.State(); s != "open" {
+		t.Fatalf("expected open breaker, got %s", s)
+	}
+
+	// Rejected requests are not charged to the budget.
+	b := NewBudget(1)
+	if _, err := c.GetTags(ContextWithBudget(context.Background(), b), cmd); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+	if n := b.Requests(); n != 0 {
+		t.Fatalf("expected no requests charged, got %d", n)
+	}
+
+	// The caller cancels the trial request.
+	now = now.Add(time.Minute)
+	mode.Store("block")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, err := c.GetTags(ctx, cmd); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+	if s := c.CircuitBreaker.State(); s != "open" {
+		t.Fatalf("expected open breaker after the canceled trial, got %s", s)
+	}
+
+	// The next request is the new trial and closes the breaker.
+	mode.Store("ok")
+	if _, err := c.GetTags(context.Background(), cmd); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if s := c.CircuitBreaker.State(); s != "closed" {
+		t.Fatalf("expected closed breaker, got %s", s)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrBudgetExceeded is returned when a request would exceed the budget.
+	ErrBudgetExceeded = errors.New("request budget exceeded")
+)
+
+// Budget limits the number of requests sent to the server.
+//
+// Attach a budget to a context with ContextWithBudget.
+// Requests served from the cache are not counted.
+type Budget struct {
+	// MaxRequests is the maximum number of requests, 0 means unlimited.
+	MaxRequests int64
+
+	requests atomic.Int64
+}
+
+// NewBudget returns a budget for at most maxRequests requests.
+func NewBudget(maxRequests int64) *Budget {
+	return &Budget{
+		MaxRequests: maxRequests,
+	}
+}
+
+// Requests returns the number of requests taken from the budget.
+func (b *Budget) Requests() int64 {
+	return b.requests.Load()
+}
+
+// take takes one request from the budget.
+func (b *Budget) take() error {
+	n := b.requests.Add(1)
+	if b.MaxRequests > 0 && n > b.MaxRequests {
+		b.requests.Add(-1)
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+type budgetKey struct{}
+
+// ContextWithBudget returns a context that charges requests to b.
+func ContextWithBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// BudgetFromContext returns the budget in the context or nil.
+func BudgetFromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(budgetKey{}).(*Budget)
+	return b
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// BuildState is the state of a build in a build status.
+type BuildState string
+
+const (
+	BuildStateSuccessful BuildState = "SUCCESSFUL"
+	BuildStateFailed     BuildState = "FAILED"
+	BuildStateInProgress BuildState = "INPROGRESS"
+)
+
+// BuildStateValues contains the allowed values for BuildState.
+var BuildStateValues = []BuildState{
+	BuildStateSuccessful,
+	BuildStateFailed,
+	BuildStateInProgress,
+}
+
+func (s BuildState) String() string {
+	return string(s)
+}
+
+// Validate returns an error if the state is not a known one.
+func (s BuildState) Validate() error {
+	if slices.Contains(BuildStateValues, s) {
+		return nil
+	}
+	return fmt.Errorf("State must be %s, %s or %s, got %q", BuildStateSuccessful, BuildStateFailed, BuildStateInProgress, s)
+}
+
+// SetBuildStatusCommand sets the status of a build on a commit. A status
+// with the key of an earlier one replaces it.
+type SetBuildStatusCommand struct {
+	CommitID string
+	State    BuildState
+	// Key identifies the build on the commit, e.g. the name of the job.
+	Key string
+	// URL links to the build.
+	URL string
+	// Name and Description are shown with the status, optional.
+	Name        string
+	Description string
+}
+
+func (c *SetBuildStatusCommand) Validate() error {
+	return errors.Join(
+		required("CommitID", c.CommitID),
+		c.State.Validate(),
+		required("Key", c.Key),
+		required("URL", c.URL),
+	)
+}
+
+func (c *SetBuildStatusCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := restURL(baseURL, "build-status", "commits", c.CommitID)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for SetBuildStatusCommand: %w", err)
+	}
+	body := struct {
+		State       BuildState `json:"state"`
+		Key         string     `json:"key"`
+		URL         string     `json:"url"`
+		Name        string     `json:"name,omitempty"`
+		Description string     `json:"description,omitempty"`
+	}{
+		State:       c.State,
+		Key:         c.Key,
+		URL:         c.URL,
+		Name:        c.Name,
+		Description: c.Description,
+	}
+	return newJSONRequest(ctx, http.MethodPost, u.String(), body)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *SetBuildStatusCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "SetBuildStatus"),
+		slog.String("commitId", c.CommitID),
+		slog.String("state", c.State.String()),
+		slog.String("key", c.Key),
+	)
+}
+
+// SetBuildStatus sets the status of a build on a commit.
+func (c *Client) SetBuildStatus(ctx context.Context, cmd *SetBuildStatusCommand) error {
+	body, err := DoCommandBody(ctx, c, cmd)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetBuildStatus(t *testing.T) {
+	var n int
+	var gotMethod, gotPath, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		data, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.Path, string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/rest/api/latest", "key")
+	cmd := &SetBuildStatusCommand{CommitID: "abc", State: BuildStateFailed, Key: "ci", URL: "https://ci.example.com/1"}
+	for range 2 {
+		if err := c.SetBuildStatus(context.Background(), cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+	if gotMethod != http.MethodPost || gotPath != "/rest/build-status/latest/commits/abc" {
+		t.Errorf("unexpected request %s %s", gotMethod, gotPath)
+	}
+	if want := `{"state":"FAILED","key":"ci","url":"https://ci.example.com/1"}`; gotBody != want {
+		t.Errorf("expected %s, got %s", want, gotBody)
+	}
+
+	invalid := []*SetBuildStatusCommand{
+		{State: BuildStateFailed, Key: "ci", URL: "u"},
+		{CommitID: "abc", State: "DONE", Key: "ci", URL: "u"},
+		{CommitID: "abc", State: BuildStateFailed, URL: "u"},
+	}
+	for _, cmd := range invalid {
+		if err := c.SetBuildStatus(context.Background(), cmd); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("%+v: expected an invalid command, got %v", cmd, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"iter"
 	"sync"
 	"time"
 
@@ -8,17 +9,22 @@ import (
 )
 
 type syncedCache[K comparable, V any] struct {
-	cache otter.Cache[K, V]
+	cache      otter.Cache[K, V]
 	clearMutex sync.RWMutex
 }
 
 func NewCache[K comparable, V any]() *syncedCache[K, V] {
-	c, err := otter.MustBuilder[K, V](10_000).
+	return newCache[K, V](10_000, time.Hour)
+}
+
+// newCache returns a cache for capacity entries that expire after ttl.
+func newCache[K comparable, V any](capacity int, ttl time.Duration) *syncedCache[K, V] {
+	c, err := otter.MustBuilder[K, V](capacity).
 		CollectStats().
 		Cost(func(key K, data V) uint32 {
 			return 1
 		}).
-		WithTTL(time.Hour).
+		WithTTL(ttl).
 		Build()
 	if err != nil {
 		panic(err)
@@ -40,6 +46,34 @@ func (b *syncedCache[K, V]) Get(key K) (V, bool) {
 	return b.cache.Get(key)
 }
 
+// Has returns true if the key is in the cache, without counting a hit or miss.
+func (b *syncedCache[K, V]) Has(key K) bool {
+	b.clearMutex.RLock()
+	defer b.clearMutex.RUnlock()
+	return b.cache.Has(key)
+}
+
+// Delete removes the key from the cache.
+func (b *syncedCache[K, V]) Delete(key K) {
+	b.clearMutex.RLock()
+	defer b.clearMutex.RUnlock()
+	b.cache.Delete(key)
+}
+
+// Stats returns the statistics collected by the cache.
+func (b *syncedCache[K, V]) Stats() otter.Stats {
+	return b.cache.Stats()
+}
+
+// All returns an iterator over the entries in the cache.
+func (b *syncedCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.clearMutex.RLock()
+		defer b.clearMutex.RUnlock()
+		b.cache.Range(yield)
+	}
+}
+
 func (b *syncedCache[K, V]) Clear() {
 	b.clearMutex.Lock()
 	defer b.clearMutex.Unlock()
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestCacheStats(t *testing.T) {
+	const body = `{"isLastPage":true,"values":[]}`
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	cmd := &GetTagsCommand{ProjectKey: "PRJ", RepoSlug: "repo"}
+	for range 3 {
+		if _, err := c.GetTags(context.Background(), cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call to the server, got %d", calls)
+	}
+
+	st := c.CacheStats()
+	if st.Entries != 1 || st.Bytes != int64(len(body)) {
+		t.Fatalf("unexpected size: %+v", st)
+	}
+	if st.Hits != 2 || st.Misses != 1 {
+		t.Fatalf("unexpected hits and misses: %+v", st)
+	}
+	var keys []string
+	for k := range c.CachedKeys() {
+		keys = append(keys, k)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 key, got %v", keys)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"isLastPage":true,"values":[]}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, MaxBodyInCache: -1}
+	cmd := &GetTagsCommand{ProjectKey: "PRJ", RepoSlug: "repo"}
+	for range 2 {
+		if _, err := c.GetTags(context.Background(), cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls to the server, got %d", calls)
+	}
+	if st := c.CacheStats(); st.Entries != 0 {
+		t.Fatalf("expected empty cache, got %+v", st)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"8.0.0"}`))
+	}))
+	defer srv.Close()
+
+	// The options are set before the initialization.
+	c := NewClient(srv.URL, "key", func(c *Client) { c.MaxBodyInCache = -1 })
+	if c.Logger == nil || c.MaxBodyInCache != -1 || c.listingsEnabled() {
+		t.Errorf("expected the defaults after the options, got %+v", c)
+	}
+
+	// The first requests of a literal initialize it once.
+	for _, c := range []*Client{NewClient(srv.URL, "key"), {BaseURL: srv.URL, AccessKey: "key"}} {
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := c.GetApplicationProperties(context.Background()); err != nil {
+					t.Errorf("error: %s", err.Error())
+				}
+			}()
+		}
+		wg.Wait()
+		if n := c.CacheStats().Entries; n != 1 {
+			t.Errorf("expected 1 entry in the cache, got %d", n)
+		}
+	}
+}
@@ -3,39 +3,29 @@ package server
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/myhops/bbfs/internal/buildinfo"
 	"github.com/myhops/bbfs/nulllog"
 )
 
 const (
 	MaxBodyInCache = 100 * 1024 * 1024
+	// DefaultMaxListingsInCache is the default of Client.MaxListingsInCache.
+	DefaultMaxListingsInCache = 1000
+	// DefaultListingTTL is the default of Client.ListingTTL.
+	DefaultListingTTL = 5 * time.Minute
 )
 
-type orderBy int
-
-const (
-	OrderByModification orderBy = iota
-	OrderByAlphabetical
-)
-
-func (o orderBy) String() string {
-	switch o {
-	case OrderByAlphabetical:
-		return "ALPHABETICAL"
-	case OrderByModification:
-		return "MODIFICATION"
-	default:
-		return ""
-	}
-}
-
 // Secret string masks the value for String to avoid accidental disclosure.
 type SecretString string
 
@@ -52,6 +42,11 @@ func (s SecretString) Secret() string {
 type bodyCache = syncedCache[string, []byte]
 
 // Client is a client for the Bitbucket repository.
+//
+// Use a Client by pointer only, a copy has a cache of its own or shares
+// parts of it, and go vet reports the copies. Create it with NewClient, or
+// as &Client{...} with the fields set before the first request, which
+// finishes the initialization.
 type Client struct {
 	BaseURL   string
 	AccessKey SecretString
@@ -60,54 +55,275 @@ type Client struct {
 	// Defaults to 100Mi.
 	// Set to a negative value to disable caching.
 	MaxBodyInCache int64
+	// MaxListingsInCache is the number of directory listings in the cache,
+	// and of the file stats of StatRawFile. The structure of a repository
+	// changes more often than the content at a path, so the listings have
+	// a cache of their own. Defaults to DefaultMaxListingsInCache.
+	// Set to a negative value to disable caching listings and stats.
+	MaxListingsInCache int
+	// ListingTTL is the time the listings and stats stay in the cache,
+	// defaults to DefaultListingTTL. The bodies stay for an hour.
+	ListingTTL time.Duration
+	// CircuitBreaker stops requests when the server is degraded.
+	// Nil disables the breaker.
+	CircuitBreaker *CircuitBreaker
+	// TokenSource returns the token for the requests, it takes precedence
+	// over AccessKey. Use it for short lived tokens.
+	TokenSource TokenSource
+	// OnTokenError is called when the TokenSource fails, e.g. to alert
+	// that the refresh of a token does not work.
+	OnTokenError func(err error)
+	// RawRetries is the number of times OpenRawFile resumes a download
+	// that fails midway. A file at a branch can change between the requests,
+	// so OpenRawFile reads it at the commit of the branch then.
+	RawRetries int
+	// Header is added to the requests, e.g. a header for the audit log or
+	// the impersonation of a gateway. The client sets Authorization itself.
+	// An Accept header replaces the one of the commands, application/json,
+	// or application/octet-stream for file content and archives.
+	// Use ContextWithHeader for the headers of a single request.
+	Header http.Header
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Compression asks the server for gzip or deflate compressed responses
+	// and decompresses them. It cuts the transfer size of large listings, also
+	// with a transport that has automatic decompression disabled.
+	Compression bool
+	// UserAgent identifies the application in the User-Agent header of the
+	// requests, after bbfs/VERSION, e.g. docs-portal/2.1. It lets the
+	// administrators of the server see which tool sends the traffic.
+	// A User-Agent in Header replaces the whole value.
+	UserAgent string
+	// SlowRequestThreshold logs the commands that take longer at warn level,
+	// with the type of the command and whether the response came from the
+	// cache. For a response that is not cached it is the time until the
+	// headers, reading the body is not included. Zero disables the log.
+	SlowRequestThreshold time.Duration
+	// OnPage is called for each page of a paged listing the client fetches
+	// from the server: FilesIterator, GetAllFilePaths and the tags and
+	// branches of BitbucketRepo. Use it to trace slow walks or to report
+	// progress. Pages from the cache are not reported. Nil disables it.
+	OnPage func(ctx context.Context, page *PageInfo)
 
-	once       sync.Once
-	cache      *bodyCache
+	once        sync.Once
+	cache       *bodyCache
+	listings    *listingCache
+	stats       *statCache
+	generations sync.Map
+	features    sync.Map
 }
 
-func (c *Client) initLogger() {
-	if c.Logger == nil {
-		c.Logger = nulllog.Logger()
+// ErrInvalidCommand is returned when a command does not validate.
+var ErrInvalidCommand = errors.New("command not valid")
+
+// StatusError is returned when the server responds with a status other than 2xx.
+type StatusError struct {
+	StatusCode int
+	// RequestID is the id of the request, to find it in the access log of the server.
+	RequestID string
+}
+
+func (e *StatusError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("bad status: %s (request id %s)", http.StatusText(e.StatusCode), e.RequestID)
 	}
+	return fmt.Sprintf("bad status: %s", http.StatusText(e.StatusCode))
+}
+
+// IsNotFound returns true if err is a StatusError for http.StatusNotFound.
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
+}
+
+// IsConflict returns true if err is a StatusError for http.StatusConflict,
+// e.g. for a write based on a commit that is no longer current.
+func IsConflict(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusConflict
+}
+
+// IsUnauthorized returns true if err is a StatusError for
+// http.StatusUnauthorized or http.StatusForbidden.
+func IsUnauthorized(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) &&
+		(se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden)
 }
 
-func checkStatus(status int) error {
-	if status < 200 || status >= 300 {
-		return fmt.Errorf("bad status: %s", http.StatusText(status))
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode, RequestID: RequestID(resp)}
 	}
 	return nil
 }
 
-func (c *Client) getCache() *bodyCache {
+// ClientOption sets fields of the client before NewClient initializes it,
+// e.g. func(c *Client) { c.MaxListingsInCache = 100 }.
+type ClientOption func(*Client)
+
+// NewClient returns the client for the server at baseURL with the access
+// key, initialized after the options. It is safe for concurrent use.
+func NewClient(baseURL string, accessKey SecretString, opts ...ClientOption) *Client {
+	c := &Client{BaseURL: baseURL, AccessKey: accessKey}
+	for _, o := range opts {
+		o(c)
+	}
+	c.init()
+	return c
+}
+
+// init sets the defaults and creates the caches, once. The cache sizes
+// and the logger are fixed from then on.
+func (c *Client) init() {
 	c.once.Do(func() {
+		if c.Logger == nil {
+			c.Logger = nulllog.Logger()
+		}
 		if c.MaxBodyInCache == 0 {
 			c.MaxBodyInCache = MaxBodyInCache
 		}
 		c.cache = NewCache[string, []byte]()
+		size := c.MaxListingsInCache
+		if size <= 0 {
+			size = DefaultMaxListingsInCache
+		}
+		ttl := c.ListingTTL
+		if ttl <= 0 {
+			ttl = DefaultListingTTL
+		}
+		c.listings = newCache[string, []*GetFilesResponse](size, ttl)
+		c.stats = newCache[string, *RawFileInfo](size, ttl)
 	})
+}
+
+func (c *Client) getCache() *bodyCache {
+	c.init()
 	return c.cache
 }
 
+// ClearCache removes all entries from the cache.
 func (c *Client) ClearCache() {
 	c.getCache().Clear()
+	c.listings.Clear()
+	c.stats.Clear()
+}
+
+// CacheStats describes the state of the response body cache.
+type CacheStats struct {
+	// Entries is the number of cached bodies.
+	Entries int
+	// Bytes is the total size of the cached bodies.
+	Bytes int64
+	// Hits is the number of requests served from the cache.
+	Hits int64
+	// Misses is the number of requests sent to the server.
+	Misses int64
+	// HitRatio is Hits divided by the number of lookups.
+	HitRatio float64
+	// Evictions is the number of bodies removed to make room.
+	Evictions int64
+	// Rejected is the number of bodies the cache refused to store.
+	Rejected int64
+	// Listings is the number of cached directory listings,
+	// they are not in the other numbers.
+	Listings int
+	// Stats is the number of cached file stats, like Listings.
+	Stats int
+}
+
+// CacheStats returns the statistics for the cache.
+// Use it to choose a value for MaxBodyInCache.
+func (c *Client) CacheStats() CacheStats {
+	cache := c.getCache()
+	st := cache.Stats()
+	res := CacheStats{
+		Hits:      st.Hits(),
+		Misses:    st.Misses(),
+		HitRatio:  st.Ratio(),
+		Evictions: st.EvictedCount(),
+		Rejected:  st.RejectedSets(),
+	}
+	for _, body := range cache.All() {
+		res.Entries++
+		res.Bytes += int64(len(body))
+	}
+	for range c.listings.All() {
+		res.Listings++
+	}
+	for range c.stats.All() {
+		res.Stats++
+	}
+	return res
+}
+
+// CachedKeys returns an iterator over the keys in the cache.
+// The keys are the request urls.
+func (c *Client) CachedKeys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for k := range c.getCache().All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
 }
 
 // AuthorizeRequest adds an Authorization bearer header to the headers.
-func (c *Client) AuthorizeRequest(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+c.AccessKey.Secret())
+// The token comes from the TokenSource, or is the AccessKey without one.
+func (c *Client) AuthorizeRequest(req *http.Request) error {
+	token := c.AccessKey.Secret()
+	if c.TokenSource != nil {
+		t, err := c.TokenSource.Token(req.Context())
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrNoToken, err)
+			if c.OnTokenError != nil {
+				c.OnTokenError(err)
+			}
+			return err
+		}
+		token = t
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
 }
 
 // GetFileContent retrieves text content from the file.
 //
 // Use OpenRawFile if you want to read the file content.
 func (c *Client) GetFileContent(ctx context.Context, cmd *GetFileContentCommand) ([]byte, error) {
-	c.initLogger()
 	return DoCommandResponse[*GetFileContentCommand, []byte](ctx, c, cmd)
 }
 
 // GetTags returns the tags in the repository.
+//
+// With cmd.WithCommits it sends an extra request for the commit of each tag.
 func (c *Client) GetTags(ctx context.Context, cmd *GetTagsCommand) (*GetTagsResponse, error) {
-	return DoCommandResponse(ctx, c, cmd)
+	resp, err := DoCommandResponse(ctx, c, cmd)
+	if err != nil || !cmd.WithCommits {
+		return resp, err
+	}
+	for _, t := range resp.Tags {
+		if t.CommitID == "" {
+			continue
+		}
+		commits, err := c.GetCommits(ctx, &GetCommitsCommand{
+			ProjectKey: cmd.ProjectKey,
+			RepoSlug:   cmd.RepoSlug,
+			CommitID:   t.CommitID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("getting commit of tag %s: %w", t.Name, err)
+		}
+		if len(commits.Commits) == 0 {
+			continue
+		}
+		commit := commits.Commits[0]
+		t.Timestamp = commit.Timestamp
+		t.Message = commit.Message
+		t.Committer = commit.Committer
+	}
+	return resp, nil
 }
 
 // GetCommits returns an array of commits or a single commit.
@@ -121,78 +337,261 @@ func addValue(v url.Values, name string, value string) {
 	}
 }
 
+// checkPage returns an error when a page that is not the last does not
+// point forward, so a malformed response cannot make a listing loop.
+func checkPage(start, nextStart int, lastPage bool) error {
+	if !lastPage && nextStart <= start {
+		return fmt.Errorf("bad page: next page start %d after start %d", nextStart, start)
+	}
+	return nil
+}
+
+// GetProjects returns the projects visible to the user.
+func (c *Client) GetProjects(ctx context.Context, cmd *GetProjectsCommand) (*GetProjectsResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetRepos returns the repositories in the project.
+func (c *Client) GetRepos(ctx context.Context, cmd *GetReposCommand) (*GetReposResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetApplicationProperties returns the version of the server.
+func (c *Client) GetApplicationProperties(ctx context.Context) (*ApplicationProperties, error) {
+	return DoCommandResponse(ctx, c, &GetApplicationPropertiesCommand{})
+}
+
+// GetRepo returns the repository.
+func (c *Client) GetRepo(ctx context.Context, cmd *GetRepoCommand) (*Repository, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetChanges returns the paths that changed between two refs.
+func (c *Client) GetChanges(ctx context.Context, cmd *GetChangesCommand) (*GetChangesResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetCompareCommits returns the commits reachable from From and not from To.
+func (c *Client) GetCompareCommits(ctx context.Context, cmd *GetCompareCommitsCommand) (*GetCommitsResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetDiff returns the diff between two refs.
+func (c *Client) GetDiff(ctx context.Context, cmd *GetDiffCommand) (*GetDiffResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetBranches returns the branches in the repository.
+func (c *Client) GetBranches(ctx context.Context, cmd *GetBranchesCommand) (*GetBranchesResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
 // GetFiles returns a GetFilesResponse that contains the list of files found.
 func (c *Client) GetFiles(ctx context.Context, cmd *GetFilesCommand) (*GetFilesResponse, error) {
 	return DoCommandResponse(ctx, c, cmd)
 }
 
 // GetFilesIterator returns a file interator for the FilePath in GetFilesCommand.
+//
+// The iterator caches the listing when it has loaded the last page, and
+// takes all pages from the cache when the listing is there. The pages of a
+// listing at a branch or tag with more than one page are loaded at the
+// commit of the ref, so they do not mix before and after a push.
 func (c *Client) GetFilesIterator(ctx context.Context, cmd *GetFilesCommand) (*FilesIterator, error) {
-	// Get the first result and pass it to the iterator.
-	res, err := c.GetFiles(ctx, cmd)
-	if err != nil {
-		return nil, err
-	}
-	return &FilesIterator{
+	cmdCopy := *cmd
+	i := &FilesIterator{
 		client:      c,
-		lastResult:  res,
-		lastCommand: cmd,
+		lastCommand: &cmdCopy,
 		ctx:         ctx,
-	}, nil
+	}
+	if err := i.start(); err != nil {
+		return nil, err
+	}
+	return i, nil
 }
 
-type command interface {
+// Command is a request to the api, the commands of this package implement it.
+// Implement it for other endpoints, e.g. of a plugin, to send them with
+// DoCommandBody and DoCommandResponse with the authorization, headers and
+// cache of the client. Only the responses of GET and HEAD requests are
+// cached, writes always go to the server.
+type Command interface {
+	slog.LogValuer
+	// Validate returns an error when the command can not be sent.
 	Validate() error
-	newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error)
+	// NewRequestWithContext returns the request of the command for the
+	// api at baseURL, the BaseURL of the client, e.g.
+	// https://bitbucket.example.com/rest/api/latest.
+	NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error)
 }
 
-type commandResponse[T any] interface {
-	command
+// CommandResponse is a Command with a response to parse.
+type CommandResponse[T any] interface {
+	Command
+	// ParseResponse parses the body of the response.
 	ParseResponse([]byte) (T, error)
 }
 
 // DoCommandBody performs Do for the given command and returns the response body.
 // You need to close the io.ReadCloser after use.
-func DoCommandBody(ctx context.Context, client *Client, cmd command) (io.ReadCloser, error) {
+func DoCommandBody(ctx context.Context, client *Client, cmd Command) (io.ReadCloser, error) {
+	client.init()
+	client.Logger.Debug("executing command", slog.Any("command", cmd))
 	// Validate the request.
 	if err := cmd.Validate(); err != nil {
-		return nil, fmt.Errorf("command not valid: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+	start := time.Now()
+	body, cache, err := doCommandBody(ctx, client, cmd)
+	client.logSlow(ctx, cmd, cache, time.Since(start), err)
+	return body, err
+}
+
+// bodyCacheKey returns the key of the response to the request in the body
+// cache, the url for a GET, or "" when the response is not cached: only
+// reads are. A HEAD has a key of its own, so its empty body never answers
+// a GET.
+func bodyCacheKey(req *http.Request) string {
+	switch req.Method {
+	case http.MethodGet:
+		return req.URL.String()
+	case http.MethodHead:
+		return req.URL.String() + "#HEAD"
 	}
+	return ""
+}
+
+// doCommandBody returns the response body of the validated command and
+// the state of the cache for it.
+func doCommandBody(ctx context.Context, client *Client, cmd Command) (io.ReadCloser, string, error) {
 	// Build a request.
-	req, err := cmd.newRequestWithContext(ctx, client.BaseURL)
+	req, err := client.newRequest(ctx, cmd)
 	if err != nil {
-		return nil, err
+		return nil, cacheBypass, err
+	}
+
+	key := bodyCacheKey(req)
+	if _, ok := cmd.(uncachedCommand); ok || key == "" {
+		resp, err := client.do(req)
+		if err != nil {
+			return nil, cacheBypass, err
+		}
+		if err := checkStatus(resp); err != nil {
+			resp.Body.Close()
+			return nil, cacheBypass, err
+		}
+		return resp.Body, cacheBypass, nil
 	}
 
 	// Get the body from the cache if present
-	if body, found := client.getCache().Get(req.URL.String()); found {
-		return io.NopCloser(bytes.NewReader(body)), nil
+	cache := client.getCache()
+	if body, found := cache.Get(key); found {
+		client.Logger.Debug("response from cache", slog.Any("command", cmd))
+		return io.NopCloser(bytes.NewReader(body)), cacheHit, nil
 	}
 
-	client.AuthorizeRequest(req)
-	resp, err := http.DefaultClient.Do(req)
+	maxSize := client.MaxBodyInCache
+	state := cacheMiss
+	if maxSize < 0 {
+		state = cacheBypass
+	}
+	resp, err := client.do(req)
 	if err != nil {
-		return nil, err
+		return nil, state, err
 	}
-	defer resp.Body.Close()
-	if err := checkStatus(resp.StatusCode); err != nil {
-		return nil, err
+	if err := checkStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, state, err
 	}
 	// Do not cache over the max size
-	if resp.ContentLength > MaxBodyInCache {
-		return resp.Body, nil
+	if maxSize < 0 || resp.ContentLength > maxSize {
+		return resp.Body, state, nil
+	}
+	// Save the body in the cache, unless it turns out to be too large.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, state, fmt.Errorf("%w: %w", errReadingBody, err)
+	}
+	if int64(len(body)) > maxSize {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}, state, nil
+	}
+	resp.Body.Close()
+	cache.Set(key, body)
+	return io.NopCloser(bytes.NewReader(body)), state, nil
+}
+
+// setHeaders sets the headers of the client and the context on the request.
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", buildinfo.UserAgent(c.UserAgent))
+	setHeader(req.Header, c.Header)
+	setHeader(req.Header, HeaderFromContext(req.Context()))
+	if c.Compression {
+		setAcceptEncoding(req)
+	}
+}
+
+// do adds the headers, authorizes and sends the request to the server,
+// consulting the circuit breaker and charging the budget in the request
+// context. Requests the breaker rejects are not charged.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	c.init()
+	c.setHeaders(req)
+	if err := c.AuthorizeRequest(req); err != nil {
+		return nil, err
+	}
+	if c.CircuitBreaker != nil {
+		if err := c.CircuitBreaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+	if b := BudgetFromContext(req.Context()); b != nil {
+		if err := b.take(); err != nil {
+			if c.CircuitBreaker != nil {
+				c.CircuitBreaker.Release()
+			}
+			return nil, err
+		}
+	}
+	id := setRequestID(req)
+	c.Logger.Debug("sending request",
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.String("request_id", id))
+	resp, err := c.httpClient().Do(req)
+	if c.CircuitBreaker != nil {
+		// Requests canceled by the caller say nothing about the server.
+		if errors.Is(err, context.Canceled) {
+			c.CircuitBreaker.Release()
+		} else {
+			c.CircuitBreaker.Record(isServerFailure(resp, err))
+		}
 	}
-	// Save the body in the cache
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading body failed: %w", err)
+		return nil, fmt.Errorf("request id %s: %w", id, err)
+	}
+	c.Logger.Debug("received response",
+		slog.Int("status", resp.StatusCode),
+		slog.String("request_id", id))
+	if err := decompress(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
 	}
-	client.getCache().Set(req.URL.String(), body)
-	return io.NopCloser(bytes.NewReader(body)), nil
+	return resp, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
 }
 
 // DoCommandResponse performs do for the given command and returns the parsed body.
-func DoCommandResponse[C commandResponse[T], T any](ctx context.Context, client *Client, cmd C) (T, error) {
+func DoCommandResponse[C CommandResponse[T], T any](ctx context.Context, client *Client, cmd C) (T, error) {
 	var nullRes T
 	body, err := DoCommandBody(ctx, client, cmd)
 	if err != nil {
@@ -204,13 +603,53 @@ func DoCommandResponse[C commandResponse[T], T any](ctx context.Context, client
 	if err != nil {
 		return nullRes, err
 	}
-	return cmd.ParseResponse(b)
+	res, err := cmd.ParseResponse(b)
+	if err != nil {
+		return nullRes, err
+	}
+	// Only log responses that know how to summarize themselves.
+	if lv, ok := any(res).(slog.LogValuer); ok {
+		client.Logger.Debug("command response", slog.Any("command", cmd), slog.Any("response", lv))
+	}
+	return res, nil
 }
 
 // OpenRawFile opens the file as specified in the cmd parameter.
 // The returned io.ReadCloser is the body of the response.
 // You need to close the io.ReadCloser after use.
+//
+// With RawRetries a download that fails midway is resumed with a Range
+// request from the last received offset. The file is then read at the
+// commit of the ref, resolved with an extra request for a branch or tag,
+// so the parts are of the same version.
 func (c *Client) OpenRawFile(ctx context.Context, cmd *OpenRawFileCommand) (io.ReadCloser, error) {
-	c.getCache()
+	c.init()
+	retries := c.RawRetries
+	if retries > 0 && !isFullCommitID(cmd.At) {
+		id, err := c.resolveCommit(ctx, cmd.ProjectKey, cmd.RepoSlug, cmd.At)
+		if err != nil {
+			return nil, err
+		}
+		pinned := *cmd
+		pinned.At = CommitRef(id)
+		cmd = &pinned
+	}
+	for {
+		body, err := DoCommandBody(ctx, c, cmd)
+		if errors.Is(err, errReadingBody) && resumable(ctx, err) && retries > 0 {
+			retries--
+			continue
+		}
+		if err != nil || retries <= 0 {
+			return body, err
+		}
+		return &resumingReader{ctx: ctx, client: c, cmd: cmd, body: body, retries: retries}, nil
+	}
+}
+
+// OpenArchive streams an archive of the repository as specified in the cmd parameter.
+// The returned io.ReadCloser is the body of the response.
+// You need to close the io.ReadCloser after use.
+func (c *Client) OpenArchive(ctx context.Context, cmd *OpenArchiveCommand) (io.ReadCloser, error) {
 	return DoCommandBody(ctx, c, cmd)
 }
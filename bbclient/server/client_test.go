@@ -8,7 +8,12 @@ import (
 	"github.com/myhops/bbfs/nulllog"
 )
 
+// getAccessKey returns the access key from BBFS_ACCESS_KEY, like bbfs.ConfigFromEnv,
+// or from the older BBFSSRV_ACCESS_KEY.
 func getAccessKey() string {
+	if key := os.Getenv("BBFS_ACCESS_KEY"); key != "" {
+		return key
+	}
 	return os.Getenv("BBFSSRV_ACCESS_KEY")
 }
 
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// reportCommand is a command of an endpoint that the package does not know,
+// it uses the exported methods only.
+type reportCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	CommitID   string
+}
+
+func (c *reportCommand) Validate() error {
+	return nil
+}
+
+func (c *reportCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := url.Parse(strings.Replace(baseURL, "/rest/api/", "/rest/insights/", 1))
+	if err != nil {
+		return nil, err
+	}
+	u = u.JoinPath("projects", c.ProjectKey, "repos", c.RepoSlug, "commits", c.CommitID, "reports")
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *reportCommand) ParseResponse(data []byte) ([]string, error) {
+	var resp struct {
+		Values []struct {
+			Key string `json:"key"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, v := range resp.Values {
+		keys = append(keys, v.Key)
+	}
+	return keys, nil
+}
+
+func (c *reportCommand) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", "GetReports"))
+}
+
+func TestCustomCommand(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.Write([]byte(`{"values":[{"key":"coverage"},{"key":"lint"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/rest/api/latest", "key")
+	var cmd CommandResponse[[]string] = &reportCommand{ProjectKey: "P", RepoSlug: "r", CommitID: "abc"}
+	keys, err := DoCommandResponse(context.Background(), c, cmd)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if strings.Join(keys, ",") != "coverage,lint" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if want := "/rest/insights/latest/projects/P/repos/r/commits/abc/reports"; gotPath != want {
+		t.Errorf("expected %s, got %s", want, gotPath)
+	}
+	if gotAuth != "Bearer key" {
+		t.Errorf("expected the authorization of the client, got %q", gotAuth)
+	}
+}
+
+// putReportCommand is a write of an endpoint that the package does not know.
+type putReportCommand struct {
+	reportCommand
+	Key string
+}
+
+func (c *putReportCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	req, err := c.reportCommand.NewRequestWithContext(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	req.Method = http.MethodPut
+	req.URL = req.URL.JoinPath(c.Key)
+	return req, nil
+}
+
+func TestCustomWriteCommand(t *testing.T) {
+	var puts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			puts++
+		}
+		w.Write([]byte(`{"values":[]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/rest/api/latest", "key")
+	cmd := &putReportCommand{reportCommand{ProjectKey: "P", RepoSlug: "r", CommitID: "abc"}, "coverage"}
+	for range 2 {
+		if _, err := DoCommandResponse(context.Background(), c, cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if puts != 2 {
+		t.Errorf("expected 2 requests to the server, got %d", puts)
+	}
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrDeleteUnsupported is returned when a change deletes a file, the rest
+// api of Bitbucket Data Center has no endpoint for it.
+var ErrDeleteUnsupported = fmt.Errorf("deleting files: %w", errors.ErrUnsupported)
+
+// CommitBuilder stages the changes of several files on a branch and
+// commits them together, so the readers of the branch see all changes or
+// none. Create it with BitbucketRepo.NewCommitBuilder.
+//
+// The rest api commits one file per request. A single change is committed
+// on the branch, more changes are committed on a temporary branch that is
+// merged with a pull request and deleted. The merge fails with a conflict,
+// see IsConflict, when a staged file changed on the branch in the meantime.
+type CommitBuilder struct {
+	repo    *BitbucketRepo
+	branch  string
+	message string
+	puts    map[string][]byte
+	deletes map[string]bool
+}
+
+// NewCommitBuilder returns a CommitBuilder for the branch, the message is
+// the message of the commit.
+func (r *BitbucketRepo) NewCommitBuilder(branch, message string) *CommitBuilder {
+	return &CommitBuilder{
+		repo:    r,
+		branch:  branch,
+		message: message,
+		puts:    map[string][]byte{},
+		deletes: map[string]bool{},
+	}
+}
+
+// Put stages the content of the file, relative to the root of the
+// repository, as an add or an update. It replaces an earlier change of the file.
+func (b *CommitBuilder) Put(filePath string, content []byte) *CommitBuilder {
+	delete(b.deletes, filePath)
+	b.puts[filePath] = content
+	return b
+}
+
+// Delete stages the delete of the file. It replaces an earlier change of the file.
+func (b *CommitBuilder) Delete(filePath string) *CommitBuilder {
+	delete(b.puts, filePath)
+	b.deletes[filePath] = true
+	return b
+}
+
+// Len returns the number of staged changes.
+func (b *CommitBuilder) Len() int {
+	return len(b.puts) + len(b.deletes)
+}
+
+// Paths returns the paths of the staged changes in lexical order.
+func (b *CommitBuilder) Paths() []string {
+	paths := make([]string, 0, b.Len())
+	for p := range b.puts {
+		paths = append(paths, p)
+	}
+	for p := range b.deletes {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// Commit commits the staged changes on the branch and returns the commit
+// that makes them visible on the branch, nil when nothing is staged.
+// The changes are based on the head of the branch when Commit starts.
+func (b *CommitBuilder) Commit(ctx context.Context) (*Commit, error) {
+	if b.Len() == 0 {
+		return nil, nil
+	}
+	if len(b.deletes) > 0 {
+		return nil, ErrDeleteUnsupported
+	}
+	r := b.repo
+	head, err := r.Client.CurrentCommit(ctx, r.ProjectKey, r.RepoSlug, BranchRef(b.branch))
+	if err != nil {
+		return nil, err
+	}
+	paths := b.Paths()
+	if len(paths) == 1 {
+		return b.put(ctx, b.branch, "", paths[0], head)
+	}
+
+	temp, err := tempBranchName(b.branch)
+	if err != nil {
+		return nil, err
+	}
+	created, err := b.commitOn(ctx, temp, paths, head)
+	var commit *Commit
+	if err == nil {
+		commit, err = b.merge(ctx, temp, paths)
+	}
+	if created {
+		// Deleting the branch of a pull request that is not merged
+		// declines it.
+		derr := r.Client.DeleteBranch(context.WithoutCancel(ctx), &DeleteBranchCommand{
+			ProjectKey: r.ProjectKey,
+			RepoSlug:   r.RepoSlug,
+			Branch:     temp,
+		})
+		if derr != nil {
+			r.Client.Logger.Warn("error deleting the temporary branch", "branch", temp, "error", derr)
+		}
+	}
+	return commit, err
+}
+
+// commitOn commits the files one by one on the temporary branch, which the
+// first commit creates from the branch. It reports whether it created the
+// temporary branch.
+func (b *CommitBuilder) commitOn(ctx context.Context, temp string, paths []string, head string) (bool, error) {
+	for i, p := range paths {
+		source := ""
+		if i == 0 {
+			source = b.branch
+		}
+		if _, err := b.put(ctx, temp, source, p, head); err != nil {
+			return i > 0, err
+		}
+	}
+	return true, nil
+}
+
+// put commits the staged content of the file on the branch, based on the
+// head of the branch of the builder. The server needs the source commit
+// for existing files only.
+func (b *CommitBuilder) put(ctx context.Context, branch, sourceBranch, filePath, head string) (*Commit, error) {
+	r := b.repo
+	source := ""
+	_, err := r.Client.StatRawFile(ctx, &StatRawFileCommand{
+		ProjectKey: r.ProjectKey,
+		RepoSlug:   r.RepoSlug,
+		FilePath:   filePath,
+		At:         CommitRef(head),
+	})
+	switch {
+	case err == nil:
+		source = head
+	case !IsNotFound(err):
+		return nil, err
+	}
+	return r.Client.PutFileContent(ctx, &PutFileContentCommand{
+		ProjectKey:     r.ProjectKey,
+		RepoSlug:       r.RepoSlug,
+		FilePath:       filePath,
+		Content:        b.puts[filePath],
+		Branch:         branch,
+		Message:        b.message,
+		SourceCommitID: source,
+		SourceBranch:   sourceBranch,
+	})
+}
+
+// merge merges the temporary branch into the branch with a pull request.
+func (b *CommitBuilder) merge(ctx context.Context, temp string, paths []string) (*Commit, error) {
+	r := b.repo
+	pr, err := r.Client.CreatePullRequest(ctx, &CreatePullRequestCommand{
+		ProjectKey: r.ProjectKey,
+		RepoSlug:   r.RepoSlug,
+		Title:      b.message,
+		FromBranch: temp,
+		ToBranch:   b.branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pr, err = r.Client.MergePullRequest(ctx, &MergePullRequestCommand{
+		ProjectKey: r.ProjectKey,
+		RepoSlug:   r.RepoSlug,
+		ID:         pr.ID,
+		Version:    pr.Version,
+		Message:    b.message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Client.invalidateBranch(r.ProjectKey, r.RepoSlug, b.branch, paths...)
+	id := pr.MergeCommit
+	if id == "" {
+		id, err = r.Client.CurrentCommit(ctx, r.ProjectKey, r.RepoSlug, BranchRef(b.branch))
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp, err := r.Client.GetCommits(ctx, &GetCommitsCommand{
+		ProjectKey: r.ProjectKey,
+		RepoSlug:   r.RepoSlug,
+		CommitID:   id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Commits) == 0 || resp.Commits[0].ID == "" {
+		return nil, fmt.Errorf("no commit %s", id)
+	}
+	return resp.Commits[0], nil
+}
+
+// tempBranchName returns a new name for the temporary branch of a commit on the branch.
+func tempBranchName(branch string) (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "bbfs/commit/" + Ref(branch).Name() + "-" + hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestCommitBuilder(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"conf/a.yaml": {Data: []byte("a1\n")},
+		"conf/b.yaml": {Data: []byte("b1\n")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	r := &BitbucketRepo{Client: &Client{BaseURL: srv.BaseURL()}, ProjectKey: "PRJ", RepoSlug: "repo"}
+	ctx := context.Background()
+
+	// Nothing staged.
+	if c, err := r.NewCommitBuilder("main", "nothing").Commit(ctx); c != nil || err != nil {
+		t.Errorf("expected no commit, got %v, %v", c, err)
+	}
+
+	// One change is committed on the branch.
+	c, err := r.NewCommitBuilder("main", "one").Put("conf/a.yaml", []byte("a2\n")).Commit(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if c.Message != "one" || len(repo.PullRequests) != 0 {
+		t.Errorf("expected a commit without pull request, got %+v and %d pull requests", c, len(repo.PullRequests))
+	}
+
+	// More changes are merged from a temporary branch.
+	b := r.NewCommitBuilder("main", "several").
+		Put("conf/a.yaml", []byte("a3\n")).
+		Put("conf/b.yaml", []byte("b3\n")).
+		Put("conf/c.yaml", []byte("c3\n"))
+	if b.Len() != 3 {
+		t.Errorf("expected 3 changes, got %d", b.Len())
+	}
+	c, err = b.Commit(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	head := repo.Resolve("main")
+	if c.ID != head.ID || c.Message != "several" {
+		t.Errorf("expected the head of main, got %+v", c)
+	}
+	for name, want := range map[string]string{"conf/a.yaml": "a3\n", "conf/b.yaml": "b3\n", "conf/c.yaml": "c3\n"} {
+		if got := string(head.Files[name].Data); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+	if len(repo.Branches) != 1 {
+		t.Errorf("expected the temporary branch to be deleted, got %v", repo.Branches)
+	}
+	if pr := repo.PullRequests[0]; pr.State != "MERGED" {
+		t.Errorf("expected a merged pull request, got %s", pr.State)
+	}
+
+	// A file that changed on the branch while the changes were committed
+	// on the temporary branch is a conflict, the temporary branch and the
+	// pull request are cleaned up.
+	concurrent := func(name string) func() {
+		return func() {
+			srv.Update(func() {
+				files := maps.Clone(repo.Resolve("main").Files)
+				files[name] = &fstest.MapFile{Data: []byte("concurrent\n")}
+				repo.Commit("main", "concurrent", files)
+			})
+		}
+	}
+	r.Client.HTTPClient = &http.Client{Transport: &beforePullRequest{do: concurrent("conf/c.yaml")}}
+	if _, err := r.NewCommitBuilder("main", "merged").
+		Put("conf/a.yaml", []byte("a4\n")).
+		Put("conf/b.yaml", []byte("b4\n")).
+		Commit(ctx); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if head := repo.Resolve("main"); string(head.Files["conf/c.yaml"].Data) != "concurrent\n" || string(head.Files["conf/a.yaml"].Data) != "a4\n" {
+		t.Errorf("expected both changes on main")
+	}
+	r.Client.HTTPClient = &http.Client{Transport: &beforePullRequest{do: concurrent("conf/a.yaml")}}
+	b = r.NewCommitBuilder("main", "conflict").
+		Put("conf/a.yaml", []byte("a5\n")).
+		Put("conf/b.yaml", []byte("b5\n"))
+	if _, err := b.Commit(ctx); !IsConflict(err) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	if len(repo.Branches) != 1 {
+		t.Errorf("expected the temporary branch to be deleted, got %v", repo.Branches)
+	}
+	if pr := repo.PullRequests[len(repo.PullRequests)-1]; pr.State != "DECLINED" {
+		t.Errorf("expected a declined pull request, got %s", pr.State)
+	}
+
+	if _, err := r.NewCommitBuilder("main", "delete").Delete("conf/a.yaml").Commit(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected unsupported, got %v", err)
+	}
+}
+
+func TestCommitBuilderNoCommit(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"conf/a.yaml": {Data: []byte("a1\n")},
+		"conf/b.yaml": {Data: []byte("b1\n")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	r := &BitbucketRepo{
+		Client:     &Client{BaseURL: srv.BaseURL(), HTTPClient: &http.Client{Transport: emptyCommit{}}},
+		ProjectKey: "PRJ",
+		RepoSlug:   "repo",
+	}
+
+	// The merge commit is missing from the response.
+	c, err := r.NewCommitBuilder("main", "several").
+		Put("conf/a.yaml", []byte("a2\n")).
+		Put("conf/b.yaml", []byte("b2\n")).
+		Commit(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no commit") {
+		t.Errorf("expected no commit error, got %v, %v", c, err)
+	}
+}
+
+// emptyCommit answers the requests for a single commit with an empty object.
+type emptyCommit struct{}
+
+func (emptyCommit) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/commits/") {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader("{}")),
+			Request:    req,
+		}, nil
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// beforePullRequest calls do before it sends the request that creates a pull request.
+type beforePullRequest struct {
+	do func()
+}
+
+func (t *beforePullRequest) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/pull-requests") {
+		t.do()
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Change types of a Change.
+const (
+	ChangeTypeAdd    = "ADD"
+	ChangeTypeModify = "MODIFY"
+	ChangeTypeDelete = "DELETE"
+	ChangeTypeMove   = "MOVE"
+	ChangeTypeCopy   = "COPY"
+)
+
+// Segment types of a Segment.
+const (
+	SegmentAdded   = "ADDED"
+	SegmentRemoved = "REMOVED"
+	SegmentContext = "CONTEXT"
+)
+
+// GetChangesCommand is the command to retrieve the paths that changed between two refs.
+//
+// The changes are those reachable from From and not from To,
+// this is the same as git diff To...From.
+type GetChangesCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	From       Ref
+	To         Ref
+	Start      int
+	Limit      int
+}
+
+// Change is a changed path.
+type Change struct {
+	Path string
+	// SrcPath is the path before a move or copy.
+	SrcPath string
+	Type    string
+	// NodeType is FILE, DIRECTORY or SUBMODULE.
+	NodeType string
+}
+
+type GetChangesResponse struct {
+	IsLastPage    bool
+	Limit         int
+	NextPageStart int
+	Size          int
+	Start         int
+	Changes       []*Change
+}
+
+func (c *GetChangesCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("From", c.From.String()),
+		required("To", c.To.String()),
+		validatePaging(c.Start, c.Limit),
+	)
+}
+
+func (c *GetChangesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "compare", "changes")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "from", c.From.String())
+	addValue(vals, "to", c.To.String())
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetChangesCommand) ParseResponse(data []byte) (*GetChangesResponse, error) {
+	type path struct {
+		ToString string `json:"toString"`
+	}
+	type response struct {
+		IsLastPage    bool `json:"isLastPage"`
+		Limit         int  `json:"limit"`
+		NextPageStart int  `json:"nextPageStart"`
+		Size          int  `json:"size"`
+		Start         int  `json:"start"`
+		Values        []struct {
+			Path     path   `json:"path"`
+			SrcPath  *path  `json:"srcPath"`
+			Type     string `json:"type"`
+			NodeType string `json:"nodeType"`
+		} `json:"values"`
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	gcr := &GetChangesResponse{
+		IsLastPage:    resp.IsLastPage,
+		Limit:         resp.Limit,
+		NextPageStart: resp.NextPageStart,
+		Size:          resp.Size,
+		Start:         resp.Start,
+	}
+	for _, v := range resp.Values {
+		ch := &Change{
+			Path:     v.Path.ToString,
+			Type:     v.Type,
+			NodeType: v.NodeType,
+		}
+		if v.SrcPath != nil {
+			ch.SrcPath = v.SrcPath.ToString
+		}
+		gcr.Changes = append(gcr.Changes, ch)
+	}
+	return gcr, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetChangesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetChanges"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("from", c.From.String()),
+		slog.String("to", c.To.String()),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetChangesResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("changes", len(r.Changes)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextPageStart),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
+
+// GetCompareCommitsCommand is the command to retrieve the commits reachable
+// from From and not from To, this is the same as git log To..From.
+type GetCompareCommitsCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	From       Ref
+	To         Ref
+	Start      int
+	Limit      int
+}
+
+func (c *GetCompareCommitsCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("From", c.From.String()),
+		required("To", c.To.String()),
+		validatePaging(c.Start, c.Limit),
+	)
+}
+
+func (c *GetCompareCommitsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "compare", "commits")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "from", c.From.String())
+	addValue(vals, "to", c.To.String())
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// ParseResponse parses the page of commits, it has the format of GetCommits.
+func (c *GetCompareCommitsCommand) ParseResponse(data []byte) (*GetCommitsResponse, error) {
+	return (&GetCommitsCommand{}).ParseResponse(data)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetCompareCommitsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetCompareCommits"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("from", c.From.String()),
+		slog.String("to", c.To.String()),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// GetDiffCommand is the command to retrieve the diff between two refs.
+//
+// From and To have the same meaning as in GetChangesCommand.
+type GetDiffCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	From       Ref
+	To         Ref
+	// FilePath limits the diff to the file, empty for all files.
+	FilePath string
+	// ContextLines is the number of context lines, zero for the server default.
+	ContextLines int
+}
+
+// Diff is the diff of a single file.
+type Diff struct {
+	// Source is the path before the change, empty for an added file.
+	Source string
+	// Destination is the path after the change, empty for a deleted file.
+	Destination string
+	Binary      bool
+	// Truncated is true when the server left out hunks or lines.
+	Truncated bool
+	Hunks     []*Hunk
+}
+
+// Hunk is a consecutive part of a Diff.
+type Hunk struct {
+	SourceLine      int
+	SourceSpan      int
+	DestinationLine int
+	DestinationSpan int
+	Segments        []*Segment
+}
+
+// Segment contains lines of the same type in a Hunk.
+type Segment struct {
+	Type  string
+	Lines []string
+}
+
+type GetDiffResponse struct {
+	Diffs []*Diff
+}
+
+func (c *GetDiffCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("From", c.From.String()),
+		required("To", c.To.String()),
+		validatePath("FilePath", c.FilePath),
+	)
+}
+
+func (c *GetDiffCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "compare", "diff", c.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "from", c.From.String())
+	addValue(vals, "to", c.To.String())
+	addValue(vals, "contextLines", strconv.Itoa(c.ContextLines))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetDiffCommand) ParseResponse(data []byte) (*GetDiffResponse, error) {
+	type path struct {
+		ToString string `json:"toString"`
+	}
+	type response struct {
+		Diffs []struct {
+			Source      *path `json:"source"`
+			Destination *path `json:"destination"`
+			Binary      bool  `json:"binary"`
+			Truncated   bool  `json:"truncated"`
+			Hunks       []struct {
+				SourceLine      int  `json:"sourceLine"`
+				SourceSpan      int  `json:"sourceSpan"`
+				DestinationLine int  `json:"destinationLine"`
+				DestinationSpan int  `json:"destinationSpan"`
+				Truncated       bool `json:"truncated"`
+				Segments        []struct {
+					Type      string `json:"type"`
+					Truncated bool   `json:"truncated"`
+					Lines     []struct {
+						Line string `json:"line"`
+					} `json:"lines"`
+				} `json:"segments"`
+			} `json:"hunks"`
+		} `json:"diffs"`
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	gdr := &GetDiffResponse{}
+	for _, d := range resp.Diffs {
+		diff := &Diff{
+			Binary:    d.Binary,
+			Truncated: d.Truncated,
+		}
+		if d.Source != nil {
+			diff.Source = d.Source.ToString
+		}
+		if d.Destination != nil {
+			diff.Destination = d.Destination.ToString
+		}
+		for _, h := range d.Hunks {
+			hunk := &Hunk{
+				SourceLine:      h.SourceLine,
+				SourceSpan:      h.SourceSpan,
+				DestinationLine: h.DestinationLine,
+				DestinationSpan: h.DestinationSpan,
+			}
+			diff.Truncated = diff.Truncated || h.Truncated
+			for _, s := range h.Segments {
+				seg := &Segment{Type: s.Type}
+				for _, l := range s.Lines {
+					seg.Lines = append(seg.Lines, l.Line)
+				}
+				diff.Truncated = diff.Truncated || s.Truncated
+				hunk.Segments = append(hunk.Segments, seg)
+			}
+			diff.Hunks = append(diff.Hunks, hunk)
+		}
+		gdr.Diffs = append(gdr.Diffs, diff)
+	}
+	return gdr, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetDiffCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetDiff"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("from", c.From.String()),
+		slog.String("to", c.To.String()),
+		slog.String("filePath", c.FilePath),
+		slog.Int("contextLines", c.ContextLines),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetDiffResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("diffs", len(r.Diffs)),
+	)
+}
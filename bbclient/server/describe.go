@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RequestDescription is the request a command makes, see Client.Describe.
+type RequestDescription struct {
+	Method string
+	URL    string
+	// Header has the values of the credentials redacted.
+	Header http.Header
+}
+
+// String returns the request line and the headers sorted by name.
+func (d *RequestDescription) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n", d.Method, d.URL)
+	d.Header.Write(&sb)
+	return sb.String()
+}
+
+// redacted replaces the values of headers with credentials.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are the headers besides Authorization that Describe redacts.
+var sensitiveHeaders = []string{"Proxy-Authorization", "Cookie"}
+
+// Describe returns the request that the command makes, without sending it.
+// Use it to debug a proxy or the url of a command.
+//
+// The headers are those of the client and of the context. The client does not
+// ask the TokenSource for a token, the Authorization header is redacted.
+// The request id is generated when the request is sent, Describe only shows
+// the id of the context.
+func (c *Client) Describe(ctx context.Context, cmd Command) (*RequestDescription, error) {
+	if err := cmd.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+	req, err := c.newRequest(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	req.Header.Set("Authorization", "Bearer "+redacted)
+	if id := RequestIDFromContext(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	for _, h := range sensitiveHeaders {
+		if req.Header.Get(h) != "" {
+			req.Header.Set(h, redacted)
+		}
+	}
+	return &RequestDescription{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+	}, nil
+}
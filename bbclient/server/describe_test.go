@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		BaseURL:     srv.URL,
+		AccessKey:   "secret-key",
+		Compression: true,
+		Header:      http.Header{"X-Audit": {"bbfs"}, "Cookie": {"session=secret"}},
+	}
+	ctx := ContextWithHeader(context.Background(), http.Header{"X-Forwarded-User": {"alice"}})
+	ctx = ContextWithRequestID(ctx, "req-1")
+	d, err := c.Describe(ctx, &GetTagsCommand{ProjectKey: "P", RepoSlug: "r", Limit: 10})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests, got %d", requests)
+	}
+	if d.Method != http.MethodGet {
+		t.Errorf("expected GET, got %s", d.Method)
+	}
+	if want := srv.URL + "/projects/P/repos/r/tags?limit=10"; !strings.HasPrefix(d.URL, want) {
+		t.Errorf("expected url %s, got %s", want, d.URL)
+	}
+	want := map[string]string{
+		"Authorization":    "Bearer REDACTED",
+		"Cookie":           "REDACTED",
+		"X-Audit":          "bbfs",
+		"X-Forwarded-User": "alice",
+		"X-Request-Id":     "req-1",
+		"Accept-Encoding":  acceptEncoding,
+		"Accept":           acceptJSON,
+	}
+	for k, v := range want {
+		if got := d.Header.Get(k); got != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got)
+		}
+	}
+	if s := d.String(); strings.Contains(s, "secret") || !strings.HasPrefix(s, "GET "+d.URL+"\n") {
+		t.Errorf("unexpected description:\n%s", s)
+	}
+
+	_, err = c.Describe(ctx, &GetTagsCommand{ProjectKey: "P"})
+	if !errors.Is(err, ErrInvalidCommand) {
+		t.Errorf("expected ErrInvalidCommand, got %v", err)
+	}
+}
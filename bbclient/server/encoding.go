@@ -0,0 +1,72 @@
+package server
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding header sent when Compression is set.
+const acceptEncoding = "gzip, deflate"
+
+// setAcceptEncoding asks for a compressed response, unless the request has
+// no body in the response or sets the header itself.
+func setAcceptEncoding(req *http.Request) {
+	if req.Method == http.MethodHead || req.Header.Get("Accept-Encoding") != "" {
+		return
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+}
+
+// decompress replaces the body of a gzip or deflate encoded response with
+// the decompressed body. The transport decompresses gzip itself when it
+// set the Accept-Encoding header, those responses have no Content-Encoding.
+func decompress(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || resp.Request.Method == http.MethodHead {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch encoding {
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		r, err = zlib.NewReader(resp.Body)
+	default:
+		return fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s body: %w", encoding, err)
+	}
+	resp.Body = &decompressedBody{Reader: r, decompressor: r, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decompressedBody closes the decompressor and the body of the response.
+type decompressedBody struct {
+	io.Reader
+	decompressor io.Closer
+	body         io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	err := b.decompressor.Close()
+	if cerr := b.body.Close(); cerr != nil {
+		err = cerr
+	}
+	return err
+}
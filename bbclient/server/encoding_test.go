@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	const content = "compressed content"
+	var gotEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Accept-Encoding")
+		var buf bytes.Buffer
+		var zw io.WriteCloser
+		switch encoding := r.URL.Query().Get("at"); encoding {
+		case "gzip":
+			zw = gzip.NewWriter(&buf)
+			w.Header().Set("Content-Encoding", encoding)
+		case "deflate":
+			zw = zlib.NewWriter(&buf)
+			w.Header().Set("Content-Encoding", encoding)
+		default:
+			buf.WriteString(content)
+		}
+		if zw != nil {
+			zw.Write([]byte(content))
+			zw.Close()
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		encoding    string
+		compression bool
+		want        string
+	}{
+		{"gzip", true, acceptEncoding},
+		{"deflate", true, acceptEncoding},
+		{"identity", true, acceptEncoding},
+		{"gzip", false, ""},
+	}
+	for _, tt := range tests {
+		c := &Client{
+			BaseURL:        srv.URL,
+			MaxBodyInCache: -1,
+			Compression:    tt.compression,
+			HTTPClient:     &http.Client{Transport: &http.Transport{DisableCompression: true}},
+		}
+		r, err := c.OpenRawFile(context.Background(), &OpenRawFileCommand{
+			ProjectKey: "PRJ",
+			RepoSlug:   "repo",
+			FilePath:   "file.txt",
+			At:         CommitRef(tt.encoding),
+		})
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected %q, got %q", tt.encoding, content, data)
+		}
+		if gotEncoding != tt.want {
+			t.Errorf("%s: expected Accept-Encoding %q, got %q", tt.encoding, tt.want, gotEncoding)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Feature is an endpoint or behavior of the api that not every version of
+// Bitbucket Server and Data Center has, or that a proxy in front of it may
+// block. The client assumes a feature is there until a request shows it is
+// not, or until ProbeFeatures finds out, and records the result:
+//
+//	feature          used by                   fallback
+//	FeatureFilePaths bbfs.WithSubtreeStats     walk the listings of the subdirectories
+//	FeatureHeadRaw   Stat of a bbfs.FileFS     list the directory
+//
+// The modification times of bbfs come from the commit of the ref, they do
+// not need the last modified endpoint of the newer versions.
+type Feature string
+
+const (
+	// FeatureFilePaths is the files endpoint that lists all files below a path.
+	FeatureFilePaths Feature = "files"
+	// FeatureHeadRaw is a HEAD request on the raw endpoint, see StatRawFile.
+	FeatureHeadRaw Feature = "head-raw"
+)
+
+// Supports returns false when a request or ProbeFeatures found that the
+// server does not have the feature.
+func (c *Client) Supports(f Feature) bool {
+	v, ok := c.features.Load(f)
+	return !ok || v.(bool)
+}
+
+// SetSupported records whether the server has the feature, e.g. when a
+// caller knows the endpoint is missing from a 404 on a path that exists.
+func (c *Client) SetSupported(f Feature, ok bool) {
+	c.features.Store(f, ok)
+}
+
+// Features returns the features recorded so far, a feature that is not in
+// the map is assumed to be there.
+func (c *Client) Features() map[Feature]bool {
+	res := map[Feature]bool{}
+	c.features.Range(func(k, v any) bool {
+		res[k.(Feature)] = v.(bool)
+		return true
+	})
+	return res
+}
+
+// ProbeFeatures checks the features with requests on the repository, at
+// its default branch, records and returns them. The repository must exist;
+// HEAD on raw is only probed when the root has a file.
+func (c *Client) ProbeFeatures(ctx context.Context, projectKey, repoSlug string) (map[Feature]bool, error) {
+	root, err := c.GetFiles(ctx, &GetFilesCommand{ProjectKey: projectKey, RepoSlug: repoSlug, Limit: 100})
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.GetFilePaths(ctx, &GetFilePathsCommand{ProjectKey: projectKey, RepoSlug: repoSlug, Limit: 1})
+	switch {
+	case err == nil:
+		c.SetSupported(FeatureFilePaths, true)
+	case IsNotFound(err) || isUnsupported(err):
+		// The root exists, so the endpoint is missing.
+		c.SetSupported(FeatureFilePaths, false)
+	default:
+		return nil, err
+	}
+	for _, f := range root.Files {
+		if f.Type != "FILE" {
+			continue
+		}
+		_, err := c.StatRawFile(ctx, &StatRawFileCommand{ProjectKey: projectKey, RepoSlug: repoSlug, FilePath: f.Name})
+		if err != nil && !isUnsupported(err) {
+			return nil, err
+		}
+		c.SetSupported(FeatureHeadRaw, err == nil)
+		break
+	}
+	return c.Features(), nil
+}
+
+// isUnsupported returns true for the status of a server that does not
+// have the method or endpoint.
+func isUnsupported(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) &&
+		(se.StatusCode == http.StatusMethodNotAllowed || se.StatusCode == http.StatusNotImplemented)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestProbeFeatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		disable [][2]string
+		want    map[Feature]bool
+	}{
+		{
+			name: "current",
+			want: map[Feature]bool{FeatureFilePaths: true, FeatureHeadRaw: true},
+		},
+		{
+			name:    "old",
+			disable: [][2]string{{"", "files"}, {"HEAD", "raw"}},
+			want:    map[Feature]bool{FeatureFilePaths: false, FeatureHeadRaw: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New()
+			defer srv.Close()
+			srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+				"docs/index.md": {Data: []byte("index")},
+				"README.md":     {Data: []byte("readme")},
+			}))
+			for _, d := range tt.disable {
+				srv.Disable(d[0], d[1])
+			}
+			c := &Client{BaseURL: srv.BaseURL()}
+			if !c.Supports(FeatureFilePaths) || len(c.Features()) != 0 {
+				t.Errorf("expected the features to be assumed before a probe")
+			}
+			got, err := c.ProbeFeatures(context.Background(), "PRJ", "repo")
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			for f, ok := range tt.want {
+				if c.Supports(f) != ok {
+					t.Errorf("%s: expected %v", f, ok)
+				}
+			}
+		})
+	}
+
+	// A failed HEAD turns the feature off without a probe.
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+	srv.Disable("HEAD", "raw")
+	c := &Client{BaseURL: srv.BaseURL()}
+	if _, err := c.StatRawFile(context.Background(), &StatRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "a.txt"}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if c.Supports(FeatureHeadRaw) {
+		t.Errorf("expected HEAD on raw to be recorded as missing")
+	}
+}
@@ -2,8 +2,10 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"iter"
+	"time"
 )
 
 // FilesIterator is an iterator for the files in a directory in the repository.
@@ -14,6 +16,11 @@ type FilesIterator struct {
 	index       int
 	lastError   error
 	ctx         context.Context
+	// pages are the pages loaded so far, page the index of lastResult.
+	pages []*GetFilesResponse
+	page  int
+	// key is the key of the listing in the cache, empty when not cached.
+	key string
 }
 
 // Next returns the next FileInfo in the directory, or nil if all entries have been read.
@@ -38,22 +45,138 @@ func (i *FilesIterator) Next() *FileInfo {
 	return res
 }
 
+// Offset returns the offset in the directory of the entry returned by the
+// next call to Next, counting from 0.
+func (i *FilesIterator) Offset() int {
+	return i.lastResult.Start + i.index
+}
+
+// Reset rewinds the iterator to the first entry, so the directory can be
+// read again. The loaded pages are not requested again.
+// An error loading the first page is returned by Err.
+func (i *FilesIterator) Reset() {
+	i.Seek(0)
+}
+
+// Seek positions the iterator so that Next returns the entry at offset in
+// the directory, counting from 0. The loaded pages are reused, another page
+// is loaded from the server.
+//
+// Seek clears the error of a failed page, so Seek(i.Offset()) resumes the
+// listing after a transient error.
+func (i *FilesIterator) Seek(offset int) error {
+	if offset < 0 {
+		return fmt.Errorf("seek to negative offset %d", offset)
+	}
+	i.lastError = nil
+	for n, page := range i.pages {
+		if offset < page.Start {
+			break
+		}
+		if offset < page.Start+len(page.Files) || page.LastPage {
+			i.page = n
+			i.lastResult = page
+			i.index = min(offset-page.Start, len(page.Files))
+			return nil
+		}
+	}
+
+	last := i.pages[len(i.pages)-1]
+	cmd := *i.lastCommand
+	cmd.Start = offset
+	res, err := i.client.getFilesPage(i.ctx, &cmd)
+	if err != nil {
+		i.lastError = err
+		return err
+	}
+	i.lastCommand.Start = offset
+	i.index = 0
+	if offset == last.NextStart {
+		// The page follows the loaded pages.
+		i.page = len(i.pages)
+		i.addPage(res)
+		return nil
+	}
+	// A listing with a gap is not cached.
+	i.key = ""
+	i.pages = []*GetFilesResponse{res}
+	i.page = 0
+	i.lastResult = res
+	return nil
+}
+
 // Err returns the last occured error.
 func (i *FilesIterator) Err() error {
 	return i.lastError
 }
 
+// start loads the first page of the directory, from the cache if present.
+func (i *FilesIterator) start() error {
+	c := i.client
+	if c.listingsEnabled() {
+		i.key = c.listingKey(i.lastCommand)
+		if pages, ok := c.listings.Get(i.key); ok {
+			i.pages = pages
+			i.lastResult = pages[0]
+			return nil
+		}
+	}
+	start := time.Now()
+	res, err := c.getFilesPage(i.ctx, i.lastCommand)
+	if err != nil {
+		return err
+	}
+	if !res.LastPage && !i.lastCommand.At.IsCommit() {
+		// List all pages at the commit of the ref, the first page may be
+		// from another commit.
+		commit, err := c.resolveCommit(i.ctx, i.lastCommand.ProjectKey, i.lastCommand.RepoSlug, i.lastCommand.At)
+		if err != nil {
+			return err
+		}
+		i.lastCommand.At = CommitRef(commit)
+		if res, err = c.getFilesPage(i.ctx, i.lastCommand); err != nil {
+			return err
+		}
+	}
+	i.addPage(res)
+	c.observePage(i.ctx, i.command(), 0, len(res.Files), start)
+	return nil
+}
+
 // loadPage loads the next page from the directory.
 func (i *FilesIterator) loadPage() error {
+	if i.page+1 < len(i.pages) {
+		i.page++
+		i.lastResult = i.pages[i.page]
+		return nil
+	}
 	i.lastCommand.Start = i.lastResult.NextStart
-	res, err := i.client.GetFiles(i.ctx, i.lastCommand)
+	start := time.Now()
+	res, err := i.client.getFilesPage(i.ctx, i.lastCommand)
 	if err != nil {
 		return err
 	}
-	i.lastResult = res
+	i.page++
+	i.addPage(res)
+	i.client.observePage(i.ctx, i.command(), i.page, len(res.Files), start)
 	return nil
 }
 
+// command returns a copy of the command of the last page.
+func (i *FilesIterator) command() *GetFilesCommand {
+	cmd := *i.lastCommand
+	return &cmd
+}
+
+// addPage adds the page to the pages, and caches the listing after the last page.
+func (i *FilesIterator) addPage(res *GetFilesResponse) {
+	i.pages = append(i.pages, res)
+	i.lastResult = res
+	if res.LastPage && i.key != "" {
+		i.client.listings.Set(i.key, i.pages)
+	}
+}
+
 // Files returns a new iter iterator
 func (i *FilesIterator) Files() iter.Seq[*FileInfo] {
 	return func(yield func(v *FileInfo) bool) {
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+// failOnce fails the first request with start in the query.
+type failOnce struct {
+	start  string
+	failed bool
+}
+
+func (f *failOnce) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !f.failed && req.URL.Query().Get("start") == f.start {
+		f.failed = true
+		return nil, errors.New("connection reset")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFilesIteratorSeek(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+		"b.txt": {Data: []byte("b")},
+		"c.txt": {Data: []byte("c")},
+		"d.txt": {Data: []byte("d")},
+		"e.txt": {Data: []byte("e")},
+	}))
+	transport := &failOnce{start: "2"}
+	c := &Client{BaseURL: srv.BaseURL(), HTTPClient: &http.Client{Transport: transport}}
+	cmd := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", Limit: 2}
+
+	rest := func(iter *FilesIterator) string {
+		var names []string
+		for f := range iter.Files() {
+			names = append(names, f.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	iter, err := c.GetFilesIterator(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	// The second page fails, resume at the offset.
+	if got := rest(iter); got != "a.txt,b.txt" || iter.Err() == nil || iter.Err() == io.EOF {
+		t.Fatalf("expected a failure after the first page, got %s %v", got, iter.Err())
+	}
+	if iter.Offset() != 2 {
+		t.Errorf("expected offset 2, got %d", iter.Offset())
+	}
+	if err := iter.Seek(iter.Offset()); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if got := rest(iter); got != "c.txt,d.txt,e.txt" || iter.Err() != io.EOF {
+		t.Fatalf("expected the rest of the listing, got %s %v", got, iter.Err())
+	}
+
+	// Reset and Seek within the loaded pages do not send requests.
+	before := srv.Requests()
+	iter.Reset()
+	if got := rest(iter); got != "a.txt,b.txt,c.txt,d.txt,e.txt" {
+		t.Errorf("expected the listing after Reset, got %s", got)
+	}
+	if err := iter.Seek(3); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if got := rest(iter); got != "d.txt,e.txt" {
+		t.Errorf("expected d and e after Seek, got %s", got)
+	}
+	if err := iter.Seek(10); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if f := iter.Next(); f != nil || iter.Err() != io.EOF {
+		t.Errorf("expected the end after the last entry, got %v %v", f, iter.Err())
+	}
+	if n := srv.Requests() - before; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+
+	// Seeking past the loaded pages loads the page at the offset.
+	c.ClearCache()
+	iter, err = c.GetFilesIterator(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := iter.Seek(4); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if got := rest(iter); got != "e.txt" {
+		t.Errorf("expected e after Seek, got %s", got)
+	}
+	if c.FilesCached(context.Background(), cmd) {
+		t.Errorf("expected a listing with a gap not to be cached")
+	}
+	if err := iter.Seek(-1); err == nil {
+		t.Errorf("expected an error for a negative offset")
+	}
+}
+
+func TestOnPage(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"b.txt":     {Data: []byte("b")},
+		"c.txt":     {Data: []byte("c")},
+		"d.txt":     {Data: []byte("d")},
+		"dir/e.txt": {Data: []byte("e")},
+	})
+	srv.AddRepo("PRJ", "repo", repo)
+	var pages []string
+	c := &Client{
+		BaseURL: srv.BaseURL(),
+		OnPage: func(ctx context.Context, p *PageInfo) {
+			var start int
+			switch cmd := p.Command.(type) {
+			case *GetFilesCommand:
+				start = cmd.Start
+			case *GetFilePathsCommand:
+				start = cmd.Start
+			}
+			if p.Elapsed <= 0 {
+				t.Errorf("page %d: expected the elapsed time", p.Index)
+			}
+			pages = append(pages, fmt.Sprintf("%T %d@%d:%d", p.Command, p.Index, start, p.Size))
+		},
+	}
+	ctx := context.Background()
+	cmd := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", At: CommitRef(repo.Commits[0].ID), Limit: 2}
+
+	// The second walk reads the cached listing.
+	for range 2 {
+		iter, err := c.GetFilesIterator(ctx, cmd)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		for range iter.Files() {
+		}
+		if !errors.Is(iter.Err(), io.EOF) {
+			t.Fatalf("error: %v", iter.Err())
+		}
+	}
+	if _, err := c.GetAllFilePaths(ctx, &GetFilePathsCommand{ProjectKey: "PRJ", RepoSlug: "repo", Limit: 3}); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := []string{
+		"*server.GetFilesCommand 0@0:2",
+		"*server.GetFilesCommand 1@2:2",
+		"*server.GetFilesCommand 2@4:1",
+		"*server.GetFilePathsCommand 0@0:3",
+		"*server.GetFilePathsCommand 1@3:2",
+	}
+	if !slices.Equal(pages, want) {
+		t.Errorf("expected pages %q, got %q", want, pages)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fixtures"
+)
+
+func TestParseFixtures(t *testing.T) {
+	head := &Commit{
+		ID:        "8d51122def5632836d1cb1026e879069e10a1e13",
+		Committer: Committer{Name: "charlie", EMail: "charlie@example.com"},
+		Timestamp: time.UnixMilli(1548720007064),
+		Message:   "Add the docs",
+		Parents:   []string{"abcdef0123abcdef4567abcdef8987abcdef6543"},
+	}
+	first := &Commit{
+		ID:        "abcdef0123abcdef4567abcdef8987abcdef6543",
+		Committer: Committer{Name: "charlie", EMail: "charlie@example.com"},
+		Timestamp: time.UnixMilli(1548633307064),
+		Message:   "Initial commit",
+	}
+	tests := []struct {
+		file  string
+		parse func(data []byte) (any, error)
+		want  any
+	}{
+		{
+			file:  "browse-dir.json",
+			parse: func(data []byte) (any, error) { return (&GetFilesCommand{}).ParseResponse(data) },
+			want: &GetFilesResponse{
+				Files: []*FileInfo{
+					{Name: "docs", Type: "DIRECTORY"},
+					{Name: "README.md", Size: 9, Type: "FILE", ContentID: "2e65efe2a145dda7ee51d1741299f848e5bf752e"},
+				},
+				LastPage: true,
+				Size:     2,
+			},
+		},
+		{
+			file:  "browse-file.json",
+			parse: func(data []byte) (any, error) { return (&GetFileContentCommand{}).ParseResponse(data) },
+			want:  []byte("# readme\n"),
+		},
+		{
+			file:  "tags.json",
+			parse: func(data []byte) (any, error) { return (&GetTagsCommand{}).ParseResponse(data) },
+			want: &GetTagsResponse{
+				IsLastPage: true,
+				Limit:      25,
+				Size:       1,
+				Tags:       []*Tag{{Name: "v1.0.0", CommitID: "8d51122def5632836d1cb1026e879069e10a1e13", Type: "TAG"}},
+			},
+		},
+		{
+			file:  "branches.json",
+			parse: func(data []byte) (any, error) { return (&GetBranchesCommand{}).ParseResponse(data) },
+			want: &GetBranchesResponse{
+				IsLastPage: true,
+				Limit:      25,
+				Size:       1,
+				Branches: []*Branch{{
+					Name:      "main",
+					Ref:       "refs/heads/main",
+					CommitID:  "8d51122def5632836d1cb1026e879069e10a1e13",
+					IsDefault: true,
+				}},
+			},
+		},
+		{
+			file:  "commits.json",
+			parse: func(data []byte) (any, error) { return (&GetCommitsCommand{}).ParseResponse(data) },
+			want:  &GetCommitsResponse{Commits: []*Commit{head, first}, IsLastPage: true},
+		},
+		{
+			file: "commit.json",
+			parse: func(data []byte) (any, error) {
+				return (&GetCommitsCommand{CommitID: head.ID}).ParseResponse(data)
+			},
+			want: &GetCommitsResponse{Commits: []*Commit{head}},
+		},
+	}
+	for _, dir := range fixtures.Server {
+		for _, tt := range tests {
+			name := path.Join(dir, tt.file)
+			t.Run(name, func(t *testing.T) {
+				got, err := tt.parse(fixtures.Read(name))
+				if err != nil {
+					t.Fatalf("error: %s", err.Error())
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("expected %#v, got %#v", tt.want, got)
+				}
+			})
+		}
+	}
+
+	versions := map[string]string{"server/7": "7.21.0", "server/8": "8.19.1"}
+	for _, dir := range fixtures.Server {
+		props, err := (&GetApplicationPropertiesCommand{}).ParseResponse(fixtures.Read(path.Join(dir, "application-properties.json")))
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if props.Version != versions[dir] || props.DisplayName != "Bitbucket" {
+			t.Errorf("%s: unexpected properties %+v", dir, props)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// GetApplicationPropertiesCommand is the command to retrieve the version of the server.
+// It does not need authorization.
+type GetApplicationPropertiesCommand struct{}
+
+// ApplicationProperties describes the server.
+type ApplicationProperties struct {
+	Version     string
+	BuildNumber string
+	BuildDate   string
+	DisplayName string
+}
+
+func (c *GetApplicationPropertiesCommand) Validate() error {
+	return nil
+}
+
+func (c *GetApplicationPropertiesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := apiURL(baseURL, "application-properties")
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetApplicationPropertiesCommand) ParseResponse(data []byte) (*ApplicationProperties, error) {
+	var resp struct {
+		Version     string `json:"version"`
+		BuildNumber string `json:"buildNumber"`
+		BuildDate   string `json:"buildDate"`
+		DisplayName string `json:"displayName"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &ApplicationProperties{
+		Version:     resp.Version,
+		BuildNumber: resp.BuildNumber,
+		BuildDate:   resp.BuildDate,
+		DisplayName: resp.DisplayName,
+	}, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetApplicationPropertiesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetApplicationProperties"),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (p *ApplicationProperties) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("displayName", p.DisplayName),
+		slog.String("version", p.Version),
+	)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetBranchesCommand is the command to retrieve the branches from the repository.
+type GetBranchesCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	OrderBy    OrderBy
+	// FilterText limits the result to branches containing the text.
+	FilterText string
+	Start      int
+	Limit      int
+}
+
+// Branch is a branch in the repository.
+type Branch struct {
+	// Name is the display name of the branch.
+	Name string
+	// Ref is the fully qualified name of the branch.
+	Ref      Ref
+	CommitID string
+	// IsDefault is true for the default branch of the repository.
+	IsDefault bool
+}
+
+type GetBranchesResponse struct {
+	IsLastPage    bool
+	Limit         int
+	NextPageStart int
+	Size          int
+	Start         int
+	Branches      []*Branch
+}
+
+func (c *GetBranchesCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		c.OrderBy.Validate(),
+		validatePaging(c.Start, c.Limit),
+	)
+}
+
+func (c *GetBranchesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "branches")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "orderBy", c.OrderBy.String())
+	addValue(vals, "filterText", c.FilterText)
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	us := u.String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (c *GetBranchesCommand) ParseResponse(data []byte) (*GetBranchesResponse, error) {
+	type response struct {
+		IsLastPage    bool `json:"isLastPage"`
+		Limit         int  `json:"limit"`
+		NextPageStart int  `json:"nextPageStart"`
+		Size          int  `json:"size"`
+		Start         int  `json:"start"`
+		Values        []struct {
+			ID           string `json:"id"`
+			DisplayID    string `json:"displayId"`
+			LatestCommit string `json:"latestCommit"`
+			IsDefault    bool   `json:"isDefault"`
+		} `json:"values"`
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	gbr := &GetBranchesResponse{
+		IsLastPage:    resp.IsLastPage,
+		Limit:         resp.Limit,
+		NextPageStart: resp.NextPageStart,
+		Size:          resp.Size,
+		Start:         resp.Start,
+	}
+	for _, b := range resp.Values {
+		gbr.Branches = append(gbr.Branches, &Branch{
+			Name:      b.DisplayID,
+			Ref:       Ref(b.ID),
+			CommitID:  b.LatestCommit,
+			IsDefault: b.IsDefault,
+		})
+	}
+	return gbr, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetBranchesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetBranches"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("orderBy", c.OrderBy.String()),
+		slog.String("filterText", c.FilterText),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetBranchesResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("branches", len(r.Branches)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextPageStart),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
@@ -3,7 +3,9 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,43 +18,56 @@ type Committer struct {
 }
 
 type Commit struct {
+	ID        string
 	Committer Committer
 	Timestamp time.Time
 	Message   string
+	// Parents are the ids of the parent commits.
+	Parents []string
 }
 
 type GetCommitsCommand struct {
 	ProjectKey string
 	RepoSlug   string
-	OrderBy    string
+	OrderBy    OrderBy
 	Start      int
 	Limit      int
 	CommitID   string // optional
+	// Until lists the commits reachable from this ref, optional.
+	Until Ref
+	// Since excludes the commits reachable from this ref, optional.
+	Since Ref
 }
 
 type GetCommitsResponse struct {
-	Commits []*Commit
+	Commits       []*Commit
+	IsLastPage    bool
+	NextPageStart int
+	Start         int
 }
 
 func (c *GetCommitsCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	return nil
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		c.OrderBy.Validate(),
+		Ref(c.CommitID).Validate(),
+		c.Until.Validate(),
+		c.Since.Validate(),
+		validatePaging(c.Start, c.Limit),
+	)
 }
 
-func (c *GetCommitsCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(baseURL)
+func (c *GetCommitsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "commits", c.CommitID)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing base url for GetCommitsCommand: %w", err)
+		return nil, fmt.Errorf("error building url for GetCommitsCommand: %w", err)
 	}
-	u = u.JoinPath("projects", c.ProjectKey, "repos", c.RepoSlug, "commits", c.CommitID)
 
 	vals := url.Values{}
-	addValue(vals, "orderBy", c.OrderBy)
+	addValue(vals, "orderBy", c.OrderBy.String())
+	addValue(vals, "until", c.Until.String())
+	addValue(vals, "since", c.Since.String())
 	addValue(vals, "start", strconv.Itoa(c.Start))
 	addValue(vals, "limit", strconv.Itoa(c.Limit))
 	u.RawQuery = vals.Encode()
@@ -65,84 +80,99 @@ func (c *GetCommitsCommand) newRequestWithContext(ctx context.Context, baseURL s
 	return req, nil
 }
 
-func (c *GetCommitsCommand) ParseResponse(data []byte) (*GetCommitsResponse, error) {
-	type Actor struct {
-		Name         string `json:"name"`
-		EmailAddress string `json:"emailAddress"`
+// jsonActor is the author or committer of a commit in a response.
+type jsonActor struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+// jsonCommit is a commit in a response.
+// Timestamps are in milliseconds since the epoch.
+type jsonCommit struct {
+	ID                 string    `json:"id"`
+	Author             jsonActor `json:"author"`
+	AuthorTimestamp    int64     `json:"authorTimestamp"`
+	Committer          jsonActor `json:"committer"`
+	CommitterTimestamp int64     `json:"committerTimestamp"`
+	Message            string    `json:"message"`
+	Parents            []struct {
+		ID string `json:"id"`
+	} `json:"parents"`
+}
+
+func (v *jsonCommit) commit() *Commit {
+	c := &Commit{
+		ID: v.ID,
+		Committer: Committer{
+			Name:  v.Committer.Name,
+			EMail: v.Committer.EmailAddress,
+		},
+		Timestamp: time.UnixMilli(v.CommitterTimestamp),
+		Message:   v.Message,
 	}
-	type Value struct {
-		ID                 string    `json:"id"`
-		Author             Actor     `json:"author"`
-		AuthorTimestamp    time.Time `json:"authorTimestamp"`
-		Committer          Actor     `json:"committer"`
-		CommitterTimestamp time.Time `json:"committerTimestamp"`
-		Message            string    `json:"message"`
+	for _, p := range v.Parents {
+		c.Parents = append(c.Parents, p.ID)
 	}
+	return c
+}
+
+func (c *GetCommitsCommand) ParseResponse(data []byte) (*GetCommitsResponse, error) {
 	type Response struct {
-		Size          int     `json:"size"`
-		IsLastPage    bool    `json:"isLastPage"`
-		NextPageStart int     `json:"nextPageStart"`
-		Start         int     `json:"start"`
-		Values        []Value `json:"values"`
+		Size          int          `json:"size"`
+		IsLastPage    bool         `json:"isLastPage"`
+		NextPageStart int          `json:"nextPageStart"`
+		Start         int          `json:"start"`
+		Values        []jsonCommit `json:"values"`
 	}
 
-	parseValue := func(data []byte) (*Value, error) {
-		var res Value
-		if err := json.Unmarshal(data, &res); err != nil {
+	// Check if the response is for a single commit
+	if c.CommitID != "" {
+		var v jsonCommit
+		if err := json.Unmarshal(data, &v); err != nil {
 			return nil, fmt.Errorf("error unmarshalling single commit: %w", err)
 		}
-		return &res, nil
-	}
-
-	parseResponse := func(data []byte) (*Response, error) {
-		var res Response
-		if err := json.Unmarshal(data, &res); err != nil {
-			return nil, fmt.Errorf("error unmarshalling list of commits: %w", err)
-		}
-		return &res, nil
-	}
-
-	// Check if the response is for a list of commits
-	if c.CommitID == "" {
-		v, err := parseValue(data)
-		if err != nil {
-			return nil, err
-		}
 		return &GetCommitsResponse{
-			Commits: []*Commit{
-				{
-					Committer: Committer{
-						Name:  v.Committer.Name,
-						EMail: v.Committer.EmailAddress,
-					},
-					Timestamp: v.CommitterTimestamp,
-					Message:   v.Message,
-				},
-			},
+			Commits: []*Commit{v.commit()},
 		}, nil
 	}
 
 	// Parse a list of commits
-	resp, err := parseResponse(data)
-	if err != nil {
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling list of commits: %w", err)
+	}
+	res := &GetCommitsResponse{
+		IsLastPage:    resp.IsLastPage,
+		NextPageStart: resp.NextPageStart,
+		Start:         resp.Start,
+	}
+	if err := checkPage(resp.Start, resp.NextPageStart, resp.IsLastPage); err != nil {
 		return nil, err
 	}
-
-	var commits []*Commit
 	for _, v := range resp.Values {
-		commits = append(commits, 
-			&Commit{
-					Committer: Committer{
-						Name:  v.Committer.Name,
-						EMail: v.Committer.EmailAddress,
-					},
-					Timestamp: v.CommitterTimestamp,
-					Message:   v.Message,
-				},
-			)
+		res.Commits = append(res.Commits, v.commit())
 	}
-	return &GetCommitsResponse{
-		Commits: commits,
-	}, nil;
+	return res, nil
 }
 
+// LogValue implements slog.LogValuer.
+func (c *GetCommitsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetCommits"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("commitID", c.CommitID),
+		slog.String("until", c.Until.String()),
+		slog.String("since", c.Since.String()),
+		slog.String("orderBy", c.OrderBy.String()),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetCommitsResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("commits", len(r.Commits)),
+	)
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 )
@@ -13,17 +15,17 @@ type GetFileContentCommand struct {
 	FilePath   string
 	ProjectKey string
 	RepoSlug   string
-	At         string
+	At         Ref
 }
 
-func (c *GetFileContentCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/raw/%s", baseURL, c.ProjectKey, c.RepoSlug, c.FilePath))
+func (c *GetFileContentCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "raw", c.FilePath)
 	if err != nil {
 		return nil, err
 	}
 
 	vals := url.Values{}
-	addValue(vals, "at", c.At)
+	addValue(vals, "at", c.At.String())
 	u.RawQuery = vals.Encode()
 	us := u.String()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
@@ -34,16 +36,13 @@ func (c *GetFileContentCommand) newRequestWithContext(ctx context.Context, baseU
 }
 
 func (c *GetFileContentCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	if c.FilePath == "" {
-		return fmt.Errorf("FilePath is missing")
-	}
-	return nil
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+		c.At.Validate(),
+	)
 }
 
 func (c *GetFileContentCommand) ParseResponse(data []byte) ([]byte, error) {
@@ -63,3 +62,14 @@ func (c *GetFileContentCommand) ParseResponse(data []byte) ([]byte, error) {
 	}
 	return b.Bytes(), nil
 }
+
+// LogValue implements slog.LogValuer.
+func (c *GetFileContentCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFileContent"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("at", c.At.String()),
+	)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetFileLinesCommand gets a page of the lines of a text file with the
+// browse endpoint. For a binary file the server returns no lines and sets
+// Binary in the response.
+type GetFileLinesCommand struct {
+	FilePath   string
+	ProjectKey string
+	RepoSlug   string
+	At         Ref
+	Start      int
+	Limit      int
+}
+
+type GetFileLinesResponse struct {
+	// Lines are the lines without the line ends.
+	Lines []string
+	// Binary is true when the server considers the file binary.
+	Binary    bool
+	Start     int
+	NextStart int
+	LastPage  bool
+}
+
+func (c *GetFileLinesCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+		c.At.Validate(),
+		validatePaging(c.Start, c.Limit),
+	)
+}
+
+func (c *GetFileLinesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "browse", c.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "at", c.At.String())
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// ErrIsDirectory is returned by GetFileLines for a directory.
+var ErrIsDirectory = errors.New("is a directory")
+
+func (c *GetFileLinesCommand) ParseResponse(data []byte) (*GetFileLinesResponse, error) {
+	var r struct {
+		Binary        bool            `json:"binary"`
+		Children      json.RawMessage `json:"children"`
+		IsLastPage    bool            `json:"isLastPage"`
+		NextPageStart int             `json:"nextPageStart"`
+		Start         int             `json:"start"`
+		Lines         []struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Children != nil {
+		return nil, ErrIsDirectory
+	}
+	res := &GetFileLinesResponse{
+		Binary:    r.Binary,
+		Start:     r.Start,
+		NextStart: r.NextPageStart,
+		LastPage:  r.IsLastPage || r.Binary,
+	}
+	for _, l := range r.Lines {
+		res.Lines = append(res.Lines, l.Text)
+	}
+	return res, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetFileLinesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFileLines"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("at", c.At.String()),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetFileLinesResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("lines", len(r.Lines)),
+		slog.Bool("binary", r.Binary),
+		slog.Int("start", r.Start),
+		slog.Bool("lastPage", r.LastPage),
+	)
+}
+
+// GetFileLines returns a page of the lines of the file, or a response with
+// Binary set for a binary file.
+func (c *Client) GetFileLines(ctx context.Context, cmd *GetFileLinesCommand) (*GetFileLinesResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GetFilePathsCommand lists the paths of all files below FilePath, in all
+// subdirectories, with the files endpoint. The endpoint does not return
+// directories or sizes.
+type GetFilePathsCommand struct {
+	FilePath   string
+	ProjectKey string
+	RepoSlug   string
+	At         Ref
+	Start      int
+	Limit      int
+}
+
+type GetFilePathsResponse struct {
+	// Paths are relative to FilePath.
+	Paths     []string
+	Start     int
+	NextStart int
+	LastPage  bool
+}
+
+func (c *GetFilePathsCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		validatePath("FilePath", c.FilePath),
+		c.At.Validate(),
+		validatePaging(c.Start, c.Limit),
+	)
+}
+
+func (c *GetFilePathsCommand) ParseResponse(data []byte) (*GetFilePathsResponse, error) {
+	var r struct {
+		IsLastPage    bool     `json:"isLastPage"`
+		NextPageStart int      `json:"nextPageStart"`
+		Start         int      `json:"start"`
+		Values        []string `json:"values"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &GetFilePathsResponse{
+		Paths:     r.Values,
+		Start:     r.Start,
+		NextStart: r.NextPageStart,
+		LastPage:  r.IsLastPage,
+	}, nil
+}
+
+func (c *GetFilePathsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "files", c.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "at", c.At.String())
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetFilePathsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFilePaths"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("at", c.At.String()),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetFilePathsResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("paths", len(r.Paths)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextStart),
+		slog.Bool("lastPage", r.LastPage),
+	)
+}
+
+// GetFilePaths returns a page of the paths of the files below cmd.FilePath.
+func (c *Client) GetFilePaths(ctx context.Context, cmd *GetFilePathsCommand) (*GetFilePathsResponse, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// GetAllFilePaths returns the paths of all files below cmd.FilePath,
+// fetching all pages from cmd.Start.
+func (c *Client) GetAllFilePaths(ctx context.Context, cmd *GetFilePathsCommand) ([]string, error) {
+	next := *cmd
+	var res []string
+	for index := 0; ; index++ {
+		start := time.Now()
+		resp, err := c.GetFilePaths(ctx, &next)
+		if err != nil {
+			return nil, err
+		}
+		page := next
+		c.observePage(ctx, &page, index, len(resp.Paths), start)
+		res = append(res, resp.Paths...)
+		if resp.LastPage || len(resp.Paths) == 0 {
+			return res, nil
+		}
+		next.Start = resp.NextStart
+	}
+}
@@ -3,17 +3,20 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 type GetFilesCommand struct {
 	FilePath   string
 	ProjectKey string
 	RepoSlug   string
-	At         string
+	At         Ref
 	Start      int
 	Limit      int
 }
@@ -27,13 +30,13 @@ type GetFilesResponse struct {
 }
 
 func (c *GetFilesCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	return nil
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		validatePath("FilePath", c.FilePath),
+		c.At.Validate(),
+		validatePaging(c.Start, c.Limit),
+	)
 }
 
 func (c *GetFilesCommand) ParseResponse(data []byte) (*GetFilesResponse, error) {
@@ -48,8 +51,10 @@ func (c *GetFilesCommand) ParseResponse(data []byte) (*GetFilesResponse, error)
 					Name       string   `json:"name"`
 					Components []string `json:"components"`
 				} `json:"path"`
-				Type string `json:"type"`
-				Size int64  `json:"size"`
+				Type       string `json:"type"`
+				Size       int64  `json:"size"`
+				ContentID  string `json:"contentId"`
+				Executable bool   `json:"executable"`
 			} `json:"values"`
 		} `json:"children"`
 	}
@@ -62,23 +67,37 @@ func (c *GetFilesCommand) ParseResponse(data []byte) (*GetFilesResponse, error)
 		NextStart: r.Children.NextPageStart,
 		LastPage:  r.Children.IsLastPage,
 	}
+	if err := checkPage(resp.Start, resp.NextStart, resp.LastPage); err != nil {
+		return nil, err
+	}
 	for _, v := range r.Children.Values {
+		// The components of a child are its name, older servers only
+		// send the name.
+		name := v.Path.Name
+		if len(v.Path.Components) > 0 {
+			name = v.Path.Components[0]
+		}
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("bad name %q in the listing", name)
+		}
 		resp.Files = append(resp.Files, &FileInfo{
-			Name: v.Path.Components[0],
-			Size: v.Size,
-			Type: v.Type,
+			Name:       name,
+			Size:       v.Size,
+			Type:       v.Type,
+			ContentID:  v.ContentID,
+			Executable: v.Executable,
 		})
 	}
 	return resp, nil
 }
 
-func (c *GetFilesCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/browse/%s", baseURL, c.ProjectKey, c.RepoSlug, c.FilePath))
+func (c *GetFilesCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "browse", c.FilePath)
 	if err != nil {
 		return nil, err
 	}
 	vals := url.Values{}
-	addValue(vals, "at", c.At)
+	addValue(vals, "at", c.At.String())
 	addValue(vals, "start", strconv.Itoa(c.Start))
 	addValue(vals, "limit", strconv.Itoa(c.Limit))
 	u.RawQuery = vals.Encode()
@@ -95,4 +114,33 @@ type FileInfo struct {
 	Name string `json:"name"`
 	Size int64  `json:"size"`
 	Type string `json:"type"`
+	// ContentID is the id of the git blob of a file, it changes with the
+	// content. Empty for a directory.
+	ContentID string `json:"contentId,omitempty"`
+	// Executable is true for a file with the executable bit, on servers
+	// that report it in the listing.
+	Executable bool `json:"executable,omitempty"`
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetFilesCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetFiles"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("at", c.At.String()),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetFilesResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("files", len(r.Files)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextStart),
+		slog.Bool("lastPage", r.LastPage),
+	)
 }
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetProjectsCommand is the command to retrieve the projects visible to the user.
+type GetProjectsCommand struct {
+	// Name limits the result to projects with a name containing the text.
+	Name  string
+	Start int
+	Limit int
+}
+
+// Project is a project on the server.
+type Project struct {
+	Key         string
+	Name        string
+	Description string
+	Public      bool
+}
+
+type GetProjectsResponse struct {
+	IsLastPage    bool
+	Limit         int
+	NextPageStart int
+	Size          int
+	Start         int
+	Projects      []*Project
+}
+
+func (c *GetProjectsCommand) Validate() error {
+	return validatePaging(c.Start, c.Limit)
+}
+
+func (c *GetProjectsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := apiURL(baseURL, "projects")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "name", c.Name)
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetProjectsCommand) ParseResponse(data []byte) (*GetProjectsResponse, error) {
+	type response struct {
+		IsLastPage    bool `json:"isLastPage"`
+		Limit         int  `json:"limit"`
+		NextPageStart int  `json:"nextPageStart"`
+		Size          int  `json:"size"`
+		Start         int  `json:"start"`
+		Values        []struct {
+			Key         string `json:"key"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Public      bool   `json:"public"`
+		} `json:"values"`
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	gpr := &GetProjectsResponse{
+		IsLastPage:    resp.IsLastPage,
+		Limit:         resp.Limit,
+		NextPageStart: resp.NextPageStart,
+		Size:          resp.Size,
+		Start:         resp.Start,
+	}
+	for _, p := range resp.Values {
+		gpr.Projects = append(gpr.Projects, &Project{
+			Key:         p.Key,
+			Name:        p.Name,
+			Description: p.Description,
+			Public:      p.Public,
+		})
+	}
+	return gpr, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetProjectsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetProjects"),
+		slog.String("filter", c.Name),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetProjectsResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("projects", len(r.Projects)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextPageStart),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetReposCommand is the command to retrieve the repositories in a project.
+type GetReposCommand struct {
+	ProjectKey string
+	Start      int
+	Limit      int
+}
+
+// Repository is a repository in a project.
+type Repository struct {
+	Slug       string
+	Name       string
+	ProjectKey string
+	Public     bool
+}
+
+// repositoryJSON is a repository as returned by the server.
+type repositoryJSON struct {
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	Public  bool   `json:"public"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+func (r *repositoryJSON) repository() *Repository {
+	return &Repository{
+		Slug:       r.Slug,
+		Name:       r.Name,
+		ProjectKey: r.Project.Key,
+		Public:     r.Public,
+	}
+}
+
+type GetReposResponse struct {
+	IsLastPage    bool
+	Limit         int
+	NextPageStart int
+	Size          int
+	Start         int
+	Repos         []*Repository
+}
+
+func (c *GetReposCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		validatePaging(c.Start, c.Limit),
+	)
+}
+
+func (c *GetReposCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := apiURL(baseURL, "projects", c.ProjectKey, "repos")
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	addValue(vals, "start", strconv.Itoa(c.Start))
+	addValue(vals, "limit", strconv.Itoa(c.Limit))
+	u.RawQuery = vals.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetReposCommand) ParseResponse(data []byte) (*GetReposResponse, error) {
+	type response struct {
+		IsLastPage    bool             `json:"isLastPage"`
+		Limit         int              `json:"limit"`
+		NextPageStart int              `json:"nextPageStart"`
+		Size          int              `json:"size"`
+		Start         int              `json:"start"`
+		Values        []repositoryJSON `json:"values"`
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	grr := &GetReposResponse{
+		IsLastPage:    resp.IsLastPage,
+		Limit:         resp.Limit,
+		NextPageStart: resp.NextPageStart,
+		Size:          resp.Size,
+		Start:         resp.Start,
+	}
+	for _, r := range resp.Values {
+		grr.Repos = append(grr.Repos, r.repository())
+	}
+	return grr, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetReposCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetRepos"),
+		slog.String("project", c.ProjectKey),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetReposResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("repos", len(r.Repos)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextPageStart),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
+
+// GetRepoCommand is the command to retrieve a single repository.
+type GetRepoCommand struct {
+	ProjectKey string
+	RepoSlug   string
+}
+
+func (c *GetRepoCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+	)
+}
+
+func (c *GetRepoCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+func (c *GetRepoCommand) ParseResponse(data []byte) (*Repository, error) {
+	var resp repositoryJSON
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.repository(), nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetRepoCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetRepo"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+	)
+}
@@ -3,10 +3,12 @@ package server
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 const (
@@ -18,15 +20,25 @@ const (
 type GetTagsCommand struct {
 	ProjectKey string
 	RepoSlug   string
-	OrderBy    string
+	OrderBy    OrderBy
+	// FilterText limits the result to tags containing the text.
+	FilterText string
 	Start      int
 	Limit      int
+	// WithCommits fills in the commit fields of the tags,
+	// Client.GetTags looks up the commit of each tag for it.
+	WithCommits bool
 }
 
 type Tag struct {
 	Name     string
 	CommitID string
 	Type     string
+	// Timestamp, Message and Committer describe the tagged commit,
+	// they are only set when the tags are requested WithCommits.
+	Timestamp time.Time
+	Message   string
+	Committer Committer
 }
 
 type GetTagsResponse struct {
@@ -39,22 +51,22 @@ type GetTagsResponse struct {
 }
 
 func (c *GetTagsCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	return nil
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		c.OrderBy.Validate(),
+		validatePaging(c.Start, c.Limit),
+	)
 }
 
-func (c *GetTagsCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/tags", baseURL, c.ProjectKey, c.RepoSlug))
+func (c *GetTagsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "tags")
 	if err != nil {
 		return nil, err
 	}
 	vals := url.Values{}
-	addValue(vals, "orderBy", c.OrderBy)
+	addValue(vals, "orderBy", c.OrderBy.String())
+	addValue(vals, "filterText", c.FilterText)
 	addValue(vals, "start", strconv.Itoa(c.Start))
 	addValue(vals, "limit", strconv.Itoa(c.Limit))
 	u.RawQuery = vals.Encode()
@@ -93,6 +105,9 @@ func (c *GetTagsCommand) ParseResponse(data []byte) (*GetTagsResponse, error) {
 		Size:          resp.Size,
 		Start:         resp.Start,
 	}
+	if err := checkPage(resp.Start, resp.NextPageStart, resp.IsLastPage); err != nil {
+		return nil, err
+	}
 
 	for _, tag := range resp.Values {
 		gtr.Tags = append(gtr.Tags, &Tag{
@@ -103,3 +118,27 @@ func (c *GetTagsCommand) ParseResponse(data []byte) (*GetTagsResponse, error) {
 	}
 	return gtr, nil
 }
+
+// LogValue implements slog.LogValuer.
+func (c *GetTagsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetTags"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("orderBy", c.OrderBy.String()),
+		slog.String("filterText", c.FilterText),
+		slog.Int("start", c.Start),
+		slog.Int("limit", c.Limit),
+		slog.Bool("withCommits", c.WithCommits),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *GetTagsResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("tags", len(r.Tags)),
+		slog.Int("start", r.Start),
+		slog.Int("nextStart", r.NextPageStart),
+		slog.Bool("lastPage", r.IsLastPage),
+	)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestGetTagsWithCommits(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{})
+	first := repo.Commits[0]
+	second := repo.Commit("main", "release 2", fstest.MapFS{})
+	repo.Tag("v1", first)
+	repo.Tag("v2", second)
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	c := &Client{BaseURL: srv.BaseURL()}
+	resp, err := c.GetTags(context.Background(), &GetTagsCommand{
+		ProjectKey:  "PRJ",
+		RepoSlug:    "repo",
+		WithCommits: true,
+	})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(resp.Tags) != 2 {
+		t.Fatalf("expected 2 tags, got %d", len(resp.Tags))
+	}
+	for i, want := range []*fakeserver.Commit{first, second} {
+		tag := resp.Tags[i]
+		if !tag.Timestamp.Equal(want.Timestamp) || tag.Message != want.Message || tag.Committer.Name != want.Author {
+			t.Errorf("tag %s: expected commit %+v, got %+v", tag.Name, want, tag)
+		}
+	}
+	if !resp.Tags[0].Timestamp.Before(resp.Tags[1].Timestamp) {
+		t.Errorf("expected v1 to be older than v2")
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+type headerKey struct{}
+
+// ContextWithHeader returns a context that adds the headers to the requests
+// sent with it, e.g. X-Forwarded-User for the audit log of a gateway.
+// They replace the headers of the same name in Client.Header.
+//
+// Responses are cached by url. When the headers change the response, like
+// impersonation does, use a client per user or disable the cache.
+func ContextWithHeader(ctx context.Context, h http.Header) context.Context {
+	if prev := HeaderFromContext(ctx); prev != nil {
+		merged := prev.Clone()
+		setHeader(merged, h)
+		h = merged
+	}
+	return context.WithValue(ctx, headerKey{}, h)
+}
+
+// HeaderFromContext returns the headers in the context or nil.
+func HeaderFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	return h
+}
+
+// setHeader replaces the values in dst with those in src.
+func setHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+}
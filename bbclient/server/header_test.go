@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"version":"8.0.0"}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		BaseURL:        srv.URL,
+		AccessKey:      "key",
+		MaxBodyInCache: -1,
+		Header: http.Header{
+			"X-Forwarded-User": {"service"},
+			"X-Audit":          {"bbfs"},
+			"Authorization":    {"Basic bogus"},
+		},
+	}
+	ctx := ContextWithHeader(context.Background(), http.Header{"X-Forwarded-User": {"alice"}})
+	ctx = ContextWithHeader(ctx, http.Header{"x-request-reason": {"build"}})
+	if _, err := c.GetApplicationProperties(ctx); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	want := map[string]string{
+		"X-Forwarded-User": "alice",
+		"X-Audit":          "bbfs",
+		"X-Request-Reason": "build",
+		"Authorization":    "Bearer key",
+	}
+	for k, v := range want {
+		if got.Get(k) != v || len(got.Values(k)) != 1 {
+			t.Errorf("%s: expected %q, got %q", k, v, got.Values(k))
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"version":"8.0.0"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		app    string
+		header http.Header
+		want   string
+	}{
+		{name: "default", want: "bbfs/devel"},
+		{name: "app", app: "docs-portal/2.1", want: "bbfs/devel docs-portal/2.1"},
+		{name: "header", app: "docs-portal/2.1", header: http.Header{"User-Agent": {"custom"}}, want: "custom"},
+	}
+	for _, tt := range tests {
+		c := &Client{BaseURL: srv.URL, AccessKey: "key", MaxBodyInCache: -1, UserAgent: tt.app, Header: tt.header}
+		if _, err := c.GetApplicationProperties(context.Background()); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAccept(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Accept"))
+		w.Write([]byte(`{"version":"8.0.0"}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, AccessKey: "key", MaxBodyInCache: -1}
+	ctx := context.Background()
+	if _, err := c.GetApplicationProperties(ctx); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	raw := &OpenRawFileCommand{ProjectKey: "P", RepoSlug: "r", FilePath: "a.txt"}
+	body, err := c.OpenRawFile(ctx, raw)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	body.Close()
+	if _, err := c.StatRawFile(ctx, &StatRawFileCommand{ProjectKey: "P", RepoSlug: "r", FilePath: "a.txt"}); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	// The header of the context replaces the one of the command.
+	ctx = ContextWithHeader(ctx, http.Header{"Accept": {"text/plain"}})
+	body, err = c.OpenRawFile(ctx, raw)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	body.Close()
+
+	want := []string{acceptJSON, acceptRaw, acceptRaw, "text/plain"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Results of an InsightReport.
+const (
+	InsightResultPass = "PASS"
+	InsightResultFail = "FAIL"
+)
+
+// Severities of an InsightAnnotation.
+const (
+	InsightSeverityLow    = "LOW"
+	InsightSeverityMedium = "MEDIUM"
+	InsightSeverityHigh   = "HIGH"
+)
+
+// Types of an InsightAnnotation.
+const (
+	InsightTypeVulnerability = "VULNERABILITY"
+	InsightTypeCodeSmell     = "CODE_SMELL"
+	InsightTypeBug           = "BUG"
+)
+
+// MaxInsightAnnotations is the maximum number of annotations the server
+// accepts in a request.
+const MaxInsightAnnotations = 1000
+
+// InsightReport is a Code Insights report on a commit, e.g. the result of a scan.
+type InsightReport struct {
+	// Key is the key of the report, set in the response.
+	Key     string
+	Title   string
+	Details string
+	// Result is InsightResultPass, InsightResultFail or empty.
+	Result   string
+	Reporter string
+	Link     string
+	LogoURL  string
+	Data     []InsightData
+	// CreatedDate is set in the response.
+	CreatedDate time.Time
+}
+
+// InsightData is a value in the summary of an InsightReport.
+type InsightData struct {
+	Title string
+	// Type is BOOLEAN, DATE, DURATION, LINK, NUMBER, PERCENTAGE or TEXT,
+	// empty lets the server infer it from the value.
+	Type  string
+	Value any
+}
+
+// InsightAnnotation is a remark of a report on a line of a file.
+type InsightAnnotation struct {
+	// ExternalID identifies the annotation for the reporter, optional.
+	ExternalID string
+	// Path is the path of the file in the repository, empty for the
+	// annotation of the commit.
+	Path string
+	// Line is the line in the file, 0 for the file.
+	Line    int
+	Message string
+	// Severity is InsightSeverityLow, InsightSeverityMedium or InsightSeverityHigh.
+	Severity string
+	// Type is InsightTypeVulnerability, InsightTypeCodeSmell, InsightTypeBug
+	// or empty.
+	Type string
+	Link string
+}
+
+// PutInsightReportCommand creates or replaces the report with the key on a commit.
+type PutInsightReportCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	CommitID   string
+	// Key identifies the report on the commit, e.g. com.example.lint.
+	Key    string
+	Report *InsightReport
+}
+
+func (c *PutInsightReportCommand) Validate() error {
+	errs := []error{
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("CommitID", c.CommitID),
+		required("Key", c.Key),
+	}
+	if c.Report == nil {
+		return errors.Join(append(errs, errors.New("Report is missing"))...)
+	}
+	errs = append(errs, required("Report.Title", c.Report.Title))
+	switch c.Report.Result {
+	case "", InsightResultPass, InsightResultFail:
+	default:
+		errs = append(errs, fmt.Errorf("Report.Result must be %s or %s, got %q", InsightResultPass, InsightResultFail, c.Report.Result))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *PutInsightReportCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := insightsURL(baseURL, c.ProjectKey, c.RepoSlug, "commits", c.CommitID, "reports", c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for PutInsightReportCommand: %w", err)
+	}
+	type data struct {
+		Title string `json:"title"`
+		Type  string `json:"type,omitempty"`
+		Value any    `json:"value"`
+	}
+	body := struct {
+		Title    string `json:"title"`
+		Details  string `json:"details,omitempty"`
+		Result   string `json:"result,omitempty"`
+		Reporter string `json:"reporter,omitempty"`
+		Link     string `json:"link,omitempty"`
+		LogoURL  string `json:"logoUrl,omitempty"`
+		Data     []data `json:"data,omitempty"`
+	}{
+		Title:    c.Report.Title,
+		Details:  c.Report.Details,
+		Result:   c.Report.Result,
+		Reporter: c.Report.Reporter,
+		Link:     c.Report.Link,
+		LogoURL:  c.Report.LogoURL,
+	}
+	for _, d := range c.Report.Data {
+		body.Data = append(body.Data, data{Title: d.Title, Type: d.Type, Value: d.Value})
+	}
+	return newJSONRequest(ctx, http.MethodPut, u.String(), body)
+}
+
+func (c *PutInsightReportCommand) ParseResponse(data []byte) (*InsightReport, error) {
+	var resp struct {
+		Key         string `json:"key"`
+		Title       string `json:"title"`
+		Details     string `json:"details"`
+		Result      string `json:"result"`
+		Reporter    string `json:"reporter"`
+		Link        string `json:"link"`
+		LogoURL     string `json:"logoUrl"`
+		CreatedDate int64  `json:"createdDate"`
+		Data        []struct {
+			Title string `json:"title"`
+			Type  string `json:"type"`
+			Value any    `json:"value"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	res := &InsightReport{
+		Key:         resp.Key,
+		Title:       resp.Title,
+		Details:     resp.Details,
+		Result:      resp.Result,
+		Reporter:    resp.Reporter,
+		Link:        resp.Link,
+		LogoURL:     resp.LogoURL,
+		CreatedDate: time.UnixMilli(resp.CreatedDate),
+	}
+	for _, d := range resp.Data {
+		res.Data = append(res.Data, InsightData{Title: d.Title, Type: d.Type, Value: d.Value})
+	}
+	return res, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *PutInsightReportCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "PutInsightReport"),
+		slog.String("projectKey", c.ProjectKey),
+		slog.String("repoSlug", c.RepoSlug),
+		slog.String("commitId", c.CommitID),
+		slog.String("key", c.Key),
+	)
+}
+
+// LogValue implements slog.LogValuer.
+func (r *InsightReport) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("key", r.Key),
+		slog.String("result", r.Result),
+	)
+}
+
+// AddInsightAnnotationsCommand adds annotations to the report with the
+// key on a commit. The report must exist.
+type AddInsightAnnotationsCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	CommitID   string
+	ReportKey  string
+	// Annotations are at most MaxInsightAnnotations.
+	Annotations []*InsightAnnotation
+}
+
+func (c *AddInsightAnnotationsCommand) Validate() error {
+	errs := []error{
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("CommitID", c.CommitID),
+		required("ReportKey", c.ReportKey),
+	}
+	if n := len(c.Annotations); n == 0 || n > MaxInsightAnnotations {
+		errs = append(errs, fmt.Errorf("Annotations must be between 1 and %d, got %d", MaxInsightAnnotations, n))
+	}
+	for i, a := range c.Annotations {
+		name := fmt.Sprintf("Annotations[%d]", i)
+		if a == nil {
+			errs = append(errs, fmt.Errorf("%s is missing", name))
+			continue
+		}
+		errs = append(errs,
+			required(name+".Message", a.Message),
+			validatePath(name+".Path", a.Path),
+		)
+		if a.Line < 0 {
+			errs = append(errs, fmt.Errorf("%s.Line must not be negative, got %d", name, a.Line))
+		}
+		switch a.Severity {
+		case InsightSeverityLow, InsightSeverityMedium, InsightSeverityHigh:
+		default:
+			errs = append(errs, fmt.Errorf("%s.Severity must be %s, %s or %s, got %q", name,
+				InsightSeverityLow, InsightSeverityMedium, InsightSeverityHigh, a.Severity))
+		}
+		switch a.Type {
+		case "", InsightTypeVulnerability, InsightTypeCodeSmell, InsightTypeBug:
+		default:
+			errs = append(errs, fmt.Errorf("%s.Type must be %s, %s or %s, got %q", name,
+				InsightTypeVulnerability, InsightTypeCodeSmell, InsightTypeBug, a.Type))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *AddInsightAnnotationsCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := insightsURL(baseURL, c.ProjectKey, c.RepoSlug, "commits", c.CommitID, "reports", c.ReportKey, "annotations")
+	if err != nil {
+		return nil, fmt.Errorf("error building url for AddInsightAnnotationsCommand: %w", err)
+	}
+	type annotation struct {
+		ExternalID string `json:"externalId,omitempty"`
+		Path       string `json:"path,omitempty"`
+		Line       int    `json:"line,omitempty"`
+		Message    string `json:"message"`
+		Severity   string `json:"severity"`
+		Type       string `json:"type,omitempty"`
+		Link       string `json:"link,omitempty"`
+	}
+	var body struct {
+		Annotations []annotation `json:"annotations"`
+	}
+	for _, a := range c.Annotations {
+		body.Annotations = append(body.Annotations, annotation{
+			ExternalID: a.ExternalID,
+			Path:       a.Path,
+			Line:       a.Line,
+			Message:    a.Message,
+			Severity:   a.Severity,
+			Type:       a.Type,
+			Link:       a.Link,
+		})
+	}
+	return newJSONRequest(ctx, http.MethodPost, u.String(), body)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *AddInsightAnnotationsCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "AddInsightAnnotations"),
+		slog.String("projectKey", c.ProjectKey),
+		slog.String("repoSlug", c.RepoSlug),
+		slog.String("commitId", c.CommitID),
+		slog.String("reportKey", c.ReportKey),
+		slog.Int("annotations", len(c.Annotations)),
+	)
+}
+
+// PutInsightReport creates or replaces a Code Insights report on a commit
+// and returns the report as stored by the server.
+func (c *Client) PutInsightReport(ctx context.Context, cmd *PutInsightReportCommand) (*InsightReport, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// AddInsightAnnotations adds annotations to a Code Insights report. Send
+// more than MaxInsightAnnotations annotations in several commands.
+func (c *Client) AddInsightAnnotations(ctx context.Context, cmd *AddInsightAnnotationsCommand) error {
+	body, err := DoCommandBody(ctx, c, cmd)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// insightsURL returns the code insights url for the elements below the
+// repository.
+func insightsURL(baseURL, project, repo string, elem ...string) (*url.URL, error) {
+	return restURL(baseURL, "insights", append([]string{"projects", project, "repos", repo}, elem...)...)
+}
+
+// newJSONRequest returns a request with the body as json.
+func newJSONRequest(ctx context.Context, method, rawURL string, body any) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInsights(t *testing.T) {
+	type request struct {
+		method, path, contentType string
+		body                      map[string]any
+	}
+	var reqs []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := request{method: r.Method, path: r.URL.Path, contentType: r.Header.Get("Content-Type")}
+		json.Unmarshal(data, &req.body)
+		reqs = append(reqs, req)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"key":"lint","title":"Lint","result":"FAIL","createdDate":1700000000000,"data":[{"title":"Issues","type":"NUMBER","value":2}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/rest/api/latest", "key")
+	ctx := context.Background()
+	put := &PutInsightReportCommand{
+		ProjectKey: "P",
+		RepoSlug:   "r",
+		CommitID:   "abc",
+		Key:        "lint",
+		Report: &InsightReport{
+			Title:  "Lint",
+			Result: InsightResultFail,
+			Data:   []InsightData{{Title: "Issues", Type: "NUMBER", Value: 2}},
+		},
+	}
+	// Twice, the writes are not cached.
+	for range 2 {
+		report, err := c.PutInsightReport(ctx, put)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if report.Key != "lint" || report.Result != InsightResultFail || len(report.Data) != 1 || report.CreatedDate.UnixMilli() != 1700000000000 {
+			t.Errorf("unexpected report %+v", report)
+		}
+	}
+	err := c.AddInsightAnnotations(ctx, &AddInsightAnnotationsCommand{
+		ProjectKey: "P",
+		RepoSlug:   "r",
+		CommitID:   "abc",
+		ReportKey:  "lint",
+		Annotations: []*InsightAnnotation{
+			{Path: "main.go", Line: 3, Message: "unused variable", Severity: InsightSeverityLow, Type: InsightTypeCodeSmell},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	tests := []struct {
+		req    request
+		method string
+		path   string
+	}{
+		{reqs[0], http.MethodPut, "/rest/insights/latest/projects/P/repos/r/commits/abc/reports/lint"},
+		{reqs[2], http.MethodPost, "/rest/insights/latest/projects/P/repos/r/commits/abc/reports/lint/annotations"},
+	}
+	for _, tt := range tests {
+		if tt.req.method != tt.method || tt.req.path != tt.path {
+			t.Errorf("expected %s %s, got %s %s", tt.method, tt.path, tt.req.method, tt.req.path)
+		}
+		if tt.req.contentType != "application/json" {
+			t.Errorf("expected json, got %q", tt.req.contentType)
+		}
+	}
+	if reqs[0].body["title"] != "Lint" || reqs[0].body["result"] != "FAIL" {
+		t.Errorf("unexpected report body %v", reqs[0].body)
+	}
+	annotations, _ := reqs[2].body["annotations"].([]any)
+	if len(annotations) != 1 {
+		t.Fatalf("expected 1 annotation, got %v", reqs[2].body)
+	}
+	if a := annotations[0].(map[string]any); a["path"] != "main.go" || a["line"] != 3.0 || a["severity"] != "LOW" {
+		t.Errorf("unexpected annotation %v", a)
+	}
+
+	// Invalid commands are not sent.
+	invalid := []Command{
+		&PutInsightReportCommand{ProjectKey: "P", RepoSlug: "r", CommitID: "abc", Key: "lint"},
+		&PutInsightReportCommand{ProjectKey: "P", RepoSlug: "r", CommitID: "abc", Key: "lint", Report: &InsightReport{Title: "Lint", Result: "OK"}},
+		&AddInsightAnnotationsCommand{ProjectKey: "P", RepoSlug: "r", CommitID: "abc", ReportKey: "lint"},
+		&AddInsightAnnotationsCommand{ProjectKey: "P", RepoSlug: "r", CommitID: "abc", ReportKey: "lint",
+			Annotations: []*InsightAnnotation{{Path: "/main.go", Message: "m", Severity: "LOW"}}},
+	}
+	for _, cmd := range invalid {
+		if _, err := DoCommandBody(ctx, c, cmd); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("%T: expected an invalid command, got %v", cmd, err)
+		}
+	}
+
+	// The insights api is next to the rest api.
+	c = NewClient(srv.URL, "key")
+	if _, err := c.PutInsightReport(ctx, put); err == nil {
+		t.Errorf("expected an error for a base url without /rest/api/")
+	}
+}
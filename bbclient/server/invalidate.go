@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// CurrentCommit returns the id of the commit the ref points to now, the
+// head of the default branch for an empty ref. The response is not cached,
+// so a poll sees a push without clearing the cache.
+func (c *Client) CurrentCommit(ctx context.Context, projectKey, repoSlug string, ref Ref) (string, error) {
+	return c.resolveCommit(ctx, projectKey, repoSlug, ref)
+}
+
+// InvalidatePaths drops the cached responses for the paths at the ref,
+// after a push changed them, and returns the number of dropped entries.
+// The other paths stay in the cache.
+//
+// It drops the content and listings of the paths, of the directories they
+// are in and of the paths below them, the archives at the ref, and the
+// branches, tags and commits that resolve the ref.
+func (c *Client) InvalidatePaths(projectKey, repoSlug string, ref Ref, paths []string) int {
+	c.init()
+	changed := make([]string, 0, len(paths))
+	for _, p := range paths {
+		changed = append(changed, strings.Trim(p, "/"))
+	}
+	affected := func(p string) bool {
+		p = strings.Trim(p, "/")
+		for _, ch := range changed {
+			if p == ch || p == "" || strings.HasPrefix(ch, p+"/") || strings.HasPrefix(p, ch+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	n := 0
+	base, err := repoURL(c.BaseURL, projectKey, repoSlug)
+	if err == nil {
+		cache := c.getCache()
+		var drop []string
+		for key := range cache.All() {
+			if bodyAffected(key, base.Path+"/", ref, affected) {
+				drop = append(drop, key)
+			}
+		}
+		for _, key := range drop {
+			cache.Delete(key)
+		}
+		n += len(drop)
+	}
+
+	prefix := projectKey + "/" + repoSlug + "/"
+	n += dropListings(c.listings, prefix, ref, affected)
+	n += dropListings(c.stats, prefix, ref, affected)
+	return n
+}
+
+// dropListings drops the entries of the listing or stat cache for the
+// affected paths at the ref, and returns their number.
+func dropListings[V any](cache *syncedCache[string, V], prefix string, ref Ref, affected func(string) bool) int {
+	var drop []string
+	for key := range cache.All() {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		p, query, _ := strings.Cut(rest, "?at=")
+		query, _, _ = strings.Cut(query, "#")
+		at, _, _ := strings.Cut(query, "&limit=")
+		if at == ref.String() && affected(p) {
+			drop = append(drop, key)
+		}
+	}
+	for _, key := range drop {
+		cache.Delete(key)
+	}
+	return len(drop)
+}
+
+// bodyAffected returns true if the cached response for the url depends on
+// the paths at the ref.
+func bodyAffected(key, repoPath string, ref Ref, affected func(string) bool) bool {
+	u, err := url.Parse(key)
+	if err != nil {
+		return false
+	}
+	rest, ok := strings.CutPrefix(path.Clean(u.Path)+"/", repoPath)
+	if !ok {
+		return false
+	}
+	endpoint, p, _ := strings.Cut(rest, "/")
+	q := u.Query()
+	switch endpoint {
+	case "raw", "browse", "files":
+		return q.Get("at") == ref.String() && affected(p)
+	case "archive":
+		return q.Get("at") == ref.String()
+	case "branches", "tags":
+		return true
+	case "commits":
+		return p == "" && q.Get("until") == ref.String()
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestInvalidatePaths(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"a.txt":       {Data: []byte("a")},
+		"dir/b.txt":   {Data: []byte("b")},
+		"dir/c.txt":   {Data: []byte("c")},
+		"other/d.txt": {Data: []byte("d")},
+	})
+	srv.AddRepo("PRJ", "repo", repo)
+	commit := repo.Commits[0].ID
+	c := &Client{BaseURL: srv.BaseURL()}
+	ctx := context.Background()
+
+	read := func(p string, at Ref) {
+		t.Helper()
+		r, err := c.OpenRawFile(ctx, &OpenRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: p, At: at})
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		io.ReadAll(r)
+		r.Close()
+	}
+	list := func(p string) {
+		t.Helper()
+		iter, err := c.GetFilesIterator(ctx, &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: p, At: "main", Limit: 1000})
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		for range iter.Files() {
+		}
+	}
+	for _, p := range []string{"a.txt", "dir/b.txt", "dir/c.txt", "other/d.txt"} {
+		read(p, "main")
+	}
+	// The content at a commit does not change.
+	read("dir/b.txt", CommitRef(commit))
+	for _, p := range []string{"", "dir", "other"} {
+		list(p)
+	}
+	if _, err := c.GetBranches(ctx, &GetBranchesCommand{ProjectKey: "PRJ", RepoSlug: "repo"}); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	if n := c.InvalidatePaths("PRJ", "repo", "main", []string{"dir/b.txt"}); n != 4 {
+		t.Errorf("expected 4 dropped entries, got %d", n)
+	}
+	var kept []string
+	for k := range c.CachedKeys() {
+		kept = append(kept, k)
+	}
+	slices.Sort(kept)
+	base := srv.BaseURL() + "/projects/PRJ/repos/repo"
+	want := []string{
+		base + "/raw/a.txt?at=main",
+		base + "/raw/dir/b.txt?at=" + commit,
+		base + "/raw/dir/c.txt?at=main",
+		base + "/raw/other/d.txt?at=main",
+	}
+	if !slices.Equal(kept, want) {
+		t.Errorf("expected the cache to keep\n%v\ngot\n%v", want, kept)
+	}
+	for p, cached := range map[string]bool{"": false, "dir": false, "other": true} {
+		cmd := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: p, At: "main", Limit: 1000}
+		if got := c.FilesCached(ctx, cmd); got != cached {
+			t.Errorf("listing of %q: expected cached %v, got %v", p, cached, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// The listings of directories are cached as a unit, all pages or none, so a
+// listing never mixes pages from before and after a change of the repository.
+// The key has a generation number per repository, InvalidateListings
+// increments it to drop all listings of the repository at once.
+
+type listingCache = syncedCache[string, []*GetFilesResponse]
+
+// statCache holds the file infos of StatRawFile, with the keys and expiry
+// of the listings.
+type statCache = syncedCache[string, *RawFileInfo]
+
+// uncachedCommand is a command whose response is not in the body cache.
+type uncachedCommand interface {
+	noCache()
+}
+
+// uncachedFiles is a GetFilesCommand that bypasses the body cache, the
+// iterator caches the pages as a listing.
+type uncachedFiles struct {
+	*GetFilesCommand
+}
+
+func (uncachedFiles) noCache() {}
+
+// uncachedCommits is a GetCommitsCommand that bypasses the body cache,
+// to resolve a ref to its current commit.
+type uncachedCommits struct {
+	*GetCommitsCommand
+}
+
+func (uncachedCommits) noCache() {}
+
+// getFilesPage gets a page of the listing from the server.
+func (c *Client) getFilesPage(ctx context.Context, cmd *GetFilesCommand) (*GetFilesResponse, error) {
+	return DoCommandResponse(ctx, c, uncachedFiles{cmd})
+}
+
+// listingsEnabled returns false when caching or caching listings is disabled.
+func (c *Client) listingsEnabled() bool {
+	c.init()
+	return c.MaxBodyInCache >= 0 && c.MaxListingsInCache >= 0
+}
+
+// listingKey returns the key of the listing for the command, the start of
+// the command is ignored.
+func (c *Client) listingKey(cmd *GetFilesCommand) string {
+	return fmt.Sprintf("%s/%s/%s?at=%s&limit=%d#%d",
+		cmd.ProjectKey, cmd.RepoSlug, cmd.FilePath, cmd.At, cmd.Limit,
+		c.generation(cmd.ProjectKey, cmd.RepoSlug).Load())
+}
+
+// statKey returns the key of the file info for the command.
+func (c *Client) statKey(cmd *StatRawFileCommand) string {
+	return fmt.Sprintf("%s/%s/%s?at=%s#%d",
+		cmd.ProjectKey, cmd.RepoSlug, cmd.FilePath, cmd.At,
+		c.generation(cmd.ProjectKey, cmd.RepoSlug).Load())
+}
+
+// generation returns the generation counter of the repository.
+func (c *Client) generation(projectKey, repoSlug string) *atomic.Uint64 {
+	v, _ := c.generations.LoadOrStore(projectKey+"/"+repoSlug, new(atomic.Uint64))
+	return v.(*atomic.Uint64)
+}
+
+// InvalidateListings drops the cached directory listings and file stats of
+// the repository, all pages of a listing together. Use it when the repository changed,
+// e.g. on a webhook for a push.
+func (c *Client) InvalidateListings(projectKey, repoSlug string) {
+	c.generation(projectKey, repoSlug).Add(1)
+}
+
+// FilesCached returns true if the listing for the command is in the cache.
+func (c *Client) FilesCached(ctx context.Context, cmd *GetFilesCommand) bool {
+	if cmd.Validate() != nil || !c.listingsEnabled() {
+		return false
+	}
+	return c.listings.Has(c.listingKey(cmd))
+}
+
+// resolveCommit returns the id of the current commit of the ref.
+func (c *Client) resolveCommit(ctx context.Context, projectKey, repoSlug string, ref Ref) (string, error) {
+	resp, err := DoCommandResponse(ctx, c, uncachedCommits{&GetCommitsCommand{
+		ProjectKey: projectKey,
+		RepoSlug:   repoSlug,
+		Until:      ref,
+		Limit:      1,
+	}})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Commits) == 0 {
+		return "", fmt.Errorf("no commit for ref %q", ref)
+	}
+	return resp.Commits[0].ID, nil
+}
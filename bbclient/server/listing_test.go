@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestListingCache(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+		"b.txt": {Data: []byte("b")},
+		"c.txt": {Data: []byte("c")},
+		"d.txt": {Data: []byte("d")},
+		"e.txt": {Data: []byte("e")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	c := &Client{BaseURL: srv.BaseURL()}
+	cmd := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", At: BranchRef("main"), Limit: 2}
+
+	list := func(push bool) string {
+		t.Helper()
+		iter, err := c.GetFilesIterator(context.Background(), cmd)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		var names []string
+		for f := range iter.Files() {
+			names = append(names, f.Name)
+			if push && len(names) == 1 {
+				// The branch moves to a commit with only z.txt while the listing is read.
+				repo.Commit("main", "add z", fstest.MapFS{"z.txt": {Data: []byte("z")}})
+			}
+		}
+		if err := iter.Err(); err != io.EOF {
+			t.Fatalf("error: %v", err)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := list(true); got != "a.txt,b.txt,c.txt,d.txt,e.txt" {
+		t.Errorf("expected the listing of one commit, got %s", got)
+	}
+	if cmd.Start != 0 || cmd.At != BranchRef("main") {
+		t.Errorf("the iterator changed the command: %+v", cmd)
+	}
+	if !c.FilesCached(context.Background(), cmd) {
+		t.Errorf("expected the listing in the cache")
+	}
+
+	before := srv.Requests()
+	if got := list(false); got != "a.txt,b.txt,c.txt,d.txt,e.txt" {
+		t.Errorf("expected the cached listing, got %s", got)
+	}
+	if n := srv.Requests() - before; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+	if st := c.CacheStats(); st.Listings != 1 {
+		t.Errorf("expected 1 listing, got %d", st.Listings)
+	}
+
+	c.InvalidateListings("PRJ", "repo")
+	if c.FilesCached(context.Background(), cmd) {
+		t.Errorf("expected the listing to be invalidated")
+	}
+	if got := list(false); got != "z.txt" {
+		t.Errorf("expected the new listing, got %s", got)
+	}
+}
+
+func TestStatCache(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"README.md": {Data: []byte("# readme\n")},
+	}))
+	ctx := context.Background()
+	cmd := &StatRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "README.md", At: BranchRef("main")}
+	list := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", At: BranchRef("main")}
+
+	c := &Client{BaseURL: srv.BaseURL(), ListingTTL: time.Minute}
+	for range 2 {
+		if _, err := c.StatRawFile(ctx, cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if n := srv.Requests(); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+	if st := c.CacheStats(); st.Stats != 1 || st.Entries != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if n := c.InvalidatePaths("PRJ", "repo", BranchRef("main"), []string{"README.md"}); n != 1 {
+		t.Errorf("expected 1 dropped entry, got %d", n)
+	}
+	if st := c.CacheStats(); st.Stats != 0 {
+		t.Errorf("expected the stat to be dropped, got %+v", st)
+	}
+
+	// Without the listing cache the bodies are still cached.
+	c = &Client{BaseURL: srv.BaseURL(), MaxListingsInCache: -1}
+	before := srv.Requests()
+	for range 2 {
+		if _, err := c.StatRawFile(ctx, cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if _, err := c.GetFiles(ctx, list); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if _, err := c.GetFilesIterator(ctx, list); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if n := srv.Requests() - before; n != 5 {
+		t.Errorf("expected 5 requests, got %d", n)
+	}
+	if c.FilesCached(ctx, list) {
+		t.Errorf("expected no cached listing")
+	}
+}
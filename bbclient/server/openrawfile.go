@@ -2,7 +2,8 @@ package server
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/url"
 )
@@ -11,16 +12,16 @@ type OpenRawFileCommand struct {
 	FilePath   string
 	ProjectKey string
 	RepoSlug   string
-	At         string
+	At         Ref
 }
 
-func (c *OpenRawFileCommand) newRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/projects/%s/repos/%s/raw/%s", baseURL, c.ProjectKey, c.RepoSlug, c.FilePath))
+func (c *OpenRawFileCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "raw", c.FilePath)
 	if err != nil {
 		return nil, err
 	}
 	vals := url.Values{}
-	addValue(vals, "at", c.At)
+	addValue(vals, "at", c.At.String())
 	u.RawQuery = vals.Encode()
 
 	us := u.String()
@@ -32,15 +33,26 @@ func (c *OpenRawFileCommand) newRequestWithContext(ctx context.Context, baseURL
 }
 
 func (c *OpenRawFileCommand) Validate() error {
-	if c.ProjectKey == "" {
-		return fmt.Errorf("ProjectKey is missing")
-	}
-	if c.RepoSlug == "" {
-		return fmt.Errorf("RepoSlug is missing")
-	}
-	if c.FilePath == "" {
-		return fmt.Errorf("FilePath is missing")
-	}
-	return nil
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+		c.At.Validate(),
+	)
 }
 
+func (c *OpenRawFileCommand) accept() string {
+	return acceptRaw
+}
+
+// LogValue implements slog.LogValuer.
+func (c *OpenRawFileCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "OpenRawFile"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("at", c.At.String()),
+	)
+}
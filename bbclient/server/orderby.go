@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrderBy is the sort order for lists of tags and branches.
+// The zero value leaves the order to the server.
+type OrderBy string
+
+const (
+	OrderByModification OrderBy = "MODIFICATION"
+	OrderByAlphabetical OrderBy = "ALPHABETICAL"
+)
+
+// OrderByValues contains the allowed values for OrderBy.
+var OrderByValues = []OrderBy{
+	OrderByAlphabetical,
+	OrderByModification,
+}
+
+func (o OrderBy) String() string {
+	return string(o)
+}
+
+// Validate returns an error if o is not empty and not one of OrderByValues.
+func (o OrderBy) Validate() error {
+	if o == "" {
+		return nil
+	}
+	for _, v := range OrderByValues {
+		if o == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid OrderBy %q, allowed values are %s", string(o), allowedOrderBy())
+}
+
+// ParseOrderBy returns the OrderBy for s, ignoring case.
+func ParseOrderBy(s string) (OrderBy, error) {
+	o := OrderBy(strings.ToUpper(s))
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+	return o, nil
+}
+
+func allowedOrderBy() string {
+	var vals []string
+	for _, v := range OrderByValues {
+		vals = append(vals, string(v))
+	}
+	return strings.Join(vals, ", ")
+}
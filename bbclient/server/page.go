@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PageInfo is a page of a paged listing, see Client.OnPage.
+type PageInfo struct {
+	// Command is the command of the page, e.g. a *GetFilesCommand with the
+	// start of the page.
+	Command Command
+	// Index is the index of the page in the listing, 0 for the first page.
+	Index int
+	// Size is the number of values on the page.
+	Size int
+	// Elapsed is the time the requests of the page took.
+	Elapsed time.Duration
+}
+
+// LogValue implements slog.LogValuer.
+func (p *PageInfo) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("command", p.Command),
+		slog.Int("index", p.Index),
+		slog.Int("size", p.Size),
+		slog.Duration("elapsed", p.Elapsed),
+	)
+}
+
+// observePage calls OnPage for the page of the command, which was fetched
+// since start.
+func (c *Client) observePage(ctx context.Context, cmd Command, index, size int, start time.Time) {
+	if c.OnPage == nil {
+		return
+	}
+	c.OnPage(ctx, &PageInfo{Command: cmd, Index: index, Size: size, Elapsed: time.Since(start)})
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// The seeds are responses of Bitbucket Server, with the malformed variants
+// that made the parsers panic or loop.
+
+func FuzzGetFilesResponse(f *testing.F) {
+	f.Add([]byte(`{"path":{"components":["docs"],"name":"docs","toString":"docs"},"revision":"main","children":{"size":2,"limit":500,"isLastPage":true,"values":[{"path":{"components":["README.md"],"parent":"","name":"README.md","extension":"md","toString":"README.md"},"contentId":"e69de29bb2d1d6434b8b29ae775ad8c2e48c5391","type":"FILE","size":0},{"path":{"components":["guide"],"parent":"","name":"guide","toString":"guide"},"node":"5f2c4e1","type":"DIRECTORY"}],"start":0}}`))
+	f.Add([]byte(`{"children":{"size":1,"limit":1,"isLastPage":false,"values":[{"path":{"components":["a.txt"]},"type":"FILE","size":1}],"start":0,"nextPageStart":1}}`))
+	f.Add([]byte(`{"children":{"isLastPage":true,"values":[{"path":{"components":[]},"type":"FILE"}]}}`))
+	f.Add([]byte(`{"children":{"isLastPage":true,"values":[{"path":{"name":"old.txt"},"type":"FILE"}]}}`))
+	f.Add([]byte(`{"children":{"isLastPage":false,"values":[],"start":5,"nextPageStart":5}}`))
+	f.Add([]byte(`{"children":{"isLastPage":true,"values":[{"path":{"components":["../x"]}}]}}`))
+	f.Add([]byte(`{"children":null}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cmd := &GetFilesCommand{}
+		resp, err := cmd.ParseResponse(data)
+		if err != nil {
+			return
+		}
+		if !resp.LastPage && resp.NextStart <= resp.Start {
+			t.Errorf("page does not move forward: %+v", resp)
+		}
+		for _, fi := range resp.Files {
+			if fi.Name == "" || fi.Name == "." || fi.Name == ".." || strings.Contains(fi.Name, "/") {
+				t.Errorf("bad name %q", fi.Name)
+			}
+		}
+	})
+}
+
+func FuzzGetTagsResponse(f *testing.F) {
+	f.Add([]byte(`{"size":1,"limit":25,"isLastPage":true,"values":[{"id":"refs/tags/v1.0.0","displayId":"v1.0.0","type":"TAG","latestCommit":"8d51122def5632836d1cb1026e879069e10a1e13","latestChangeset":"8d51122def5632836d1cb1026e879069e10a1e13","hash":"8d51122def5632836d1cb1026e879069e10a1e13"}],"start":0}`))
+	f.Add([]byte(`{"size":1,"limit":1,"isLastPage":false,"values":[{"displayId":"v1"}],"start":0,"nextPageStart":1}`))
+	f.Add([]byte(`{"isLastPage":false,"values":[]}`))
+	f.Add([]byte(`{"values":[null]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cmd := &GetTagsCommand{}
+		resp, err := cmd.ParseResponse(data)
+		if err != nil {
+			return
+		}
+		if !resp.IsLastPage && resp.NextPageStart <= resp.Start {
+			t.Errorf("page does not move forward: %+v", resp)
+		}
+	})
+}
+
+func FuzzGetCommitsResponse(f *testing.F) {
+	commit := `{"id":"def0123abcdef4567abcdef8987abcdef6543abc","displayId":"def0123abcd","author":{"name":"charlie","emailAddress":"charlie@example.com"},"authorTimestamp":1548719707064,"committer":{"name":"charlie","emailAddress":"charlie@example.com"},"committerTimestamp":1548719707064,"message":"More work on feature 1","parents":[{"id":"abcdef0123abcdef4567abcdef8987abcdef6543","displayId":"abcdef0"}]}`
+	f.Add([]byte(commit), false)
+	f.Add([]byte(`{"size":1,"limit":1,"isLastPage":false,"values":[`+commit+`],"start":0,"nextPageStart":1}`), false)
+	f.Add([]byte(`{"isLastPage":false,"values":[],"start":3}`), false)
+	f.Add([]byte(`{"values":[{"parents":[null]}]}`), false)
+	f.Add([]byte(commit), true)
+	f.Add([]byte(`{"committerTimestamp":-9223372036854775808}`), true)
+	f.Fuzz(func(t *testing.T, data []byte, single bool) {
+		cmd := &GetCommitsCommand{}
+		if single {
+			cmd.CommitID = "def0123abcdef4567abcdef8987abcdef6543abc"
+		}
+		resp, err := cmd.ParseResponse(data)
+		if err != nil {
+			return
+		}
+		if single && len(resp.Commits) != 1 {
+			t.Errorf("expected 1 commit, got %d", len(resp.Commits))
+		}
+		if !single && !resp.IsLastPage && resp.NextPageStart <= resp.Start {
+			t.Errorf("page does not move forward: %+v", resp)
+		}
+	})
+}
+
+func FuzzGetFileContentResponse(f *testing.F) {
+	f.Add([]byte(`{"lines":[{"text":"# readme"},{"text":""},{"text":"Some text."}],"start":0,"size":3,"isLastPage":true}`))
+	f.Add([]byte(`{"lines":[null,{"text":null}]}`))
+	f.Add([]byte(`{"lines":{}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cmd := &GetFileContentCommand{}
+		content, err := cmd.ParseResponse(data)
+		if err != nil {
+			return
+		}
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			t.Errorf("expected the content to end with a newline, got %q", content)
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientPool hands out a client per tenant, a combination of a server and
+// an access token, for services that serve the repositories of many teams.
+//
+// The clients share the connections of one transport, but each has its own
+// cache, so a tenant never sees responses fetched with the token of another.
+// The pool keeps a hash of the token, not the token, in its keys and stats.
+type ClientPool struct {
+	transport http.RoundTripper
+	logger    *slog.Logger
+	maxBody   int64
+	listings  int
+	ttl       time.Duration
+	rate      float64
+	burst     int
+
+	mu      sync.Mutex
+	tenants map[tenantKey]*tenant
+}
+
+// PoolOption is an option for NewClientPool.
+type PoolOption func(*ClientPool)
+
+// WithPoolTransport sets the transport shared by the clients,
+// defaults to http.DefaultTransport.
+func WithPoolTransport(rt http.RoundTripper) PoolOption {
+	return func(p *ClientPool) {
+		p.transport = rt
+	}
+}
+
+// WithPoolLogger sets the logger of the clients.
+func WithPoolLogger(l *slog.Logger) PoolOption {
+	return func(p *ClientPool) {
+		p.logger = l
+	}
+}
+
+// WithPoolMaxBodyInCache sets MaxBodyInCache of the clients.
+func WithPoolMaxBodyInCache(size int64) PoolOption {
+	return func(p *ClientPool) {
+		p.maxBody = size
+	}
+}
+
+// WithPoolListingCache sets MaxListingsInCache and ListingTTL of the clients.
+func WithPoolListingCache(size int, ttl time.Duration) PoolOption {
+	return func(p *ClientPool) {
+		p.listings = size
+		p.ttl = ttl
+	}
+}
+
+// WithTenantRateLimit limits the requests of each tenant to perSecond,
+// with bursts of up to burst requests. Requests over the limit wait.
+func WithTenantRateLimit(perSecond float64, burst int) PoolOption {
+	return func(p *ClientPool) {
+		p.rate = perSecond
+		p.burst = max(burst, 1)
+	}
+}
+
+// NewClientPool returns an empty pool.
+func NewClientPool(opts ...PoolOption) *ClientPool {
+	p := &ClientPool{
+		transport: http.DefaultTransport,
+		tenants:   map[tenantKey]*tenant{},
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+type tenantKey struct {
+	baseURL   string
+	tokenHash string
+}
+
+// tenant is a client in the pool with the transport that counts its requests.
+type tenant struct {
+	key       tenantKey
+	client    *Client
+	transport *tenantTransport
+}
+
+// TokenHash returns the hash that identifies the token in the pool.
+// It is safe to log and to use as a metrics label.
+func TokenHash(accessKey SecretString) string {
+	sum := sha256.Sum256([]byte(accessKey.Secret()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Client returns the client for the server at baseURL with the access key,
+// creating it on first use. Do not change the fields of the client, it is
+// shared by all users of the tenant.
+func (p *ClientPool) Client(baseURL string, accessKey SecretString) *Client {
+	key := tenantKey{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		tokenHash: TokenHash(accessKey),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.tenants[key]; ok {
+		return t.client
+	}
+
+	tt := &tenantTransport{base: p.transport}
+	if p.rate > 0 {
+		tt.limiter = newRateLimiter(p.rate, p.burst)
+	}
+	c := NewClient(baseURL, accessKey, func(c *Client) {
+		c.MaxBodyInCache = p.maxBody
+		c.MaxListingsInCache = p.listings
+		c.ListingTTL = p.ttl
+		c.HTTPClient = &http.Client{Transport: tt}
+		if p.logger != nil {
+			c.Logger = p.logger.With(slog.String("tenant", key.tokenHash))
+		}
+	})
+	p.tenants[key] = &tenant{key: key, client: c, transport: tt}
+	return c
+}
+
+// Remove removes the client of the tenant from the pool, for example when
+// the token is revoked. Users of the client can keep using it.
+func (p *ClientPool) Remove(baseURL string, accessKey SecretString) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.tenants, tenantKey{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		tokenHash: TokenHash(accessKey),
+	})
+}
+
+// TenantStats are the statistics of a tenant in the pool.
+type TenantStats struct {
+	// BaseURL and TokenHash identify the tenant, use them as metrics labels.
+	BaseURL   string
+	TokenHash string
+	// Requests is the number of requests sent to the server.
+	Requests int64
+	// Failures is the number of requests that failed or got a 5xx or 429 status.
+	Failures int64
+	// Throttled is the number of requests that waited for the rate limit.
+	Throttled int64
+	// Cache describes the cache of the client.
+	Cache CacheStats
+}
+
+// Stats returns the statistics of the tenants, sorted by BaseURL and TokenHash.
+func (p *ClientPool) Stats() []TenantStats {
+	p.mu.Lock()
+	tenants := make([]*tenant, 0, len(p.tenants))
+	for _, t := range p.tenants {
+		tenants = append(tenants, t)
+	}
+	p.mu.Unlock()
+
+	res := make([]TenantStats, 0, len(tenants))
+	for _, t := range tenants {
+		res = append(res, TenantStats{
+			BaseURL:   t.key.baseURL,
+			TokenHash: t.key.tokenHash,
+			Requests:  t.transport.requests.Load(),
+			Failures:  t.transport.failures.Load(),
+			Throttled: t.transport.throttled.Load(),
+			Cache:     t.client.CacheStats(),
+		})
+	}
+	slices.SortFunc(res, func(a, b TenantStats) int {
+		if c := strings.Compare(a.BaseURL, b.BaseURL); c != 0 {
+			return c
+		}
+		return strings.Compare(a.TokenHash, b.TokenHash)
+	})
+	return res
+}
+
+// tenantTransport counts and rate limits the requests of a tenant
+// on the shared transport.
+type tenantTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+
+	requests  atomic.Int64
+	failures  atomic.Int64
+	throttled atomic.Int64
+}
+
+func (t *tenantTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.limiter != nil {
+		waited, err := t.limiter.wait(req.Context())
+		if waited {
+			t.throttled.Add(1)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	t.requests.Add(1)
+	resp, err := t.base.RoundTrip(req)
+	if isServerFailure(resp, err) {
+		t.failures.Add(1)
+	}
+	return resp, err
+}
+
+// rateLimiter is a token bucket.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// wait takes a token, waiting until one is available.
+// It returns true if it had to wait.
+func (l *rateLimiter) wait(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return false, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		// Give the token back, the request is not sent.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return true, ctx.Err()
+	}
+}
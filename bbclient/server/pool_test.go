@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestClientPool(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+
+	pool := NewClientPool()
+	a := pool.Client(srv.BaseURL(), "token-a")
+	if pool.Client(srv.BaseURL()+"/", "token-a") != a {
+		t.Errorf("expected the same client for the same tenant")
+	}
+	b := pool.Client(srv.BaseURL(), "token-b")
+	if b == a {
+		t.Fatalf("expected another client for another token")
+	}
+
+	cmd := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo", Limit: 10}
+	for range 2 {
+		if _, err := a.GetFiles(context.Background(), cmd); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	// The cache of a is not shared with b.
+	if b.FilesCached(context.Background(), cmd) {
+		t.Errorf("expected the caches of the tenants to be separate")
+	}
+
+	stats := pool.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 tenants, got %d", len(stats))
+	}
+	for _, st := range stats {
+		want := int64(0)
+		if st.TokenHash == TokenHash("token-a") {
+			want = 1
+		}
+		if st.Requests != want || st.BaseURL != srv.BaseURL() {
+			t.Errorf("%s: expected %d requests, got %+v", st.TokenHash, want, st)
+		}
+	}
+
+	pool.Remove(srv.BaseURL(), "token-a")
+	if pool.Client(srv.BaseURL(), "token-a") == a {
+		t.Errorf("expected a new client after Remove")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(1, 2)
+	l.now = func() time.Time { return now }
+
+	for range 2 {
+		if waited, err := l.wait(context.Background()); waited || err != nil {
+			t.Fatalf("expected a burst of 2, got %v %v", waited, err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if waited, err := l.wait(ctx); !waited || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected to wait, got %v %v", waited, err)
+	}
+	now = now.Add(time.Second)
+	if waited, err := l.wait(context.Background()); waited || err != nil {
+		t.Errorf("expected a token after a second, got %v %v", waited, err)
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// PullRequest is a pull request of a repository.
+type PullRequest struct {
+	ID int
+	// Version is the version the merge of the pull request needs.
+	Version int
+	// State is OPEN, DECLINED or MERGED.
+	State   string
+	Title   string
+	FromRef Ref
+	ToRef   Ref
+	// MergeCommit is the commit that merged the pull request, for a merged
+	// pull request on servers that report it.
+	MergeCommit string
+}
+
+// parsePullRequest parses a pull request of a response.
+func parsePullRequest(data []byte) (*PullRequest, error) {
+	var resp struct {
+		ID      int    `json:"id"`
+		Version int    `json:"version"`
+		State   string `json:"state"`
+		Title   string `json:"title"`
+		FromRef struct {
+			ID string `json:"id"`
+		} `json:"fromRef"`
+		ToRef struct {
+			ID string `json:"id"`
+		} `json:"toRef"`
+		Properties struct {
+			MergeCommit struct {
+				ID string `json:"id"`
+			} `json:"mergeCommit"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling pull request: %w", err)
+	}
+	return &PullRequest{
+		ID:          resp.ID,
+		Version:     resp.Version,
+		State:       resp.State,
+		Title:       resp.Title,
+		FromRef:     Ref(resp.FromRef.ID),
+		ToRef:       Ref(resp.ToRef.ID),
+		MergeCommit: resp.Properties.MergeCommit.ID,
+	}, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (p *PullRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", p.ID),
+		slog.String("state", p.State),
+	)
+}
+
+// CreatePullRequestCommand creates a pull request from a branch to another
+// branch of the repository.
+type CreatePullRequestCommand struct {
+	ProjectKey  string
+	RepoSlug    string
+	Title       string
+	Description string
+	// FromBranch is the branch with the changes, ToBranch the branch to
+	// merge them into.
+	FromBranch string
+	ToBranch   string
+}
+
+func (c *CreatePullRequestCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("Title", c.Title),
+		required("FromBranch", c.FromBranch),
+		required("ToBranch", c.ToBranch),
+	)
+}
+
+func (c *CreatePullRequestCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "pull-requests")
+	if err != nil {
+		return nil, fmt.Errorf("error building url for CreatePullRequestCommand: %w", err)
+	}
+	type project struct {
+		Key string `json:"key"`
+	}
+	type repository struct {
+		Slug    string  `json:"slug"`
+		Project project `json:"project"`
+	}
+	type ref struct {
+		ID         string     `json:"id"`
+		Repository repository `json:"repository"`
+	}
+	repo := repository{Slug: c.RepoSlug, Project: project{Key: c.ProjectKey}}
+	body := struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		FromRef     ref    `json:"fromRef"`
+		ToRef       ref    `json:"toRef"`
+	}{
+		Title:       c.Title,
+		Description: c.Description,
+		FromRef:     ref{ID: BranchRef(c.FromBranch).String(), Repository: repo},
+		ToRef:       ref{ID: BranchRef(c.ToBranch).String(), Repository: repo},
+	}
+	return newJSONRequest(ctx, http.MethodPost, u.String(), body)
+}
+
+func (c *CreatePullRequestCommand) ParseResponse(data []byte) (*PullRequest, error) {
+	return parsePullRequest(data)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *CreatePullRequestCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "CreatePullRequest"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("from", c.FromBranch),
+		slog.String("to", c.ToBranch),
+	)
+}
+
+// MergePullRequestCommand merges a pull request.
+type MergePullRequestCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	ID         int
+	// Version is the version of the pull request, the merge fails with
+	// 409 Conflict when the pull request changed since.
+	Version int
+	// Message is the message of the merge commit, optional.
+	Message string
+}
+
+func (c *MergePullRequestCommand) Validate() error {
+	var errs []error
+	if c.ID <= 0 {
+		errs = append(errs, fmt.Errorf("ID must be positive, got %d", c.ID))
+	}
+	if c.Version < 0 {
+		errs = append(errs, fmt.Errorf("Version must not be negative, got %d", c.Version))
+	}
+	return errors.Join(append(errs,
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+	)...)
+}
+
+func (c *MergePullRequestCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "pull-requests", strconv.Itoa(c.ID), "merge")
+	if err != nil {
+		return nil, fmt.Errorf("error building url for MergePullRequestCommand: %w", err)
+	}
+	u.RawQuery = url.Values{"version": {strconv.Itoa(c.Version)}}.Encode()
+	body := struct {
+		Message string `json:"message,omitempty"`
+	}{Message: c.Message}
+	return newJSONRequest(ctx, http.MethodPost, u.String(), body)
+}
+
+func (c *MergePullRequestCommand) ParseResponse(data []byte) (*PullRequest, error) {
+	return parsePullRequest(data)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *MergePullRequestCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "MergePullRequest"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.Int("id", c.ID),
+		slog.Int("version", c.Version),
+	)
+}
+
+// DeleteBranchCommand deletes a branch with the branch utils api next to
+// the rest api.
+type DeleteBranchCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	Branch     string
+}
+
+func (c *DeleteBranchCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("Branch", c.Branch),
+	)
+}
+
+func (c *DeleteBranchCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := restURL(baseURL, "branch-utils", "projects", c.ProjectKey, "repos", c.RepoSlug, "branches")
+	if err != nil {
+		return nil, fmt.Errorf("error building url for DeleteBranchCommand: %w", err)
+	}
+	body := struct {
+		Name   string `json:"name"`
+		DryRun bool   `json:"dryRun"`
+	}{Name: BranchRef(c.Branch).String()}
+	return newJSONRequest(ctx, http.MethodDelete, u.String(), body)
+}
+
+// LogValue implements slog.LogValuer.
+func (c *DeleteBranchCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "DeleteBranch"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("branch", c.Branch),
+	)
+}
+
+// CreatePullRequest creates a pull request.
+func (c *Client) CreatePullRequest(ctx context.Context, cmd *CreatePullRequestCommand) (*PullRequest, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// MergePullRequest merges a pull request. The cached responses at the
+// branch it merged into stay, use InvalidatePaths for the changed paths.
+func (c *Client) MergePullRequest(ctx context.Context, cmd *MergePullRequestCommand) (*PullRequest, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
+
+// DeleteBranch deletes a branch.
+func (c *Client) DeleteBranch(ctx context.Context, cmd *DeleteBranchCommand) error {
+	body, err := DoCommandBody(ctx, c, cmd)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
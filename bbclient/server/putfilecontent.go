@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"path"
+)
+
+// PutFileContentCommand commits the content of a file on a branch, with the
+// multipart form of the browse endpoint.
+//
+// SourceCommitID is the commit the change is based on, it is required to
+// update an existing file and must be empty for a new file. The server
+// refuses the change with 409 Conflict when the file changed on the branch
+// since that commit, see IsConflict.
+type PutFileContentCommand struct {
+	ProjectKey string
+	RepoSlug   string
+	FilePath   string
+	Content    []byte
+	// Branch is the branch to commit on.
+	Branch  string
+	Message string
+	// SourceCommitID is the commit of the file that is updated, optional
+	// for a new file.
+	SourceCommitID string
+	// SourceBranch creates Branch from this branch when Branch does not
+	// exist, optional.
+	SourceBranch string
+}
+
+func (c *PutFileContentCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+		required("Branch", c.Branch),
+		Ref(c.SourceCommitID).Validate(),
+	)
+}
+
+func (c *PutFileContentCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := repoURL(baseURL, c.ProjectKey, c.RepoSlug, "browse", c.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for PutFileContentCommand: %w", err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"branch", c.Branch},
+		{"message", c.Message},
+		{"sourceCommitId", c.SourceCommitID},
+		{"sourceBranch", c.SourceBranch},
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return nil, err
+		}
+	}
+	fw, err := mw.CreateFormFile("content", path.Base(c.FilePath))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(c.Content); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req, nil
+}
+
+func (c *PutFileContentCommand) ParseResponse(data []byte) (*Commit, error) {
+	var v jsonCommit
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("error unmarshalling commit: %w", err)
+	}
+	return v.commit(), nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *PutFileContentCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "PutFileContent"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("branch", c.Branch),
+		slog.String("sourceCommitId", c.SourceCommitID),
+		slog.Int("size", len(c.Content)),
+	)
+}
+
+// PutFileContent commits the content of a file on a branch and returns the
+// new commit. It drops the cached responses for the file at the branch.
+func (c *Client) PutFileContent(ctx context.Context, cmd *PutFileContentCommand) (*Commit, error) {
+	commit, err := DoCommandResponse(ctx, c, cmd)
+	if err != nil {
+		return nil, err
+	}
+	c.invalidateBranch(cmd.ProjectKey, cmd.RepoSlug, cmd.Branch, cmd.FilePath)
+	return commit, nil
+}
+
+// invalidateBranch drops the cached responses for the paths at the branch,
+// by its name and as full ref, after a commit on it.
+func (c *Client) invalidateBranch(projectKey, repoSlug, branch string, paths ...string) {
+	ref := BranchRef(branch)
+	c.InvalidatePaths(projectKey, repoSlug, ref, paths)
+	c.InvalidatePaths(projectKey, repoSlug, Ref(ref.Name()), paths)
+}
@@ -0,0 +1,63 @@
+package server
+
+import "strings"
+
+const (
+	branchPrefix = "refs/heads/"
+	tagPrefix    = "refs/tags/"
+)
+
+// Ref is a branch, tag or commit in a repository.
+//
+// Use BranchRef, TagRef or CommitRef to get the form the server expects.
+// A plain string is passed to the server as is.
+type Ref string
+
+// BranchRef returns the ref for the branch with the given name.
+func BranchRef(name string) Ref {
+	return Ref(branchPrefix + strings.TrimPrefix(name, branchPrefix))
+}
+
+// TagRef returns the ref for the tag with the given name.
+func TagRef(name string) Ref {
+	return Ref(tagPrefix + strings.TrimPrefix(name, tagPrefix))
+}
+
+// CommitRef returns the ref for the commit with the given id.
+func CommitRef(id string) Ref {
+	return Ref(id)
+}
+
+// String returns the ref in the form used in requests.
+func (r Ref) String() string {
+	return string(r)
+}
+
+// IsBranch returns true if the ref is a fully qualified branch.
+func (r Ref) IsBranch() bool {
+	return strings.HasPrefix(string(r), branchPrefix)
+}
+
+// IsTag returns true if the ref is a fully qualified tag.
+func (r Ref) IsTag() bool {
+	return strings.HasPrefix(string(r), tagPrefix)
+}
+
+// IsCommit returns true if the ref looks like a, possibly abbreviated, commit id.
+func (r Ref) IsCommit() bool {
+	if len(r) < 7 || len(r) > 40 {
+		return false
+	}
+	for _, c := range r {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Name returns the name of the ref without the refs/heads/ or refs/tags/ prefix.
+func (r Ref) Name() string {
+	s := strings.TrimPrefix(string(r), branchPrefix)
+	return strings.TrimPrefix(s, tagPrefix)
+}
@@ -0,0 +1,31 @@
+package server
+
+import "testing"
+
+func TestRef(t *testing.T) {
+	tests := []struct {
+		ref    Ref
+		want   string
+		name   string
+		branch bool
+		tag    bool
+		commit bool
+	}{
+		{ref: BranchRef("main"), want: "refs/heads/main", name: "main", branch: true},
+		{ref: BranchRef("refs/heads/main"), want: "refs/heads/main", name: "main", branch: true},
+		{ref: TagRef("comp/1.0.0"), want: "refs/tags/comp/1.0.0", name: "comp/1.0.0", tag: true},
+		{ref: CommitRef("0a1b2c3d4e5f"), want: "0a1b2c3d4e5f", name: "0a1b2c3d4e5f", commit: true},
+		{ref: Ref("develop"), want: "develop", name: "develop"},
+	}
+	for _, tt := range tests {
+		if got := tt.ref.String(); got != tt.want {
+			t.Errorf("expected %s, got %s", tt.want, got)
+		}
+		if got := tt.ref.Name(); got != tt.name {
+			t.Errorf("expected name %s, got %s", tt.name, got)
+		}
+		if tt.ref.IsBranch() != tt.branch || tt.ref.IsTag() != tt.tag || tt.ref.IsCommit() != tt.commit {
+			t.Errorf("wrong kind for %s", tt.ref)
+		}
+	}
+}
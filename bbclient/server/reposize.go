@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// RepoSize is the disk usage of a repository, in bytes.
+type RepoSize struct {
+	// Repository is the size of the git repository.
+	Repository int64
+	// Attachments is the size of the attachments of comments and descriptions.
+	Attachments int64
+}
+
+// GetRepoSizeCommand gets the size of a repository from the sizes endpoint
+// of the web interface, which is not part of the REST api. Servers that do
+// not have the endpoint respond with not found.
+type GetRepoSizeCommand struct {
+	ProjectKey string
+	RepoSlug   string
+}
+
+func (c *GetRepoSizeCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+	)
+}
+
+func (c *GetRepoSizeCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	u, err := apiURL(siteURL(baseURL), "projects", c.ProjectKey, "repos", c.RepoSlug, "sizes")
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// The size changes with each push.
+func (c *GetRepoSizeCommand) noCache() {}
+
+func (c *GetRepoSizeCommand) ParseResponse(data []byte) (*RepoSize, error) {
+	var resp struct {
+		Repository  int64 `json:"repository"`
+		Attachments int64 `json:"attachments"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &RepoSize{Repository: resp.Repository, Attachments: resp.Attachments}, nil
+}
+
+// LogValue implements slog.LogValuer.
+func (c *GetRepoSizeCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "GetRepoSize"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+	)
+}
+
+// siteURL returns the url of the server for the url of the REST api,
+// without /rest/api/<version>.
+func siteURL(baseURL string) string {
+	trimmed := strings.TrimSuffix(baseURL, "/")
+	dir, version := path.Split(trimmed)
+	if site, ok := strings.CutSuffix(dir, "/rest/api/"); ok && version != "" {
+		return site
+	}
+	return trimmed
+}
+
+// GetRepoSize returns the size of the repository. The response is not cached.
+func (c *Client) GetRepoSize(ctx context.Context, cmd *GetRepoSizeCommand) (*RepoSize, error) {
+	return DoCommandResponse(ctx, c, cmd)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header that carries the id of a request.
+// Configure the access log of the server or its proxy to log it, to find
+// the requests of a client in the log.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context that sends id as the request id of
+// the requests sent with it, e.g. the id of the incoming request that needs
+// them. All requests of the context share the id. Without one, the client
+// generates an id per request.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id in the context or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID returns the request id sent with the request of the response,
+// e.g. to attach it as an exemplar to the metrics of the call.
+func RequestID(resp *http.Response) string {
+	if resp == nil || resp.Request == nil {
+		return ""
+	}
+	return resp.Request.Header.Get(RequestIDHeader)
+}
+
+// setRequestID sets the request id header, unless the headers of the client
+// or the context set it. It returns the id.
+func setRequestID(req *http.Request) string {
+	if id := req.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+	id := RequestIDFromContext(req.Context())
+	if id == "" {
+		id = newRequestID()
+	}
+	req.Header.Set(RequestIDHeader, id)
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(RequestIDHeader))
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"version":"8.0.0"}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, AccessKey: "key", MaxBodyInCache: -1}
+
+	// Generated per request.
+	for range 2 {
+		if _, err := c.GetApplicationProperties(context.Background()); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if len(got) != 2 || len(got[0]) != 32 || got[0] == got[1] {
+		t.Errorf("expected two different generated ids, got %q", got)
+	}
+
+	// Taken from the context.
+	got = nil
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	if _, err := c.GetApplicationProperties(ctx); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("expected req-1, got %q", got)
+	}
+
+	// Reported in the error.
+	_, err := c.StatRawFile(ctx, &StatRawFileCommand{ProjectKey: "P", RepoSlug: "r", FilePath: "missing"})
+	var se *StatusError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a StatusError, got %v", err)
+	}
+	if se.RequestID != "req-1" || !strings.Contains(err.Error(), "req-1") {
+		t.Errorf("expected request id req-1 in %q", err.Error())
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errReadingBody is returned by DoCommandBody when reading the body for
+// the cache fails.
+var errReadingBody = errors.New("reading body failed")
+
+// resumable returns true for errors of a download that a new request can
+// recover from.
+func resumable(ctx context.Context, err error) bool {
+	return err != nil && err != io.EOF && ctx.Err() == nil &&
+		!errors.Is(err, ErrBudgetExceeded) && !errors.Is(err, ErrCircuitOpen)
+}
+
+// isFullCommitID returns true if ref is a complete commit id.
+func isFullCommitID(ref Ref) bool {
+	return len(ref) == 40 && ref.IsCommit()
+}
+
+// resumingReader reads a raw file and resumes it with a Range request
+// from the last received offset when the download fails.
+type resumingReader struct {
+	ctx     context.Context
+	client  *Client
+	cmd     *OpenRawFileCommand
+	body    io.ReadCloser
+	offset  int64
+	retries int
+}
+
+func (r *resumingReader) Read(b []byte) (int, error) {
+	for {
+		n, err := r.body.Read(b)
+		r.offset += int64(n)
+		if !resumable(r.ctx, err) || r.retries <= 0 {
+			return n, err
+		}
+		r.retries--
+		r.client.Logger.Info("resuming download",
+			slog.Any("command", r.cmd),
+			slog.Int64("offset", r.offset),
+			slog.String("error", err.Error()))
+		r.body.Close()
+		body, rerr := r.client.openRawFileFrom(r.ctx, r.cmd, r.offset)
+		if rerr != nil {
+			// Keep failing with the original error.
+			r.body = io.NopCloser(&errReader{err: err})
+			r.retries = 0
+			return n, nil
+		}
+		r.body = body
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *resumingReader) Close() error {
+	return r.body.Close()
+}
+
+// errReader fails with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// openRawFileFrom opens the raw file from the offset with a Range request.
+// The response is not cached. The ref of cmd must be a commit, so the
+// content can not change between the requests.
+func (c *Client) openRawFileFrom(ctx context.Context, cmd *OpenRawFileCommand, offset int64) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		return resp.Body, nil
+	case http.StatusOK:
+		// The server ignored the range, skip to the offset.
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return resp.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The offset is the end of the file only when the size of the file,
+		// in a Content-Range of "bytes */size", is the offset.
+		resp.Body.Close()
+		cr := resp.Header.Get("Content-Range")
+		if size, ok := strings.CutPrefix(cr, "bytes */"); ok && size == strconv.FormatInt(offset, 10) {
+			return http.NoBody, nil
+		}
+		return nil, fmt.Errorf("range from %d not satisfiable, Content-Range %q", offset, cr)
+	}
+	resp.Body.Close()
+	return nil, checkStatus(resp)
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCommit is the commit the servers of the tests resolve refs to.
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+// serveCommit answers a request on the commits endpoint with the commit id
+// and returns true, false for other requests.
+func serveCommit(w http.ResponseWriter, r *http.Request, id string) bool {
+	if !strings.HasSuffix(r.URL.Path, "/commits") {
+		return false
+	}
+	w.Write([]byte(`{"isLastPage":true,"values":[{"id":"` + id + `"}]}`))
+	return true
+}
+
+// cutRaw writes the content from the start of the Range of the request,
+// and breaks the connection after half of it.
+func cutRaw(w http.ResponseWriter, r *http.Request, content []byte) {
+	start := 0
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(content)-1)+"/"+strconv.Itoa(len(content)))
+	}
+	rest := content[start:]
+	w.Header().Set("Content-Length", strconv.Itoa(len(rest)))
+	if start > 0 {
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	w.Write(rest[:len(rest)/2])
+	w.(http.Flusher).Flush()
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+// cuttingServer serves content on the raw endpoint and breaks the
+// connection after half of the content for the first cuts requests.
+func cuttingServer(t *testing.T, content []byte, cuts int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveCommit(w, r, testCommit) {
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		if cuts <= 0 {
+			http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+			return
+		}
+		cuts--
+		cutRaw(w, r, content)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &ranges
+}
+
+func TestResumeRawFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	cases := []struct {
+		name       string
+		maxBody    int64
+		retries    int
+		cuts       int
+		wantErr    bool
+		wantRanges int
+	}{
+		{name: "streamed", maxBody: -1, retries: 3, cuts: 2, wantRanges: 2},
+		{name: "streamed no retries", maxBody: -1, retries: 0, cuts: 1, wantErr: true},
+		{name: "streamed too many cuts", maxBody: -1, retries: 1, cuts: 3, wantErr: true, wantRanges: 1},
+		{name: "cached", maxBody: 0, retries: 1, cuts: 1},
+		{name: "cached no retries", maxBody: 0, retries: 0, cuts: 1, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, ranges := cuttingServer(t, content, tc.cuts)
+			c := &Client{BaseURL: srv.URL, MaxBodyInCache: tc.maxBody, RawRetries: tc.retries}
+			var data []byte
+			r, err := c.OpenRawFile(context.Background(), &OpenRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "file.bin"})
+			if err == nil {
+				data, err = io.ReadAll(r)
+				r.Close()
+			}
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("error: %s", err.Error())
+				}
+				if !bytes.Equal(data, content) {
+					t.Errorf("content differs, got %d bytes", len(data))
+				}
+			}
+			n := 0
+			for _, r := range *ranges {
+				if r != "" {
+					n++
+				}
+			}
+			if n != tc.wantRanges {
+				t.Errorf("expected %d range requests, got %v", tc.wantRanges, *ranges)
+			}
+		})
+	}
+}
+
+func TestResumeRawFileChanged(t *testing.T) {
+	const newCommit = "89abcdef0123456789abcdef0123456789abcdef"
+	versions := map[string][]byte{
+		testCommit: bytes.Repeat([]byte("old "), 10000),
+		newCommit:  bytes.Repeat([]byte("new "), 5000),
+	}
+	var ats []string
+	head := testCommit
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveCommit(w, r, head) {
+			return
+		}
+		at := r.URL.Query().Get("at")
+		ats = append(ats, at)
+		content, ok := versions[at]
+		if !ok {
+			content = versions[head]
+		}
+		if r.Header.Get("Range") == "" {
+			// A push between the first request and the resume.
+			head = newCommit
+			cutRaw(w, r, content)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, MaxBodyInCache: -1, RawRetries: 1}
+	r, err := c.OpenRawFile(context.Background(), &OpenRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "file.bin", At: BranchRef("main")})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if !bytes.Equal(data, versions[testCommit]) {
+		t.Errorf("expected the content of the first commit, got %d bytes", len(data))
+	}
+	if len(ats) != 2 || ats[0] != testCommit || ats[1] != testCommit {
+		t.Errorf("expected both requests at %s, got %v", testCommit, ats)
+	}
+}
+
+func TestResumeRangeNotSatisfiable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes */5")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	cmd := &OpenRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "file.bin", At: CommitRef(testCommit)}
+	// The offset is the size of the file.
+	body, err := c.openRawFileFrom(context.Background(), cmd, 5)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if data, _ := io.ReadAll(body); len(data) != 0 {
+		t.Errorf("expected no data, got %q", data)
+	}
+	// The file is shorter than the offset.
+	if _, err := c.openRawFileFrom(context.Background(), cmd, 8); err == nil {
+		t.Errorf("expected an error for an offset past the end")
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// The cache states of a command in the log of the slow requests.
+const (
+	// cacheHit is a response from the cache.
+	cacheHit = "hit"
+	// cacheMiss is a response from the server that the cache may keep.
+	cacheMiss = "miss"
+	// cacheBypass is a response from the server that the cache does not
+	// keep, because the command is not cached or caching is disabled.
+	cacheBypass = "bypass"
+)
+
+// logSlow logs the command at warn level when it took longer than
+// SlowRequestThreshold, with the time that was left until the deadline
+// of the context, if any.
+func (c *Client) logSlow(ctx context.Context, cmd Command, cache string, elapsed time.Duration, err error) {
+	if c.SlowRequestThreshold <= 0 || elapsed <= c.SlowRequestThreshold {
+		return
+	}
+	attrs := []any{
+		slog.String("type", commandType(cmd)),
+		slog.Any("command", cmd),
+		slog.String("cache", cache),
+		slog.Duration("elapsed", elapsed),
+		slog.Duration("threshold", c.SlowRequestThreshold),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs, slog.Duration("deadline_left", time.Until(deadline)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.Logger.Warn("slow request", attrs...)
+}
+
+// commandType returns the name of the type of the command, e.g. GetFilesCommand.
+func commandType(cmd Command) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", cmd), "*server.")
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"version":"8.0.0"}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := &Client{
+		BaseURL:              srv.URL,
+		AccessKey:            "key",
+		Logger:               slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		SlowRequestThreshold: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// From the server, then from the cache.
+	for range 2 {
+		if _, err := c.GetApplicationProperties(ctx); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if _, err := c.StatRawFile(context.Background(), &StatRawFileCommand{ProjectKey: "P", RepoSlug: "r", FilePath: "a.txt"}); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 slow requests, got %q", lines)
+	}
+	for _, want := range []string{`msg="slow request"`, "type=GetApplicationPropertiesCommand", "cache=miss", "deadline_left="} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("expected %s in %q", want, lines[0])
+		}
+	}
+	for _, want := range []string{"type=StatRawFileCommand", "cache=miss"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("expected %s in %q", want, lines[1])
+		}
+	}
+	if strings.Contains(lines[1], "deadline_left=") {
+		t.Errorf("expected no deadline in %q", lines[1])
+	}
+
+	// Disabled by default.
+	buf.Reset()
+	c = &Client{BaseURL: srv.URL, AccessKey: "key", Logger: c.Logger}
+	if _, err := c.GetApplicationProperties(ctx); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no log, got %q", buf.String())
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// StatRawFileCommand gets the size and content type of a file with a HEAD
+// request on the raw endpoint, without reading the content.
+type StatRawFileCommand struct {
+	FilePath   string
+	ProjectKey string
+	RepoSlug   string
+	At         Ref
+}
+
+// RawFileInfo is the response of StatRawFile.
+type RawFileInfo struct {
+	// Size is the size of the file, -1 when the server does not report it.
+	Size int64
+	// ContentType is the content type reported by the server.
+	ContentType string
+}
+
+func (c *StatRawFileCommand) NewRequestWithContext(ctx context.Context, baseURL string) (*http.Request, error) {
+	raw := OpenRawFileCommand(*c)
+	req, err := raw.NewRequestWithContext(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	req.Method = http.MethodHead
+	return req, nil
+}
+
+func (c *StatRawFileCommand) Validate() error {
+	return errors.Join(
+		required("ProjectKey", c.ProjectKey),
+		required("RepoSlug", c.RepoSlug),
+		required("FilePath", c.FilePath),
+		validatePath("FilePath", c.FilePath),
+		c.At.Validate(),
+	)
+}
+
+func (c *StatRawFileCommand) accept() string {
+	return acceptRaw
+}
+
+// LogValue implements slog.LogValuer.
+func (c *StatRawFileCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", "StatRawFile"),
+		slog.String("project", c.ProjectKey),
+		slog.String("repo", c.RepoSlug),
+		slog.String("path", c.FilePath),
+		slog.String("at", c.At.String()),
+	)
+}
+
+// StatRawFile returns the size and content type of the file.
+//
+// It sends a HEAD request, which is cheaper than a listing of the parent
+// directory. The info is kept with the listings, for ListingTTL, the body
+// cache holds the content of the file. Directories are not found on the
+// raw endpoint. A server that does not allow HEAD on the raw endpoint
+// turns off FeatureHeadRaw.
+func (c *Client) StatRawFile(ctx context.Context, cmd *StatRawFileCommand) (*RawFileInfo, error) {
+	c.init()
+	c.Logger.Debug("executing command", slog.Any("command", cmd))
+	if err := cmd.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+	start := time.Now()
+	info, cache, err := c.statRawFile(ctx, cmd)
+	c.logSlow(ctx, cmd, cache, time.Since(start), err)
+	return info, err
+}
+
+// statRawFile returns the file info of the validated command and the
+// state of the cache for it.
+func (c *Client) statRawFile(ctx context.Context, cmd *StatRawFileCommand) (*RawFileInfo, string, error) {
+	var key string
+	state := cacheBypass
+	if c.listingsEnabled() {
+		key = c.statKey(cmd)
+		if info, ok := c.stats.Get(key); ok {
+			c.Logger.Debug("stat from cache", slog.Any("command", cmd))
+			res := *info
+			return &res, cacheHit, nil
+		}
+		state = cacheMiss
+	}
+	req, err := c.newRequest(ctx, cmd)
+	if err != nil {
+		return nil, state, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, state, err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		if isUnsupported(err) {
+			c.SetSupported(FeatureHeadRaw, false)
+		}
+		return nil, state, err
+	}
+	info := &RawFileInfo{
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if key != "" {
+		res := *info
+		c.stats.Set(key, &res)
+	}
+	return info, state, nil
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestStatRawFile(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"README.md": {Data: []byte("# readme\n")},
+	}))
+	c := &Client{BaseURL: srv.BaseURL()}
+
+	cmd := &StatRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "README.md"}
+	info, err := c.StatRawFile(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if info.Size != 9 || info.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected info %+v", info)
+	}
+	// The HEAD request must not put an empty body in the cache.
+	r, err := c.OpenRawFile(context.Background(), &OpenRawFileCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "README.md"})
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "# readme\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	cmd.FilePath = "missing.md"
+	if _, err := c.StatRawFile(context.Background(), cmd); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
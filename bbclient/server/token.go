@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultTokenExpiryMargin is the time before the expiry of a token at which
+// a RefreshingTokenSource gets a new one.
+const DefaultTokenExpiryMargin = 30 * time.Second
+
+// ErrNoToken is matched by the errors of requests for which the TokenSource
+// of the client failed.
+var ErrNoToken = errors.New("no token")
+
+// TokenSource returns the access token for a request. Implementations
+// refresh short lived tokens, e.g. OAuth or Vault issued tokens, and must
+// be safe for concurrent use.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is a function that implements TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// AccessToken is a token with its expiry, the zero Expiry never expires.
+type AccessToken struct {
+	Value  SecretString
+	Expiry time.Time
+}
+
+// RefreshingTokenSource caches the token from Refresh and calls Refresh
+// again when the token is about to expire.
+type RefreshingTokenSource struct {
+	// Refresh gets a new token.
+	Refresh func(ctx context.Context) (*AccessToken, error)
+	// ExpiryMargin is the time before the expiry at which the token
+	// is refreshed, defaults to DefaultTokenExpiryMargin.
+	ExpiryMargin time.Duration
+
+	mu    sync.Mutex
+	token *AccessToken
+	now   func() time.Time
+}
+
+// NewRefreshingTokenSource returns a token source for the tokens from refresh.
+func NewRefreshingTokenSource(refresh func(ctx context.Context) (*AccessToken, error)) *RefreshingTokenSource {
+	return &RefreshingTokenSource{Refresh: refresh}
+}
+
+// Token returns the cached token, refreshing it when it is about to expire.
+// When the refresh fails, the cached token is returned while it is valid.
+func (s *RefreshingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	margin := s.ExpiryMargin
+	if margin == 0 {
+		margin = DefaultTokenExpiryMargin
+	}
+	if s.token != nil && (s.token.Expiry.IsZero() || now.Add(margin).Before(s.token.Expiry)) {
+		return s.token.Value.Secret(), nil
+	}
+
+	token, err := s.Refresh(ctx)
+	if err != nil {
+		if s.token != nil && now.Before(s.token.Expiry) {
+			return s.token.Value.Secret(), nil
+		}
+		return "", err
+	}
+	s.token = token
+	return token.Value.Secret(), nil
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestRefreshingTokenSource(t *testing.T) {
+	now := time.Unix(0, 0)
+	refreshes := 0
+	var refreshErr error
+	ts := NewRefreshingTokenSource(func(ctx context.Context) (*AccessToken, error) {
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+		refreshes++
+		return &AccessToken{
+			Value:  SecretString(fmt.Sprintf("token-%d", refreshes)),
+			Expiry: now.Add(time.Minute),
+		}, nil
+	})
+	ts.now = func() time.Time { return now }
+
+	tests := []struct {
+		advance time.Duration
+		fail    bool
+		want    string
+	}{
+		{0, false, "token-1"},
+		{10 * time.Second, false, "token-1"},
+		// Within the margin of the expiry.
+		{40 * time.Second, false, "token-2"},
+		// The refresh fails, the token is still valid.
+		{40 * time.Second, true, "token-2"},
+		// The token expired.
+		{30 * time.Second, true, ""},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		refreshErr = nil
+		if tt.fail {
+			refreshErr = errors.New("vault unavailable")
+		}
+		got, err := ts.Token(context.Background())
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%d: expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("%d: expected %s, got %s", i, tt.want, got)
+		}
+	}
+}
+
+func TestClientTokenSource(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.SetAccessKey("secret")
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+
+	var token string
+	var tokenErrs []error
+	c := &Client{
+		BaseURL: srv.BaseURL(),
+		TokenSource: TokenSourceFunc(func(ctx context.Context) (string, error) {
+			if token == "" {
+				return "", errors.New("no token")
+			}
+			return token, nil
+		}),
+		OnTokenError:   func(err error) { tokenErrs = append(tokenErrs, err) },
+		MaxBodyInCache: -1,
+	}
+	cmd := &GetFilesCommand{ProjectKey: "PRJ", RepoSlug: "repo"}
+
+	if _, err := c.GetFiles(context.Background(), cmd); err == nil {
+		t.Fatalf("expected an error without a token")
+	}
+	if len(tokenErrs) != 1 || srv.Requests() != 0 {
+		t.Errorf("expected 1 token error and no requests, got %d and %d", len(tokenErrs), srv.Requests())
+	}
+	token = "secret"
+	if _, err := c.GetFiles(context.Background(), cmd); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+}
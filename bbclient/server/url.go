@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// repoURL returns the api url for the elements below the repository.
+//
+// The project, repo and elements are not escaped, the escaping is done when
+// the url is encoded. Elements may contain slashes.
+func repoURL(baseURL, project, repo string, elem ...string) (*url.URL, error) {
+	return apiURL(baseURL, append([]string{"projects", project, "repos", repo}, elem...)...)
+}
+
+// restURL returns the url for the elements below the rest api with the
+// name, e.g. insights, that is next to the rest api of the base url, at
+// /rest/insights/ instead of /rest/api/.
+func restURL(baseURL, name string, elem ...string) (*url.URL, error) {
+	if !strings.Contains(baseURL, "/rest/api/") {
+		return nil, fmt.Errorf("base url %q has no /rest/api/", baseURL)
+	}
+	return apiURL(strings.Replace(baseURL, "/rest/api/", "/rest/"+name+"/", 1), elem...)
+}
+
+// apiURL returns the api url for the elements below the base url.
+func apiURL(baseURL string, elem ...string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base url: %w", err)
+	}
+	u.Path = path.Join(append([]string{u.Path}, elem...)...)
+	u.RawPath = ""
+	return u, nil
+}
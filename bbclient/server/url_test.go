@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestURLEscaping(t *testing.T) {
+	const base = "https://bitbucket.example.com/rest/api/latest"
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{
+			cmd: &OpenRawFileCommand{
+				ProjectKey: "~user",
+				RepoSlug:   "repo",
+				FilePath:   "dir with space/file#1 100%.txt",
+				At:         TagRef("comp/1.0.0+build"),
+			},
+			want: base + "/projects/~user/repos/repo/raw/dir%20with%20space/file%231%20100%25.txt?at=refs%2Ftags%2Fcomp%2F1.0.0%2Bbuild",
+		},
+		{
+			cmd: &GetFilesCommand{
+				ProjectKey: "PRJ",
+				RepoSlug:   "repo",
+				FilePath:   "a+b/ünïcode?",
+				Limit:      10,
+			},
+			want: base + "/projects/PRJ/repos/repo/browse/a+b/%C3%BCn%C3%AFcode%3F?limit=10",
+		},
+		{
+			cmd: &GetFilesCommand{
+				ProjectKey: "PRJ",
+				RepoSlug:   "repo",
+			},
+			want: base + "/projects/PRJ/repos/repo/browse",
+		},
+		{
+			cmd: &GetCommitsCommand{
+				ProjectKey: "PRJ",
+				RepoSlug:   "repo",
+				Until:      BranchRef("feature/x y"),
+				Limit:      1,
+			},
+			want: base + "/projects/PRJ/repos/repo/commits?limit=1&until=refs%2Fheads%2Ffeature%2Fx+y",
+		},
+		{
+			cmd: &GetDiffCommand{
+				ProjectKey: "PRJ",
+				RepoSlug:   "repo",
+				From:       BranchRef("feature"),
+				To:         "main",
+				FilePath:   "dir/a b.txt",
+			},
+			want: base + "/projects/PRJ/repos/repo/compare/diff/dir/a%20b.txt?from=refs%2Fheads%2Ffeature&to=main",
+		},
+		{
+			cmd:  &GetReposCommand{ProjectKey: "~user"},
+			want: base + "/projects/~user/repos",
+		},
+		{
+			cmd:  &GetFilePathsCommand{ProjectKey: "PRJ", RepoSlug: "repo", FilePath: "docs", Limit: 1000},
+			want: base + "/projects/PRJ/repos/repo/files/docs?limit=1000",
+		},
+		{
+			cmd:  &GetRepoSizeCommand{ProjectKey: "PRJ", RepoSlug: "repo"},
+			want: "https://bitbucket.example.com/projects/PRJ/repos/repo/sizes",
+		},
+	}
+	for _, tt := range tests {
+		req, err := tt.cmd.NewRequestWithContext(context.Background(), base)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got := req.URL.String(); got != tt.want {
+			t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+		}
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://host/rest/api/latest", "https://host"},
+		{"https://host/rest/api/1.0/", "https://host"},
+		{"https://host/bitbucket/rest/api/latest", "https://host/bitbucket"},
+		{"https://host/other", "https://host/other"},
+	}
+	for _, tt := range tests {
+		if got := siteURL(tt.baseURL); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.baseURL, tt.want, got)
+		}
+	}
+}
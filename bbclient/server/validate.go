@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MaxLimit is the maximum page size the server accepts.
+const MaxLimit = 1000
+
+// required returns an error if value is empty.
+func required(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is missing", name)
+	}
+	return nil
+}
+
+// validatePaging checks the start and limit of a paged request.
+func validatePaging(start, limit int) error {
+	if start < 0 {
+		return fmt.Errorf("Start must not be negative, got %d", start)
+	}
+	if limit < 0 || limit > MaxLimit {
+		return fmt.Errorf("Limit must be between 0 and %d, got %d", MaxLimit, limit)
+	}
+	return nil
+}
+
+// validatePath checks that p is a clean relative path.
+// The empty path denotes the root of the repository.
+func validatePath(name, p string) error {
+	if p == "" {
+		return nil
+	}
+	if strings.HasPrefix(p, "/") {
+		return fmt.Errorf("%s must be relative, got %q", name, p)
+	}
+	if path.Clean(p) != p || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return fmt.Errorf("%s is not a normalized path, got %q", name, p)
+	}
+	return nil
+}
+
+// Validate returns an error if the ref is malformed.
+// The rules follow git check-ref-format, the empty ref is valid.
+func (r Ref) Validate() error {
+	if r == "" {
+		return nil
+	}
+	s := string(r)
+	bad := func(reason string) error {
+		return fmt.Errorf("ref %q is malformed: %s", s, reason)
+	}
+	if (r.IsBranch() || r.IsTag()) && r.Name() == "" {
+		return bad("missing name")
+	}
+	if strings.Contains(s, "..") || strings.Contains(s, "@{") || strings.Contains(s, "//") {
+		return bad("contains '..', '@{' or '//'")
+	}
+	if strings.HasPrefix(s, "/") || strings.HasSuffix(s, "/") || strings.HasSuffix(s, ".") || strings.HasSuffix(s, ".lock") {
+		return bad("bad start or end")
+	}
+	for _, c := range s {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(" ~^:?*[\\", c) {
+			return bad(fmt.Sprintf("contains %q", c))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cmd := &GetFilesCommand{
+		FilePath: "a/../b",
+		At:       "refs/heads/bad name",
+		Limit:    5000,
+	}
+	err := cmd.Validate()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	for _, want := range []string{"ProjectKey is missing", "RepoSlug is missing", "FilePath", "malformed", "Limit"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err.Error())
+		}
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	for _, p := range []string{"", "a", "a/b.txt", "dir with space/file#1"} {
+		if err := validatePath("FilePath", p); err != nil {
+			t.Errorf("%q: unexpected error: %s", p, err.Error())
+		}
+	}
+	for _, p := range []string{"/a", "a/", "a//b", "./a", "..", "../a", "."} {
+		if err := validatePath("FilePath", p); err == nil {
+			t.Errorf("%q: expected an error", p)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+/*
+bbfstest contains an in memory implementation of bbfs.BitbucketFS for tests.
+*/
+package bbfstest
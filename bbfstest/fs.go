@@ -0,0 +1,50 @@
+package bbfstest
+
+import (
+	"context"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// FS is a bbfs.BitbucketFS backed by a fstest.MapFS.
+type FS struct {
+	fstest.MapFS
+	// TagList is returned by Tags.
+	TagList []*server.Tag
+	// TagsErr is returned by Tags when not nil.
+	TagsErr error
+	// At is returned by Ref.
+	At bbfs.Ref
+}
+
+// New returns an FS with the files at the given ref.
+func New(files fstest.MapFS, at bbfs.Ref, tags ...string) *FS {
+	res := &FS{
+		MapFS: files,
+		At:    at,
+	}
+	for _, t := range tags {
+		res.TagList = append(res.TagList, &server.Tag{
+			Name: t,
+			Type: server.TagTypeTag,
+		})
+	}
+	return res
+}
+
+// Tags returns TagList or TagsErr.
+func (f *FS) Tags(ctx context.Context) ([]*server.Tag, error) {
+	if f.TagsErr != nil {
+		return nil, f.TagsErr
+	}
+	return f.TagList, nil
+}
+
+// Ref returns At.
+func (f *FS) Ref() bbfs.Ref {
+	return f.At
+}
+
+var _ bbfs.BitbucketFS = &FS{}
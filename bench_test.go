@@ -0,0 +1,124 @@
+package bbfs
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+// The benchmarks run against the fake server, which answers from memory, so
+// they measure the work of the client: requests, paging, decoding and the
+// cache. Each reports requests/op next to the time, the number to watch
+// when changing the caching or paging. Compare runs with benchstat:
+//
+//	go test -run '^$' -bench . -count 10 > old.txt
+//	go test -run '^$' -bench . -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// A cold run reads with a new FS and an empty cache for each operation,
+// a warm run reads with one FS after a first read filled the cache.
+
+// benchSizes are the numbers of entries of the benchmarks.
+var benchSizes = []int{1_000, 10_000}
+
+// benchRepo is a repository with n files in the directory flat, and the
+// same files in tree below directories of 100 files.
+type benchRepo struct {
+	srv *fakeserver.Server
+	cfg *Config
+	// file is a file in the middle of tree.
+	file string
+}
+
+func newBenchRepo(b *testing.B, n int) *benchRepo {
+	b.Helper()
+	files := fstest.MapFS{}
+	for i := range n {
+		data := []byte(fmt.Sprintf("file %d\n", i))
+		files[fmt.Sprintf("flat/f%05d.txt", i)] = &fstest.MapFile{Data: data}
+		files[fmt.Sprintf("tree/d%03d/f%05d.txt", i/100, i)] = &fstest.MapFile{Data: data}
+	}
+	srv := fakeserver.New()
+	b.Cleanup(srv.Close)
+	srv.AddRepo("PRJ", "bench", fakeserver.NewRepo(files))
+	return &benchRepo{
+		srv:  srv,
+		cfg:  &Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "bench"},
+		file: fmt.Sprintf("tree/d%03d/f%05d.txt", n/200, n/2),
+	}
+}
+
+// runBench runs op for each size with a cold and a warm cache.
+func runBench(b *testing.B, op func(b *testing.B, fsys fs.FS, r *benchRepo)) {
+	for _, n := range benchSizes {
+		r := newBenchRepo(b, n)
+		b.Run(fmt.Sprintf("entries=%d/cache=cold", n), func(b *testing.B) {
+			before := r.srv.Requests()
+			b.ResetTimer()
+			for range b.N {
+				op(b, NewFS(r.cfg), r)
+			}
+			b.StopTimer()
+			reportRequests(b, r.srv.Requests()-before)
+		})
+		b.Run(fmt.Sprintf("entries=%d/cache=warm", n), func(b *testing.B) {
+			fsys := NewFS(r.cfg)
+			op(b, fsys, r)
+			before := r.srv.Requests()
+			b.ResetTimer()
+			for range b.N {
+				op(b, fsys, r)
+			}
+			b.StopTimer()
+			reportRequests(b, r.srv.Requests()-before)
+		})
+	}
+}
+
+func reportRequests(b *testing.B, requests int64) {
+	b.ReportMetric(float64(requests)/float64(b.N), "requests/op")
+}
+
+func BenchmarkOpen(b *testing.B) {
+	runBench(b, func(b *testing.B, fsys fs.FS, r *benchRepo) {
+		f, err := fsys.Open(r.file)
+		if err != nil {
+			b.Fatalf("error: %s", err.Error())
+		}
+		f.Close()
+	})
+}
+
+func BenchmarkReadDir(b *testing.B) {
+	runBench(b, func(b *testing.B, fsys fs.FS, r *benchRepo) {
+		entries, err := fs.ReadDir(fsys, "flat")
+		if err != nil {
+			b.Fatalf("error: %s", err.Error())
+		}
+		if len(entries) == 0 {
+			b.Fatalf("no entries")
+		}
+	})
+}
+
+func BenchmarkWalkDir(b *testing.B) {
+	runBench(b, func(b *testing.B, fsys fs.FS, r *benchRepo) {
+		err := fs.WalkDir(fsys, "tree", func(name string, d fs.DirEntry, err error) error {
+			return err
+		})
+		if err != nil {
+			b.Fatalf("error: %s", err.Error())
+		}
+	})
+}
+
+func BenchmarkReadFile(b *testing.B) {
+	runBench(b, func(b *testing.B, fsys fs.FS, r *benchRepo) {
+		if _, err := fs.ReadFile(fsys, r.file); err != nil {
+			b.Fatalf("error: %s", err.Error())
+		}
+	})
+}
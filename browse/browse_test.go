@@ -0,0 +1,89 @@
+package browse
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestHandler(t *testing.T) {
+	files := fstest.MapFS{
+		"docs/main.go":   {Data: []byte("package main\n\nfunc main() {}\n")},
+		"docs/img.png":   {Data: []byte("\x89PNG\x00\x01")},
+		"docs/a/b.txt":   {Data: []byte("b <at> main")},
+		"docs/page.html": {Data: []byte("<script>alert(1)</script>")},
+	}
+	repo := fakeserver.NewRepo(files)
+	repo.Tag("v1", repo.Commits[0])
+	second := maps.Clone(files)
+	second["docs/main.go"] = &fstest.MapFile{Data: []byte("package main\n\nfunc main() { println(2) }\n")}
+	repo.Commit("main", "second", second)
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	h := NewHandler(bbfs.NewRepo(&bbfs.Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"}),
+		WithRoot("docs"), WithTitle("PRJ/repo"))
+	hs := httptest.NewServer(h)
+	defer hs.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		want   []string
+	}{
+		{"/", http.StatusOK, []string{`<a href="a/">a/</a>`, `<a href="main.go">main.go</a>`, `>tag v1</option>`, `<title>PRJ/repo - .</title>`}},
+		{"/?ref=refs%2Ftags%2Fv1", http.StatusOK, []string{`<a href="a/?ref=refs%2Ftags%2Fv1">`, `<option value="refs/tags/v1" selected>`}},
+		{"/a", http.StatusOK, []string{`<a href="b.txt">b.txt</a>`, `<a href="../">/</a> / <a href="./">a</a>`}},
+		{"/a/b.txt", http.StatusOK, []string{`b &lt;at&gt; main`, `<a href="b.txt?raw">raw</a>`}},
+		{"/main.go", http.StatusOK, []string{`<span class="k">func</span> main() { println(<span class="n">2</span>) }`}},
+		{"/main.go?ref=refs%2Ftags%2Fv1", http.StatusOK, []string{`main() {}`, `<a href="main.go?ref=refs%2Ftags%2Fv1&amp;raw">raw</a>`}},
+		{"/img.png", http.StatusOK, []string{"The file is binary."}},
+		{"/page.html?raw", http.StatusOK, []string{"<script>alert(1)</script>"}},
+		{"/missing", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(hs.URL + tt.path)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, resp.StatusCode)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(b), want) {
+				t.Errorf("%s: expected %q in:\n%s", tt.path, want, b)
+			}
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"a.go", `x := "a\"b" // c`, `x := <span class="s">&#34;a\&#34;b&#34;</span> <span class="c">// c</span>`},
+		{"a.go", "/* a\nb */ return", "<span class=\"c\">/* a\nb */</span> <span class=\"k\">return</span>"},
+		{"a.yaml", "url: http://x#y # z", `url: http://x#y <span class="c"># z</span>`},
+		{"a.py", "def f(): return 0x1f", `<span class="k">def</span> f(): <span class="k">return</span> <span class="n">0x1f</span>`},
+		{"a.txt", "if <b>", "if &lt;b&gt;"},
+		{"Dockerfile", "FROM x1", `<span class="k">FROM</span> x1`},
+	}
+	for _, tt := range tests {
+		if got := string(highlight(tt.name, tt.src)); got != tt.want {
+			t.Errorf("%s %q: expected\n%s\ngot\n%s", tt.name, tt.src, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+/*
+Package browse serves a small web UI to browse a repository.
+
+The UI lists the directories, shows text files with syntax highlighting
+and switches between the branches and tags of the repository. It is plain
+HTML from templates, without a JavaScript build, for quick internal sharing:
+
+	repo := bbfs.NewRepo(cfg)
+	h := browse.NewHandler(repo, browse.WithTitle("PRJ/docs"))
+	http.ListenAndServe(":8080", h)
+
+The ref is in the ref query parameter, e.g. /docs/?ref=refs/tags/v1.0.0,
+the default ref of the repository without it. The raw query parameter
+serves the content of a file as is.
+*/
+package browse
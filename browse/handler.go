@@ -0,0 +1,348 @@
+package browse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/nulllog"
+)
+
+// MaxViewSize is the size of the largest file that the UI shows,
+// larger files are only served raw.
+const MaxViewSize = 1 << 20
+
+// Repo is the repository that the UI shows, *bbfs.Repo implements it.
+type Repo interface {
+	// FS returns the file system at the ref, the default ref when empty.
+	FS(ref bbfs.Ref) fs.FS
+	// ListRefs returns the branches and tags.
+	ListRefs(ctx context.Context) ([]*bbfs.RefInfo, error)
+}
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handler)
+
+// WithLogger sets the logger for failed requests.
+func WithLogger(l *slog.Logger) HandlerOption {
+	return func(h *handler) {
+		h.logger = l
+	}
+}
+
+// WithRoot shows the directory of the repository instead of its root.
+func WithRoot(dir string) HandlerOption {
+	return func(h *handler) {
+		h.root = dir
+	}
+}
+
+// WithTitle sets the title of the pages, e.g. the project and repository.
+func WithTitle(title string) HandlerOption {
+	return func(h *handler) {
+		h.title = title
+	}
+}
+
+// NewHandler returns the handler of the UI for the repository.
+func NewHandler(repo Repo, opts ...HandlerOption) http.Handler {
+	h := &handler{repo: repo, root: ".", title: "Repository", logger: nulllog.Logger()}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+type handler struct {
+	repo   Repo
+	root   string
+	title  string
+	logger *slog.Logger
+}
+
+// crumb is a link to a parent directory.
+type crumb struct {
+	Name string
+	Href string
+}
+
+// entry is an entry of a directory.
+type entry struct {
+	Name  string
+	Href  string
+	Size  int64
+	IsDir bool
+}
+
+// refOption is an option of the ref selector.
+type refOption struct {
+	Value    string
+	Label    string
+	Selected bool
+}
+
+// page is the data of the page template.
+type page struct {
+	Title  string
+	Ref    string
+	Refs   []refOption
+	Crumbs []crumb
+	Name   string
+	// Entries are set for a directory.
+	Entries []entry
+	IsDir   bool
+	// Code is the highlighted content of a text file, Note explains why
+	// a file is not shown.
+	Code template.HTML
+	Note string
+	Raw  string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ref := r.URL.Query().Get("ref")
+	fsys, err := fs.Sub(h.repo.FS(bbfs.Ref(ref)), h.root)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	name := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if fi.IsDir() && !strings.HasSuffix(r.URL.Path, "/") {
+		u := *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	if !fi.IsDir() && r.URL.Query().Has("raw") {
+		h.serveRaw(w, r, fsys, name, fi)
+		return
+	}
+
+	p := &page{
+		Title: h.title,
+		Ref:   ref,
+		Refs:  h.refOptions(r.Context(), ref),
+		Name:  name,
+		IsDir: fi.IsDir(),
+	}
+	query := refQuery(ref)
+	if fi.IsDir() {
+		p.Crumbs = crumbs(name, true, query)
+		if err := h.readDir(p, fsys, name, query); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+	} else {
+		p.Crumbs = crumbs(name, false, query)
+		p.Raw = url.PathEscape(path.Base(name)) + "?raw"
+		if query != "" {
+			p.Raw = url.PathEscape(path.Base(name)) + query + "&raw"
+		}
+		if err := h.readFile(p, fsys, name, fi); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+	}
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, p); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+func (h *handler) readDir(p *page, fsys fs.FS, name, query string) error {
+	des, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return err
+	}
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			return err
+		}
+		href := url.PathEscape(de.Name())
+		if de.IsDir() {
+			href += "/"
+		}
+		p.Entries = append(p.Entries, entry{Name: de.Name(), Href: href + query, Size: fi.Size(), IsDir: de.IsDir()})
+	}
+	// Directories first.
+	slices.SortStableFunc(p.Entries, func(a, b entry) int {
+		switch {
+		case a.IsDir == b.IsDir:
+			return 0
+		case a.IsDir:
+			return -1
+		}
+		return 1
+	})
+	return nil
+}
+
+func (h *handler) readFile(p *page, fsys fs.FS, name string, fi fs.FileInfo) error {
+	if fi.Size() > MaxViewSize {
+		p.Note = "The file is too large to show."
+		return nil
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		p.Note = "The file is binary."
+		return nil
+	}
+	p.Code = highlight(name, string(data))
+	return nil
+}
+
+// serveRaw serves the content of the file. The sandbox keeps the scripts
+// of HTML files in the repository away from the UI.
+func (h *handler) serveRaw(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, fi fs.FileInfo) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	http.ServeContent(w, r, name, fi.ModTime(), bytes.NewReader(data))
+}
+
+// refOptions returns the options of the ref selector. The selector only
+// has the current ref when the refs cannot be listed.
+func (h *handler) refOptions(ctx context.Context, current string) []refOption {
+	res := []refOption{{Value: "", Label: "default", Selected: current == ""}}
+	refs, err := h.repo.ListRefs(ctx)
+	if err != nil {
+		h.logger.Info("listing the refs failed", slog.String("error", err.Error()))
+	}
+	found := current == ""
+	for _, ri := range refs {
+		label := ri.Name
+		if ri.Type == bbfs.RefTypeTag {
+			label = "tag " + ri.Name
+		}
+		selected := current == ri.Ref.String() || current == ri.Name
+		found = found || selected
+		res = append(res, refOption{Value: ri.Ref.String(), Label: label, Selected: selected})
+	}
+	if !found {
+		res = append(res, refOption{Value: current, Label: current, Selected: true})
+	}
+	return res
+}
+
+// refQuery returns the query that keeps the ref in the links.
+func refQuery(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	return "?" + url.Values{"ref": {ref}}.Encode()
+}
+
+// crumbs returns the relative links to the root and the directories of
+// name, the current directory for a directory.
+func crumbs(name string, isDir bool, query string) []crumb {
+	var dirs []string
+	if name != "." {
+		dirs = strings.Split(name, "/")
+	}
+	if !isDir {
+		dirs = dirs[:len(dirs)-1]
+	}
+	res := []crumb{{Name: "/", Href: upLink(len(dirs)) + query}}
+	for i, d := range dirs {
+		res = append(res, crumb{Name: d, Href: upLink(len(dirs)-i-1) + query})
+	}
+	return res
+}
+
+func upLink(n int) string {
+	if n == 0 {
+		return "./"
+	}
+	return strings.Repeat("../", n)
+}
+
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		http.NotFound(w, r)
+		return
+	}
+	h.logger.Info("request failed", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - {{.Name}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0 auto; max-width: 72rem; padding: 1rem; color: #222; }
+header { display: flex; gap: 1rem; align-items: center; flex-wrap: wrap; border-bottom: 1px solid #ddd; padding-bottom: .5rem; }
+header h1 { font-size: 1.2rem; margin: 0; }
+nav a { text-decoration: none; }
+table { border-collapse: collapse; width: 100%; margin-top: 1rem; }
+td { padding: .2rem .5rem; border-bottom: 1px solid #eee; }
+td.size { text-align: right; color: #666; }
+pre { background: #f6f8fa; padding: 1rem; overflow: auto; line-height: 1.4; }
+.c { color: #6a737d; } .s { color: #032f62; } .n { color: #005cc5; } .k { color: #d73a49; font-weight: bold; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<form method="get">
+<select name="ref" onchange="this.form.submit()">
+{{- range .Refs}}
+<option value="{{.Value}}"{{if .Selected}} selected{{end}}>{{.Label}}</option>
+{{- end}}
+</select>
+<noscript><button>Switch</button></noscript>
+</form>
+<nav>{{range $i, $c := .Crumbs}}{{if $i}} / {{end}}<a href="{{$c.Href}}">{{$c.Name}}</a>{{end}}</nav>
+</header>
+{{- if .IsDir}}
+<table>
+{{- range .Entries}}
+<tr><td><a href="{{.Href}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td class="size">{{if not .IsDir}}{{.Size}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p><a href="{{.Raw}}">raw</a></p>
+{{- if .Note}}
+<p>{{.Note}}</p>
+{{- else}}
+<pre><code>{{.Code}}</code></pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
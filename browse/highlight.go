@@ -0,0 +1,224 @@
+package browse
+
+import (
+	"html/template"
+	"path"
+	"strings"
+)
+
+// language has the tokens of a programming or configuration language
+// that highlight knows.
+type language struct {
+	lineComments []string
+	blockComment [2]string
+	// quotes start and end strings, a backquote string may span lines.
+	quotes   string
+	keywords []string
+}
+
+func (l *language) isKeyword(w string) bool {
+	for _, k := range l.keywords {
+		if k == w {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	goLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords: []string{"break", "case", "chan", "const", "continue", "default", "defer", "else",
+			"fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map", "package",
+			"range", "return", "select", "struct", "switch", "type", "var", "nil", "true", "false"},
+	}
+	cLikeLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords: []string{"break", "case", "catch", "class", "const", "continue", "default", "do",
+			"else", "enum", "export", "extends", "final", "finally", "fn", "for", "function", "if",
+			"impl", "implements", "import", "interface", "let", "match", "mut", "new", "null",
+			"package", "private", "protected", "public", "pub", "return", "static", "struct",
+			"switch", "this", "throw", "try", "type", "typedef", "use", "var", "void", "while",
+			"true", "false"},
+	}
+	pythonLanguage = &language{
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords: []string{"and", "as", "assert", "async", "await", "break", "class", "continue",
+			"def", "del", "elif", "else", "except", "finally", "for", "from", "if", "import", "in",
+			"is", "lambda", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield",
+			"None", "True", "False"},
+	}
+	shellLanguage = &language{
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords: []string{"case", "do", "done", "elif", "else", "esac", "export", "fi", "for",
+			"function", "if", "in", "local", "return", "then", "until", "while"},
+	}
+	configLanguage = &language{
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords:     []string{"true", "false", "null", "yes", "no"},
+	}
+	jsonLanguage = &language{
+		quotes:   "\"",
+		keywords: []string{"true", "false", "null"},
+	}
+	dockerfileLanguage = &language{
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords: []string{"FROM", "AS", "RUN", "CMD", "COPY", "ADD", "ENV", "ARG", "WORKDIR",
+			"EXPOSE", "ENTRYPOINT", "USER", "VOLUME", "LABEL", "HEALTHCHECK", "SHELL"},
+	}
+)
+
+// languages are the languages by file extension.
+var languages = map[string]*language{
+	".go":   goLanguage,
+	".c":    cLikeLanguage,
+	".h":    cLikeLanguage,
+	".cc":   cLikeLanguage,
+	".cpp":  cLikeLanguage,
+	".hpp":  cLikeLanguage,
+	".cs":   cLikeLanguage,
+	".java": cLikeLanguage,
+	".kt":   cLikeLanguage,
+	".js":   cLikeLanguage,
+	".mjs":  cLikeLanguage,
+	".jsx":  cLikeLanguage,
+	".ts":   cLikeLanguage,
+	".tsx":  cLikeLanguage,
+	".rs":   cLikeLanguage,
+	".py":   pythonLanguage,
+	".sh":   shellLanguage,
+	".bash": shellLanguage,
+	".yaml": configLanguage,
+	".yml":  configLanguage,
+	".toml": configLanguage,
+	".tf":   configLanguage,
+	".env":  configLanguage,
+	".json": jsonLanguage,
+}
+
+// languageFor returns the language of the file, nil when unknown.
+func languageFor(name string) *language {
+	base := path.Base(name)
+	if base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") {
+		return dockerfileLanguage
+	}
+	if base == "Makefile" {
+		return shellLanguage
+	}
+	return languages[strings.ToLower(path.Ext(base))]
+}
+
+// highlight returns the source as HTML with the comments, strings, numbers
+// and keywords in spans of the classes c, s, n and k. It knows the tokens,
+// not the grammar, which is good enough to read code.
+func highlight(name, src string) template.HTML {
+	lang := languageFor(name)
+	if lang == nil {
+		return template.HTML(template.HTMLEscapeString(src))
+	}
+	var b strings.Builder
+	span := func(class, text string) {
+		b.WriteString(`<span class="` + class + `">`)
+		b.WriteString(template.HTMLEscapeString(text))
+		b.WriteString(`</span>`)
+	}
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		if start := lang.blockComment[0]; start != "" && strings.HasPrefix(rest, start) {
+			end := strings.Index(rest[len(start):], lang.blockComment[1])
+			n := len(rest)
+			if end >= 0 {
+				n = len(start) + end + len(lang.blockComment[1])
+			}
+			span("c", rest[:n])
+			i += n
+			continue
+		}
+		if lineComment(lang, src, i) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			span("c", rest[:n])
+			i += n
+			continue
+		}
+		c := src[i]
+		switch {
+		case strings.IndexByte(lang.quotes, c) >= 0:
+			n := stringLength(rest)
+			span("s", rest[:n])
+			i += n
+		case isDigit(c) && (i == 0 || !isWordByte(src[i-1])):
+			n := 1
+			for n < len(rest) && (isWordByte(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			span("n", rest[:n])
+			i += n
+		case isWordByte(c):
+			n := 1
+			for n < len(rest) && isWordByte(rest[n]) {
+				n++
+			}
+			if lang.isKeyword(rest[:n]) {
+				span("k", rest[:n])
+			} else {
+				b.WriteString(template.HTMLEscapeString(rest[:n]))
+			}
+			i += n
+		default:
+			b.WriteString(template.HTMLEscapeString(rest[:1]))
+			i++
+		}
+	}
+	return template.HTML(b.String())
+}
+
+// lineComment returns true if a line comment starts at i. A # only starts
+// a comment at the start of a line or after white space, not in a url.
+func lineComment(lang *language, src string, i int) bool {
+	for _, p := range lang.lineComments {
+		if !strings.HasPrefix(src[i:], p) {
+			continue
+		}
+		if p == "#" && i > 0 && !strings.ContainsRune(" \t\n", rune(src[i-1])) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// stringLength returns the length of the string that starts with the quote
+// at s[0], up to the closing quote or the end of the line.
+func stringLength(s string) int {
+	q := s[0]
+	for n := 1; n < len(s); n++ {
+		switch {
+		case s[n] == '\\' && q != '`':
+			n++
+		case s[n] == q:
+			return n + 1
+		case s[n] == '\n' && q != '`':
+			return n
+		}
+	}
+	return len(s)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
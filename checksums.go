@@ -0,0 +1,83 @@
+package bbfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Checksum is the SHA-256 sum of a file.
+type Checksum struct {
+	// Path is relative to the root passed to Checksums.
+	Path string
+	Sum  [sha256.Size]byte
+}
+
+// Checksums returns the SHA-256 sums of the files below root in lexical order.
+// It stops when the context is done.
+func Checksums(ctx context.Context, fsys fs.FS, root string) ([]Checksum, error) {
+	if root == "" {
+		root = "."
+	}
+	var res []Checksum
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sum, err := sha256File(fsys, name)
+		if err != nil {
+			return err
+		}
+		rel := name
+		if root != "." {
+			rel = strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		}
+		// A root that is a file has the name of the file.
+		if rel == "" {
+			rel = path.Base(name)
+		}
+		res = append(res, Checksum{Path: rel, Sum: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func sha256File(fsys fs.FS, name string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := fsys.Open(name)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// WriteSHA256Sums writes the checksums in the format of sha256sum, so
+// sha256sum -c SHA256SUMS verifies the files after a checkout.
+func WriteSHA256Sums(w io.Writer, sums []Checksum) error {
+	for _, s := range sums {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(s.Sum[:]), s.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
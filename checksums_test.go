@@ -0,0 +1,50 @@
+package bbfs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestChecksums(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"README.md":       {Data: []byte("hello\n")},
+		"config/app.yaml": {Data: []byte("a\n")},
+		"config/b/c.txt":  {Data: []byte("")},
+	}))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	tests := []struct {
+		root string
+		want string
+	}{
+		{"", "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  README.md\n" +
+			"87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7  config/app.yaml\n" +
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  config/b/c.txt\n"},
+		{"config", "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7  app.yaml\n" +
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  b/c.txt\n"},
+		{"README.md", "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  README.md\n"},
+	}
+	for _, tt := range tests {
+		sums, err := Checksums(context.Background(), fsys, tt.root)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		var buf bytes.Buffer
+		if err := WriteSHA256Sums(&buf, sums); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%q: expected\n%s\ngot\n%s", tt.root, tt.want, buf.String())
+		}
+	}
+
+	if _, err := Checksums(context.Background(), fsys, "missing"); err == nil {
+		t.Errorf("expected an error for a missing root")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/myhops/bbfs"
+)
+
+// errNotAncestor makes is-ancestor exit with exitFailure, like git merge-base --is-ancestor.
+var errNotAncestor = errors.New("-from is not an ancestor of -to")
+
+// requireFromTo returns a usage error if -from or -to is missing.
+func requireFromTo(opts *options) error {
+	if opts.From == "" || opts.To == "" {
+		return usageErrorf("%s needs -from and -to", opts.Command)
+	}
+	return requireRepo(opts)
+}
+
+// cmdIsAncestor prints true if -from is an ancestor of -to, and fails otherwise.
+func cmdIsAncestor(opts *options) error {
+	if err := requireFromTo(opts); err != nil {
+		return err
+	}
+	ok, err := bbfs.IsAncestor(context.Background(), getClient(opts), opts.ProjectKey, opts.RepoSlug, bbfs.Ref(opts.From), bbfs.Ref(opts.To))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, ok)
+	if !ok {
+		return errNotAncestor
+	}
+	return nil
+}
+
+// cmdMergeBase prints the best common ancestor of -from and -to.
+func cmdMergeBase(opts *options) error {
+	if err := requireFromTo(opts); err != nil {
+		return err
+	}
+	id, err := bbfs.MergeBase(context.Background(), getClient(opts), opts.ProjectKey, opts.RepoSlug, bbfs.Ref(opts.From), bbfs.Ref(opts.To))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, id)
+	return nil
+}
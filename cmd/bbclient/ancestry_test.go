@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestAncestry(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	files := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	repo := fakeserver.NewRepo(files)
+	first := repo.Resolve("main")
+	repo.Tag("v1.0.0", first)
+	repo.Branches["feature"] = first.ID
+	repo.Commit("feature", "feature", files)
+	repo.Commit("main", "main", files)
+	srv.AddRepo("PRJ", "repo", repo)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"ancestor", []string{"is-ancestor", "-from", "v1.0.0", "-to", "main"}, exitOK, "true\n"},
+		{"not ancestor", []string{"is-ancestor", "-from", "feature", "-to", "main"}, exitFailure, "false\n"},
+		{"merge base", []string{"merge-base", "-from", "feature", "-to", "main"}, exitOK, first.ID + "\n"},
+		{"missing to", []string{"merge-base", "-from", "feature"}, exitUsage, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			stdout = &out
+			defer func() { stdout = nil }()
+			args := append([]string{"bbclient"}, tt.args...)
+			args = append(args, "-base-url", srv.BaseURL(), "-project-key", "PRJ", "-repo-slug", "repo")
+			err := run(args, func(string) string { return "" })
+			if _, code := errorKind(err); code != tt.code {
+				t.Errorf("expected exit code %d, got %d for %v", tt.code, code, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/myhops/bbfs/httpauth"
+)
+
+// authenticator returns the authenticator for -basic-auth-file and
+// -oidc-issuer, nil when neither is set.
+func authenticator(opts *options) (httpauth.Authenticator, error) {
+	var auth httpauth.Authenticators
+	if opts.BasicAuthFile != "" {
+		b, err := httpauth.LoadBasicAuth(opts.BasicAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, b)
+	}
+	if opts.OIDCIssuer != "" {
+		if opts.OIDCAudience == "" {
+			return nil, usageErrorf("-oidc-issuer needs -oidc-audience")
+		}
+		auth = append(auth, httpauth.NewOIDC(opts.OIDCIssuer, opts.OIDCAudience))
+	}
+	if len(auth) == 0 {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+// protector returns the function that protects the handlers of a command
+// with the authenticator and the rules. Without an authenticator the
+// handlers are open, and rules with allow lists are an error.
+func protector(opts *options, rules []httpauth.Rule) (func(http.Handler) http.Handler, error) {
+	auth, err := authenticator(opts)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		for _, r := range rules {
+			if len(r.Allow) > 0 {
+				return nil, usageErrorf("allow lists need -basic-auth-file or -oidc-issuer")
+			}
+		}
+		return func(h http.Handler) http.Handler { return h }, nil
+	}
+	return func(h http.Handler) http.Handler {
+		return httpauth.Handler(h, auth, rules)
+	}, nil
+}
+
+// allowRule returns the rule for all paths with the -allow list.
+func allowRule(opts *options) []httpauth.Rule {
+	allow := allowList(opts)
+	if len(allow) == 0 {
+		return nil
+	}
+	return []httpauth.Rule{{Prefix: "/", Allow: allow}}
+}
+
+// allowList returns the users and groups in -allow.
+func allowList(opts *options) []string {
+	var res []string
+	for _, a := range strings.Split(opts.Allow, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			res = append(res, a)
+		}
+	}
+	return res
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// cmdBuildStatus sets the -state of the build with -build-key on -commit-id,
+// or on the commit of the -at ref of the repository.
+func cmdBuildStatus(opts *options) error {
+	if opts.State == "" || opts.BuildKey == "" || opts.BuildURL == "" {
+		return usageErrorf("build-status needs -state, -build-key and -build-url")
+	}
+	if err := opts.State.Validate(); err != nil {
+		return usageErrorf("build-status: %w", err)
+	}
+	ctx := context.Background()
+	client := getClient(opts)
+	commit := opts.CommitID
+	if commit == "" {
+		if opts.At == "" {
+			return usageErrorf("build-status needs -commit-id or -at")
+		}
+		if err := requireRepo(opts); err != nil {
+			return err
+		}
+		id, err := bbfs.ResolveRef(ctx, client, opts.ProjectKey, opts.RepoSlug, bbfs.Ref(opts.At))
+		if err != nil {
+			return err
+		}
+		commit = id
+	}
+	cmd := &server.SetBuildStatusCommand{
+		CommitID:    commit,
+		State:       opts.State,
+		Key:         opts.BuildKey,
+		URL:         opts.BuildURL,
+		Name:        opts.BuildName,
+		Description: opts.Description,
+	}
+	if opts.DryRun {
+		return printRequest(client.Describe(ctx, cmd))
+	}
+	return client.SetBuildStatus(ctx, cmd)
+}
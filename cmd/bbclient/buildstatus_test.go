@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestBuildStatus(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]string
+	bs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer bs.Close()
+
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	srv.AddRepo("PRJ", "repo", repo)
+	head := repo.Resolve("main").ID
+
+	status := []string{"-state", "successful", "-build-key", "ci", "-build-url", "https://ci.example.com/1"}
+	tests := []struct {
+		name    string
+		baseURL string
+		args    []string
+		code    int
+		want    string
+	}{
+		{"commit", bs.URL + "/rest/api/latest", []string{"-commit-id", "abc"}, exitOK, ""},
+		{"dry run at", srv.BaseURL(), []string{"-at", "main", "-project-key", "PRJ", "-repo-slug", "repo", "-dry-run"}, exitOK,
+			"POST " + srv.URL + "/rest/build-status/latest/commits/" + head + "\n"},
+		{"missing commit", srv.BaseURL(), nil, exitUsage, ""},
+		{"unknown state", srv.BaseURL(), []string{"-commit-id", "abc", "-state", "DONE"}, exitUsage, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			stdout = &out
+			defer func() { stdout = nil }()
+			args := append([]string{"bbclient", "build-status", "-base-url", tt.baseURL}, status...)
+			args = append(args, tt.args...)
+			err := run(args, func(string) string { return "" })
+			if _, code := errorKind(err); code != tt.code {
+				t.Errorf("expected exit code %d, got %d for %v", tt.code, code, err)
+			}
+			if !strings.HasPrefix(out.String(), tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+	if gotPath != "/rest/build-status/latest/commits/abc" {
+		t.Errorf("unexpected path %s", gotPath)
+	}
+	if gotBody["state"] != "SUCCESSFUL" || gotBody["key"] != "ci" || gotBody["url"] != "https://ci.example.com/1" {
+		t.Errorf("unexpected body %v", gotBody)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+
+	"github.com/myhops/bbfs"
+)
+
+// cmdChecksums prints the SHA256SUMS of the files in -file-path at the -at ref.
+func cmdChecksums(opts *options) error {
+	fsys, err := newFS(opts)
+	if err != nil {
+		return err
+	}
+	sums, err := bbfs.Checksums(context.Background(), fsys, opts.FilePath)
+	if err != nil {
+		return err
+	}
+	return bbfs.WriteSHA256Sums(stdout, sums)
+}
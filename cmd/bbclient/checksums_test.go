@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestChecksums(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"README.md":       {Data: []byte("hello\n")},
+		"config/app.yaml": {Data: []byte("a\n")},
+	}))
+
+	var out strings.Builder
+	stdout = &out
+	defer func() { stdout = nil }()
+	args := []string{"bbclient", "checksums", "-base-url", srv.BaseURL(), "-project-key", "PRJ", "-repo-slug", "repo", "-file-path", "config"}
+	if err := run(args, func(string) string { return "" }); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7  app.yaml\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
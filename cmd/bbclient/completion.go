@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// completionFlag is a flag with the way its value is completed.
+type completionFlag struct {
+	Name  string
+	Usage string
+	Bool  bool
+	// Dynamic is the name passed to __complete to get the values.
+	Dynamic string
+	// Values are the static values.
+	Values []string
+}
+
+type completionData struct {
+	Commands []*command
+	Flags    []completionFlag
+}
+
+func newCompletionData() *completionData {
+	d := &completionData{
+		Commands: visibleCommands(),
+	}
+	var names []string
+	for _, c := range d.Commands {
+		names = append(names, c.Name)
+	}
+	for _, f := range flagDefs {
+		cf := completionFlag{
+			Name: f.Name,
+			// Only the first line fits in the completion menus.
+			Usage: strings.SplitN(f.Usage, "\n", 2)[0],
+			Bool:  f.Bool,
+		}
+		switch f.Name {
+		case "command":
+			cf.Values = names
+		case "order-by":
+			for _, v := range server.OrderByValues {
+				cf.Values = append(cf.Values, v.String())
+			}
+		case "state":
+			for _, v := range server.BuildStateValues {
+				cf.Values = append(cf.Values, v.String())
+			}
+		case "error-format":
+			cf.Values = []string{errorFormatText, errorFormatJSON}
+		case "project-key", "repo-slug":
+			cf.Dynamic = f.Name
+		}
+		d.Flags = append(d.Flags, cf)
+	}
+	return d
+}
+
+var completionFuncs = template.FuncMap{
+	"join": strings.Join,
+	// zsh escapes the text for use in an _arguments spec.
+	"zsh": strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace,
+	// fish escapes the text for use in a single quoted string.
+	"fish": strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace,
+}
+
+var completionTemplates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(completionFuncs).Parse(bashCompletion)),
+	"zsh":  template.Must(template.New("zsh").Funcs(completionFuncs).Parse(zshCompletion)),
+	"fish": template.Must(template.New("fish").Funcs(completionFuncs).Parse(fishCompletion)),
+}
+
+// cmdCompletion prints the completion script for the shell in the first argument.
+func cmdCompletion(opts *options) error {
+	if len(opts.Args) != 1 {
+		return usageErrorf("completion needs one argument: bash, zsh or fish")
+	}
+	tmpl, ok := completionTemplates[opts.Args[0]]
+	if !ok {
+		return usageErrorf("unsupported shell: %s", opts.Args[0])
+	}
+	return tmpl.Execute(stdout, newCompletionData())
+}
+
+// cmdComplete prints the values for the flag in the first argument, one per line.
+// It is called by the completion scripts.
+func cmdComplete(opts *options) error {
+	if len(opts.Args) != 1 {
+		return usageErrorf("__complete needs one argument: project-key or repo-slug")
+	}
+	ctx := context.Background()
+	client := getClient(opts)
+	switch opts.Args[0] {
+	case "project-key":
+		projects, err := listProjects(ctx, client)
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			fmt.Fprintln(stdout, p.Key)
+		}
+	case "repo-slug":
+		repos, err := listRepos(ctx, client, opts.ProjectKey)
+		if err != nil {
+			return err
+		}
+		for _, r := range repos {
+			fmt.Fprintln(stdout, r.Slug)
+		}
+	default:
+		return usageErrorf("no completions for %s", opts.Args[0])
+	}
+	return nil
+}
+
+const bashCompletion = `# bash completion for bbclient
+# Load with: source <(bbclient completion bash)
+
+_bbclient_flag_value() {
+	local i
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		if [[ "${COMP_WORDS[i]}" == "$1" ]]; then
+			echo "${COMP_WORDS[i+1]}"
+			return
+		fi
+	done
+}
+
+_bbclient() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+{{- range .Flags}}{{if not .Bool}}
+	-{{.Name}})
+{{- if eq .Dynamic "repo-slug"}}
+		COMPREPLY=($(compgen -W "$(bbclient __complete -project-key "$(_bbclient_flag_value -project-key)" repo-slug 2>/dev/null)" -- "$cur"))
+{{- else if .Dynamic}}
+		COMPREPLY=($(compgen -W "$(bbclient __complete {{.Dynamic}} 2>/dev/null)" -- "$cur"))
+{{- else if .Values}}
+		COMPREPLY=($(compgen -W "{{join .Values " "}}" -- "$cur"))
+{{- end}}
+		return
+		;;
+{{- end}}{{end}}
+	esac
+	if [[ $COMP_CWORD -eq 1 && "$cur" != -* ]]; then
+		COMPREPLY=($(compgen -W "{{range .Commands}}{{.Name}} {{end}}" -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -W "{{range .Flags}}-{{.Name}} {{end}}" -- "$cur"))
+}
+
+complete -F _bbclient bbclient
+`
+
+const zshCompletion = `#compdef bbclient
+# zsh completion for bbclient
+# Load with: source <(bbclient completion zsh)
+
+_bbclient() {
+	local curcontext="$curcontext" state line
+	typeset -A opt_args
+	local -a commands
+	commands=(
+{{- range .Commands}}
+		'{{.Name}}:{{zsh .Summary}}'
+{{- end}}
+	)
+	_arguments -C \
+{{- range .Flags}}
+		'-{{.Name}}[{{zsh .Usage}}]{{if not .Bool}}:{{.Name}}:{{if .Dynamic}}->{{.Dynamic}}{{else if .Values}}({{join .Values " "}}){{else}} {{end}}{{end}}' \
+{{- end}}
+		'1: :->command'
+	case $state in
+	command)
+		_describe -t commands 'bbclient command' commands
+		;;
+	project-key)
+		compadd -- ${(f)"$(bbclient __complete project-key 2>/dev/null)"}
+		;;
+	repo-slug)
+		compadd -- ${(f)"$(bbclient __complete -project-key "${opt_args[-project-key]}" repo-slug 2>/dev/null)"}
+		;;
+	esac
+}
+
+if [[ "$funcstack[1]" == "_bbclient" ]]; then
+	_bbclient "$@"
+else
+	compdef _bbclient bbclient
+fi
+`
+
+const fishCompletion = `# fish completion for bbclient
+# Load with: bbclient completion fish | source
+
+function __bbclient_flag_value
+	set -l tokens (commandline -opc)
+	if set -l i (contains -i -- $argv[1] $tokens)
+		echo $tokens[(math $i + 1)]
+	end
+end
+
+complete -c bbclient -f
+{{- range .Commands}}
+complete -c bbclient -n __fish_use_subcommand -a {{.Name}} -d '{{fish .Summary}}'
+{{- end}}
+{{- range .Flags}}
+{{- if .Bool}}
+complete -c bbclient -o {{.Name}} -d '{{fish .Usage}}'
+{{- else if eq .Dynamic "repo-slug"}}
+complete -c bbclient -o {{.Name}} -x -a '(bbclient __complete -project-key (__bbclient_flag_value -project-key) repo-slug 2>/dev/null)' -d '{{fish .Usage}}'
+{{- else if .Dynamic}}
+complete -c bbclient -o {{.Name}} -x -a '(bbclient __complete {{.Dynamic}} 2>/dev/null)' -d '{{fish .Usage}}'
+{{- else if .Values}}
+complete -c bbclient -o {{.Name}} -x -a '{{join .Values " "}}' -d '{{fish .Usage}}'
+{{- else}}
+complete -c bbclient -o {{.Name}} -x -d '{{fish .Usage}}'
+{{- end}}
+{{- end}}
+`
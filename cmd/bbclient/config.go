@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadOptionsFile reads the -config file, a JSON or YAML object with the
+// flag names as keys, and returns its values by environment variable.
+//
+//	listen: ":8080"
+//	project-key: PRJ
+//	access-key-secret: file:/var/run/secrets/bbfs/token
+func loadOptionsFile(path string) (func(string) string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.NewDecoder(bytes.NewReader(data)).Decode(&m)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("config %s: unsupported format %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	vals := map[string]string{}
+	for k, v := range m {
+		f := findFlagDef(k)
+		if f == nil || f.Name == "command" || f.Name == "config" {
+			return nil, fmt.Errorf("config %s: unknown option %q", path, k)
+		}
+		switch v.(type) {
+		case map[string]any, []any:
+			return nil, fmt.Errorf("config %s: %s must be a string, number or bool", path, k)
+		case nil:
+			continue
+		}
+		vals[f.Env] = fmt.Sprint(v)
+	}
+	return func(key string) string { return vals[key] }, nil
+}
+
+func findFlagDef(name string) *flagDef {
+	for i := range flagDefs {
+		if flagDefs[i].Name == name {
+			return &flagDefs[i]
+		}
+	}
+	return nil
+}
+
+// generation is the handler of serve or proxy for one version of the options.
+type generation struct {
+	opts    *options
+	handler http.Handler
+	ready   func(ctx context.Context) error
+	cancel  context.CancelFunc
+}
+
+// reloader serves the current generation of the handler and builds a new
+// one when the options are parsed again, so the -config file, -routes,
+// -basic-auth-file and the secret of -access-key-secret are read again
+// without a restart. A new generation gets a new client and so an empty cache.
+type reloader struct {
+	ctx   context.Context
+	build func(ctx context.Context, opts *options) (http.Handler, error)
+
+	mu  sync.RWMutex
+	gen *generation
+}
+
+func newReloader(ctx context.Context, opts *options, build func(ctx context.Context, opts *options) (http.Handler, error)) (*reloader, error) {
+	r := &reloader{ctx: ctx, build: build}
+	gen, err := r.newGeneration(opts)
+	if err != nil {
+		return nil, err
+	}
+	r.gen = gen
+	return r, nil
+}
+
+func (r *reloader) newGeneration(opts *options) (*generation, error) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	h, err := r.build(ctx, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	ready, err := readiness(opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &generation{opts: opts, handler: h, ready: ready, cancel: cancel}, nil
+}
+
+func (r *reloader) current() *generation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.gen
+}
+
+func (r *reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.current().handler.ServeHTTP(w, req)
+}
+
+// checkReady checks the readiness of the current generation.
+func (r *reloader) checkReady(ctx context.Context) error {
+	return r.current().ready(ctx)
+}
+
+// reload parses the options again and replaces the handler. On error the
+// current handler stays. The address and the timeouts of the server only
+// change with a restart.
+func (r *reloader) reload() error {
+	old := r.current()
+	if old.opts.reparse == nil {
+		return nil
+	}
+	opts, err := old.opts.reparse()
+	if err != nil {
+		return err
+	}
+	if opts.Listen != old.opts.Listen || opts.ReadTimeout != old.opts.ReadTimeout ||
+		opts.WriteTimeout != old.opts.WriteTimeout || opts.ShutdownTimeout != old.opts.ShutdownTimeout {
+		fmt.Fprintf(os.Stderr, "-listen and the timeouts change with a restart\n")
+	}
+	gen, err := r.newGeneration(opts)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.gen = gen
+	r.mu.Unlock()
+	// Ends the watch and the event streams of the old generation,
+	// the clients of the streams reconnect.
+	old.cancel()
+	return nil
+}
+
+// runServer serves the handler that build returns until interrupted, and
+// reloads it on SIGHUP.
+func runServer(opts *options, build func(ctx context.Context, opts *options) (http.Handler, error)) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	r, err := newReloader(ctx, opts, build)
+	if err != nil {
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := r.reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "reload failed, keeping the current configuration: %s\n", err.Error())
+					continue
+				}
+				fmt.Fprintf(os.Stderr, "reloaded the configuration\n")
+			}
+		}
+	}()
+	return newHTTPServer(opts, r, r.checkReady).Run(ctx)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestOptionsFile(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bbclient.yaml")
+	os.WriteFile(config, []byte("listen: :8080\nproject-key: FILE\nrepo-slug: file\nevents: true\nmax-age: 5m\n"), 0o644)
+	unknown := filepath.Join(dir, "unknown.json")
+	os.WriteFile(unknown, []byte(`{"repo": "x"}`), 0o644)
+
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_CONFIG":      config,
+			"BBFS_CLIENT_PROJECT_KEY": "ENV",
+		}[name]
+	}
+	opts, err := parseOptions([]string{"bbclient", "serve", "-repo-slug", "flag"}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if opts.Listen != ":8080" || opts.ProjectKey != "ENV" || opts.RepoSlug != "flag" || !opts.Events || opts.MaxAge != 5*time.Minute {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	if _, err := parseOptions([]string{"bbclient", "serve", "-config", unknown}, func(string) string { return "" }); err == nil {
+		t.Errorf("expected an error for an unknown option")
+	}
+}
+
+func TestReload(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "blue", fakeserver.NewRepo(fstest.MapFS{"index.html": {Data: []byte("blue")}}))
+	srv.AddRepo("PRJ", "green", fakeserver.NewRepo(fstest.MapFS{"index.html": {Data: []byte("green")}}))
+
+	config := filepath.Join(t.TempDir(), "bbclient.yaml")
+	os.WriteFile(config, []byte("repo-slug: blue\n"), 0o644)
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_BASE_URL":    srv.BaseURL(),
+			"BBFS_CLIENT_PROJECT_KEY": "PRJ",
+			"BBFS_CLIENT_LISTEN":      "127.0.0.1:0",
+			"BBFS_CLIENT_CONFIG":      config,
+		}[name]
+	}
+	opts, err := parseOptions([]string{"bbclient", "serve"}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	r, err := newReloader(context.Background(), opts, serveHandler)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	hs := httptest.NewServer(r)
+	defer hs.Close()
+
+	get := func() string {
+		resp, err := http.Get(hs.URL + "/")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	if got := get(); got != "blue" {
+		t.Fatalf("expected blue, got %q", got)
+	}
+
+	os.WriteFile(config, []byte("repo-slug: green\n"), 0o644)
+	if err := r.reload(); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if got := get(); got != "green" {
+		t.Errorf("expected green after the reload, got %q", got)
+	}
+
+	// A bad file keeps the current handler.
+	os.WriteFile(config, []byte("repo-slug: [\n"), 0o644)
+	if err := r.reload(); err == nil {
+		t.Errorf("expected an error for a bad file")
+	}
+	if got := get(); got != "green" {
+		t.Errorf("expected green after the failed reload, got %q", got)
+	}
+	if err := r.checkReady(context.Background()); err != nil {
+		t.Errorf("error: %s", err.Error())
+	}
+}
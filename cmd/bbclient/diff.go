@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// cmdDiff prints the diff, the changed paths or the diff stat between -to and -from.
+func cmdDiff(opts *options) error {
+	if opts.NameOnly && opts.Stat {
+		return usageErrorf("-name-only and -stat can not be combined")
+	}
+	ctx := context.Background()
+	client := getClient(opts)
+
+	if opts.NameOnly {
+		changes, err := listChanges(ctx, client, opts)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			fmt.Fprintln(stdout, c.Path)
+		}
+		return nil
+	}
+
+	resp, err := client.GetDiff(ctx, &server.GetDiffCommand{
+		ProjectKey: opts.ProjectKey,
+		RepoSlug:   opts.RepoSlug,
+		From:       server.Ref(opts.From),
+		To:         server.Ref(opts.To),
+		FilePath:   opts.FilePath,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.Stat {
+		writeDiffStat(stdout, resp.Diffs)
+		return nil
+	}
+	for _, d := range resp.Diffs {
+		writeUnifiedDiff(stdout, d)
+	}
+	return nil
+}
+
+// listChanges returns the changes between -to and -from in -file-path,
+// the same scope as the diff.
+func listChanges(ctx context.Context, client *server.Client, opts *options) ([]*server.Change, error) {
+	var res []*server.Change
+	cmd := &server.GetChangesCommand{
+		ProjectKey: opts.ProjectKey,
+		RepoSlug:   opts.RepoSlug,
+		From:       server.Ref(opts.From),
+		To:         server.Ref(opts.To),
+		Limit:      server.MaxLimit,
+	}
+	for {
+		resp, err := client.GetChanges(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range resp.Changes {
+			if inFilePath(c.Path, opts.FilePath) || inFilePath(c.SrcPath, opts.FilePath) {
+				res = append(res, c)
+			}
+		}
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// inFilePath reports whether p is the file or in the directory filePath.
+// Every path is in the empty filePath.
+func inFilePath(p, filePath string) bool {
+	filePath = strings.Trim(filePath, "/")
+	if filePath == "" {
+		return true
+	}
+	return p == filePath || strings.HasPrefix(p, filePath+"/")
+}
+
+// diffPaths returns the names of the file before and after the change in git format.
+func diffPaths(d *server.Diff) (string, string) {
+	src, dst := "/dev/null", "/dev/null"
+	if d.Source != "" {
+		src = "a/" + d.Source
+	}
+	if d.Destination != "" {
+		dst = "b/" + d.Destination
+	}
+	return src, dst
+}
+
+// diffName returns the name used for the file in the output.
+func diffName(d *server.Diff) string {
+	if d.Destination != "" {
+		return d.Destination
+	}
+	return d.Source
+}
+
+// writeUnifiedDiff writes the diff in unified format.
+func writeUnifiedDiff(w io.Writer, d *server.Diff) {
+	src, dst := diffPaths(d)
+	fmt.Fprintf(w, "diff --git a/%s b/%s\n", orDefault(d.Source, d.Destination), orDefault(d.Destination, d.Source))
+	if d.Binary {
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", src, dst)
+		return
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", src, dst)
+	for _, h := range d.Hunks {
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", h.SourceLine, h.SourceSpan, h.DestinationLine, h.DestinationSpan)
+		for _, s := range h.Segments {
+			prefix := " "
+			switch s.Type {
+			case server.SegmentAdded:
+				prefix = "+"
+			case server.SegmentRemoved:
+				prefix = "-"
+			}
+			for _, l := range s.Lines {
+				fmt.Fprintf(w, "%s%s\n", prefix, l)
+			}
+		}
+	}
+	if d.Truncated {
+		fmt.Fprintf(w, "# diff truncated by the server\n")
+	}
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// diffStat returns the number of added and removed lines.
+func diffStat(d *server.Diff) (int, int) {
+	var added, removed int
+	for _, h := range d.Hunks {
+		for _, s := range h.Segments {
+			switch s.Type {
+			case server.SegmentAdded:
+				added += len(s.Lines)
+			case server.SegmentRemoved:
+				removed += len(s.Lines)
+			}
+		}
+	}
+	return added, removed
+}
+
+// writeDiffStat writes the stat in the format of git diff --stat.
+func writeDiffStat(w io.Writer, diffs []*server.Diff) {
+	const maxBar = 50
+	width, maxChanges := 0, 0
+	for _, d := range diffs {
+		width = max(width, len(diffName(d)))
+		added, removed := diffStat(d)
+		maxChanges = max(maxChanges, added+removed)
+	}
+	var totalAdded, totalRemoved int
+	for _, d := range diffs {
+		if d.Binary {
+			fmt.Fprintf(w, " %-*s | Bin\n", width, diffName(d))
+			continue
+		}
+		added, removed := diffStat(d)
+		totalAdded += added
+		totalRemoved += removed
+		// Scale the bar when the largest change does not fit.
+		plus, minus := added, removed
+		if maxChanges > maxBar {
+			plus = added * maxBar / maxChanges
+			minus = removed * maxBar / maxChanges
+		}
+		fmt.Fprintf(w, " %-*s | %d %s%s\n", width, diffName(d), added+removed,
+			strings.Repeat("+", plus), strings.Repeat("-", minus))
+	}
+	fmt.Fprintf(w, " %d files changed, %d insertions(+), %d deletions(-)\n", len(diffs), totalAdded, totalRemoved)
+}
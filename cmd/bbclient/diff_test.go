@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestDiff(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"keep.txt":   {Data: []byte("keep\n")},
+		"change.txt": {Data: []byte("old\n")},
+		"remove.txt": {Data: []byte("gone\n")},
+	})
+	repo.Commit("main", "second", fstest.MapFS{
+		"keep.txt":   {Data: []byte("keep\n")},
+		"change.txt": {Data: []byte("new\nlines\n")},
+		"add.txt":    {Data: []byte("added\n")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "name only",
+			args: []string{"-name-only"},
+			want: "add.txt\nchange.txt\nremove.txt\n",
+		},
+		{
+			name: "name only in file path",
+			args: []string{"-name-only", "-file-path", "change.txt"},
+			want: "change.txt\n",
+		},
+		{
+			name: "stat",
+			args: []string{"-stat"},
+			want: " add.txt    | 1 +\n" +
+				" change.txt | 3 ++-\n" +
+				" remove.txt | 1 -\n" +
+				" 3 files changed, 3 insertions(+), 2 deletions(-)\n",
+		},
+		{
+			name: "unified",
+			args: []string{"-file-path", "change.txt"},
+			want: "diff --git a/change.txt b/change.txt\n" +
+				"--- a/change.txt\n" +
+				"+++ b/change.txt\n" +
+				"@@ -1,1 +1,2 @@\n" +
+				"-old\n" +
+				"+new\n" +
+				"+lines\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			stdout = &out
+			defer func() { stdout = nil }()
+			args := append([]string{"bbclient", "diff", "-base-url", srv.BaseURL(),
+				"-project-key", "PRJ", "-repo-slug", "repo", "-from", repo.Commits[1].ID, "-to", repo.Commits[0].ID}, tt.args...)
+			if err := run(args, func(string) string { return "" }); err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			if out.String() != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// Exit codes of bbclient, scripts can rely on them.
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitAuth     = 3
+	exitNotFound = 4
+	exitNetwork  = 5
+)
+
+// Error formats for -error-format.
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// usageError is an error in the invocation of bbclient.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf returns a usageError with the formatted message.
+func usageErrorf(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// errorKind returns the kind of the error and the exit code for it.
+func errorKind(err error) (string, int) {
+	var ue *usageError
+	var ne net.Error
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return "", exitOK
+	case errors.As(err, &ue), errors.Is(err, server.ErrInvalidCommand):
+		return "usage", exitUsage
+	case server.IsUnauthorized(err), errors.Is(err, server.ErrNoToken):
+		return "auth", exitAuth
+	case server.IsNotFound(err), errors.Is(err, bbfs.ErrRefNotFound):
+		return "not_found", exitNotFound
+	case errors.As(err, &ne), errors.Is(err, server.ErrCircuitOpen):
+		return "network", exitNetwork
+	}
+	return "failure", exitFailure
+}
+
+// jsonError is the error as printed with -error-format=json.
+type jsonError struct {
+	Error      string `json:"error"`
+	Kind       string `json:"kind"`
+	ExitCode   int    `json:"exitCode"`
+	StatusCode int    `json:"statusCode,omitempty"`
+}
+
+// reportError writes the error in the format to w and returns the exit code.
+func reportError(w io.Writer, err error, format string) int {
+	kind, code := errorKind(err)
+	if code == exitOK {
+		return code
+	}
+	if format != errorFormatJSON {
+		fmt.Fprintf(w, "run failed: %s\n", err.Error())
+		return code
+	}
+	je := jsonError{
+		Error:    err.Error(),
+		Kind:     kind,
+		ExitCode: code,
+	}
+	var se *server.StatusError
+	if errors.As(err, &se) {
+		je.StatusCode = se.StatusCode
+	}
+	json.NewEncoder(w).Encode(je)
+	return code
+}
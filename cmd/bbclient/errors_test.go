@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestExitCodes(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{}))
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		args    []string
+		code    int
+	}{
+		{"ok", srv.BaseURL(), []string{"tags", "-project-key", "PRJ", "-repo-slug", "repo"}, exitOK},
+		{"help", srv.BaseURL(), []string{"-h"}, exitOK},
+		{"bad command", srv.BaseURL(), []string{"bogus"}, exitUsage},
+		{"bad flag", srv.BaseURL(), []string{"tags", "-bogus"}, exitUsage},
+		{"no command", srv.BaseURL(), nil, exitUsage},
+		{"missing repo", srv.BaseURL(), []string{"tags", "-project-key", "PRJ"}, exitUsage},
+		{"bad secret", srv.BaseURL(), []string{"tags", "-project-key", "PRJ", "-repo-slug", "repo", "-access-key-secret", "bogus"}, exitUsage},
+		{"key and secret", srv.BaseURL(), []string{"tags", "-access-key", "key", "-access-key-secret", "env:TOKEN"}, exitUsage},
+		{"missing secret", srv.BaseURL(), []string{"tags", "-project-key", "PRJ", "-repo-slug", "repo", "-access-key-secret", "env:BBFS_TEST_UNSET_TOKEN"}, exitAuth},
+		{"not found", srv.BaseURL(), []string{"tags", "-project-key", "PRJ", "-repo-slug", "nope"}, exitNotFound},
+		{"unauthorized", unauthorized.URL, []string{"tags", "-project-key", "PRJ", "-repo-slug", "repo"}, exitAuth},
+		{"network", closed.URL, []string{"tags", "-project-key", "PRJ", "-repo-slug", "repo"}, exitNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout = &strings.Builder{}
+			defer func() { stdout = nil }()
+			args := append([]string{"bbclient", "-base-url", tt.baseURL}, tt.args...)
+			err := run(args, func(string) string { return "" })
+			if _, code := errorKind(err); code != tt.code {
+				t.Errorf("expected exit code %d, got %d for %v", tt.code, code, err)
+			}
+		})
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	opts, err := parseOptions([]string{"bbclient", "-error-format", "json", "bogus"}, func(string) string { return "" })
+	if err == nil {
+		err = execute(opts)
+	}
+	var out strings.Builder
+	if code := reportError(&out, err, opts.ErrorFormat); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+	var je jsonError
+	if err := json.Unmarshal([]byte(out.String()), &je); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if je.Kind != "usage" || je.ExitCode != exitUsage || je.Error != "bad command: bogus" {
+		t.Errorf("unexpected error %+v", je)
+	}
+}
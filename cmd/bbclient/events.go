@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// Paths of the server-sent events and of the refs changed webhook of serve -events.
+const (
+	eventsPath  = "/events"
+	webhookPath = "/webhook"
+)
+
+// heartbeat is the interval of the comments that keep idle event streams open
+// through proxies.
+var heartbeat = 30 * time.Second
+
+// eventJSON is the data of a ref event on the stream.
+type eventJSON struct {
+	Ref  string `json:"ref"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	Time string `json:"time"`
+}
+
+// eventHub sends the ref events to the clients of the event stream.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan bbfs.RefEvent]struct{}
+	// done ends the streams, nil never.
+	done <-chan struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: map[chan bbfs.RefEvent]struct{}{}}
+}
+
+// publish sends the event to the clients. Slow clients miss events
+// instead of holding up the others.
+func (h *eventHub) publish(ev bbfs.RefEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (h *eventHub) subscribe() chan bbfs.RefEvent {
+	ch := make(chan bbfs.RefEvent, 8)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan bbfs.RefEvent) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
+
+// ServeHTTP streams the events as server-sent events of type ref.
+func (h *eventHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev := <-ch:
+			data, _ := json.Marshal(eventJSON{
+				Ref:  ev.Ref.String(),
+				From: ev.From,
+				To:   ev.To,
+				Time: ev.Time.UTC().Format(time.RFC3339),
+			})
+			fmt.Fprintf(w, "event: ref\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+// withEvents serves the events of the -at ref on eventsPath next to h.
+// The events come from polls every -interval, or with -webhook-secret from
+// the webhook on webhookPath. A move of the ref clears the cache of the
+// client, so h serves the new commit. protect wraps h and the events, the
+// webhook checks the signature of the calls itself.
+func withEvents(ctx context.Context, opts *options, client *server.Client, h http.Handler, protect func(http.Handler) http.Handler) (http.Handler, error) {
+	if opts.At == "" {
+		return nil, usageErrorf("serve -events needs -at")
+	}
+	interval, err := watchInterval(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hub := newEventHub()
+	hub.done = ctx.Done()
+	mux := http.NewServeMux()
+	mux.Handle(eventsPath, protect(hub))
+	mux.Handle("/", protect(h))
+	if opts.WebhookSecret != "" {
+		mux.Handle(webhookPath, bbfs.WebhookHandler(opts.ProjectKey, opts.RepoSlug, bbfs.Ref(opts.At), opts.WebhookSecret.Secret(), func(ev bbfs.RefEvent) {
+			client.ClearCache()
+			hub.publish(ev)
+		}))
+		return mux, nil
+	}
+
+	w := &bbfs.Watcher{
+		Client:     client,
+		ProjectKey: opts.ProjectKey,
+		RepoSlug:   opts.RepoSlug,
+		Ref:        bbfs.Ref(opts.At),
+		Interval:   interval,
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "poll failed: %s\n", err.Error())
+		},
+	}
+	go func() {
+		err := w.Watch(ctx, func(ev bbfs.RefEvent) error {
+			hub.publish(ev)
+			return nil
+		})
+		if ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "watch of %s stopped: %s\n", opts.At, err.Error())
+		}
+	}()
+	return mux, nil
+}
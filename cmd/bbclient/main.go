@@ -4,14 +4,21 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/myhops/bbfs"
 	"github.com/myhops/bbfs/bbclient/server"
 	"github.com/myhops/bbfs/nulllog"
+	"github.com/myhops/bbfs/secrets"
 )
 
+// stdout is where the commands write their output.
+var stdout io.Writer = os.Stdout
+
 // options contains the options for all commands
 type options struct {
 	Command    string
@@ -19,17 +26,76 @@ type options struct {
 	AccessKey  server.SecretString
 	ProjectKey string
 	RepoSlug   string
-	OrderBy    string
+	OrderBy    server.OrderBy
 	Limit      int
 	FilePath   string
 	At         string
 	CommitID   string
+	// ErrorFormat is text or json.
+	ErrorFormat string
+	// From and To are the refs to compare.
+	From string
+	To   string
+	// NameOnly and Stat select the output of diff.
+	NameOnly bool
+	Stat     bool
+	// Interval, Listen and WebhookSecret configure watch, Listen also proxy and serve.
+	Interval      string
+	Listen        string
+	WebhookSecret server.SecretString
+	// Events makes serve send the moves of the -at ref on /events.
+	Events bool
+	// DirIndex, NotFound and MaxAge configure serve -mode files.
+	DirIndex bool
+	NotFound string
+	MaxAge   time.Duration
+	// BasicAuthFile, OIDCIssuer and OIDCAudience protect serve and proxy,
+	// Allow lists the users and groups that may access them.
+	BasicAuthFile string
+	OIDCIssuer    string
+	OIDCAudience  string
+	Allow         string
+	// ReadTimeout, WriteTimeout and ShutdownTimeout configure the http
+	// server of serve and proxy.
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	// Routes is a file with the repositories that serve mounts under url prefixes.
+	Routes string
+	// Mode is what serve serves, Module the module path for the goproxy mode.
+	Mode   string
+	Module string
+	// AccessKeySecret is a reference to the access key in a secret store.
+	AccessKeySecret string
+	// DryRun prints the request of the command instead of sending it.
+	DryRun bool
+	// Config is a file with options, below the environment and the flags.
+	Config string
+	// Bucket, Region, Endpoint, Delete and IgnoreFile configure mirror.
+	Bucket     string
+	Region     string
+	Endpoint   string
+	Delete     bool
+	IgnoreFile string
+	// State, BuildKey, BuildURL, BuildName and Description are the build
+	// status of build-status.
+	State       server.BuildState
+	BuildKey    string
+	BuildURL    string
+	BuildName   string
+	Description string
+	// Args are the arguments after the command.
+	Args []string
+
+	// reparse parses the options again, for the reload of serve and proxy.
+	reparse func() (*options, error)
 }
 
 func defaultOptions() *options {
 	return &options{
-		BaseURL: "https://bitbucket.belastingdienst.nl/rest/api/latest",
-		OrderBy: server.OrderByModification.String(),
+		BaseURL:     "https://bitbucket.belastingdienst.nl/rest/api/latest",
+		OrderBy:     server.OrderByModification,
+		ErrorFormat: errorFormatText,
 	}
 }
 
@@ -57,75 +123,244 @@ func setIfSetSecretString(v string, val *server.SecretString) {
 	}
 }
 
+// setIfSetBool sets val if v is not empty and a bool value
+func setIfSetBool(v string, val *bool) {
+	if v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return
+		}
+		*val = b
+	}
+}
+
+// setIfSetDuration sets val if v is not empty and a duration
+func setIfSetDuration(v string, val *time.Duration) {
+	if v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return
+		}
+		*val = d
+	}
+}
+
+// setIfSetOrderBy sets val if v is not empty, case is ignored
+func setIfSetOrderBy(v string, val *server.OrderBy) {
+	if v != "" {
+		*val = server.OrderBy(strings.ToUpper(v))
+	}
+}
+
+// setIfSetBuildState sets val if v is not empty, case is ignored
+func setIfSetBuildState(v string, val *server.BuildState) {
+	if v != "" {
+		*val = server.BuildState(strings.ToUpper(v))
+	}
+}
+
+// envPrefix is the prefix of the environment variables of bbclient.
+// The repository coordinates use the names of bbfs.ConfigFromEnv.
+const envPrefix = "BBFS_CLIENT_"
+
 func setFromEnv(opts *options, getenv func(string) string) {
+	cfg := bbfs.ConfigFromEnvFunc(envPrefix, getenv)
 	setIfSet(getenv("BBFS_CLIENT_COMMAND"), &opts.Command)
-	setIfSet(getenv("BBFS_CLIENT_BASE_URL"), &opts.BaseURL)
-	setIfSetSecretString(getenv("BBFS_CLIENT_ACCESS_KEY"), &opts.AccessKey)
-	setIfSet(getenv("BBFS_CLIENT_PROJECT_KEY"), &opts.ProjectKey)
-	setIfSet(getenv("BBFS_CLIENT_REPO_SLUG"), &opts.RepoSlug)
-	setIfSet(getenv("BBFS_CLIENT_ORDER_BY"), &opts.OrderBy)
+	setIfSet(cfg.BaseURL, &opts.BaseURL)
+	setIfSetSecretString(cfg.AccessKey, &opts.AccessKey)
+	setIfSet(cfg.AccessKeySecret, &opts.AccessKeySecret)
+	setIfSet(cfg.ProjectKey, &opts.ProjectKey)
+	setIfSet(cfg.RepositorySlug, &opts.RepoSlug)
+	setIfSet(cfg.At.String(), &opts.At)
+	setIfSetOrderBy(getenv("BBFS_CLIENT_ORDER_BY"), &opts.OrderBy)
 	setIfSetInt(getenv("BBFS_CLIENT_LIMIT"), &opts.Limit)
 	setIfSet(getenv("BBFS_CLIENT_FILE_PATH"), &opts.FilePath)
-	setIfSet(getenv("BBFS_CLIENT_AT"), &opts.At)
 	setIfSet(getenv("BBFS_CLIENT_COMMIT_ID"), &opts.CommitID)
+	setIfSet(getenv("BBFS_CLIENT_ERROR_FORMAT"), &opts.ErrorFormat)
+	setIfSet(getenv("BBFS_CLIENT_FROM"), &opts.From)
+	setIfSet(getenv("BBFS_CLIENT_TO"), &opts.To)
+	setIfSetBool(getenv("BBFS_CLIENT_NAME_ONLY"), &opts.NameOnly)
+	setIfSetBool(getenv("BBFS_CLIENT_STAT"), &opts.Stat)
+	setIfSet(getenv("BBFS_CLIENT_INTERVAL"), &opts.Interval)
+	setIfSet(getenv("BBFS_CLIENT_LISTEN"), &opts.Listen)
+	setIfSetSecretString(getenv("BBFS_CLIENT_WEBHOOK_SECRET"), &opts.WebhookSecret)
+	setIfSet(getenv("BBFS_CLIENT_MODE"), &opts.Mode)
+	setIfSet(getenv("BBFS_CLIENT_MODULE"), &opts.Module)
+	setIfSetBool(getenv("BBFS_CLIENT_DRY_RUN"), &opts.DryRun)
+	setIfSetBool(getenv("BBFS_CLIENT_EVENTS"), &opts.Events)
+	setIfSetBool(getenv("BBFS_CLIENT_DIR_INDEX"), &opts.DirIndex)
+	setIfSet(getenv("BBFS_CLIENT_NOT_FOUND"), &opts.NotFound)
+	setIfSetDuration(getenv("BBFS_CLIENT_MAX_AGE"), &opts.MaxAge)
+	setIfSet(getenv("BBFS_CLIENT_ROUTES"), &opts.Routes)
+	setIfSetDuration(getenv("BBFS_CLIENT_READ_TIMEOUT"), &opts.ReadTimeout)
+	setIfSetDuration(getenv("BBFS_CLIENT_WRITE_TIMEOUT"), &opts.WriteTimeout)
+	setIfSetDuration(getenv("BBFS_CLIENT_SHUTDOWN_TIMEOUT"), &opts.ShutdownTimeout)
+	setIfSet(getenv("BBFS_CLIENT_BASIC_AUTH_FILE"), &opts.BasicAuthFile)
+	setIfSet(getenv("BBFS_CLIENT_OIDC_ISSUER"), &opts.OIDCIssuer)
+	setIfSet(getenv("BBFS_CLIENT_OIDC_AUDIENCE"), &opts.OIDCAudience)
+	setIfSet(getenv("BBFS_CLIENT_ALLOW"), &opts.Allow)
+	setIfSet(getenv("BBFS_CLIENT_CONFIG"), &opts.Config)
+	setIfSet(getenv("BBFS_CLIENT_BUCKET"), &opts.Bucket)
+	setIfSet(getenv("BBFS_CLIENT_REGION"), &opts.Region)
+	setIfSet(getenv("BBFS_CLIENT_ENDPOINT"), &opts.Endpoint)
+	setIfSetBool(getenv("BBFS_CLIENT_DELETE"), &opts.Delete)
+	setIfSet(getenv("BBFS_CLIENT_IGNORE_FILE"), &opts.IgnoreFile)
+	setIfSetBuildState(getenv("BBFS_CLIENT_STATE"), &opts.State)
+	setIfSet(getenv("BBFS_CLIENT_BUILD_KEY"), &opts.BuildKey)
+	setIfSet(getenv("BBFS_CLIENT_BUILD_URL"), &opts.BuildURL)
+	setIfSet(getenv("BBFS_CLIENT_BUILD_NAME"), &opts.BuildName)
+	setIfSet(getenv("BBFS_CLIENT_DESCRIPTION"), &opts.Description)
+}
+
+// flagDef is a command line flag and the environment variable it overrides.
+type flagDef struct {
+	Name  string
+	Env   string
+	Usage string
+	// Bool flags do not take a value.
+	Bool bool
+}
+
+var flagDefs = []flagDef{
+	{"command", "BBFS_CLIENT_COMMAND", "The command to execute, can also be given as first argument", false},
+	{"base-url", "BBFS_CLIENT_BASE_URL", "Base url of the bitbucket server on premises,\ndefaults to https://bitbucket.belastingdienst.nl/rest/api/latest", false},
+	{"access-key", "BBFS_CLIENT_ACCESS_KEY", "Access key for the repository", false},
+	{"access-key-secret", "BBFS_CLIENT_ACCESS_KEY_SECRET", "Reference to the access key in a secret store,\ne.g. env:TOKEN, file:/path, exec:command or vault:secret/data/bbfs#token", false},
+	{"project-key", "BBFS_CLIENT_PROJECT_KEY", "The bitbucket project or the user name", false},
+	{"repo-slug", "BBFS_CLIENT_REPO_SLUG", "repo name", false},
+	{"order-by", "BBFS_CLIENT_ORDER_BY", "Order by [ ALPHABETICAL | MODIFICATION ]", false},
+	{"limit", "BBFS_CLIENT_LIMIT", "Maximum number of entries to return, defauls to 25", false},
+	{"file-path", "BBFS_CLIENT_FILE_PATH", "File path", false},
+	{"at", "BBFS_CLIENT_AT", "branch or tag", false},
+	{"commit-id", "BBFS_CLIENT_COMMIT_ID", "commit id", false},
+	{"error-format", "BBFS_CLIENT_ERROR_FORMAT", "Format of errors [ text | json ]", false},
+	{"from", "BBFS_CLIENT_FROM", "ref with the changes for diff, the ancestor for is-ancestor", false},
+	{"to", "BBFS_CLIENT_TO", "ref to compare with for diff, the descendant for is-ancestor", false},
+	{"name-only", "BBFS_CLIENT_NAME_ONLY", "diff prints the changed paths only", true},
+	{"stat", "BBFS_CLIENT_STAT", "diff prints the number of changed lines per file", true},
+	{"interval", "BBFS_CLIENT_INTERVAL", "Poll interval for watch, defaults to 1m", false},
+	{"listen", "BBFS_CLIENT_LISTEN", "Address for the webhook of watch, e.g. :8080, instead of polling, or of proxy and serve", false},
+	{"webhook-secret", "BBFS_CLIENT_WEBHOOK_SECRET", "Secret of the webhook for watch", false},
+	{"mode", "BBFS_CLIENT_MODE", "What serve serves [ files | helm | goproxy | browse ], defaults to files", false},
+	{"module", "BBFS_CLIENT_MODULE", "Module path in -file-path for serve -mode goproxy", false},
+	{"events", "BBFS_CLIENT_EVENTS", "serve sends the moves of the -at ref as server-sent events on /events", true},
+	{"dir-index", "BBFS_CLIENT_DIR_INDEX", "serve lists the directories without an index.html", true},
+	{"not-found", "BBFS_CLIENT_NOT_FOUND", "Page in -file-path that serve returns for missing files, e.g. 404.html", false},
+	{"max-age", "BBFS_CLIENT_MAX_AGE", "Cache-Control max-age of serve for content at a branch or tag, e.g. 5m,\ncontent at a commit is cached for a year", false},
+	{"routes", "BBFS_CLIENT_ROUTES", "JSON or YAML file with the prefixes, repositories and refs that serve mounts", false},
+	{"basic-auth-file", "BBFS_CLIENT_BASIC_AUTH_FILE", "File with user:password lines for basic authentication at serve and proxy", false},
+	{"oidc-issuer", "BBFS_CLIENT_OIDC_ISSUER", "OpenID Connect issuer of the bearer tokens for serve and proxy", false},
+	{"oidc-audience", "BBFS_CLIENT_OIDC_AUDIENCE", "Audience of the bearer tokens for serve and proxy", false},
+	{"allow", "BBFS_CLIENT_ALLOW", "Comma separated users and groups, as group:name, that may access serve and proxy", false},
+	{"read-timeout", "BBFS_CLIENT_READ_TIMEOUT", "Maximum time to read a request for serve and proxy, e.g. 30s, defaults to no limit", false},
+	{"write-timeout", "BBFS_CLIENT_WRITE_TIMEOUT", "Maximum time to write a response for serve and proxy, defaults to no limit,\nit ends the streams of -events", false},
+	{"shutdown-timeout", "BBFS_CLIENT_SHUTDOWN_TIMEOUT", "Time serve and proxy wait for requests in progress when stopped, defaults to 5s", false},
+	{"bucket", "BBFS_CLIENT_BUCKET", "Bucket for mirror, s3://bucket/prefix, gs://bucket/prefix or a directory", false},
+	{"region", "BBFS_CLIENT_REGION", "Region of the s3 -bucket, defaults to AWS_REGION", false},
+	{"endpoint", "BBFS_CLIENT_ENDPOINT", "Url of the S3 compatible service of -bucket, e.g. http://localhost:9000", false},
+	{"delete", "BBFS_CLIENT_DELETE", "mirror deletes the objects of removed files", true},
+	{"ignore-file", "BBFS_CLIENT_IGNORE_FILE", "Local file with patterns in .gitignore syntax of the files that mirror skips,\nafter those of the .bbfsignore in -file-path", false},
+	{"state", "BBFS_CLIENT_STATE", "State of the build for build-status [ SUCCESSFUL | FAILED | INPROGRESS ]", false},
+	{"build-key", "BBFS_CLIENT_BUILD_KEY", "Key of the build for build-status, a later status with the key replaces it", false},
+	{"build-url", "BBFS_CLIENT_BUILD_URL", "Url of the build for build-status", false},
+	{"build-name", "BBFS_CLIENT_BUILD_NAME", "Name of the build for build-status, optional", false},
+	{"description", "BBFS_CLIENT_DESCRIPTION", "Description of the build for build-status, optional", false},
+	{"dry-run", "BBFS_CLIENT_DRY_RUN", "Print the request of tags, projects, repos or build-status, or the changes of mirror, instead of sending it", true},
+	{"config", "BBFS_CLIENT_CONFIG", "JSON or YAML file with flags as keys, e.g. listen: :8080,\nserve and proxy read it again on SIGHUP", false},
+}
+
+// newFlagSet returns the flag set and the flag values by environment variable.
+func newFlagSet() (*flag.FlagSet, map[string]*string) {
+	fs := flag.NewFlagSet("bbclient", flag.ContinueOnError)
+	vals := map[string]*string{}
+	for _, f := range flagDefs {
+		if f.Bool {
+			v := new(string)
+			fs.Var(boolString{v}, f.Name, f.Usage)
+			vals[f.Env] = v
+			continue
+		}
+		vals[f.Env] = fs.String(f.Name, "", f.Usage)
+	}
+	fs.Usage = func() { writeUsage(fs.Output(), fs) }
+	return fs, vals
+}
+
+// boolString is a bool flag that stores its value as string.
+type boolString struct {
+	s *string
+}
+
+func (b boolString) String() string {
+	if b.s == nil {
+		return ""
+	}
+	return *b.s
+}
+
+func (b boolString) Set(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return err
+	}
+	*b.s = v
+	return nil
+}
+
+func (b boolString) IsBoolFlag() bool {
+	return true
 }
 
 func setFromArgs(opts *options, args []string) error {
 	// Get the flags
-	fs := flag.NewFlagSet("temp", flag.ContinueOnError)
-	command := fs.String("command", "", "The command to execute")
-	baseURL := fs.String("base-url", "", "Base url of the bitbucket server on premises,\ndefaults to https://bitbucket.belastingdienst.nl/rest/api/latest")
-	accessKey := fs.String("access-key", "", "Access key for the repository")
-	projectKey := fs.String("project-key", "", "The bitbucket project or the user name")
-	repoSlug := fs.String("repo-slug", "", "repo name")
-	orderBy := fs.String("order-by", "", "Order by [ ALPHABETICAL | ... ]")
-	limit := fs.String("limit", "", "Maximum number of entries to return, defauls to 25")
-	filePath := fs.String("file-path", "", "File path")
-	at := fs.String("at", "", "branch or tag")
-	commitID := fs.String("commit-id", "", "commit id")
-
-	if err := fs.Parse(args[1:]); err != nil {
-		return err
+	fs, vals := newFlagSet()
+
+	// The command is the first argument or the first argument after the flags.
+	args = args[1:]
+	var command string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	parseErr := fs.Parse(args)
+	rest := fs.Args()
+	if parseErr == nil && command == "" && len(rest) > 0 {
+		// Parse the flags after the command.
+		command = rest[0]
+		parseErr = fs.Parse(rest[1:])
+		rest = fs.Args()
 	}
+	if command != "" {
+		*vals["BBFS_CLIENT_COMMAND"] = command
+	}
+	opts.Args = rest
 
 	getenv := func(key string) string {
-		switch key {
-		case "BBFS_CLIENT_COMMAND":
-			return *command
-		case "BBFS_CLIENT_BASE_URL":
-			return *baseURL
-		case "BBFS_CLIENT_PROJECT_KEY":
-			return *projectKey
-		case "BBFS_CLIENT_REPO_SLUG":
-			return *repoSlug
-		case "BBFS_CLIENT_ORDER_BY":
-			return *orderBy
-		case "BBFS_CLIENT_FILE_PATH":
-			return *filePath
-		case "BBFS_CLIENT_AT":
-			return *at
-		case "BBFS_CLIENT_COMMIT_ID":
-			return *commitID
-		case "BBFS_CLIENT_ACCESS_KEY":
-			return *accessKey
-		case "BBFS_CLIENT_LIMIT":
-			return *limit
+		if v, ok := vals[key]; ok {
+			return *v
 		}
 		return ""
 	}
 
+	// Apply the flags parsed so far, -error-format applies to parse errors too.
 	setFromEnv(opts, getenv)
 
+	if parseErr != nil {
+		return &usageError{err: parseErr}
+	}
 	return nil
 }
 
+// userAgent identifies bbclient in the User-Agent of the requests.
+var userAgent = "bbclient/" + bbfs.BuildVersion()
+
 func getClient(opts *options) *server.Client {
-	c := &server.Client{
-		BaseURL:   opts.BaseURL,
-		AccessKey: opts.AccessKey,
-		Logger:    nulllog.Logger(),
-	}
-	return c
+	return server.NewClient(opts.BaseURL, opts.AccessKey, func(c *server.Client) {
+		c.Logger = nulllog.Logger()
+		c.UserAgent = userAgent
+		// parseOptions checked the reference.
+		if p, err := secrets.Parse(opts.AccessKeySecret); err == nil {
+			c.TokenSource = secrets.TokenSource(p, 0)
+		}
+	})
 }
 
 func cmdGetTags(opts *options) error {
@@ -140,6 +375,10 @@ func cmdGetTags(opts *options) error {
 		OrderBy:    opts.OrderBy,
 	}
 
+	if opts.DryRun {
+		return printRequest(client.Describe(context.Background(), cmd))
+	}
+
 	// execute command
 	resp, err := client.GetTags(context.Background(), cmd)
 	if err != nil {
@@ -148,34 +387,231 @@ func cmdGetTags(opts *options) error {
 
 	// Print the result.
 	for _, e := range resp.Tags {
-		fmt.Printf("name %s, type %s\n", e.Name, e.Type)
+		fmt.Fprintf(stdout, "name %s, type %s\n", e.Name, e.Type)
 	}
 	return nil
 }
 
-func run(args []string, getenv func(string) string) error {
+// listProjects returns all projects visible to the user.
+func listProjects(ctx context.Context, client *server.Client) ([]*server.Project, error) {
+	var res []*server.Project
+	cmd := &server.GetProjectsCommand{Limit: server.MaxLimit}
+	for {
+		resp, err := client.GetProjects(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, resp.Projects...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// listRepos returns all repositories in the project.
+func listRepos(ctx context.Context, client *server.Client, project string) ([]*server.Repository, error) {
+	var res []*server.Repository
+	cmd := &server.GetReposCommand{ProjectKey: project, Limit: server.MaxLimit}
+	for {
+		resp, err := client.GetRepos(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, resp.Repos...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// printRequest prints the request of a command for -dry-run.
+func printRequest(d *server.RequestDescription, err error) error {
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(stdout, d)
+	return nil
+}
+
+func cmdGetProjects(opts *options) error {
+	if opts.DryRun {
+		return printRequest(getClient(opts).Describe(context.Background(),
+			&server.GetProjectsCommand{Limit: server.MaxLimit}))
+	}
+	projects, err := listProjects(context.Background(), getClient(opts))
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		fmt.Fprintf(stdout, "key %s, name %s\n", p.Key, p.Name)
+	}
+	return nil
+}
+
+func cmdGetRepos(opts *options) error {
+	if opts.DryRun {
+		return printRequest(getClient(opts).Describe(context.Background(),
+			&server.GetReposCommand{ProjectKey: opts.ProjectKey, Limit: server.MaxLimit}))
+	}
+	repos, err := listRepos(context.Background(), getClient(opts), opts.ProjectKey)
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		fmt.Fprintf(stdout, "slug %s, name %s\n", r.Slug, r.Name)
+	}
+	return nil
+}
+
+func cmdHelp(opts *options) error {
+	fs, _ := newFlagSet()
+	writeUsage(stdout, fs)
+	return nil
+}
+
+func cmdVersion(opts *options) error {
+	fmt.Fprintln(stdout, bbfs.BuildVersion())
+	return nil
+}
+
+// command is a command of bbclient.
+type command struct {
+	Name    string
+	Summary string
+	Run     func(opts *options) error
+	// Hidden commands are not shown in the usage and completions.
+	Hidden bool
+	// DryRun commands support -dry-run.
+	DryRun bool
+}
+
+// commands returns all commands.
+func commands() []*command {
+	return []*command{
+		{Name: "tags", Summary: "List the tags of the repository", Run: cmdGetTags, DryRun: true},
+		{Name: "projects", Summary: "List the projects", Run: cmdGetProjects, DryRun: true},
+		{Name: "repos", Summary: "List the repositories of the project", Run: cmdGetRepos, DryRun: true},
+		{Name: "diff", Summary: "Print the diff between -to and -from", Run: cmdDiff},
+		{Name: "is-ancestor", Summary: "Check that -from is an ancestor of -to", Run: cmdIsAncestor},
+		{Name: "merge-base", Summary: "Print the best common ancestor of -from and -to", Run: cmdMergeBase},
+		{Name: "watch", Summary: "Print or run the arguments when the -at ref changes", Run: cmdWatch},
+		{Name: "proxy", Summary: "Serve the -at ref to proxy clients on -listen", Run: cmdProxy},
+		{Name: "serve", Summary: "Serve -file-path at the -at ref over http on -listen", Run: cmdServe},
+		{Name: "mirror", Summary: "Sync -file-path at the -at ref to the -bucket", Run: cmdMirror, DryRun: true},
+		{Name: "build-status", Summary: "Set the -state of a build on -commit-id or the commit of the -at ref", Run: cmdBuildStatus, DryRun: true},
+		{Name: "checksums", Summary: "Print the SHA256SUMS of the files in -file-path at the -at ref", Run: cmdChecksums},
+		{Name: "verify", Summary: "Check the connection, access key, repository, -at ref and read access", Run: cmdVerify},
+		{Name: "completion", Summary: "Print the completion script for bash, zsh or fish", Run: cmdCompletion},
+		{Name: "version", Summary: "Print the version of bbclient", Run: cmdVersion},
+		{Name: "help", Summary: "Print this help", Run: cmdHelp},
+		{Name: "__complete", Summary: "Print the completions for a flag", Run: cmdComplete, Hidden: true},
+	}
+}
+
+// visibleCommands returns the commands that are not hidden.
+func visibleCommands() []*command {
+	var res []*command
+	for _, c := range commands() {
+		if !c.Hidden {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// writeUsage writes the usage with the commands and flags.
+func writeUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: bbclient <command> [flags]\n\nCommands:\n")
+	for _, c := range visibleCommands() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(w, "\nFlags:\n")
+	out := fs.Output()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fs.SetOutput(out)
+	fmt.Fprintf(w, "\nFlags can also be set with the environment variables BBFS_CLIENT_<FLAG>,\ne.g. BBFS_CLIENT_PROJECT_KEY for -project-key.\n")
+}
+
+// parseOptions returns the options from the environment and the arguments.
+// The options are returned on error too, for reporting the error.
+func parseOptions(args []string, getenv func(string) string) (*options, error) {
 	opts := defaultOptions()
 	setFromEnv(opts, getenv)
 	if err := setFromArgs(opts, args); err != nil {
-		return err
+		return opts, err
+	}
+	if opts.Config != "" {
+		fromFile, err := loadOptionsFile(opts.Config)
+		if err != nil {
+			return opts, err
+		}
+		// The file is below the environment and the flags.
+		opts = defaultOptions()
+		setFromEnv(opts, fromFile)
+		setFromEnv(opts, getenv)
+		setFromArgs(opts, args)
+	}
+	opts.reparse = func() (*options, error) {
+		return parseOptions(args, getenv)
+	}
+	if opts.ErrorFormat != errorFormatText && opts.ErrorFormat != errorFormatJSON {
+		format := opts.ErrorFormat
+		opts.ErrorFormat = errorFormatText
+		return opts, usageErrorf("bad -error-format: %q, allowed are text and json", format)
 	}
-
 	if opts.Command == "" {
-		return fmt.Errorf("no command specified")
+		return opts, usageErrorf("no command specified")
+	}
+	if err := opts.OrderBy.Validate(); err != nil {
+		return opts, &usageError{err: fmt.Errorf("bad -order-by: %w", err)}
 	}
+	if opts.AccessKeySecret != "" {
+		if opts.AccessKey != "" {
+			return opts, usageErrorf("-access-key and -access-key-secret are both set")
+		}
+		if _, err := secrets.Parse(opts.AccessKeySecret); err != nil {
+			return opts, &usageError{err: fmt.Errorf("bad -access-key-secret: %w", err)}
+		}
+	}
+	return opts, nil
+}
 
-	switch cmd := opts.Command; cmd {
-	case "tags":
-		return cmdGetTags(opts)
+// execute runs the command in the options.
+func execute(opts *options) error {
+	cmd := findCommand(opts.Command)
+	if cmd == nil {
+		return usageErrorf("bad command: %s", opts.Command)
 	}
+	if opts.DryRun && !cmd.DryRun {
+		return usageErrorf("%s does not support -dry-run", opts.Command)
+	}
+	return cmd.Run(opts)
+}
 
-	return fmt.Errorf("bad command: %s", opts.Command)
+func run(args []string, getenv func(string) string) error {
+	opts, err := parseOptions(args, getenv)
+	if err != nil {
+		return err
+	}
+	return execute(opts)
 }
 
 func main() {
-
-	if err := run(os.Args, os.Getenv); err != nil {
-		log.Fatalf("run failed: %s", err.Error())
+	opts, err := parseOptions(os.Args, os.Getenv)
+	if err == nil {
+		err = execute(opts)
 	}
-
+	os.Exit(reportError(os.Stderr, err, opts.ErrorFormat))
 }
@@ -1,6 +1,14 @@
 package main
 
-import "testing"
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
 
 func TestTags(t *testing.T) {
 	args := []string{"bbclient", "-project-key", "~zandp06"}
@@ -11,3 +19,128 @@ func TestTags(t *testing.T) {
 		t.Fatalf("error: %s", err.Error())
 	}
 }
+
+func TestSubcommandArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		command string
+		rest    []string
+	}{
+		{[]string{"bbclient", "tags", "-project-key", "p"}, "tags", nil},
+		{[]string{"bbclient", "-project-key", "p", "tags"}, "tags", []string{}},
+		{[]string{"bbclient", "-command", "tags", "-project-key", "p"}, "tags", []string{}},
+		{[]string{"bbclient", "completion", "bash"}, "completion", []string{"bash"}},
+	}
+	for _, tt := range tests {
+		opts := defaultOptions()
+		if err := setFromArgs(opts, tt.args); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if opts.Command != tt.command {
+			t.Errorf("%v: expected command %q, got %q", tt.args, tt.command, opts.Command)
+		}
+		if len(opts.Args) != len(tt.rest) {
+			t.Errorf("%v: expected args %v, got %v", tt.args, tt.rest, opts.Args)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var out strings.Builder
+		stdout = &out
+		err := run([]string{"bbclient", "completion", shell}, func(string) string { return "" })
+		stdout = os.Stdout
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		for _, want := range []string{"__complete", "-project-key", "repos", "ALPHABETICAL"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: completion does not contain %q", shell, want)
+			}
+		}
+		if strings.Contains(out.String(), "__complete:") {
+			t.Errorf("%s: completion contains hidden command", shell)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "one", fakeserver.NewRepo(fstest.MapFS{}))
+	srv.AddRepo("PRJ", "two", fakeserver.NewRepo(fstest.MapFS{}))
+	srv.AddRepo("OTHER", "three", fakeserver.NewRepo(fstest.MapFS{}))
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"bbclient", "__complete", "project-key"}, "OTHER\nPRJ\n"},
+		{[]string{"bbclient", "__complete", "-project-key", "PRJ", "repo-slug"}, "one\ntwo\n"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		stdout = &out
+		err := run(tt.args, func(key string) string {
+			if key == "BBFS_CLIENT_BASE_URL" {
+				return srv.BaseURL()
+			}
+			return ""
+		})
+		stdout = os.Stdout
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if out.String() != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.want, out.String())
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	getenv := func(key string) string {
+		if key == "BBFS_CLIENT_BASE_URL" {
+			return srv.BaseURL()
+		}
+		return ""
+	}
+
+	var out strings.Builder
+	stdout = &out
+	err := run([]string{"bbclient", "tags", "-project-key", "PRJ", "-repo-slug", "r", "-access-key", "secret", "-dry-run"}, getenv)
+	stdout = os.Stdout
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := "GET " + srv.BaseURL() + "/projects/PRJ/repos/r/tags?orderBy=MODIFICATION\n"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+	if strings.Contains(out.String(), "secret") {
+		t.Errorf("access key not redacted: %q", out.String())
+	}
+	if n := srv.Requests(); n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+
+	err = run([]string{"bbclient", "diff", "-dry-run"}, getenv)
+	if !strings.Contains(fmt.Sprint(err), "does not support -dry-run") {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	var out strings.Builder
+	stdout = &out
+	err := run([]string{"bbclient", "version"}, func(string) string { return "" })
+	stdout = os.Stdout
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if out.String() != "devel\n" {
+		t.Errorf("expected devel, got %q", out.String())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/mirror"
+)
+
+// cmdMirror syncs -file-path at the -at ref to the -bucket.
+func cmdMirror(opts *options) error {
+	bucket, prefix, err := newBucket(opts)
+	if err != nil {
+		return err
+	}
+	fsys, err := newFS(opts)
+	if err != nil {
+		return err
+	}
+	ignore, err := mirrorIgnore(opts, fsys)
+	if err != nil {
+		return err
+	}
+	mopts := []mirror.Option{mirror.WithPrefix(prefix), mirror.WithIgnore(ignore)}
+	if opts.Delete {
+		mopts = append(mopts, mirror.WithDelete())
+	}
+	if opts.DryRun {
+		mopts = append(mopts, mirror.WithDryRun())
+	}
+	res, err := mirror.Sync(context.Background(), fsys, opts.FilePath, bucket, mopts...)
+	if err != nil {
+		return err
+	}
+	for _, name := range res.Uploaded {
+		fmt.Fprintf(stdout, "upload %s\n", name)
+	}
+	for _, name := range res.Deleted {
+		fmt.Fprintf(stdout, "delete %s\n", name)
+	}
+	fmt.Fprintf(stdout, "%d uploaded, %d deleted, %d unchanged\n", len(res.Uploaded), len(res.Deleted), res.Unchanged)
+	return nil
+}
+
+// mirrorIgnore returns the patterns of the .bbfsignore in -file-path,
+// followed by those of the local -ignore-file.
+func mirrorIgnore(opts *options, fsys fs.FS) (*bbfs.Ignore, error) {
+	ignore, err := bbfs.ReadIgnore(fsys, path.Join(cmp.Or(opts.FilePath, "."), bbfs.IgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+	if opts.IgnoreFile == "" {
+		return ignore, nil
+	}
+	local, err := bbfs.LoadIgnore(opts.IgnoreFile)
+	if err != nil {
+		return nil, err
+	}
+	return ignore.Append(local), nil
+}
+
+// newBucket returns the bucket and the prefix of -bucket: s3://bucket/prefix,
+// gs://bucket/prefix or a local directory. -endpoint and -region override
+// those of the service, AWS_REGION is the default region.
+func newBucket(opts *options) (mirror.Bucket, string, error) {
+	if opts.Bucket == "" {
+		return nil, "", usageErrorf("mirror needs -bucket")
+	}
+	u, err := url.Parse(opts.Bucket)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		dir := strings.TrimPrefix(opts.Bucket, "file://")
+		return mirror.DirBucket(dir), "", nil
+	}
+	var b *mirror.S3
+	switch u.Scheme {
+	case "s3":
+		region := opts.Region
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		if region == "" {
+			return nil, "", usageErrorf("an s3 -bucket needs -region or AWS_REGION")
+		}
+		b = mirror.NewS3(u.Host, region)
+	case "gs":
+		b = mirror.NewGCS(u.Host)
+	default:
+		return nil, "", usageErrorf("bad -bucket: %q, want s3://bucket/prefix, gs://bucket/prefix or a directory", opts.Bucket)
+	}
+	if opts.Endpoint != "" {
+		b.Endpoint = opts.Endpoint
+	}
+	return b, strings.Trim(u.Path, "/"), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestMirror(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "www", fakeserver.NewRepo(fstest.MapFS{
+		"site/index.html":    {Data: []byte("<h1>home</h1>")},
+		"site/css/a.css":     {Data: []byte("body {}")},
+		"site/.bbfsignore":   {Data: []byte(".bbfsignore\ndrafts/\n")},
+		"site/drafts/x.html": {Data: []byte("draft")},
+		"site/css/a.css.map": {Data: []byte("{}")},
+	}))
+
+	var out strings.Builder
+	stdout = &out
+	defer func() { stdout = nil }()
+	dir := t.TempDir()
+	ignoreFile := filepath.Join(t.TempDir(), "ignore")
+	if err := os.WriteFile(ignoreFile, []byte("*.map\n"), 0o644); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	args := []string{"bbclient", "mirror", "-base-url", srv.BaseURL(), "-project-key", "PRJ", "-repo-slug", "www", "-file-path", "site", "-bucket", dir, "-ignore-file", ignoreFile}
+	getenv := func(string) string { return "" }
+
+	if err := run(append(args, "-dry-run"), getenv); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if want := "upload css/a.css\nupload index.html\n2 uploaded, 0 deleted, 0 unchanged\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.html")); err == nil {
+		t.Errorf("the dry run wrote the bucket")
+	}
+
+	for _, want := range []string{"2 uploaded", "0 uploaded, 0 deleted, 2 unchanged"} {
+		out.Reset()
+		if err := run(args, getenv); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in %q", want, out.String())
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "index.html")); string(data) != "<h1>home</h1>" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	if err := run([]string{"bbclient", "mirror", "-project-key", "PRJ", "-repo-slug", "www", "-bucket", "ftp://x"}, getenv); err == nil {
+		t.Errorf("expected an error for a bad bucket")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/proxy"
+)
+
+// cmdProxy serves the repository at -at to proxy clients on -listen until interrupted.
+func cmdProxy(opts *options) error {
+	return runServer(opts, func(ctx context.Context, opts *options) (http.Handler, error) {
+		return proxyHandler(opts)
+	})
+}
+
+// proxyHandler returns the proxy service for the repository in the options.
+func proxyHandler(opts *options) (http.Handler, error) {
+	if opts.Listen == "" {
+		return nil, usageErrorf("proxy needs -listen")
+	}
+	protect, err := protector(opts, allowRule(opts))
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := newFS(opts)
+	if err != nil {
+		return nil, err
+	}
+	return protect(proxy.NewHandler(fsys)), nil
+}
+
+// requireRepo returns a usage error if the repository is not set.
+func requireRepo(opts *options) error {
+	if opts.ProjectKey == "" || opts.RepoSlug == "" {
+		return usageErrorf("%s needs -project-key and -repo-slug", opts.Command)
+	}
+	return nil
+}
+
+// newFS returns the file system for the repository at -at.
+func newFS(opts *options) (fs.FS, error) {
+	repo, err := newRepo(opts)
+	if err != nil {
+		return nil, err
+	}
+	return repo.FS(""), nil
+}
+
+// newRepo returns the repository with -at as default ref.
+func newRepo(opts *options) (*bbfs.Repo, error) {
+	if err := requireRepo(opts); err != nil {
+		return nil, err
+	}
+	return bbfs.NewRepo(&bbfs.Config{
+		BaseURL:         opts.BaseURL,
+		AccessKey:       opts.AccessKey.Secret(),
+		AccessKeySecret: opts.AccessKeySecret,
+		ProjectKey:      opts.ProjectKey,
+		RepositorySlug:  opts.RepoSlug,
+		At:              bbfs.Ref(opts.At),
+	}, bbfs.WithUserAgent(userAgent)), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+	"github.com/myhops/bbfs/proxy"
+)
+
+func TestProxy(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"README.md": {Data: []byte("# readme\n")},
+	}))
+	srv.SetAccessKey("secret")
+
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_BASE_URL":    srv.BaseURL(),
+			"BBFS_CLIENT_ACCESS_KEY":  "secret",
+			"BBFS_CLIENT_PROJECT_KEY": "PRJ",
+			"BBFS_CLIENT_REPO_SLUG":   "repo",
+		}[name]
+	}
+	opts, err := parseOptions([]string{"bbclient", "proxy", "-listen", "127.0.0.1:0"}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	h, err := proxyHandler(opts)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	ps := httptest.NewServer(h)
+	defer ps.Close()
+
+	data, err := fs.ReadFile(proxy.NewFS(ps.URL), "README.md")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "# readme\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	opts.Listen = ""
+	var ue *usageError
+	if _, err := proxyHandler(opts); !errors.As(err, &ue) {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
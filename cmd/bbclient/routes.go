@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/browse"
+	"github.com/myhops/bbfs/helm"
+	"github.com/myhops/bbfs/httpauth"
+	"github.com/myhops/bbfs/secrets"
+	"github.com/myhops/bbfs/site"
+)
+
+// route mounts a directory of a repository at a ref under a url prefix.
+// Empty fields take the value of the flag.
+type route struct {
+	Prefix         string `json:"prefix" yaml:"prefix"`
+	ProjectKey     string `json:"projectKey" yaml:"projectKey"`
+	RepositorySlug string `json:"repositorySlug" yaml:"repositorySlug"`
+	At             string `json:"at" yaml:"at"`
+	FilePath       string `json:"filePath" yaml:"filePath"`
+	Mode           string `json:"mode" yaml:"mode"`
+	// Allow are the users and groups, as group:name, that may access the
+	// route, see package httpauth. Empty takes -allow.
+	Allow []string `json:"allow" yaml:"allow"`
+}
+
+// routesFile is the file of serve -routes.
+type routesFile struct {
+	Routes []*route `json:"routes" yaml:"routes"`
+}
+
+// loadRoutes reads the routes from a JSON or YAML file.
+func loadRoutes(path string) ([]*route, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rf routesFile
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&rf)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&rf)
+	default:
+		return nil, fmt.Errorf("routes %s: unsupported format %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("routes %s: %w", path, err)
+	}
+	if len(rf.Routes) == 0 {
+		return nil, fmt.Errorf("routes %s: no routes", path)
+	}
+	return rf.Routes, nil
+}
+
+// routesHandler serves the routes in the -routes file. The repositories
+// share a client, and so its cache, through a client pool.
+func routesHandler(opts *options) (http.Handler, error) {
+	routes, err := loadRoutes(opts.Routes)
+	if err != nil {
+		return nil, err
+	}
+	pool := server.NewClientPool()
+	poolOpts := []bbfs.Option{bbfs.WithClientPool(pool), bbfs.WithUserAgent(userAgent)}
+	// parseOptions checked the reference.
+	if p, err := secrets.Parse(opts.AccessKeySecret); err == nil {
+		poolOpts = append(poolOpts, bbfs.WithTokenSource(secrets.TokenSource(p, 0), nil))
+	}
+
+	mux := http.NewServeMux()
+	var rules []httpauth.Rule
+	seen := map[string]bool{}
+	for _, r := range routes {
+		prefix := "/" + strings.Trim(r.Prefix, "/")
+		if seen[prefix] {
+			return nil, fmt.Errorf("routes %s: duplicate prefix %s", opts.Routes, prefix)
+		}
+		seen[prefix] = true
+		allow := r.Allow
+		if len(allow) == 0 {
+			allow = allowList(opts)
+		}
+		rules = append(rules, httpauth.Rule{Prefix: strings.TrimSuffix(prefix, "/") + "/", Allow: allow})
+
+		cfg := &bbfs.Config{
+			BaseURL:         opts.BaseURL,
+			AccessKey:       opts.AccessKey.Secret(),
+			AccessKeySecret: opts.AccessKeySecret,
+			ProjectKey:      cmp.Or(r.ProjectKey, opts.ProjectKey),
+			RepositorySlug:  cmp.Or(r.RepositorySlug, opts.RepoSlug),
+			At:              bbfs.Ref(cmp.Or(r.At, opts.At)),
+		}
+		if cfg.ProjectKey == "" || cfg.RepositorySlug == "" {
+			return nil, fmt.Errorf("routes %s: %s needs projectKey and repositorySlug", opts.Routes, prefix)
+		}
+		repo := bbfs.NewRepo(cfg, poolOpts...)
+		fsys := repo.FS("")
+		dir := cmp.Or(r.FilePath, opts.FilePath, ".")
+
+		var h http.Handler
+		switch r.Mode {
+		case "", serveModeFiles:
+			sub, err := fs.Sub(fsys, dir)
+			if err != nil {
+				return nil, err
+			}
+			h = site.NewHandler(sub, siteOptions(opts)...)
+		case serveModeHelm:
+			h = helm.NewHandler(fsys, dir)
+		case serveModeBrowse:
+			h = browse.NewHandler(repo, browseOptions(cfg.ProjectKey, cfg.RepositorySlug, dir)...)
+		default:
+			return nil, fmt.Errorf("routes %s: %s: bad mode %q, allowed are %s, %s and %s", opts.Routes, prefix, r.Mode, serveModeFiles, serveModeHelm, serveModeBrowse)
+		}
+		if prefix == "/" {
+			mux.Handle("/", h)
+			continue
+		}
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, h))
+	}
+	protect, err := protector(opts, rules)
+	if err != nil {
+		return nil, err
+	}
+	return protect(mux), nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// Paths of the probes of serve and proxy.
+const (
+	healthPath = "/healthz"
+	readyPath  = "/readyz"
+)
+
+// defaultShutdownTimeout is the time that Run waits for requests in progress.
+const defaultShutdownTimeout = 5 * time.Second
+
+// readyInterval is how long the result of a readiness check is reused,
+// so frequent probes do not load the server.
+const readyInterval = 5 * time.Second
+
+// httpServer runs the handler of serve or proxy with the probes.
+//
+// /healthz reports that the process runs, /readyz that the repositories
+// can be read and that the server is not shutting down. The probes do not
+// need authentication.
+type httpServer struct {
+	addr    string
+	handler http.Handler
+	// ready checks that the server can serve, nil is always ready.
+	ready           func(ctx context.Context) error
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	shutdownTimeout time.Duration
+
+	stopping atomic.Bool
+	mu       sync.Mutex
+	checked  time.Time
+	readyErr error
+}
+
+// newHTTPServer returns the server for h with the timeouts of the options.
+func newHTTPServer(opts *options, h http.Handler, ready func(ctx context.Context) error) *httpServer {
+	return &httpServer{
+		addr:            opts.Listen,
+		handler:         h,
+		ready:           ready,
+		readTimeout:     opts.ReadTimeout,
+		writeTimeout:    opts.WriteTimeout,
+		shutdownTimeout: opts.ShutdownTimeout,
+	}
+}
+
+// Run serves until the context is done, then stops accepting connections
+// and waits up to the shutdown timeout for the requests in progress.
+// Long lived requests, like the event streams, end when the shutdown starts.
+func (s *httpServer) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "listening on %s\n", ln.Addr())
+	return s.serve(ctx, ln)
+}
+
+func (s *httpServer) serve(ctx context.Context, ln net.Listener) error {
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &http.Server{
+		Handler:           s.mux(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       s.readTimeout,
+		WriteTimeout:      s.writeTimeout,
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+	srv.RegisterOnShutdown(cancel)
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case <-ctx.Done():
+		s.stopping.Store(true)
+		shutdownCtx, stop := context.WithTimeout(context.Background(), cmp.Or(s.shutdownTimeout, defaultShutdownTimeout))
+		defer stop()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+			return fmt.Errorf("shutdown: %w", err)
+		}
+		return nil
+	case err := <-errc:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+func (s *httpServer) mux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc(readyPath, func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkReady(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/", s.handler)
+	return mux
+}
+
+// checkReady returns nil when the server is ready.
+func (s *httpServer) checkReady(ctx context.Context) error {
+	if s.stopping.Load() {
+		return errors.New("shutting down")
+	}
+	if s.ready == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checked.IsZero() && time.Since(s.checked) < readyInterval {
+		return s.readyErr
+	}
+	s.readyErr = s.ready(ctx)
+	s.checked = time.Now()
+	return s.readyErr
+}
+
+// readiness returns the check that the repositories of the command, those of
+// -routes or -project-key and -repo-slug at -at, can be read.
+func readiness(opts *options) (func(ctx context.Context) error, error) {
+	type target struct {
+		project, repo string
+		ref           server.Ref
+	}
+	var targets []target
+	if opts.Routes != "" {
+		routes, err := loadRoutes(opts.Routes)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range routes {
+			targets = append(targets, target{
+				cmp.Or(r.ProjectKey, opts.ProjectKey),
+				cmp.Or(r.RepositorySlug, opts.RepoSlug),
+				server.Ref(cmp.Or(r.At, opts.At)),
+			})
+		}
+	} else {
+		targets = append(targets, target{opts.ProjectKey, opts.RepoSlug, server.Ref(opts.At)})
+	}
+	client := getClient(opts)
+	return func(ctx context.Context) error {
+		for _, t := range targets {
+			if _, err := client.CurrentCommit(ctx, t.project, t.repo, t.ref); err != nil {
+				return fmt.Errorf("%s/%s at %q: %w", t.project, t.repo, t.ref, err)
+			}
+		}
+		return nil
+	}, nil
+}
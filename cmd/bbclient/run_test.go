@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	started := make(chan struct{})
+	h := http.NewServeMux()
+	h.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "content")
+	})
+	// stream blocks like an event stream until the request is done.
+	h.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	})
+	readyErr := errors.New("repository not found")
+	s := &httpServer{
+		handler:         h,
+		ready:           func(ctx context.Context) error { return readyErr },
+		shutdownTimeout: 5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.serve(ctx, ln) }()
+
+	base := "http://" + ln.Addr().String()
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{healthPath, http.StatusOK, "ok"},
+		{readyPath, http.StatusServiceUnavailable, "repository not found"},
+		{"/file", http.StatusOK, "content"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(base + tt.path)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || !strings.Contains(string(b), tt.want) {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.status, tt.want, resp.StatusCode, b)
+		}
+	}
+
+	resp, err := http.Get(base + "/stream")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	<-started
+
+	start := time.Now()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("the stream held up the shutdown for %s", d)
+	}
+	if err := s.checkReady(context.Background()); err == nil || err.Error() != "shutting down" {
+		t.Errorf("expected not ready after the shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/browse"
+	"github.com/myhops/bbfs/goproxy"
+	"github.com/myhops/bbfs/helm"
+	"github.com/myhops/bbfs/site"
+)
+
+// Modes of serve.
+const (
+	serveModeFiles   = "files"
+	serveModeHelm    = "helm"
+	serveModeGoProxy = "goproxy"
+	serveModeBrowse  = "browse"
+)
+
+// cmdServe serves -file-path at the -at ref over http on -listen until interrupted.
+func cmdServe(opts *options) error {
+	return runServer(opts, serveHandler)
+}
+
+// serveHandler returns the handler for the -mode of serve. With -events it
+// watches the -at ref until the context is done.
+func serveHandler(ctx context.Context, opts *options) (http.Handler, error) {
+	if opts.Listen == "" {
+		return nil, usageErrorf("serve needs -listen")
+	}
+	if opts.Routes != "" {
+		if opts.Events {
+			return nil, usageErrorf("-events does not work with -routes")
+		}
+		return routesHandler(opts)
+	}
+	protect, err := protector(opts, allowRule(opts))
+	if err != nil {
+		return nil, err
+	}
+	h, client, err := modeHandler(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Events {
+		return withEvents(ctx, opts, client, h, protect)
+	}
+	return protect(h), nil
+}
+
+// modeHandler returns the handler for the -mode and the client it uses.
+func modeHandler(opts *options) (http.Handler, *server.Client, error) {
+	if opts.Mode == serveModeGoProxy {
+		return goProxyHandler(opts)
+	}
+	repo, err := newRepo(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	fsys := repo.FS("")
+	dir := opts.FilePath
+	if dir == "" {
+		dir = "."
+	}
+	switch opts.Mode {
+	case "", serveModeFiles:
+		sub, err := fs.Sub(fsys, dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		return site.NewHandler(sub, siteOptions(opts)...), repo.Client(), nil
+	case serveModeHelm:
+		return helm.NewHandler(fsys, dir), repo.Client(), nil
+	case serveModeBrowse:
+		return browse.NewHandler(repo, browseOptions(opts.ProjectKey, opts.RepoSlug, dir)...), repo.Client(), nil
+	}
+	return nil, nil, usageErrorf("bad -mode: %q, allowed are %s, %s, %s and %s", opts.Mode, serveModeFiles, serveModeHelm, serveModeGoProxy, serveModeBrowse)
+}
+
+// siteOptions returns the options of the site for -dir-index, -not-found
+// and -max-age. Content at a commit never changes, it is cached for a year.
+func siteOptions(opts *options) []site.HandlerOption {
+	var res []site.HandlerOption
+	if opts.DirIndex {
+		res = append(res, site.WithDirectoryIndex())
+	}
+	if opts.NotFound != "" {
+		res = append(res, site.WithNotFoundPage(opts.NotFound))
+	}
+	if opts.MaxAge > 0 {
+		res = append(res, site.WithCacheControl(
+			fmt.Sprintf("public, max-age=%d", int(opts.MaxAge.Seconds())),
+			"public, max-age=31536000, immutable",
+		))
+	}
+	return res
+}
+
+// browseOptions returns the options of the UI for the directory of the repository.
+func browseOptions(project, repo, dir string) []browse.HandlerOption {
+	return []browse.HandlerOption{
+		browse.WithRoot(dir),
+		browse.WithTitle(project + "/" + repo),
+	}
+}
+
+// goProxyHandler serves the -module in -file-path with the GOPROXY protocol.
+func goProxyHandler(opts *options) (http.Handler, *server.Client, error) {
+	if err := requireRepo(opts); err != nil {
+		return nil, nil, err
+	}
+	if opts.Module == "" {
+		return nil, nil, usageErrorf("-mode goproxy needs -module")
+	}
+	client := getClient(opts)
+	return goproxy.NewHandler(client, opts.ProjectKey, opts.RepoSlug, []goproxy.Module{
+		{Path: opts.Module, Dir: opts.FilePath},
+	}), client, nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestServe(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"site/index.html":        {Data: []byte("<h1>home</h1>\n")},
+		"charts/app/Chart.yaml":  {Data: []byte("apiVersion: v2\nname: app\nversion: 1.2.3\n")},
+		"charts/app/values.yaml": {Data: []byte("replicas: 1\n")},
+		"lib/go.mod":             {Data: []byte("module example.com/lib\n")},
+	})
+	repo.Tag("lib/v1.0.0", repo.Resolve("main"))
+	srv.AddRepo("PRJ", "repo", repo)
+	srv.SetAccessKey("secret")
+
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_BASE_URL":    srv.BaseURL(),
+			"BBFS_CLIENT_ACCESS_KEY":  "secret",
+			"BBFS_CLIENT_PROJECT_KEY": "PRJ",
+			"BBFS_CLIENT_REPO_SLUG":   "repo",
+			"BBFS_CLIENT_LISTEN":      "127.0.0.1:0",
+		}[name]
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		path string
+		want string
+	}{
+		{"files", []string{"-file-path", "site"}, "/", "<h1>home</h1>"},
+		{"dir-index", []string{"-dir-index"}, "/", `<a href="site/">site/</a>`},
+		{"helm", []string{"-mode", "helm", "-file-path", "charts"}, "/index.yaml", "app-1.2.3.tgz"},
+		{"goproxy", []string{"-mode", "goproxy", "-module", "example.com/lib", "-file-path", "lib"}, "/example.com/lib/@v/list", "v1.0.0"},
+		{"browse", []string{"-mode", "browse", "-file-path", "site"}, "/index.html", "&lt;h1&gt;home&lt;/h1&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseOptions(append([]string{"bbclient", "serve"}, tt.args...), getenv)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			h, err := serveHandler(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			hs := httptest.NewServer(h)
+			defer hs.Close()
+
+			resp, err := http.Get(hs.URL + tt.path)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || !strings.Contains(string(b), tt.want) {
+				t.Errorf("expected %q, got %d %q", tt.want, resp.StatusCode, b)
+			}
+		})
+	}
+
+	opts, err := parseOptions([]string{"bbclient", "serve", "-mode", "webdav"}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var ue *usageError
+	if _, err := serveHandler(context.Background(), opts); !errors.As(err, &ue) {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
+
+func TestServeEvents(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{"index.html": {Data: []byte("v1")}})
+	srv.AddRepo("PRJ", "repo", repo)
+
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_BASE_URL":    srv.BaseURL(),
+			"BBFS_CLIENT_PROJECT_KEY": "PRJ",
+			"BBFS_CLIENT_REPO_SLUG":   "repo",
+			"BBFS_CLIENT_LISTEN":      "127.0.0.1:0",
+		}[name]
+	}
+	opts, err := parseOptions([]string{"bbclient", "serve", "-events", "-at", "main", "-interval", "10ms"}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h, err := serveHandler(ctx, opts)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	hs := httptest.NewServer(h)
+	defer hs.Close()
+
+	get := func(path string) string {
+		resp, err := http.Get(hs.URL + path)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	if got := get("/index.html"); got != "v1" {
+		t.Fatalf("expected v1, got %q", got)
+	}
+
+	resp, err := http.Get(hs.URL + "/events")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %s", ct)
+	}
+
+	var c *fakeserver.Commit
+	srv.Update(func() {
+		c = repo.Commit("main", "update", fstest.MapFS{"index.html": {Data: []byte("v2")}})
+	})
+
+	sc := bufio.NewScanner(resp.Body)
+	var data string
+	for sc.Scan() {
+		if d, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			data = d
+			break
+		}
+	}
+	if !strings.Contains(data, `"ref":"main"`) || !strings.Contains(data, `"to":"`+c.ID+`"`) {
+		t.Errorf("unexpected event %q", data)
+	}
+	if got := get("/index.html"); got != "v2" {
+		t.Errorf("expected v2 after the event, got %q", got)
+	}
+}
+
+func TestServeRoutes(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "docs", fakeserver.NewRepo(fstest.MapFS{
+		"site/index.html": {Data: []byte("<h1>docs</h1>")},
+	}))
+	srv.AddRepo("PRJ", "charts", fakeserver.NewRepo(fstest.MapFS{
+		"app/Chart.yaml": {Data: []byte("apiVersion: v2\nname: app\nversion: 1.2.3\n")},
+	}))
+	srv.AddRepo("OTHER", "home", fakeserver.NewRepo(fstest.MapFS{
+		"index.html": {Data: []byte("<h1>home</h1>")},
+	}))
+
+	dir := t.TempDir()
+	routes := filepath.Join(dir, "routes.yaml")
+	os.WriteFile(routes, []byte(`routes:
+- prefix: /docs
+  repositorySlug: docs
+  filePath: site
+- prefix: /charts/
+  repositorySlug: charts
+  mode: helm
+- prefix: /
+  projectKey: OTHER
+  repositorySlug: home
+`), 0o644)
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte(`{"routes": [{"prefix": "/", "repo": "x"}]}`), 0o644)
+
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_BASE_URL":    srv.BaseURL(),
+			"BBFS_CLIENT_PROJECT_KEY": "PRJ",
+			"BBFS_CLIENT_LISTEN":      "127.0.0.1:0",
+		}[name]
+	}
+	opts, err := parseOptions([]string{"bbclient", "serve", "-routes", routes}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	h, err := serveHandler(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	hs := httptest.NewServer(h)
+	defer hs.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/docs/", "<h1>docs</h1>"},
+		{"/docs", "<h1>docs</h1>"},
+		{"/charts/index.yaml", "app-1.2.3.tgz"},
+		{"/", "<h1>home</h1>"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(hs.URL + tt.path)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(b), tt.want) {
+			t.Errorf("%s: expected %q, got %d %q", tt.path, tt.want, resp.StatusCode, b)
+		}
+	}
+
+	opts.Routes = bad
+	if _, err := serveHandler(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+}
+
+func TestServeAuth(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"index.html": {Data: []byte("home")}}))
+
+	users := filepath.Join(t.TempDir(), "users.txt")
+	os.WriteFile(users, []byte("alice:a\nbob:b\n"), 0o600)
+	getenv := func(name string) string {
+		return map[string]string{
+			"BBFS_CLIENT_BASE_URL":    srv.BaseURL(),
+			"BBFS_CLIENT_PROJECT_KEY": "PRJ",
+			"BBFS_CLIENT_REPO_SLUG":   "repo",
+			"BBFS_CLIENT_LISTEN":      "127.0.0.1:0",
+		}[name]
+	}
+	opts, err := parseOptions([]string{"bbclient", "serve", "-basic-auth-file", users, "-allow", "alice"}, getenv)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	h, err := serveHandler(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	tests := []struct {
+		user   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"bob", http.StatusForbidden},
+		{"alice", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.user[:1])
+		}
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%q: expected %d, got %d", tt.user, tt.status, rec.Code)
+		}
+	}
+
+	opts.BasicAuthFile = ""
+	var ue *usageError
+	if _, err := serveHandler(context.Background(), opts); !errors.As(err, &ue) {
+		t.Errorf("expected a usage error for -allow without authentication, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// verifyStep is a check of verify.
+type verifyStep struct {
+	Name string
+	// Check returns a description of the result or an error.
+	Check func(ctx context.Context) (string, error)
+	// Hint helps to fix the cause of the error.
+	Hint func(err error) string
+}
+
+// cmdVerify checks the configuration step by step and prints the result of
+// each step. The steps after a failure are skipped.
+func cmdVerify(opts *options) error {
+	ctx := context.Background()
+	client := getClient(opts)
+	// The application properties need no access key, so the connectivity
+	// check does not depend on the key or its secret store.
+	anonymous := getClient(&options{BaseURL: opts.BaseURL})
+	var commitID string
+
+	steps := []verifyStep{
+		{
+			Name: "connectivity",
+			Check: func(ctx context.Context) (string, error) {
+				props, err := anonymous.GetApplicationProperties(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s at %s", props.DisplayName, props.Version, opts.BaseURL), nil
+			},
+			Hint: func(err error) string {
+				var ne net.Error
+				if errors.As(err, &ne) {
+					return "check the host in -base-url, the network and the proxy settings"
+				}
+				return "check that -base-url points to the REST api, e.g. https://host/rest/api/latest"
+			},
+		},
+		{
+			Name: "authentication",
+			Check: func(ctx context.Context) (string, error) {
+				if opts.AccessKey == "" && opts.AccessKeySecret == "" {
+					return "no -access-key or -access-key-secret, continuing anonymously", nil
+				}
+				if _, err := client.GetProjects(ctx, &server.GetProjectsCommand{Limit: 1}); err != nil {
+					return "", err
+				}
+				if opts.AccessKeySecret != "" {
+					return "access key of -access-key-secret accepted", nil
+				}
+				return "access key accepted", nil
+			},
+			Hint: func(err error) string {
+				if errors.Is(err, server.ErrNoToken) {
+					return "the access key could not be read, check -access-key-secret and the access to the secret store"
+				}
+				return "the access key is invalid or expired, create a new HTTP access token"
+			},
+		},
+		{
+			Name: "repository",
+			Check: func(ctx context.Context) (string, error) {
+				repo, err := client.GetRepo(ctx, &server.GetRepoCommand{
+					ProjectKey: opts.ProjectKey,
+					RepoSlug:   opts.RepoSlug,
+				})
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s/%s exists", repo.ProjectKey, repo.Slug), nil
+			},
+			Hint: func(err error) string {
+				if server.IsNotFound(err) {
+					return "the repository does not exist or the access key has no access to it, check -project-key and -repo-slug"
+				}
+				return "check -project-key and -repo-slug"
+			},
+		},
+		{
+			Name: "ref",
+			Check: func(ctx context.Context) (string, error) {
+				if opts.At == "" {
+					return "no -at, using the default branch", nil
+				}
+				id, err := bbfs.ResolveRef(ctx, client, opts.ProjectKey, opts.RepoSlug, bbfs.Ref(opts.At))
+				if err != nil {
+					return "", err
+				}
+				commitID = id
+				return fmt.Sprintf("%s is commit %s", opts.At, id), nil
+			},
+			Hint: func(err error) string {
+				return "the branch, tag or commit in -at does not exist"
+			},
+		},
+		{
+			Name: "read",
+			Check: func(ctx context.Context) (string, error) {
+				resp, err := client.GetFiles(ctx, &server.GetFilesCommand{
+					ProjectKey: opts.ProjectKey,
+					RepoSlug:   opts.RepoSlug,
+					FilePath:   opts.FilePath,
+					At:         server.CommitRef(commitID),
+					Limit:      1,
+				})
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("listed %q, %d entries", opts.FilePath, resp.Size), nil
+			},
+			Hint: func(err error) string {
+				if server.IsNotFound(err) {
+					return "the path in -file-path does not exist at the ref"
+				}
+				return "the access key needs the repository read permission"
+			},
+		},
+		{
+			Name: "features",
+			Check: func(ctx context.Context) (string, error) {
+				features, err := client.ProbeFeatures(ctx, opts.ProjectKey, opts.RepoSlug)
+				if err != nil {
+					return "", err
+				}
+				var missing []string
+				for _, f := range slices.Sorted(maps.Keys(features)) {
+					if !features[f] {
+						missing = append(missing, string(f))
+					}
+				}
+				if len(missing) == 0 {
+					return "all features available", nil
+				}
+				return fmt.Sprintf("using fallbacks for %s", strings.Join(missing, ", ")), nil
+			},
+			Hint: func(err error) string {
+				return "the probes need read access to the default branch"
+			},
+		},
+	}
+
+	var failed error
+	for _, step := range steps {
+		if failed != nil {
+			fmt.Fprintf(stdout, "SKIP %s\n", step.Name)
+			continue
+		}
+		msg, err := step.Check(ctx)
+		if err != nil {
+			fmt.Fprintf(stdout, "FAIL %s: %s\n     %s\n", step.Name, err.Error(), step.Hint(err))
+			failed = fmt.Errorf("verify %s: %w", step.Name, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "ok   %s: %s\n", step.Name, msg)
+	}
+	return failed
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestVerify(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.SetAccessKey("secret")
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(keyFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want []string
+	}{
+		{
+			name: "ok",
+			args: []string{"-access-key", "secret", "-repo-slug", "repo", "-at", "main"},
+			code: exitOK,
+			want: []string{"ok   connectivity: Bitbucket " + fakeserver.Version, "ok   authentication", "ok   repository", "ok   ref", "ok   read", "ok   features: all features available"},
+		},
+		{
+			name: "bad key",
+			args: []string{"-access-key", "wrong", "-repo-slug", "repo"},
+			code: exitAuth,
+			want: []string{"ok   connectivity", "FAIL authentication", "SKIP repository", "SKIP read"},
+		},
+		{
+			name: "secret",
+			args: []string{"-access-key-secret", "file:" + keyFile, "-repo-slug", "repo"},
+			code: exitOK,
+			want: []string{"ok   authentication: access key of -access-key-secret accepted", "ok   read"},
+		},
+		{
+			name: "missing secret",
+			args: []string{"-access-key-secret", "file:" + filepath.Join(dir, "missing"), "-repo-slug", "repo"},
+			code: exitAuth,
+			want: []string{"ok   connectivity", "FAIL authentication", "check -access-key-secret", "SKIP repository"},
+		},
+		{
+			name: "no repo",
+			args: []string{"-access-key", "secret", "-repo-slug", "nope"},
+			code: exitNotFound,
+			want: []string{"ok   authentication", "FAIL repository", "does not exist", "SKIP ref"},
+		},
+		{
+			name: "no ref",
+			args: []string{"-access-key", "secret", "-repo-slug", "repo", "-at", "nope"},
+			code: exitNotFound,
+			want: []string{"ok   repository", "FAIL ref", "SKIP read"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			stdout = &out
+			defer func() { stdout = nil }()
+			args := append([]string{"bbclient", "verify", "-base-url", srv.BaseURL(), "-project-key", "PRJ"}, tt.args...)
+			err := run(args, func(string) string { return "" })
+			if _, code := errorKind(err); code != tt.code {
+				t.Errorf("expected exit code %d, got %d for %v", tt.code, code, err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("output does not contain %q:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/myhops/bbfs"
+)
+
+// cmdWatch reports changes of the -at ref until interrupted.
+func cmdWatch(opts *options) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return watch(ctx, opts)
+}
+
+// watch polls the ref, or listens for webhook calls with -listen, and prints
+// each change or runs the command in the arguments for it.
+func watch(ctx context.Context, opts *options) error {
+	if opts.At == "" {
+		return usageErrorf("watch needs -at")
+	}
+	interval, err := watchInterval(opts)
+	if err != nil {
+		return err
+	}
+
+	handle := func(ev bbfs.RefEvent) error {
+		onRefEvent(ctx, opts, ev)
+		return nil
+	}
+	if opts.Listen != "" {
+		err = listenWebhook(ctx, opts, handle)
+	} else {
+		w := &bbfs.Watcher{
+			Client:     getClient(opts),
+			ProjectKey: opts.ProjectKey,
+			RepoSlug:   opts.RepoSlug,
+			Ref:        bbfs.Ref(opts.At),
+			Interval:   interval,
+			OnError: func(err error) {
+				fmt.Fprintf(os.Stderr, "poll failed: %s\n", err.Error())
+			},
+		}
+		err = w.Watch(ctx, handle)
+	}
+	// Stopping the watch is not an error.
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return nil
+	}
+	return err
+}
+
+// watchInterval returns the -interval, DefaultWatchInterval when not set.
+func watchInterval(opts *options) (time.Duration, error) {
+	if opts.Interval == "" {
+		return bbfs.DefaultWatchInterval, nil
+	}
+	d, err := time.ParseDuration(opts.Interval)
+	if err != nil {
+		return 0, usageErrorf("bad -interval: %w", err)
+	}
+	return d, nil
+}
+
+// onRefEvent prints the event or runs the command in the arguments with
+// BBFS_REF, BBFS_FROM and BBFS_TO in the environment.
+// A failing command is reported and does not stop the watch.
+func onRefEvent(ctx context.Context, opts *options, ev bbfs.RefEvent) {
+	if len(opts.Args) == 0 {
+		fmt.Fprintf(stdout, "%s %s %s -> %s\n", ev.Time.Format(time.RFC3339), ev.Ref, ev.From, ev.To)
+		return
+	}
+	cmd := exec.CommandContext(ctx, opts.Args[0], opts.Args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"BBFS_REF="+ev.Ref.String(),
+		"BBFS_FROM="+ev.From,
+		"BBFS_TO="+ev.To,
+	)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "command for %s failed: %s\n", ev.Ref, err.Error())
+	}
+}
+
+// listenWebhook serves the webhook on -listen until the context is done.
+// The events are handled one at a time, after the webhook call returned.
+func listenWebhook(ctx context.Context, opts *options, handle func(bbfs.RefEvent) error) error {
+	events := make(chan bbfs.RefEvent, 16)
+	h := bbfs.WebhookHandler(opts.ProjectKey, opts.RepoSlug, bbfs.Ref(opts.At), opts.WebhookSecret.Secret(), func(ev bbfs.RefEvent) {
+		select {
+		case events <- ev:
+		default:
+			fmt.Fprintf(os.Stderr, "dropped event for %s, too many pending\n", ev.Ref)
+		}
+	})
+	ln, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: h}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	defer srv.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+			return ctx.Err()
+		case err := <-errc:
+			return err
+		case ev := <-events:
+			if err := handle(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestWatchExec(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"a": {Data: []byte("a")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	opts, err := parseOptions([]string{"bbclient", "watch", "-base-url", srv.BaseURL(),
+		"-project-key", "PRJ", "-repo-slug", "repo", "-at", "main", "-interval", "10ms",
+		"--", "sh", "-c", "echo $BBFS_REF $BBFS_TO"}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	var second string
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.Update(func() {
+			second = repo.Commit("main", "second", fstest.MapFS{"b": {Data: []byte("b")}}).ID
+		})
+	}()
+
+	var out strings.Builder
+	stdout = &out
+	defer func() { stdout = nil }()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := watch(ctx, opts); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var want string
+	srv.Update(func() { want = "main " + second + "\n" })
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
@@ -0,0 +1,113 @@
+package bbfs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/myhops/bbfs/secrets"
+)
+
+// Config contains the configuration for a bitbucket file system.
+type Config struct {
+	// Host is the hostname of the server
+	Host string
+	// BaseURL is the url of the REST api, e.g. https://host/rest/api/latest.
+	// It takes precedence over Host and ApiVersion,
+	// use it for servers that do not use the standard api path.
+	BaseURL string
+	// ProjectKey is the name of the project or the user of the repo
+	ProjectKey string
+	// RepositorySlug is the name of the repository
+	RepositorySlug string
+	// Root is the root of the file system in the repo,
+	// must be a an existing directory
+	Root string
+	// AccessKey is an http access key for the repo or the project
+	AccessKey string
+	// AccessKeySecret is a reference to the access key in a secret store,
+	// e.g. vault:secret/data/bbfs#token, see package secrets.
+	// It is read when needed and again when it expires.
+	AccessKeySecret string
+	// At is a branch, tag or commit,
+	// use BranchRef, TagRef or CommitRef to create it.
+	At Ref
+	// ApiVersion is the version of the api, defaults to DefaultVersion.
+	ApiVersion string
+	// MaxDownloadBytes limits the bytes of file content each FS reads,
+	// cached content included. Reads beyond it fail with a LimitError,
+	// a file larger than what is left is not downloaded. Zero is no limit.
+	MaxDownloadBytes int64
+}
+
+// Validate returns an error describing all problems with the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Host == "" && c.BaseURL == "" {
+		errs = append(errs, errors.New("Host or BaseURL is missing"))
+	}
+	if c.BaseURL != "" {
+		if _, err := parseBaseURL(c.BaseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.ProjectKey == "" {
+		errs = append(errs, errors.New("ProjectKey is missing"))
+	}
+	if c.RepositorySlug == "" {
+		errs = append(errs, errors.New("RepositorySlug is missing"))
+	}
+	if err := c.At.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.AccessKeySecret != "" {
+		if c.AccessKey != "" {
+			errs = append(errs, errors.New("AccessKey and AccessKeySecret are both set"))
+		}
+		if _, err := secrets.Parse(c.AccessKeySecret); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.MaxDownloadBytes < 0 {
+		errs = append(errs, errors.New("MaxDownloadBytes is negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// baseURL returns the url of the api.
+func (c *Config) baseURL() (string, error) {
+	if c.BaseURL != "" {
+		u, err := parseBaseURL(c.BaseURL)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	version := c.ApiVersion
+	if version == "" {
+		version = DefaultVersion
+	}
+	u := url.URL{
+		Scheme: "https",
+		Host:   c.Host,
+		Path:   path.Join(ApiPath, version),
+	}
+	return u.String(), nil
+}
+
+// parseBaseURL parses s and checks that it is an absolute http or https url.
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("BaseURL is invalid: %w", err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("BaseURL must be an absolute url, got %q", s)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("BaseURL must use http or https, got %q", u.Scheme)
+	}
+	return u, nil
+}
@@ -0,0 +1,142 @@
+package bbfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxDecodeSize is the default maximum size of a file read by
+// ReadJSON and ReadYAML.
+const DefaultMaxDecodeSize = 4 * 1024 * 1024
+
+// errTrailingData is returned by ReadJSON for a file with more than one value.
+var errTrailingData = errors.New("data after the JSON value")
+
+// DecodeOption is an option for ReadJSON and ReadYAML.
+type DecodeOption func(*decodeConfig)
+
+type decodeConfig struct {
+	maxSize     int64
+	knownFields bool
+}
+
+// WithMaxDecodeSize sets the maximum size of the file, defaults to
+// DefaultMaxDecodeSize. A negative size disables the guard.
+func WithMaxDecodeSize(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxSize = n
+	}
+}
+
+// WithKnownFields fails the decoding of fields that are not in the value.
+func WithKnownFields() DecodeOption {
+	return func(c *decodeConfig) {
+		c.knownFields = true
+	}
+}
+
+// DecodeError is returned by ReadJSON and ReadYAML. It unwraps to the error
+// of the read or the decoder, errors.Is(err, fs.ErrNotExist) works.
+type DecodeError struct {
+	// Path is the name of the file in the FS.
+	Path string
+	// Ref is the ref of the FS, empty when the FS is not from this package.
+	Ref Ref
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Ref == "" {
+		return "decode " + e.Path + ": " + e.Err.Error()
+	}
+	return "decode " + e.Path + " at " + e.Ref.String() + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ReadJSON reads the JSON file into v.
+//
+// Files larger than the maximum size fail with a LimitError, before they
+// are downloaded when the FS knows the size.
+func ReadJSON(fsys fs.FS, name string, v any, opts ...DecodeOption) error {
+	return decodeFile(fsys, name, opts, func(data []byte, cfg *decodeConfig) error {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if cfg.knownFields {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(v); err != nil {
+			return err
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return errTrailingData
+		}
+		return nil
+	})
+}
+
+// ReadYAML reads the YAML file into v. An empty file leaves v unchanged.
+//
+// Files larger than the maximum size fail with a LimitError, before they
+// are downloaded when the FS knows the size.
+func ReadYAML(fsys fs.FS, name string, v any, opts ...DecodeOption) error {
+	return decodeFile(fsys, name, opts, func(data []byte, cfg *decodeConfig) error {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(cfg.knownFields)
+		if err := dec.Decode(v); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	})
+}
+
+// decodeFile reads the file within the maximum size and decodes it,
+// wrapping the errors in a DecodeError.
+func decodeFile(fsys fs.FS, name string, opts []DecodeOption, decode func([]byte, *decodeConfig) error) error {
+	cfg := &decodeConfig{maxSize: DefaultMaxDecodeSize}
+	for _, o := range opts {
+		o(cfg)
+	}
+	var ref Ref
+	if b, ok := fsys.(Bitbucket); ok {
+		ref = b.Ref()
+	}
+	data, err := readLimited(fsys, name, cfg.maxSize)
+	if err == nil {
+		err = decode(data, cfg)
+	}
+	if err != nil {
+		return &DecodeError{Path: name, Ref: ref, Err: err}
+	}
+	return nil
+}
+
+// readLimited reads the file, failing with a LimitError when it is larger
+// than maxSize. A negative maxSize disables the guard.
+func readLimited(fsys fs.FS, name string, maxSize int64) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if maxSize < 0 {
+		return io.ReadAll(f)
+	}
+	if fi, err := f.Stat(); err == nil && fi.Size() > maxSize {
+		return nil, &LimitError{Limit: LimitSize, Max: maxSize, Path: name}
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, &LimitError{Limit: LimitSize, Max: maxSize, Path: name}
+	}
+	return data, nil
+}
@@ -0,0 +1,95 @@
+package bbfs
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+type decodeTestConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func TestReadJSONAndYAML(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.json": {Data: []byte(`{"name": "app", "count": 3}`)},
+		"config.yaml": {Data: []byte("name: app\ncount: 3\n")},
+		"extra.json":  {Data: []byte(`{"name": "app", "other": 1}`)},
+		"extra.yaml":  {Data: []byte("name: app\nother: 1\n")},
+		"two.json":    {Data: []byte(`{"name": "a"} {"name": "b"}`)},
+		"broken.json": {Data: []byte(`{"name": `)},
+		"broken.yaml": {Data: []byte("name: [\n")},
+		"empty.yaml":  {Data: []byte("")},
+		"large.json":  {Data: []byte(`{"name": "` + strings.Repeat("x", 100) + `"}`)},
+	}
+	read := map[string]func(fs.FS, string, any, ...DecodeOption) error{
+		"json": ReadJSON,
+		"yaml": ReadYAML,
+	}
+	cases := []struct {
+		name    string
+		file    string
+		opts    []DecodeOption
+		want    decodeTestConfig
+		wantErr error
+	}{
+		{name: "json", file: "config.json", want: decodeTestConfig{Name: "app", Count: 3}},
+		{name: "yaml", file: "config.yaml", want: decodeTestConfig{Name: "app", Count: 3}},
+		{name: "json unknown field", file: "extra.json", want: decodeTestConfig{Name: "app"}},
+		{name: "yaml unknown field", file: "extra.yaml", want: decodeTestConfig{Name: "app"}},
+		{name: "json known fields", file: "extra.json", opts: []DecodeOption{WithKnownFields()}, wantErr: errAny},
+		{name: "yaml known fields", file: "extra.yaml", opts: []DecodeOption{WithKnownFields()}, wantErr: errAny},
+		{name: "json two values", file: "two.json", wantErr: errTrailingData},
+		{name: "json broken", file: "broken.json", wantErr: errAny},
+		{name: "yaml broken", file: "broken.yaml", wantErr: errAny},
+		{name: "yaml empty", file: "empty.yaml"},
+		{name: "json missing", file: "missing.json", wantErr: fs.ErrNotExist},
+		{name: "json too large", file: "large.json", opts: []DecodeOption{WithMaxDecodeSize(50)}, wantErr: ErrLimitExceeded},
+		{name: "json no limit", file: "large.json", opts: []DecodeOption{WithMaxDecodeSize(-1)}, want: decodeTestConfig{Name: strings.Repeat("x", 100)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got decodeTestConfig
+			err := read[tc.file[strings.LastIndex(tc.file, ".")+1:]](fsys, tc.file, &got, tc.opts...)
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("error: %s", err.Error())
+				}
+				if got != tc.want {
+					t.Errorf("expected %+v, got %+v", tc.want, got)
+				}
+				return
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) || de.Path != tc.file {
+				t.Fatalf("expected a DecodeError for %s, got %v", tc.file, err)
+			}
+			if tc.wantErr != errAny && !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// errAny matches any error in the test cases.
+var errAny = errors.New("any error")
+
+func TestReadJSONRef(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"config.json": {Data: []byte(`{"name": `)},
+	}))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: BranchRef("main")})
+
+	var v decodeTestConfig
+	err := ReadJSON(fsys, "config.json", &v)
+	if err == nil || errors.Is(err, fs.ErrNotExist) || !strings.HasPrefix(err.Error(), "decode config.json at refs/heads/main: ") {
+		t.Errorf("expected the path and ref in the error, got %v", err)
+	}
+}
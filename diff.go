@@ -0,0 +1,198 @@
+package bbfs
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+)
+
+// Kinds of DiffEntry.
+const (
+	DiffAdded    = "ADDED"
+	DiffRemoved  = "REMOVED"
+	DiffModified = "MODIFIED"
+)
+
+// DiffEntry is a file that differs between two file systems.
+type DiffEntry struct {
+	// Path is the path of the file in the file systems.
+	Path string
+	// Kind is DiffAdded, DiffRemoved or DiffModified.
+	Kind string
+}
+
+// DiffFS returns the files that were added, removed or modified in b
+// compared to a, in lexical order. Directories are not reported, the files
+// below an added or removed directory are.
+//
+// Files whose file info has a content id, like those of the FS of this
+// package at two refs, are compared by id without reading them. Other
+// files are compared by size, then by content. Comparing the trees of two
+// refs of a repository reads the listings of the directories only.
+//
+// The iteration stops at the first error, which is yielded with an empty entry.
+func DiffFS(a, b fs.FS) iter.Seq2[DiffEntry, error] {
+	return func(yield func(DiffEntry, error) bool) {
+		d := &differ{a: a, b: b, yield: yield}
+		if err := d.dir("."); err != nil && !errors.Is(err, errStopDiff) {
+			yield(DiffEntry{}, err)
+		}
+	}
+}
+
+// errStopDiff stops the walk when the consumer stops the iteration.
+var errStopDiff = errors.New("diff stopped")
+
+type differ struct {
+	a, b  fs.FS
+	yield func(DiffEntry, error) bool
+}
+
+func (d *differ) emit(name, kind string) error {
+	if !d.yield(DiffEntry{Path: name, Kind: kind}, nil) {
+		return errStopDiff
+	}
+	return nil
+}
+
+// dir compares the directory, which exists in a or b or both.
+func (d *differ) dir(dir string) error {
+	as, err := readDirIfExists(d.a, dir)
+	if err != nil {
+		return err
+	}
+	bs, err := readDirIfExists(d.b, dir)
+	if err != nil {
+		return err
+	}
+	for len(as) > 0 || len(bs) > 0 {
+		switch {
+		case len(bs) == 0 || len(as) > 0 && as[0].Name() < bs[0].Name():
+			if err := d.all(d.a, path.Join(dir, as[0].Name()), as[0], DiffRemoved); err != nil {
+				return err
+			}
+			as = as[1:]
+		case len(as) == 0 || bs[0].Name() < as[0].Name():
+			if err := d.all(d.b, path.Join(dir, bs[0].Name()), bs[0], DiffAdded); err != nil {
+				return err
+			}
+			bs = bs[1:]
+		default:
+			if err := d.entry(path.Join(dir, as[0].Name()), as[0], bs[0]); err != nil {
+				return err
+			}
+			as, bs = as[1:], bs[1:]
+		}
+	}
+	return nil
+}
+
+// entry compares the entry that is in a and in b.
+func (d *differ) entry(name string, ea, eb fs.DirEntry) error {
+	switch {
+	case ea.IsDir() && eb.IsDir():
+		return d.dir(name)
+	case ea.IsDir():
+		// A directory became a file.
+		if err := d.all(d.a, name, ea, DiffRemoved); err != nil {
+			return err
+		}
+		return d.emit(name, DiffAdded)
+	case eb.IsDir():
+		if err := d.emit(name, DiffRemoved); err != nil {
+			return err
+		}
+		return d.all(d.b, name, eb, DiffAdded)
+	}
+	same, err := d.sameFile(name, ea, eb)
+	if err != nil || same {
+		return err
+	}
+	return d.emit(name, DiffModified)
+}
+
+// all reports the file, or all files below the directory, as kind.
+func (d *differ) all(fsys fs.FS, name string, e fs.DirEntry, kind string) error {
+	if !e.IsDir() {
+		return d.emit(name, kind)
+	}
+	return fs.WalkDir(fsys, name, func(name string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		return d.emit(name, kind)
+	})
+}
+
+// sameFile reports whether the file has the same content in a and b.
+func (d *differ) sameFile(name string, ea, eb fs.DirEntry) (bool, error) {
+	fa, err := ea.Info()
+	if err != nil {
+		return false, err
+	}
+	fb, err := eb.Info()
+	if err != nil {
+		return false, err
+	}
+	ida, idb := contentID(fa), contentID(fb)
+	if ida != "" && idb != "" {
+		return ida == idb, nil
+	}
+	if fa.Size() != fb.Size() {
+		return false, nil
+	}
+	// Read the file without an id only, when the other one has one.
+	switch {
+	case ida != "":
+		data, err := fs.ReadFile(d.b, name)
+		if err != nil {
+			return false, err
+		}
+		return gitBlobID(data) == ida, nil
+	case idb != "":
+		data, err := fs.ReadFile(d.a, name)
+		if err != nil {
+			return false, err
+		}
+		return gitBlobID(data) == idb, nil
+	}
+	da, err := fs.ReadFile(d.a, name)
+	if err != nil {
+		return false, err
+	}
+	db, err := fs.ReadFile(d.b, name)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(da, db), nil
+}
+
+// readDirIfExists returns the entries of the directory, none when it does not exist.
+func readDirIfExists(fsys fs.FS, dir string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return entries, err
+}
+
+// contentID returns the id of the git blob of the file, if the file info has one.
+func contentID(fi fs.FileInfo) string {
+	if c, ok := fi.(interface{ ContentID() string }); ok {
+		return c.ContentID()
+	}
+	return ""
+}
+
+// gitBlobID returns the id of the git blob with the data.
+func gitBlobID(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,101 @@
+package bbfs
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestDiffFS(t *testing.T) {
+	v1 := fstest.MapFS{
+		"README.md":        {Data: []byte("# readme\n")},
+		"docs/guide.md":    {Data: []byte("guide\n")},
+		"docs/old.md":      {Data: []byte("old\n")},
+		"conf":             {Data: []byte("conf\n")},
+		"lib/a/a.go":       {Data: []byte("package a\n")},
+		"lib/a/b/b.go":     {Data: []byte("package b\n")},
+		"same/size.txt":    {Data: []byte("aaaa")},
+		"unchanged/u.json": {Data: []byte("{}")},
+	}
+	v2 := maps.Clone(v1)
+	delete(v2, "docs/old.md")
+	delete(v2, "conf")
+	delete(v2, "lib/a/a.go")
+	delete(v2, "lib/a/b/b.go")
+	v2["docs/guide.md"] = &fstest.MapFile{Data: []byte("guide v2\n")}
+	v2["docs/new.md"] = &fstest.MapFile{Data: []byte("new\n")}
+	v2["conf/app.yaml"] = &fstest.MapFile{Data: []byte("app: 2\n")}
+	v2["lib/a"] = &fstest.MapFile{Data: []byte("a is a file now\n")}
+	v2["same/size.txt"] = &fstest.MapFile{Data: []byte("bbbb")}
+
+	repo := fakeserver.NewRepo(v1)
+	repo.Tag("v1", repo.Commits[0])
+	repo.Commit("main", "v2", v2)
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	a := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: TagRef("v1")})
+	b, err := At(a, BranchRef("main"))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := []string{
+		"REMOVED conf",
+		"ADDED conf/app.yaml",
+		"MODIFIED docs/guide.md",
+		"ADDED docs/new.md",
+		"REMOVED docs/old.md",
+		"REMOVED lib/a/a.go",
+		"REMOVED lib/a/b/b.go",
+		"ADDED lib/a",
+		"MODIFIED same/size.txt",
+	}
+	tests := []struct {
+		name string
+		a, b fs.FS
+	}{
+		{"refs", a, b},
+		{"mixed", a, v2},
+		{"maps", v1, v2},
+	}
+	for _, tt := range tests {
+		got, err := collectDiff(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("%s: error: %s", tt.name, err.Error())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, want, got)
+		}
+	}
+	// The refs are compared by the listings.
+	if n := DownloadedBytes(a) + DownloadedBytes(b); n != 0 {
+		t.Errorf("expected no downloads, got %d bytes", n)
+	}
+
+	// Stop early.
+	var n int
+	for range DiffFS(v1, v2) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("expected 1 entry, got %d", n)
+	}
+}
+
+func collectDiff(a, b fs.FS) ([]string, error) {
+	var res []string
+	for e, err := range DiffFS(a, b) {
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fmt.Sprintf("%s %s", e.Kind, e.Path))
+	}
+	return res, nil
+}
@@ -0,0 +1,71 @@
+package bbfs
+
+import (
+	"os"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by
+// ConfigFromEnv when the prefix is empty.
+const DefaultEnvPrefix = "BBFS_"
+
+// EnvVar documents an environment variable read by ConfigFromEnv.
+type EnvVar struct {
+	// Name is the name without the prefix.
+	Name string
+	// Field is the name of the field in Config.
+	Field string
+	// Description is a one line description for help texts.
+	Description string
+}
+
+// envVars lists the variables with a setter for the Config field.
+var envVars = []struct {
+	EnvVar
+	set func(c *Config, v string)
+}{
+	{EnvVar{"HOST", "Host", "hostname of the server"}, func(c *Config, v string) { c.Host = v }},
+	{EnvVar{"BASE_URL", "BaseURL", "url of the REST api, takes precedence over HOST"}, func(c *Config, v string) { c.BaseURL = v }},
+	{EnvVar{"API_VERSION", "ApiVersion", "version of the REST api, defaults to latest"}, func(c *Config, v string) { c.ApiVersion = v }},
+	{EnvVar{"PROJECT_KEY", "ProjectKey", "project key or user of the repository"}, func(c *Config, v string) { c.ProjectKey = v }},
+	{EnvVar{"REPO_SLUG", "RepositorySlug", "slug of the repository"}, func(c *Config, v string) { c.RepositorySlug = v }},
+	{EnvVar{"ROOT", "Root", "directory in the repository that is the root of the FS"}, func(c *Config, v string) { c.Root = v }},
+	{EnvVar{"ACCESS_KEY", "AccessKey", "http access token"}, func(c *Config, v string) { c.AccessKey = v }},
+	{EnvVar{"ACCESS_KEY_SECRET", "AccessKeySecret", "reference to the access token in a secret store, e.g. vault:secret/data/bbfs#token"}, func(c *Config, v string) { c.AccessKeySecret = v }},
+	{EnvVar{"AT", "At", "branch, tag or commit"}, func(c *Config, v string) { c.At = Ref(v) }},
+}
+
+// EnvVars returns the environment variables read by ConfigFromEnv.
+func EnvVars() []EnvVar {
+	res := make([]EnvVar, 0, len(envVars))
+	for _, v := range envVars {
+		res = append(res, v.EnvVar)
+	}
+	return res
+}
+
+// ConfigFromEnv returns the configuration from the environment variables
+// with the prefix, DefaultEnvPrefix when empty. See EnvVars for the names.
+//
+// The configuration is not validated, fields can be changed before use.
+func ConfigFromEnv(prefix string) *Config {
+	return ConfigFromEnvFunc(prefix, os.Getenv)
+}
+
+// ConfigFromEnvFunc is ConfigFromEnv with getenv to look up the variables.
+func ConfigFromEnvFunc(prefix string, getenv func(string) string) *Config {
+	cfg := &Config{}
+	UpdateConfigFromEnv(cfg, prefix, getenv)
+	return cfg
+}
+
+// UpdateConfigFromEnv sets the fields of cfg for the variables that are set.
+func UpdateConfigFromEnv(cfg *Config, prefix string, getenv func(string) string) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	for _, v := range envVars {
+		if val := getenv(prefix + v.Name); val != "" {
+			v.set(cfg, val)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package bbfs
+
+import (
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"APP_BASE_URL":    "https://bitbucket.example.com/rest/api/latest",
+		"APP_PROJECT_KEY": "PRJ",
+		"APP_REPO_SLUG":   "repo",
+		"APP_ACCESS_KEY":  "secret",
+		"APP_AT":          "refs/heads/main",
+		"BBFS_ROOT":       "ignored",
+	}
+	cfg := ConfigFromEnvFunc("APP_", func(key string) string { return env[key] })
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := Config{
+		BaseURL:        "https://bitbucket.example.com/rest/api/latest",
+		ProjectKey:     "PRJ",
+		RepositorySlug: "repo",
+		AccessKey:      "secret",
+		At:             BranchRef("main"),
+	}
+	if *cfg != want {
+		t.Errorf("expected %+v, got %+v", want, *cfg)
+	}
+
+	cfg = ConfigFromEnvFunc("", func(key string) string { return env[key] })
+	if cfg.Root != "ignored" {
+		t.Errorf("expected the default prefix to be used, got %+v", *cfg)
+	}
+}
@@ -0,0 +1,114 @@
+package bbfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"testing/fstest"
+)
+
+// DefaultMaxExportSize is the default maximum of the total size of the files Export reads.
+const DefaultMaxExportSize = 64 * 1024 * 1024
+
+// LimitSize is the limit of a LimitError for the total size of an Export
+// and the size of a file read by ReadJSON or ReadYAML.
+const LimitSize = "size"
+
+// ExportOption is an option for Export.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	maxSize int64
+	ignore  *Ignore
+}
+
+// WithMaxExportSize sets the maximum of the total size of the files, defaults
+// to DefaultMaxExportSize. A negative size disables the guard.
+func WithMaxExportSize(n int64) ExportOption {
+	return func(c *exportConfig) {
+		c.maxSize = n
+	}
+}
+
+// WithExportIgnore skips the files and directories that the patterns ignore,
+// see Ignore.
+func WithExportIgnore(ig *Ignore) ExportOption {
+	return func(c *exportConfig) {
+		c.ignore = ig
+	}
+}
+
+// Export reads the whole file system into memory. Use fs.Sub for a subtree.
+//
+// The result serves repeated reads without requests, and seeds tests with
+// the content of a real repository. Export fails with a LimitError when the
+// files are larger than the maximum size in total, and stops when the
+// context is done.
+func Export(ctx context.Context, fsys fs.FS, opts ...ExportOption) (fstest.MapFS, error) {
+	cfg := &exportConfig{maxSize: DefaultMaxExportSize}
+	for _, o := range opts {
+		o(cfg)
+	}
+	res := fstest.MapFS{}
+	var total int64
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if cfg.ignore.Match(name, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			res[name] = &fstest.MapFile{Mode: fi.Mode(), ModTime: fi.ModTime()}
+			return nil
+		}
+		if cfg.maxSize >= 0 && total+fi.Size() > cfg.maxSize {
+			return &LimitError{Limit: LimitSize, Max: cfg.maxSize, Path: name}
+		}
+		remaining := int64(-1)
+		if cfg.maxSize >= 0 {
+			remaining = cfg.maxSize - total
+		}
+		data, err := readAtMost(fsys, name, remaining)
+		if err != nil {
+			return err
+		}
+		total += int64(len(data))
+		if cfg.maxSize >= 0 && total > cfg.maxSize {
+			return &LimitError{Limit: LimitSize, Max: cfg.maxSize, Path: name}
+		}
+		res[name] = &fstest.MapFile{Data: data, Mode: fi.Mode(), ModTime: fi.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readAtMost reads the file up to one byte over n, so the caller can
+// detect a larger file. A negative n reads the whole file.
+func readAtMost(fsys fs.FS, name string, n int64) ([]byte, error) {
+	if n < 0 {
+		return fs.ReadFile(fsys, name)
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, n+1))
+}
@@ -0,0 +1,73 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestExport(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	files := fstest.MapFS{
+		"README.md":       {Data: []byte("# readme\n")},
+		"config/app.yaml": {Data: []byte("replicas: 1\n")},
+		"config/db.yaml":  {Data: []byte("host: localhost\n")},
+	}
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(files))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	res, err := Export(context.Background(), fsys)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	for name, f := range files {
+		data, err := fs.ReadFile(res, name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != string(f.Data) {
+			t.Errorf("%s: expected %q, got %q", name, f.Data, data)
+		}
+	}
+	if fi, err := fs.Stat(res, "config"); err != nil || !fi.IsDir() {
+		t.Errorf("expected directory config, got %v, %v", fi, err)
+	}
+
+	// The export serves without requests.
+	requests := srv.Requests()
+	if err := fstest.TestFS(res, "README.md", "config/app.yaml"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if srv.Requests() != requests {
+		t.Errorf("expected no requests, got %d", srv.Requests()-requests)
+	}
+
+	sub, err := fs.Sub(fsys, "config")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	res, err = Export(context.Background(), sub)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(res) != 2 || res["app.yaml"] == nil {
+		t.Errorf("unexpected export of the subtree %v", res)
+	}
+
+	var le *LimitError
+	_, err = Export(context.Background(), fsys, WithMaxExportSize(20))
+	if !errors.As(err, &le) || le.Limit != LimitSize || le.Max != 20 {
+		t.Errorf("expected a size LimitError, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Export(ctx, fsys); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+}
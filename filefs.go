@@ -0,0 +1,134 @@
+package bbfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// FileFS returns a file system with only the file at name, relative to the
+// root in the configuration, for APIs that load a named file from an fs.FS.
+// Other names, including ".", do not exist.
+//
+// The FS does not list directories: Stat and Open get the size of the file
+// with a HEAD request and ReadFile gets the content with one request.
+func FileFS(cfg *Config, name string, opts ...Option) fs.FS {
+	f := &fileFS{name: name}
+	f.b, _ = NewFS(cfg, opts...).(*bbFS)
+	return f
+}
+
+// fileFS is the FS returned by FileFS.
+type fileFS struct {
+	b    *bbFS
+	name string
+}
+
+var (
+	_ fs.ReadFileFS = &fileFS{}
+	_ fs.StatFS     = &fileFS{}
+	_ Bitbucket     = &fileFS{}
+)
+
+// check returns an error for names other than the file.
+func (f *fileFS) check(op, name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name != f.name {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// Open opens the file, its content is read on the first Read.
+func (f *fileFS) Open(name string) (fs.File, error) {
+	if err := f.check("open", name); err != nil {
+		return nil, err
+	}
+	fi, ok, err := f.b.statRaw(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if !ok {
+		// Let the FS report the error, or the directory.
+		file, err := f.b.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		if fi, _ := file.Stat(); fi.IsDir() {
+			file.Close()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+		}
+		return file, nil
+	}
+	return &bbFile{
+		bfs:      f.b,
+		fullPath: path.Join(f.b.root, name),
+		fi:       fi.(*bbFileInfo),
+	}, nil
+}
+
+// ReadFile reads the file with one request.
+func (f *fileFS) ReadFile(name string) ([]byte, error) {
+	if err := f.check("readfile", name); err != nil {
+		return nil, err
+	}
+	b := f.b
+	if b.err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: b.err}
+	}
+	if err := b.checkRef(); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: b.repoError(err)}
+	}
+	if err := b.checkSignature(); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	fullPath := path.Join(b.root, name)
+	if !b.filter.visible(fullPath, false) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := b.limits.checkDepth(fullPath); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	file := &bbFile{bfs: b, fullPath: fullPath, fi: &bbFileInfo{name: path.Base(fullPath)}}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if server.IsNotFound(err) {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			return nil, err
+		}
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+func (f *fileFS) isBinary(name string) (bool, error) {
+	if err := f.check("isbinary", name); err != nil {
+		return false, err
+	}
+	return f.b.isBinary(name)
+}
+
+// Stat returns the info of the file.
+func (f *fileFS) Stat(name string) (fs.FileInfo, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+func (f *fileFS) Project() string        { return f.b.Project() }
+func (f *fileFS) Repo() string           { return f.b.Repo() }
+func (f *fileFS) Ref() Ref               { return f.b.Ref() }
+func (f *fileFS) Root() string           { return f.b.Root() }
+func (f *fileFS) Client() *server.Client { return f.b.Client() }
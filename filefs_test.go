@@ -0,0 +1,78 @@
+package bbfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestFileFS(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"certs/tls.crt": {Data: []byte("certificate")},
+		"certs/tls.key": {Data: []byte("key")},
+	}))
+	cfg := &Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", Root: "certs"}
+
+	requests := func(t *testing.T, want int64, f func()) {
+		t.Helper()
+		before := srv.Requests()
+		f()
+		if n := srv.Requests() - before; n != want {
+			t.Errorf("expected %d requests, got %d", want, n)
+		}
+	}
+
+	fsys := FileFS(cfg, "tls.crt")
+	requests(t, 1, func() {
+		data, err := fs.ReadFile(fsys, "tls.crt")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != "certificate" {
+			t.Errorf("unexpected content %q", data)
+		}
+	})
+	requests(t, 1, func() {
+		fi, err := fs.Stat(fsys, "tls.crt")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if fi.Name() != "tls.crt" || fi.Size() != 11 || fi.IsDir() {
+			t.Errorf("unexpected info %s %d %v", fi.Name(), fi.Size(), fi.IsDir())
+		}
+	})
+	requests(t, 0, func() {
+		for _, name := range []string{"tls.key", ".", "certs/tls.crt"} {
+			if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("expected %s not to exist, got %v", name, err)
+			}
+		}
+	})
+
+	f, err := fsys.Open("tls.crt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "certificate" {
+		t.Errorf("unexpected content %q, error %v", data, err)
+	}
+
+	missing := FileFS(cfg, "missing.crt")
+	if _, err := fs.ReadFile(missing, "missing.crt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist, got %v", err)
+	}
+	if _, err := fs.Stat(missing, "missing.crt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist, got %v", err)
+	}
+	if _, err := fs.ReadFile(FileFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"}, "certs"), "certs"); err == nil {
+		t.Errorf("expected an error for a directory")
+	}
+}
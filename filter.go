@@ -0,0 +1,155 @@
+package bbfs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// WithInclude only exposes the files that match one of the patterns, and the
+// directories leading to them. A directory that matches exposes its subtree.
+//
+// Patterns use the syntax of path.Match, relative to the root of the FS,
+// and ** matches any number of directories. A pattern without a slash
+// matches the name at any depth, as in .gitignore.
+func WithInclude(patterns ...string) Option {
+	return func(f *bbFS) {
+		f.filterPatterns(patterns, &f.pathFilter().include)
+	}
+}
+
+// WithExclude hides the files and directories that match one of the patterns,
+// the subtree of a hidden directory is not listed.
+// The patterns are as for WithInclude, exclusion takes precedence.
+func WithExclude(patterns ...string) Option {
+	return func(f *bbFS) {
+		f.filterPatterns(patterns, &f.pathFilter().exclude)
+	}
+}
+
+// pathFilter returns the filter of the FS, creating it if needed.
+func (b *bbFS) pathFilter() *pathFilter {
+	if b.filter == nil {
+		b.filter = &pathFilter{base: b.root}
+	}
+	return b.filter
+}
+
+// filterPatterns checks the patterns and adds them to list.
+func (b *bbFS) filterPatterns(patterns []string, list *[][]string) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			if b.err == nil {
+				b.err = fmt.Errorf("bad pattern %q: %w", p, path.ErrBadPattern)
+			}
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			p = "**/" + p
+		}
+		*list = append(*list, strings.Split(p, "/"))
+	}
+}
+
+// pathFilter decides which paths in the repository the FS exposes.
+type pathFilter struct {
+	// base is the directory the patterns are relative to.
+	base    string
+	include [][]string
+	exclude [][]string
+}
+
+// segments returns the segments of the path relative to the base,
+// nil for the base itself.
+func (p *pathFilter) segments(fullPath string) []string {
+	rel := fullPath
+	if p.base != "" {
+		rel = strings.TrimPrefix(strings.TrimPrefix(fullPath, p.base), "/")
+	}
+	if rel == "" || rel == "." {
+		return nil
+	}
+	return strings.Split(rel, "/")
+}
+
+// excluded returns true if the path or one of its parents is excluded.
+func (p *pathFilter) excluded(fullPath string) bool {
+	return p != nil && matchAny(p.exclude, p.segments(fullPath))
+}
+
+// visible returns true if the FS exposes the path.
+func (p *pathFilter) visible(fullPath string, isDir bool) bool {
+	if p == nil {
+		return true
+	}
+	segs := p.segments(fullPath)
+	if segs == nil {
+		return true
+	}
+	if matchAny(p.exclude, segs) {
+		return false
+	}
+	if len(p.include) == 0 || matchAny(p.include, segs) {
+		return true
+	}
+	if !isDir {
+		return false
+	}
+	for _, pat := range p.include {
+		if matchBelow(pat, segs) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny returns true if one of the patterns matches the path or one of its parents.
+func matchAny(patterns [][]string, segs []string) bool {
+	for i := 1; i <= len(segs); i++ {
+		for _, pat := range patterns {
+			if matchSegments(pat, segs[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSegments matches the path segments against the pattern segments.
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// matchBelow returns true if the pattern can match a path below the directory.
+func matchBelow(pat, dir []string) bool {
+	for len(dir) > 0 {
+		if len(pat) == 0 {
+			return false
+		}
+		if pat[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pat[0], dir[0]); !ok {
+			return false
+		}
+		pat, dir = pat[1:], dir[1:]
+	}
+	return len(pat) > 0
+}
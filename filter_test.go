@@ -0,0 +1,67 @@
+package bbfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPathFilter(t *testing.T) {
+	files := fstest.MapFS{
+		"README.md":                 {Data: []byte("readme")},
+		"config/app.yaml":           {Data: []byte("app")},
+		"config/secret.key":         {Data: []byte("secret")},
+		"config/env/prod.yaml":      {Data: []byte("prod")},
+		"web/node_modules/x/a.js":   {Data: []byte("x")},
+		"web/index.js":              {Data: []byte("index")},
+		"docs/guide/intro.md":       {Data: []byte("intro")},
+		"docs/guide/img/banner.png": {Data: []byte("png")},
+	}
+	tests := []struct {
+		name    string
+		opts    []Option
+		visible []string
+		hidden  []string
+	}{
+		{
+			name:    "exclude",
+			opts:    []Option{WithExclude("*.key", "node_modules")},
+			visible: []string{"README.md", "config/app.yaml", "config/env/prod.yaml", "web/index.js"},
+			hidden:  []string{"config/secret.key", "web/node_modules", "web/node_modules/x/a.js"},
+		},
+		{
+			name:    "include",
+			opts:    []Option{WithInclude("config/**/*.yaml", "docs/guide")},
+			visible: []string{"config/app.yaml", "config/env/prod.yaml", "docs/guide/intro.md", "docs/guide/img/banner.png"},
+			hidden:  []string{"README.md", "config/secret.key", "web", "web/index.js"},
+		},
+		{
+			name:    "include and exclude",
+			opts:    []Option{WithInclude("*.yaml"), WithExclude("config/env")},
+			visible: []string{"config/app.yaml"},
+			hidden:  []string{"config/env/prod.yaml", "config/env", "README.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newFakeFS(t, files, tt.opts...)
+			if err := fstest.TestFS(fsys, tt.visible...); err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			for _, name := range tt.hidden {
+				if _, err := fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%s: expected fs.ErrNotExist, got %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBadPattern(t *testing.T) {
+	fsys := newFakeFS(t, fstest.MapFS{}, WithExclude("[a-"))
+	if _, err := fsys.Open("."); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("expected path.ErrBadPattern, got %v", err)
+	}
+}
@@ -3,23 +3,40 @@ package bbfs
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
-	"net/url"
-	"path/filepath"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/secrets"
 )
 
-// Define the interface for accessing the Bitbucket repositories.
+// Bitbucket exposes the repository behind an FS returned by this package.
+// Wrappers use it to find out which repository and ref they serve.
 type Bitbucket interface {
+	// Project returns the project key or user of the repository.
+	Project() string
+	// Repo returns the repository slug.
+	Repo() string
+	// Ref returns the branch, tag or commit of the FS.
+	Ref() Ref
+	// Root returns the directory in the repository that is the root of the FS.
+	Root() string
+	// Client returns the client used to access the server.
+	Client() *server.Client
 }
 
 const (
 	// Default api path for Bitbucket Server
-	ApiPath        = "/rest/api"
+	ApiPath = "/rest/api"
 	// API version
 	DefaultVersion = "latest"
 )
@@ -28,57 +45,143 @@ var (
 	ErrNotImplementedYet = errors.New("not implemented yet")
 )
 
-// Config contains the configuration for a bitbucket file system.
-type Config struct {
-	// Host is the hostname of the server
-	Host string
-	// ProjectKey is the name of the project or the user of the repo
-	ProjectKey string
-	// RepositorySlug is the name of the repository
-	RepositorySlug string
-	// Root is the root of the file system in the repo,
-	// must be a an existing directory
-	Root string
-	// AccessKey is an http access key for the repo or the project
-	AccessKey string
-	// At is a branch, tag or commit
-	At string
-	// ApiVersion is ignored
-	ApiVersion string
+// BitbucketFS is the interface of the FS returned by NewFS.
+// Depend on it to replace the FS with the mock in bbfstest in tests.
+type BitbucketFS interface {
+	fs.ReadDirFS
+	fs.ReadFileFS
+	fs.StatFS
+	// Tags returns the tags in the repository.
+	Tags(ctx context.Context) ([]*server.Tag, error)
+	// Ref returns the branch, tag or commit of the FS.
+	Ref() Ref
+}
+
+// Ref is a branch, tag or commit in the repository.
+type Ref = server.Ref
+
+// BranchRef returns the ref for a branch.
+func BranchRef(name string) Ref {
+	return server.BranchRef(name)
+}
+
+// TagRef returns the ref for a tag.
+func TagRef(name string) Ref {
+	return server.TagRef(name)
+}
+
+// CommitRef returns the ref for a commit.
+func CommitRef(id string) Ref {
+	return server.CommitRef(id)
 }
 
 // Options is the type for NewFS options.
 type Option func(*bbFS)
 
 // NewFS returns a new FS.
+//
+// An invalid configuration is reported by the first call to Open.
+// Use NewRepo to serve several refs of the repository with one client.
 func NewFS(cfg *Config, opts ...Option) fs.FS {
-	version := cfg.ApiVersion
-	if version == "" {
-		version = DefaultVersion
-	}
-	u := url.URL{
-		Scheme: "https",
-		Host:   cfg.Host,
-		Path:   filepath.Join(ApiPath, version),
+	return NewRepo(cfg, opts...).FS(cfg.At)
+}
+
+// Repo is a repository on the server.
+// The FS values it returns share the client, the cache and the request budget.
+type Repo struct {
+	base bbFS
+}
+
+// NewRepo returns the repository in the configuration.
+// The At field of the configuration is the default ref for FS.
+//
+// An invalid configuration is reported by the first call to Open of the FS values.
+func NewRepo(cfg *Config, opts ...Option) *Repo {
+	baseURL, err := cfg.baseURL()
+	if err == nil {
+		err = cfg.Validate()
 	}
 
-	res := &bbFS{
-		client: &server.Client{
-			BaseURL:   u.String(),
-			AccessKey: server.SecretString(cfg.AccessKey),
+	res := &Repo{
+		base: bbFS{
+			client: &server.Client{
+				BaseURL:   baseURL,
+				AccessKey: server.SecretString(cfg.AccessKey),
+			},
+			repoSlug:   cfg.RepositorySlug,
+			projectKey: cfg.ProjectKey,
+			accessKey:  cfg.AccessKey,
+			root:       cfg.Root,
+			at:         cfg.At,
+			err:        err,
 		},
-		repoSlug:   cfg.RepositorySlug,
-		projectKey: cfg.ProjectKey,
-		accessKey:  cfg.AccessKey,
-		root:       cfg.Root,
-		at:         cfg.At,
+	}
+	if cfg.AccessKeySecret != "" && err == nil {
+		p, _ := secrets.Parse(cfg.AccessKeySecret)
+		res.base.client.TokenSource = secrets.TokenSource(p, 0)
+	}
+	if cfg.MaxDownloadBytes > 0 {
+		res.base.walkLimits().maxDownload = cfg.MaxDownloadBytes
 	}
 	for _, o := range opts {
-		o(res)
+		o(&res.base)
 	}
 	return res
 }
 
+// FS returns the file system at the ref, the default ref when empty.
+// It is cheap, the FS shares the client and cache of the repository.
+func (r *Repo) FS(ref Ref) fs.FS {
+	if ref == "" {
+		ref = r.base.at
+	}
+	f := r.base.withRef(ref)
+	if f.err == nil {
+		f.err = f.at.Validate()
+	}
+	return f
+}
+
+// At returns a view of the repository of fsys at the ref, the default
+// branch when empty. It has the root and options of fsys and shares its
+// client, cache and request budget, which makes comparing the trees of two
+// refs cheap. The root of a Sub must exist at the ref too. fsys must be an
+// FS of this package.
+func At(fsys fs.FS, ref Ref) (fs.FS, error) {
+	b, ok := fsys.(*bbFS)
+	if !ok {
+		return nil, fmt.Errorf("%T has no refs: %w", fsys, ErrNotImplementedYet)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	return b.withRef(ref), nil
+}
+
+// withRef returns a copy of the FS at the ref, with the counters and
+// the signature check of its own.
+func (b *bbFS) withRef(ref Ref) *bbFS {
+	f := *b
+	f.at = ref
+	if f.limits != nil {
+		f.entries = new(atomic.Int64)
+	}
+	f.downloaded = new(atomic.Int64)
+	f.refCheck = &refCheck{}
+	if f.signature != nil {
+		f.signature = f.signature.fresh()
+	}
+	return &f
+}
+
+// Client returns the client shared by the FS values.
+func (r *Repo) Client() *server.Client {
+	return r.base.client
+}
+
 // WithLogger adds a logger to the FS.
 func WithLogger(l *slog.Logger) Option {
 	return func(f *bbFS) {
@@ -98,65 +201,258 @@ func WithDisabledCache() Option {
 	return WithMaxCachedItemSize(-1)
 }
 
+// WithListingCache sets the number of directory listings and file stats in
+// the cache, and how long they stay, apart from the file content. A short
+// ttl shows added and removed files sooner, without reading the content
+// again. A negative size disables caching listings and stats.
+func WithListingCache(size int, ttl time.Duration) Option {
+	return func(f *bbFS) {
+		f.client.MaxListingsInCache = size
+		f.client.ListingTTL = ttl
+	}
+}
+
+// WithCircuitBreaker stops sending requests when the error rate reaches threshold,
+// for the duration of coolDown.
+func WithCircuitBreaker(threshold float64, coolDown time.Duration) Option {
+	return func(f *bbFS) {
+		f.client.CircuitBreaker = server.NewCircuitBreaker(threshold, coolDown)
+	}
+}
+
+// WithHTTPClient sets the http client for the requests to the server.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(f *bbFS) {
+		f.client.HTTPClient = hc
+	}
+}
+
+// WithClientPool takes the client of the FS from the pool, so the FS values
+// for the same server and access key share a client and its cache.
+// Pass it before the options that change the client, they change the
+// client for all users of the pool with the same access key.
+// The pool sets the sizes of the caches, see WithPoolListingCache.
+func WithClientPool(pool *server.ClientPool) Option {
+	return func(f *bbFS) {
+		f.client = pool.Client(f.client.BaseURL, f.client.AccessKey)
+	}
+}
+
+// WithTokenSource gets the access token of the requests from ts instead of
+// the AccessKey of the configuration. onError, if not nil, is called when
+// ts fails.
+func WithTokenSource(ts server.TokenSource, onError func(error)) Option {
+	return func(f *bbFS) {
+		f.client.TokenSource = ts
+		f.client.OnTokenError = onError
+	}
+}
+
+// WithHeader adds a header to the requests to the server,
+// e.g. X-Forwarded-User for the audit log of a gateway.
+func WithHeader(key, value string) Option {
+	return func(f *bbFS) {
+		if f.client.Header == nil {
+			f.client.Header = http.Header{}
+		}
+		f.client.Header.Add(key, value)
+	}
+}
+
+// WithDownloadRetries resumes a file download that fails midway up to
+// retries times, with a request for the rest of the file. A file at a
+// branch or tag is then read at its commit, which takes one more request.
+func WithDownloadRetries(retries int) Option {
+	return func(f *bbFS) {
+		f.client.RawRetries = retries
+	}
+}
+
+// WithCompression asks the server for compressed responses,
+// which cuts the transfer size of large listings on slow links.
+func WithCompression() Option {
+	return func(f *bbFS) {
+		f.client.Compression = true
+	}
+}
+
+// WithUserAgent identifies the application in the User-Agent header of the
+// requests, after bbfs/VERSION, e.g. docs-portal/2.1, so the administrators
+// of the server can see which tool sends the traffic.
+func WithUserAgent(app string) Option {
+	return func(f *bbFS) {
+		f.client.UserAgent = app
+	}
+}
+
+// WithSlowRequestThreshold logs the requests that take longer than d at warn
+// level to the logger of the FS, to find out which calls make it slow.
+func WithSlowRequestThreshold(d time.Duration) Option {
+	return func(f *bbFS) {
+		f.client.SlowRequestThreshold = d
+	}
+}
+
+// WithRequestBudget limits the number of requests the FS sends to the server.
+// The budget is shared with the FS values returned by Sub.
+// Operations fail with server.ErrBudgetExceeded when the budget is spent.
+func WithRequestBudget(maxRequests int64) Option {
+	return func(f *bbFS) {
+		f.budget = server.NewBudget(maxRequests)
+	}
+}
+
 type bbFS struct {
 	client     *server.Client
 	projectKey string
 	repoSlug   string
 	accessKey  string
 	root       string
-	at         string
+	at         Ref
+	budget     *server.Budget
+	// filter hides paths, nil exposes all.
+	filter *pathFilter
+	// limits are the walk limits, nil for none.
+	limits *walkLimits
+	// entries counts the entries returned by ReadDir for limits.
+	entries *atomic.Int64
+	// downloaded counts the bytes of file content read from the server.
+	downloaded *atomic.Int64
+	// newlines are the line ends text files are converted to.
+	newlines Newline
+	// subtreeStats adds SubtreeStats to the directories from ReadDir.
+	subtreeStats bool
+	// signature checks the signature of the ref, nil for none.
+	signature *signatureCheck
+	// refCheck checks that the ref exists, nil for no check.
+	refCheck *refCheck
+	// err is the configuration error returned by Open.
+	err error
+}
+
+// requestContext returns the context for requests to the server.
+func (b *bbFS) requestContext() context.Context {
+	return b.withBudget(context.Background())
+}
+
+// withBudget returns ctx with the request budget of the FS, if any.
+func (b *bbFS) withBudget(ctx context.Context) context.Context {
+	if b.budget != nil {
+		ctx = server.ContextWithBudget(ctx, b.budget)
+	}
+	return ctx
+}
+
+// Project returns the project key.
+func (b *bbFS) Project() string {
+	return b.projectKey
+}
+
+// Repo returns the repository slug.
+func (b *bbFS) Repo() string {
+	return b.repoSlug
+}
+
+// Ref returns the ref of the FS.
+func (b *bbFS) Ref() Ref {
+	return b.at
+}
+
+// Root returns the root of the FS in the repository.
+func (b *bbFS) Root() string {
+	return b.root
+}
+
+// Client returns the client.
+func (b *bbFS) Client() *server.Client {
+	return b.client
 }
 
 // Sub returns a new FS with dir as root.
 func (b *bbFS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if dir == "." {
+		return b, nil
+	}
+
 	// check if the dir exists.
 	f, err := b.Open(dir)
 	if err != nil {
-		return nil, err
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			err = pe.Err
+		}
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: err}
 	}
+	defer f.Close()
 
 	fi, err := f.Stat()
 	if err != nil {
-		return nil, err
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: err}
 	}
 	if !fi.IsDir() {
-		return nil, fs.ErrInvalid
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
 	}
 
-	return &bbFS{
-		root:       filepath.Join(b.root, fi.Name()),
-		client:     b.client,
-		projectKey: b.projectKey,
-		repoSlug:   b.repoSlug,
-		accessKey:  b.accessKey,
-		at:         b.at,
-	}, nil
+	sub := *b
+	sub.root = path.Join(b.root, dir)
+	return &sub, nil
 }
 
-func isModeDir(t string) fs.FileMode {
-	if t == "DIRECTORY" {
+// fileMode returns the mode of a file in a listing. The permission bits
+// are 0, except the executable bits of an executable file.
+func fileMode(f *server.FileInfo) fs.FileMode {
+	switch {
+	case f.Type == "DIRECTORY":
 		return fs.ModeDir
+	case f.Executable:
+		return 0o111
 	}
 	return 0
 }
 
 // Open opens the file on the repository.
+//
+// It takes the type, size and mode from a cached listing of the parent
+// directory, otherwise it browses the path itself, which tells a file from
+// a directory in one request. The size and mode of a file are then fetched
+// by Stat.
 func (b *bbFS) Open(name string) (fs.File, error) {
+	f, err := b.open("open", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file for op. With list it reads the listing of the parent
+// directory when it is not cached, instead of browsing the path.
+func (b *bbFS) open(op, name string, list bool) (*bbFile, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{
 			Path: name,
-			Op:   "open",
+			Op:   op,
 			Err:  fs.ErrInvalid,
 		}
 	}
+	if b.err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: b.err}
+	}
+	if err := b.checkRef(); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: b.repoError(err)}
+	}
+	if err := b.checkSignature(); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
 
 	// Get the directory listing of the parent path.
-	fullPath := filepath.Join(b.root, name)
-	parent := filepath.Dir(fullPath)
-	base := filepath.Base(fullPath)
+	fullPath := path.Join(b.root, name)
+	parent := path.Dir(fullPath)
 
 	// Test if in root.
-	if fullPath == "." {
+	if name == "." {
 		return &bbFile{
 			fullPath: fullPath,
 			bfs:      b,
@@ -169,28 +465,28 @@ func (b *bbFS) Open(name string) (fs.File, error) {
 	if parent == "." {
 		parent = ""
 	}
-
-	// Check if the file exists in the directory.
-	iter, err := b.client.GetFilesIterator(context.Background(), &server.GetFilesCommand{
-		FilePath:   parent,
-		ProjectKey: b.projectKey,
-		RepoSlug:   b.repoSlug,
-		Limit:      1000,
-		At:         b.at,
-	})
-	if err != nil {
-		return nil, err
+	// Do not list the parent of an excluded path.
+	if b.filter.excluded(fullPath) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if err := b.limits.checkDepth(fullPath); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
 	}
 
+	// A cached listing of the parent has the file, otherwise browse the path.
+	ctx := b.requestContext()
 	var found *server.FileInfo
-	// Use the new iter over function
-	for f := range iter.Files() {
-		if f.Name == base {
-			found = f
-		}
+	var err error
+	if list || b.client.FilesCached(ctx, b.listCommand(parent)) {
+		found, err = b.listedFile(ctx, fullPath)
+	} else {
+		found, err = b.browsePath(ctx, fullPath)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: b.repoError(err)}
 	}
-	if found == nil {
-		return nil, fs.ErrNotExist
+	if found == nil || !b.filter.visible(fullPath, found.Type == "DIRECTORY") {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
 	}
 
 	// Create the file.
@@ -198,9 +494,10 @@ func (b *bbFS) Open(name string) (fs.File, error) {
 		fullPath: fullPath,
 		bfs:      b,
 		fi: &bbFileInfo{
-			name: found.Name,
-			mode: isModeDir(found.Type),
-			size: found.Size,
+			name:      found.Name,
+			mode:      fileMode(found),
+			size:      found.Size,
+			contentID: found.ContentID,
 		},
 	}
 	if res.IsDir() {
@@ -209,54 +506,295 @@ func (b *bbFS) Open(name string) (fs.File, error) {
 	return res, nil
 }
 
+// listedFile returns the entry of fullPath in the listing of its directory,
+// nil when the directory does not exist or does not have it.
+func (b *bbFS) listedFile(ctx context.Context, fullPath string) (*server.FileInfo, error) {
+	parent := path.Dir(fullPath)
+	if parent == "." {
+		parent = ""
+	}
+	iter, err := b.client.GetFilesIterator(ctx, b.listCommand(parent))
+	if server.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	base := path.Base(fullPath)
+	for f := range iter.Files() {
+		if f.Name == base {
+			return f, nil
+		}
+	}
+	if err := iter.Err(); !errors.Is(err, io.EOF) && !server.IsNotFound(err) {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// browsePath tells a file from a directory with one request on the browse
+// endpoint, which returns the lines of a file and the children of a
+// directory. The size of a file is -1, Stat gets it when needed.
+// It returns nil for a path that does not exist.
+func (b *bbFS) browsePath(ctx context.Context, fullPath string) (*server.FileInfo, error) {
+	_, err := b.client.GetFileLines(ctx, &server.GetFileLinesCommand{
+		FilePath:   fullPath,
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		At:         b.at,
+		Limit:      1,
+	})
+	switch {
+	case errors.Is(err, server.ErrIsDirectory):
+		return &server.FileInfo{Name: path.Base(fullPath), Type: "DIRECTORY"}, nil
+	case server.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return &server.FileInfo{Name: path.Base(fullPath), Type: "FILE", Size: -1}, nil
+}
+
+// listCommand returns the command for the listing of the directory in the repository.
+func (b *bbFS) listCommand(dir string) *server.GetFilesCommand {
+	return &server.GetFilesCommand{
+		FilePath:   dir,
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		Limit:      1000,
+		At:         b.at,
+	}
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+func (b *bbFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := b.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := f.(*bbFile).ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	if b.subtreeStats {
+		if err := b.addSubtreeStats(f.(*bbFile).fullPath, entries); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: b.repoError(err)}
+		}
+	}
+	return entries, nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (b *bbFS) ReadFile(name string) ([]byte, error) {
+	f, err := b.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Stat returns a FileInfo for the named file.
+//
+// It takes the info from the listing of the parent directory, which has the
+// size, the mode and the blob id of a file in one request and is cached for
+// the other files in the directory. So Stat, Open and ReadDir report the
+// same mode.
+func (b *bbFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := b.open("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// statRaw returns the info of a file with a HEAD request on the raw endpoint,
+// for FileFS, which does not list directories. The mode of the info has no
+// executable bits. It returns false when FileFS should use Open: for the
+// root, paths that Open rejects, a cached listing of the parent, paths that
+// are not a file and servers without FeatureHeadRaw.
+func (b *bbFS) statRaw(name string) (fs.FileInfo, bool, error) {
+	if !fs.ValidPath(name) || name == "." || b.err != nil || b.checkRef() != nil || b.checkSignature() != nil {
+		return nil, false, nil
+	}
+	fullPath := path.Join(b.root, name)
+	if !b.filter.visible(fullPath, false) || b.limits.checkDepth(fullPath) != nil {
+		return nil, false, nil
+	}
+	parent := path.Dir(fullPath)
+	if parent == "." {
+		parent = ""
+	}
+	ctx := b.requestContext()
+	if b.client.FilesCached(ctx, b.listCommand(parent)) || !b.client.Supports(server.FeatureHeadRaw) {
+		return nil, false, nil
+	}
+	info, err := b.client.StatRawFile(ctx, &server.StatRawFileCommand{
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		FilePath:   fullPath,
+		At:         b.at,
+	})
+	// A directory is not found on the raw endpoint, and an older server
+	// may not allow HEAD.
+	if server.IsNotFound(err) || !b.client.Supports(server.FeatureHeadRaw) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if info.Size < 0 {
+		return nil, false, nil
+	}
+	return &bbFileInfo{name: path.Base(fullPath), size: info.Size}, true, nil
+}
+
+// Tags returns all tags in the repository.
+func (b *bbFS) Tags(ctx context.Context) ([]*server.Tag, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.listTags(b.withBudget(ctx), "")
+}
+
 // bbFile implements fs.File.
+//
+// A file is safe for use by multiple goroutines: mu serializes Read, ReadDir,
+// Stat and Close, so the body is opened once and reads do not interleave.
 type bbFile struct {
 	bfs      *bbFS
 	fullPath string
 	fi       *bbFileInfo
 
-	data io.ReadCloser
+	mu     sync.Mutex
+	closed bool
+	data   io.ReadCloser
+	// text converts the line ends of data, nil to read data as is.
+	text io.Reader
 
 	dirIter *server.FilesIterator
 	lastErr error
 }
 
-// Read reads from the file.
+// Read reads from the file. It returns fs.ErrClosed after Close.
 func (f *bbFile) Read(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.fullPath, Err: fs.ErrClosed}
+	}
+	if f.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.fullPath, Err: errors.New("is a directory")}
+	}
 	if f.data != nil {
 		// read the data as a whole
-		return f.data.Read(b)
+		return f.readText(b)
+	}
+	// Do not start downloading a file that does not fit in the limit.
+	if f.fi.size < 0 && f.bfs.limits.limitsDownload() {
+		if err := f.stat(); err != nil {
+			return 0, err
+		}
+	}
+	if err := f.bfs.limits.checkDownload(f.bfs.downloaded, f.fullPath, max(f.fi.size, 0)); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.fullPath, Err: err}
 	}
 
-	r, err := f.bfs.client.OpenRawFile(context.Background(), &server.OpenRawFileCommand{
+	r, err := f.bfs.client.OpenRawFile(f.bfs.requestContext(), &server.OpenRawFileCommand{
 		ProjectKey: f.bfs.projectKey,
 		RepoSlug:   f.bfs.repoSlug,
 		FilePath:   f.fullPath,
 		At:         f.bfs.at,
 	})
 	if err != nil {
-		return 0, err
+		return 0, &fs.PathError{Op: "read", Path: f.fullPath, Err: f.bfs.repoError(err)}
 	}
 	f.data = r
-	return f.data.Read(b)
+	if f.bfs.newlines != NewlineKeep {
+		f.text = newNewlineReader(readFunc(f.read), f.bfs.newlines)
+	}
+	return f.readText(b)
+}
+
+// readText reads from the data, converting the line ends if needed.
+func (f *bbFile) readText(b []byte) (int, error) {
+	if f.text != nil {
+		return f.text.Read(b)
+	}
+	return f.read(b)
+}
+
+// read reads from the data and counts the downloaded bytes.
+func (f *bbFile) read(b []byte) (int, error) {
+	n, err := f.data.Read(b)
+	if lerr := f.bfs.limits.addDownload(f.bfs.downloaded, f.fullPath, n); lerr != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.fullPath, Err: lerr}
+	}
+	return n, err
 }
 
 // Stat returns a FileInfo.
+// It gets the size and mode of a file opened without a listing of its
+// directory from that listing.
 func (f *bbFile) Stat() (fs.FileInfo, error) {
-	return f.fi, nil
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.stat(); err != nil {
+		return nil, err
+	}
+	// A copy, the size of the file may still change.
+	fi := *f.fi
+	return &fi, nil
 }
 
+// stat gets the size and mode of the file if unknown, f.mu must be held.
+func (f *bbFile) stat() error {
+	if f.fi.size >= 0 || f.IsDir() {
+		return nil
+	}
+	found, err := f.bfs.listedFile(f.bfs.requestContext(), f.fullPath)
+	if err == nil && found == nil {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return &fs.PathError{Op: "stat", Path: f.fullPath, Err: f.bfs.repoError(err)}
+	}
+	f.fi.size = found.Size
+	f.fi.mode = fileMode(found)
+	f.fi.contentID = found.ContentID
+	return nil
+}
+
+// Close closes the file, closing it again does nothing.
 func (f *bbFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
 	if f.data == nil {
 		return nil
 	}
 	tmp := f.data
 	f.data = nil
+	f.text = nil
 	return tmp.Close()
 }
 
-// ReadDir returns an array of DirEntry's.
+// ReadDir returns an array of DirEntry's. It returns fs.ErrClosed after Close.
 func (f *bbFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: f.fullPath, Err: fs.ErrClosed}
+	}
+	if !f.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.fullPath, Err: errors.New("not a directory")}
+	}
 	if f.lastErr != nil {
 		return nil, f.lastErr
 	}
@@ -265,52 +803,71 @@ func (f *bbFile) ReadDir(n int) ([]fs.DirEntry, error) {
 		fullPath = ""
 	}
 	if f.dirIter == nil {
-		iter, err := f.bfs.client.GetFilesIterator(context.Background(), &server.GetFilesCommand{
+		if err := f.bfs.limits.checkListing(f.fullPath); err != nil {
+			f.lastErr = &fs.PathError{Op: "readdir", Path: f.fullPath, Err: err}
+			return nil, f.lastErr
+		}
+		iter, err := f.bfs.client.GetFilesIterator(f.bfs.requestContext(), &server.GetFilesCommand{
 			FilePath:   fullPath,
 			ProjectKey: f.bfs.projectKey,
 			RepoSlug:   f.bfs.repoSlug,
 			Limit:      1000,
 			At:         f.bfs.at,
 		})
+		if server.IsNotFound(err) && f.fullPath == path.Join(f.bfs.root, ".") {
+			err = f.bfs.rootNotFound(f.bfs.requestContext(), err)
+			if err == nil {
+				// The default branch of an empty repository.
+				if n > 0 {
+					return []fs.DirEntry{}, io.EOF
+				}
+				return []fs.DirEntry{}, nil
+			}
+		}
 		if err != nil {
-			return nil, err
+			return nil, &fs.PathError{Op: "readdir", Path: f.fullPath, Err: f.bfs.repoError(err)}
 		}
 		f.dirIter = iter
 	}
 
 	res := []fs.DirEntry{}
-	var i int
-	for {
-		// Check if done
-		if n > 0 && i == n {
-			return res, nil
-		}
-
+	for n <= 0 || len(res) < n {
 		ff := f.dirIter.Next()
 		if ff == nil {
-			if !errors.Is(f.dirIter.Err(), io.EOF) {
-				f.lastErr = f.dirIter.Err()
-				return res, f.dirIter.Err()
+			if err := f.dirIter.Err(); !errors.Is(err, io.EOF) {
+				f.lastErr = &fs.PathError{Op: "readdir", Path: f.fullPath, Err: f.bfs.repoError(err)}
+				return res, f.lastErr
 			}
-			if len(res) == 0 {
-				f.lastErr = io.EOF
-			}
-			return res, nil
+			break
 		}
-
-		bf := &bbFile{
-			fullPath: filepath.Join(f.fullPath, ff.Name),
-			fi: &bbFileInfo{
-				name: ff.Name,
-				mode: isModeDir(ff.Type),
-				size: ff.Size,
-			},
-		}
-		if bf.IsDir() {
-			bf.fi.mode = fs.ModeDir
+		e := f.newEntry(ff)
+		if !f.bfs.filter.visible(e.fullPath, e.IsDir()) {
+			continue
 		}
-		res = append(res, bf)
-		i++
+		res = append(res, e)
+	}
+	if err := f.bfs.limits.addEntries(f.bfs.entries, f.fullPath, len(res)); err != nil {
+		f.lastErr = &fs.PathError{Op: "readdir", Path: f.fullPath, Err: err}
+		return nil, f.lastErr
+	}
+	// Only signal the end of the directory when asked for a limited number of entries.
+	if n > 0 && len(res) == 0 {
+		return res, io.EOF
+	}
+	return res, nil
+}
+
+// newEntry returns the file for an entry in the directory.
+func (f *bbFile) newEntry(ff *server.FileInfo) *bbFile {
+	return &bbFile{
+		bfs:      f.bfs,
+		fullPath: path.Join(f.fullPath, ff.Name),
+		fi: &bbFileInfo{
+			name:      ff.Name,
+			mode:      fileMode(ff),
+			size:      ff.Size,
+			contentID: ff.ContentID,
+		},
 	}
 }
 
@@ -320,6 +877,10 @@ type bbFileInfo struct {
 	size    int64
 	mode    fs.FileMode
 	modTime time.Time
+	// contentID is the id of the git blob of a file from a listing.
+	contentID string
+	// sys is returned by Sys.
+	sys any
 }
 
 // Name returns the name of the file.
@@ -347,6 +908,13 @@ func (b *bbFileInfo) ModTime() time.Time {
 	return b.modTime
 }
 
+// ContentID returns the id of the git blob of the file, it changes with
+// the content. It is empty for directories and for files that were not
+// looked up in a listing of their directory.
+func (b *bbFileInfo) ContentID() string {
+	return b.contentID
+}
+
 // IsDir returns true if the file is a directory.
 func (b *bbFileInfo) IsDir() bool {
 	return b.mode.IsDir()
@@ -356,17 +924,22 @@ func (b *bbFile) IsDir() bool {
 	return b.fi.IsDir()
 }
 
+// Sys returns the SubtreeStats of a directory from ReadDir of an FS with
+// WithSubtreeStats, nil otherwise.
 func (b *bbFileInfo) Sys() any {
-	return nil
+	return b.sys
 }
 
 func (f *bbFile) Type() fs.FileMode {
-	return f.fi.mode
+	return f.fi.mode.Type()
 }
 
 func (f *bbFile) Info() (fs.FileInfo, error) {
 	return f.fi, nil
 }
 
+var _ Bitbucket = &bbFS{}
+var _ BitbucketFS = &bbFS{}
+var _ fs.SubFS = &bbFS{}
 var _ fs.DirEntry = &bbFile{}
 var _ fs.ReadDirFile = &bbFile{}
@@ -1,11 +1,22 @@
 package bbfs
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log/slog"
+	"maps"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
 )
 
 const (
@@ -13,10 +24,10 @@ const (
 )
 
 var testCfg = &Config{
-	Host: "bitbucket.org",
-	ProjectKey: "myhops",
+	Host:           "bitbucket.org",
+	ProjectKey:     "myhops",
 	RepositorySlug: "testflags",
-	AccessKey: accessKey,
+	AccessKey:      accessKey,
 }
 
 func TestMe(t *testing.T) {
@@ -56,3 +67,604 @@ func TestGlob(t *testing.T) {
 	t.Logf("%#v", matches)
 	// t.Error()
 }
+
+// newFakeFS returns an FS for the files on a fake server.
+func newFakeFS(t *testing.T, files fstest.MapFS, opts ...Option) fs.FS {
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(files))
+
+	return NewFS(&Config{
+		BaseURL:        srv.BaseURL(),
+		ProjectKey:     "PRJ",
+		RepositorySlug: "repo",
+	}, opts...)
+}
+
+func TestSpecialNames(t *testing.T) {
+	files := fstest.MapFS{
+		"dir with space/file 1.txt": {Data: []byte("space")},
+		"plus+sign.txt":             {Data: []byte("plus")},
+		"percent%20.txt":            {Data: []byte("percent")},
+		"hash#tag.md":               {Data: []byte("hash")},
+		"question?.txt":             {Data: []byte("question")},
+		"ünïcödé/日本語.txt":           {Data: []byte("unicode")},
+		"a/b/c/deep.txt":            {Data: []byte("deep")},
+	}
+	bfs := newFakeFS(t, files)
+
+	var names []string
+	for name, f := range files {
+		names = append(names, name)
+		data, err := fs.ReadFile(bfs, name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != string(f.Data) {
+			t.Errorf("%s: expected %q, got %q", name, f.Data, data)
+		}
+	}
+	if err := fstest.TestFS(bfs, names...); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	if _, err := bfs.Open("not there.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+	if _, err := bfs.Open("no dir/file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, cfg := range []*Config{
+		{BaseURL: "/rest/api/latest", ProjectKey: "PRJ", RepositorySlug: "repo"},
+		{BaseURL: "ftp://host/rest/api/latest", ProjectKey: "PRJ", RepositorySlug: "repo"},
+		{Host: "host"},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+		if _, err := NewFS(cfg).Open("file.txt"); err == nil {
+			t.Errorf("%+v: expected an error from Open", cfg)
+		}
+	}
+}
+
+func TestSub(t *testing.T) {
+	bfs := newFakeFS(t, fstest.MapFS{
+		"a/b/c/file.txt":   {Data: []byte("file")},
+		"a/b/c/d/deep.txt": {Data: []byte("deep")},
+		"a/b/other.txt":    {Data: []byte("other")},
+		"top.txt":          {Data: []byte("top")},
+	})
+
+	sub, err := fs.Sub(bfs, "a/b/c")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := fstest.TestFS(sub, "file.txt", "d/deep.txt"); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	// Sub of a Sub.
+	sub2, err := fs.Sub(bfs, "a")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	sub2, err = fs.Sub(sub2, "b/c/d")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := fstest.TestFS(sub2, "deep.txt"); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	var pe *fs.PathError
+	if _, err := fs.Sub(bfs, "a/x"); !errors.As(err, &pe) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected PathError with ErrNotExist, got %v", err)
+	}
+	if _, err := fs.Sub(bfs, "top.txt"); !errors.As(err, &pe) {
+		t.Errorf("expected PathError, got %v", err)
+	}
+}
+
+func TestBitbucketFS(t *testing.T) {
+	bfs := newFakeFS(t, fstest.MapFS{
+		"b.txt":     {Data: []byte("b")},
+		"a.txt":     {Data: []byte("a")},
+		"dir/c.txt": {Data: []byte("c")},
+	}).(BitbucketFS)
+
+	entries, err := bfs.ReadDir(".")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "a.txt,b.txt,dir" {
+		t.Errorf("unexpected entries %v", names)
+	}
+	fi, err := bfs.Stat("dir/c.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if fi.Size() != 1 {
+		t.Errorf("expected size 1, got %d", fi.Size())
+	}
+	if _, err := bfs.Tags(context.Background()); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+}
+
+func TestRepoFS(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"version.txt": {Data: []byte("1")}})
+	c := repo.Commit("main", "second", fstest.MapFS{"version.txt": {Data: []byte("2")}})
+	repo.Tag("v1", repo.Commits[0])
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+	tests := []struct {
+		ref  Ref
+		want string
+	}{
+		{"", "2"},
+		{TagRef("v1"), "1"},
+		{CommitRef(c.ID), "2"},
+		{BranchRef("main"), "2"},
+	}
+	for _, tt := range tests {
+		fsys := r.FS(tt.ref)
+		data, err := fs.ReadFile(fsys, "version.txt")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.ref, tt.want, data)
+		}
+		if fsys.(Bitbucket).Client() != r.Client() {
+			t.Errorf("%q: FS does not share the client", tt.ref)
+		}
+	}
+
+	// The file at the default ref comes from the shared cache.
+	before := srv.Requests()
+	if _, err := fs.ReadFile(r.FS(""), "version.txt"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if srv.Requests() != before {
+		t.Errorf("expected no requests, got %d", srv.Requests()-before)
+	}
+
+	if _, err := r.FS("bad..ref").Open("version.txt"); err == nil {
+		t.Errorf("expected an error for an invalid ref")
+	}
+}
+
+func TestAt(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"docs/version.txt": {Data: []byte("1")}})
+	repo.Tag("v1", repo.Commits[0])
+	repo.Commit("main", "second", fstest.MapFS{"docs/version.txt": {Data: []byte("2")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: TagRef("v1")})
+	sub, err := fs.Sub(fsys, "docs")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	main, err := At(sub, BranchRef("main"))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	for _, tt := range []struct {
+		fsys fs.FS
+		want string
+	}{{sub, "1"}, {main, "2"}} {
+		data, err := fs.ReadFile(tt.fsys, "version.txt")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, data)
+		}
+	}
+	if main.(Bitbucket).Client() != fsys.(Bitbucket).Client() || main.(Bitbucket).Root() != "docs" {
+		t.Errorf("expected the client and root of the FS")
+	}
+
+	if _, err := At(fsys, "bad..ref"); err == nil {
+		t.Errorf("expected an error for an invalid ref")
+	}
+	if _, err := At(fstest.MapFS{}, BranchRef("main")); !errors.Is(err, ErrNotImplementedYet) {
+		t.Errorf("expected ErrNotImplementedYet, got %v", err)
+	}
+}
+
+func TestRepoError(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	// The file opens, reading it fails.
+	srv.Disable("GET", "raw")
+	id := repo.Commits[0].ID
+	_, err := fs.ReadFile(r.FS(CommitRef(id)), "a.txt")
+	var re *RepoError
+	if !errors.As(err, &re) || re.Commit != id {
+		t.Errorf("expected a RepoError with commit %s, got %v", id, err)
+	}
+
+	srv.Disable("GET", "browse")
+	_, err = r.FS(BranchRef("main")).Open("a.txt")
+	if !errors.As(err, &re) {
+		t.Fatalf("expected a RepoError, got %v", err)
+	}
+	// The error has the commit the branch resolved to.
+	if re.Project != "PRJ" || re.Repo != "repo" || re.Ref != BranchRef("main") || re.Commit != id {
+		t.Errorf("unexpected coordinates %+v", re)
+	}
+	var se *server.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected the status error, got %v", err)
+	}
+	if want := "open a.txt: PRJ/repo at refs/heads/main (" + id + "): "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestStatRaw(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"dir/c.txt": {Data: []byte("hello")},
+	}))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	// A file takes a single request for the listing of its directory.
+	before := srv.Requests()
+	fi, err := fs.Stat(fsys, "dir/c.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if fi.Name() != "c.txt" || fi.Size() != 5 || fi.IsDir() {
+		t.Errorf("unexpected info %s %d %v", fi.Name(), fi.Size(), fi.IsDir())
+	}
+	if n := srv.Requests() - before; n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+
+	fi, err = fs.Stat(fsys, "dir")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if !fi.IsDir() {
+		t.Errorf("expected a directory")
+	}
+	if _, err := fs.Stat(fsys, "dir/missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+
+	// Stat does not need HEAD on raw, the second Stat uses the cached listing.
+	srv.Disable("HEAD", "raw")
+	fsys = NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+	for _, want := range []int64{1, 0} {
+		before := srv.Requests()
+		fi, err := fs.Stat(fsys, "dir/c.txt")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if fi.Name() != "c.txt" || fi.Size() != 5 {
+			t.Errorf("unexpected info %s %d", fi.Name(), fi.Size())
+		}
+		if n := srv.Requests() - before; n != want {
+			t.Errorf("expected %d requests, got %d", want, n)
+		}
+	}
+}
+
+func TestOpenBrowsePath(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"a/b/c/d.txt": {Data: []byte("hello")},
+		"a/b/e.txt":   {Data: []byte("e")},
+	}))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	tests := []struct {
+		name    string
+		isDir   bool
+		missing bool
+	}{
+		{name: "a/b/c/d.txt"},
+		{name: "a/b/c", isDir: true},
+		{name: "a/b/missing.txt", missing: true},
+	}
+	for _, tt := range tests {
+		// The path itself is browsed, not its parent.
+		before := srv.Requests()
+		f, err := fsys.Open(tt.name)
+		if n := srv.Requests() - before; n != 1 {
+			t.Errorf("%s: expected 1 request, got %d", tt.name, n)
+		}
+		if tt.missing {
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s: expected ErrNotExist, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		fi, err := f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if fi.IsDir() != tt.isDir || (!tt.isDir && fi.Size() != 5) {
+			t.Errorf("%s: unexpected info dir %v size %d", tt.name, fi.IsDir(), fi.Size())
+		}
+	}
+
+	// A cached listing of the parent answers without a request.
+	if _, err := fs.ReadDir(fsys, "a/b"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	before := srv.Requests()
+	f, err := fsys.Open("a/b/e.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if fi, _ := f.Stat(); fi.Size() != 1 {
+		t.Errorf("expected size 1 from the listing, got %d", fi.Size())
+	}
+	f.Close()
+	if n := srv.Requests() - before; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
+
+func TestOpenLargeDirectory(t *testing.T) {
+	files := fstest.MapFS{}
+	for i := range 2500 {
+		files[fmt.Sprintf("big/f%04d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(files))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	// Files after the first 1000 entries exist, with and without a cached listing.
+	for range 2 {
+		for _, name := range []string{"big/f0000.txt", "big/f1500.txt", "big/f2499.txt"} {
+			if _, err := fs.Stat(fsys, name); err != nil {
+				t.Errorf("error: %s", err.Error())
+			}
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil || string(data) != "x" {
+				t.Errorf("%s: unexpected content %q, error %v", name, data, err)
+			}
+		}
+		if _, err := fsys.Open("big/f2500.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected ErrNotExist, got %v", err)
+		}
+		entries, err := fs.ReadDir(fsys, "big")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if len(entries) != 2500 {
+			t.Errorf("expected 2500 entries, got %d", len(entries))
+		}
+	}
+}
+
+func TestAccessKeySecret(t *testing.T) {
+	t.Setenv("BBFS_TEST_TOKEN", "secret")
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.SetAccessKey("secret")
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+
+	fsys := NewFS(&Config{
+		BaseURL:         srv.BaseURL(),
+		ProjectKey:      "PRJ",
+		RepositorySlug:  "repo",
+		AccessKeySecret: "env:BBFS_TEST_TOKEN",
+	})
+	data, err := fs.ReadFile(fsys, "a.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "a" {
+		t.Errorf("expected a, got %q", data)
+	}
+}
+
+func TestFileConcurrency(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"dir/a.txt": {Data: []byte(content)},
+		"dir/b.txt": {Data: []byte("b")},
+	}))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+
+	f, err := fsys.Open("dir/a.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	// The goroutines share the file, together they read it once.
+	var wg sync.WaitGroup
+	var total atomic.Int64
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 100)
+			for {
+				n, err := f.Read(buf)
+				total.Add(int64(n))
+				if err != nil {
+					return
+				}
+				if _, err := f.Stat(); err != nil {
+					t.Errorf("error: %s", err.Error())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if total.Load() != int64(len(content)) {
+		t.Errorf("expected %d bytes, got %d", len(content), total.Load())
+	}
+
+	for range 2 {
+		if err := f.Close(); err != nil {
+			t.Errorf("expected Close to be idempotent, got %v", err)
+		}
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	d, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	d.Close()
+	if _, err := d.(fs.ReadDirFile).ReadDir(-1); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestExecutable(t *testing.T) {
+	files := fstest.MapFS{
+		"run.sh":     {Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+		"README.md":  {Data: []byte("# readme\n"), Mode: 0o644},
+		"bin/run.sh": {Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+	}
+	fsys := newFakeFS(t, files)
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	modes := map[string]fs.FileMode{}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		modes[e.Name()] = fi.Mode()
+	}
+	want := map[string]fs.FileMode{"README.md": 0, "bin": fs.ModeDir, "run.sh": 0o111}
+	if !maps.Equal(modes, want) {
+		t.Errorf("expected modes %v, got %v", want, modes)
+	}
+	for _, e := range entries {
+		if e.Type() != want[e.Name()].Type() {
+			t.Errorf("%s: expected type %v, got %v", e.Name(), want[e.Name()].Type(), e.Type())
+		}
+	}
+
+	// Stat and Open of a new FS, without the listings in the cache, report
+	// the same modes as the listings.
+	for _, name := range []string{"bin/run.sh", "run.sh"} {
+		cold := newFakeFS(t, files)
+		fi, err := fs.Stat(cold, name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if fi.Mode() != 0o111 {
+			t.Errorf("stat %s: expected mode %v, got %v", name, fs.FileMode(0o111), fi.Mode())
+		}
+		f, err := newFakeFS(t, files).Open(name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		fi, err = f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if fi.Mode() != 0o111 {
+			t.Errorf("open %s: expected mode %v, got %v", name, fs.FileMode(0o111), fi.Mode())
+		}
+	}
+	if err := fstest.TestFS(fsys, "run.sh", "README.md", "bin/run.sh"); err != nil {
+		t.Errorf("error: %s", err.Error())
+	}
+
+	// Export keeps the executable bits of the files in the listings.
+	exported, err := Export(context.Background(), fsys)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if exported["bin/run.sh"].Mode&0o111 == 0 || exported["README.md"].Mode&0o111 != 0 {
+		t.Errorf("unexpected modes %v and %v", exported["bin/run.sh"].Mode, exported["README.md"].Mode)
+	}
+}
+
+func TestEmptyRepo(t *testing.T) {
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+	srv.AddRepo("PRJ", "empty", &fakeserver.Repo{Branches: map[string]string{}, Tags: map[string]string{}, DefaultBranch: "main"})
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"README.md": {Data: []byte("# readme\n")}}))
+	newFS := func(repo string, ref Ref, root string) fs.FS {
+		return NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: repo, At: ref, Root: root})
+	}
+
+	// The default branch of an empty repository has no files.
+	fsys := newFS("empty", "", "")
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(entries) != 0 {
+		t.Errorf("unexpected entries %v", entries)
+	}
+	if err := fstest.TestFS(fsys); err != nil {
+		t.Errorf("error: %s", err.Error())
+	}
+	if _, err := fs.Stat(fsys, "README.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		fsys  fs.FS
+		want  []error
+		inMsg string
+	}{
+		{"empty at a branch", newFS("empty", BranchRef("main"), ""), []error{ErrEmptyRepository, ErrRefNotFound}, "refs/heads/main"},
+		{"missing ref", newFS("repo", "missing", ""), []error{ErrRefNotFound}, "missing"},
+		{"missing root", newFS("repo", "", "docs"), nil, "docs"},
+	}
+	for _, tt := range tests {
+		_, err := fs.ReadDir(tt.fsys, ".")
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: expected %v, got %v", tt.name, want, err)
+			}
+		}
+		if tt.want == nil && errors.Is(err, ErrRefNotFound) {
+			t.Errorf("%s: unexpected %v", tt.name, err)
+		}
+		if !strings.Contains(err.Error(), tt.inMsg) {
+			t.Errorf("%s: expected %q in %q", tt.name, tt.inMsg, err)
+		}
+	}
+}
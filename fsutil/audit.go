@@ -0,0 +1,120 @@
+package fsutil
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/myhops/bbfs"
+)
+
+// AuditRecord describes a read from an FS returned by AuditFS.
+type AuditRecord struct {
+	Time time.Time
+	// Op is OpOpen, OpReadFile or OpReadDir.
+	Op string
+	// Path is the path in the FS.
+	Path string
+	// Project, Repo, Ref and RepoPath locate the path in the repository
+	// when the FS is a bbfs.Bitbucket, they are empty otherwise.
+	Project  string
+	Repo     string
+	Ref      string
+	RepoPath string
+	// Labels are the labels passed to AuditFS.
+	Labels map[string]string
+	// Err is the error of the operation.
+	Err error
+}
+
+// Auditor receives the records of an FS returned by AuditFS.
+type Auditor func(AuditRecord)
+
+// SlogAuditor returns an Auditor that logs the records at info level.
+func SlogAuditor(logger *slog.Logger) Auditor {
+	return func(r AuditRecord) {
+		attrs := []slog.Attr{
+			slog.String("op", r.Op),
+			slog.String("path", r.Path),
+		}
+		if r.Repo != "" {
+			attrs = append(attrs,
+				slog.String("project", r.Project),
+				slog.String("repo", r.Repo),
+				slog.String("ref", r.Ref),
+				slog.String("repoPath", r.RepoPath),
+			)
+		}
+		for _, k := range slices.Sorted(maps.Keys(r.Labels)) {
+			attrs = append(attrs, slog.String(k, r.Labels[k]))
+		}
+		if r.Err != nil {
+			attrs = append(attrs, slog.String("error", r.Err.Error()))
+		}
+		logger.LogAttrs(context.Background(), slog.LevelInfo, "fs read", attrs...)
+	}
+}
+
+// AuditFS returns a read only FS that records every open, read and listing
+// of fsys with the labels.
+//
+// The files of the FS only have the methods of fs.ReadDirFile, other methods
+// of the files of fsys, like Write, can not be reached through it.
+func AuditFS(fsys fs.FS, audit Auditor, labels map[string]string) fs.FS {
+	a := &auditFS{
+		audit:  audit,
+		labels: maps.Clone(labels),
+	}
+	a.observedFS = &observedFS{
+		fsys:    fsys,
+		observe: a.observer(fsys),
+	}
+	return a
+}
+
+type auditFS struct {
+	*observedFS
+	audit  Auditor
+	labels map[string]string
+}
+
+// observer returns the observer that records the reads from fsys.
+func (a *auditFS) observer(fsys fs.FS) Observer {
+	bb, _ := fsys.(bbfs.Bitbucket)
+	return func(op, name string, elapsed time.Duration, err error) {
+		switch op {
+		case OpOpen, OpReadFile, OpReadDir:
+		default:
+			return
+		}
+		r := AuditRecord{
+			Time:   time.Now(),
+			Op:     op,
+			Path:   name,
+			Labels: a.labels,
+			Err:    err,
+		}
+		if bb != nil {
+			r.Project = bb.Project()
+			r.Repo = bb.Repo()
+			r.Ref = bb.Ref().String()
+			r.RepoPath = path.Join(bb.Root(), name)
+		}
+		a.audit(r)
+	}
+}
+
+// Sub implements fs.SubFS, the sub FS is audited with the same labels.
+func (a *auditFS) Sub(dir string) (fs.FS, error) {
+	sub, err := fs.Sub(a.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return AuditFS(sub, a.audit, a.labels), nil
+}
+
+var _ fs.SubFS = &auditFS{}
@@ -0,0 +1,115 @@
+package fsutil
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+// writableFS returns files with a Write method.
+type writableFS struct {
+	fstest.MapFS
+}
+
+type writableFile struct {
+	fs.File
+}
+
+func (f writableFile) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w writableFS) Open(name string) (fs.File, error) {
+	f, err := w.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return writableFile{f}, nil
+}
+
+func TestAuditFS(t *testing.T) {
+	var records []AuditRecord
+	fsys := AuditFS(writableFS{fstest.MapFS{
+		"dir/a.txt": {Data: []byte("a")},
+	}}, func(r AuditRecord) {
+		records = append(records, r)
+	}, map[string]string{"user": "alice"})
+
+	f, err := fsys.Open("dir/a.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if _, ok := f.(io.Writer); ok {
+		t.Errorf("file of AuditFS is writable")
+	}
+	io.ReadAll(f)
+	f.Close()
+
+	sub, err := fs.Sub(fsys, "dir")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	fs.ReadFile(sub, "a.txt")
+	fs.ReadFile(sub, "missing.txt")
+
+	want := []struct {
+		op, path string
+		failed   bool
+	}{
+		{OpOpen, "dir/a.txt", false},
+		{OpReadFile, "a.txt", false},
+		{OpReadFile, "missing.txt", true},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %d: %+v", len(want), len(records), records)
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.Op != w.op || r.Path != w.path || (r.Err != nil) != w.failed || r.Labels["user"] != "alice" {
+			t.Errorf("expected %+v, got %+v", w, r)
+		}
+	}
+}
+
+func TestSlogAuditor(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	fsys := AuditFS(fstest.MapFS{"a.txt": {Data: []byte("a")}}, SlogAuditor(logger), map[string]string{"user": "alice"})
+	fs.ReadFile(fsys, "a.txt")
+	if got := buf.String(); !strings.Contains(got, `msg="fs read" op=readfile path=a.txt user=alice`) {
+		t.Errorf("unexpected log %q", got)
+	}
+}
+
+func TestAuditFSRepository(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{"conf/app.yaml": {Data: []byte("a")}}))
+
+	var records []AuditRecord
+	fsys := AuditFS(bbfs.NewFS(&bbfs.Config{
+		BaseURL:        srv.BaseURL(),
+		ProjectKey:     "PRJ",
+		RepositorySlug: "repo",
+		Root:           "conf",
+		At:             bbfs.BranchRef("main"),
+	}), func(r AuditRecord) { records = append(records, r) }, nil)
+
+	if _, err := fs.ReadFile(fsys, "app.yaml"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r.Project != "PRJ" || r.Repo != "repo" || r.Ref != "refs/heads/main" || r.RepoPath != "conf/app.yaml" {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
@@ -0,0 +1,4 @@
+/*
+fsutil contains wrappers that add logging, metrics and auditing to any fs.FS.
+*/
+package fsutil
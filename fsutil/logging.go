@@ -0,0 +1,26 @@
+package fsutil
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// LoggingFS returns an FS that logs every operation on fsys.
+// Successful operations are logged at debug level, failures at warn level.
+func LoggingFS(fsys fs.FS, logger *slog.Logger) fs.FS {
+	return Wrap(fsys, func(op, name string, elapsed time.Duration, err error) {
+		level := slog.LevelDebug
+		attrs := []slog.Attr{
+			slog.String("op", op),
+			slog.String("name", name),
+			slog.Duration("elapsed", elapsed),
+		}
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(context.Background(), level, "fs operation", attrs...)
+	})
+}
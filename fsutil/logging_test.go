@@ -0,0 +1,90 @@
+package fsutil
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// loggedOp is an operation logged by LoggingFS.
+type loggedOp struct {
+	level slog.Level
+	op    string
+	name  string
+	err   string
+}
+
+// recordingHandler keeps the operations logged to it.
+type recordingHandler struct {
+	mu  sync.Mutex
+	ops []loggedOp
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	op := loggedOp{level: r.Level}
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case "op":
+			op.op = a.Value.String()
+		case "name":
+			op.name = a.Value.String()
+		case "error":
+			op.err = a.Value.String()
+		}
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ops = append(h.ops, op)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *recordingHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func TestLoggingFS(t *testing.T) {
+	h := &recordingHandler{}
+	fsys := LoggingFS(fstest.MapFS{
+		"dir/a.txt": {Data: []byte("a")},
+	}, slog.New(h))
+
+	if _, err := fs.ReadFile(fsys, "dir/a.txt"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if _, err := fs.ReadDir(fsys, "dir"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if _, err := fs.Stat(fsys, "missing.txt"); err == nil {
+		t.Fatalf("expected an error for missing.txt")
+	}
+
+	want := []loggedOp{
+		{level: slog.LevelDebug, op: OpReadFile, name: "dir/a.txt"},
+		{level: slog.LevelDebug, op: OpReadDir, name: "dir"},
+		{level: slog.LevelWarn, op: OpStat, name: "missing.txt"},
+	}
+	if len(h.ops) != len(want) {
+		t.Fatalf("expected %d operations, got %v", len(want), h.ops)
+	}
+	for i, w := range want {
+		got := h.ops[i]
+		if got.level != w.level || got.op != w.op || got.name != w.name {
+			t.Errorf("operation %d: expected %v, got %v", i, w, got)
+		}
+		if (got.err != "") != (w.level == slog.LevelWarn) {
+			t.Errorf("operation %d: unexpected error %q", i, got.err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package fsutil
+
+import (
+	"io/fs"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Stats contains the totals for an operation or a file.
+type Stats struct {
+	Count  int64
+	Errors int64
+	// Total is the total time spent.
+	Total time.Duration
+	// Max is the longest time spent in a single call.
+	Max time.Duration
+}
+
+func (s *Stats) add(elapsed time.Duration, err error) {
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += elapsed
+	s.Max = max(s.Max, elapsed)
+}
+
+// Metrics collects the latency and errors per operation and per file.
+// The zero value is ready to use.
+type Metrics struct {
+	mu    sync.Mutex
+	ops   map[string]*Stats
+	files map[string]*Stats
+}
+
+// Observe records an operation, it is an Observer.
+func (m *Metrics) Observe(op, name string, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ops == nil {
+		m.ops = map[string]*Stats{}
+		m.files = map[string]*Stats{}
+	}
+	get(m.ops, op).add(elapsed, err)
+	get(m.files, name).add(elapsed, err)
+}
+
+func get(m map[string]*Stats, key string) *Stats {
+	s, ok := m[key]
+	if !ok {
+		s = &Stats{}
+		m[key] = s
+	}
+	return s
+}
+
+// Operations returns the stats per operation.
+func (m *Metrics) Operations() map[string]Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return snapshot(m.ops)
+}
+
+// Files returns the stats per file, summed over all operations.
+func (m *Metrics) Files() map[string]Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return snapshot(m.files)
+}
+
+// Reset clears the collected metrics.
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops = nil
+	m.files = nil
+}
+
+func snapshot(m map[string]*Stats) map[string]Stats {
+	res := make(map[string]Stats, len(m))
+	for k, v := range maps.All(m) {
+		res[k] = *v
+	}
+	return res
+}
+
+// MetricsFS returns an FS that records the operations on fsys in m.
+func MetricsFS(fsys fs.FS, m *Metrics) fs.FS {
+	return Wrap(fsys, m.Observe)
+}
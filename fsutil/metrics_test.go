@@ -0,0 +1,36 @@
+package fsutil
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMetricsFS(t *testing.T) {
+	base := fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"dir/b.txt": {Data: []byte("b")},
+	}
+	m := &Metrics{}
+	fsys := MetricsFS(base, m)
+
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	ops := m.Operations()
+	if ops[OpOpen].Count == 0 || ops[OpRead].Count == 0 {
+		t.Errorf("expected open and read operations, got %v", ops)
+	}
+	if m.Files()["a.txt"].Count == 0 {
+		t.Errorf("expected operations on a.txt")
+	}
+
+	m.Reset()
+	if _, err := fs.ReadFile(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+	if n := m.Operations()[OpReadFile].Errors; n != 1 {
+		t.Errorf("expected 1 readfile error, got %d", n)
+	}
+}
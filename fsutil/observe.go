@@ -0,0 +1,141 @@
+package fsutil
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Observer is called after each operation on a wrapped FS.
+// Reaching the end of a file or directory is not reported as an error.
+type Observer func(op, name string, elapsed time.Duration, err error)
+
+// Operations reported to an Observer.
+const (
+	OpOpen     = "open"
+	OpStat     = "stat"
+	OpReadDir  = "readdir"
+	OpReadFile = "readfile"
+	OpSub      = "sub"
+	OpRead     = "read"
+	OpClose    = "close"
+)
+
+// Wrap returns an FS that reports every operation on fsys and its files to observe.
+func Wrap(fsys fs.FS, observe Observer) fs.FS {
+	return &observedFS{
+		fsys:    fsys,
+		observe: observe,
+	}
+}
+
+type observedFS struct {
+	fsys    fs.FS
+	observe Observer
+}
+
+func (o *observedFS) report(op, name string, start time.Time, err error) {
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	o.observe(op, name, time.Since(start), err)
+}
+
+// Open implements fs.FS.
+func (o *observedFS) Open(name string) (fs.File, error) {
+	start := time.Now()
+	f, err := o.fsys.Open(name)
+	o.report(OpOpen, name, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &observedFile{
+		file: f,
+		name: name,
+		fsys: o,
+	}, nil
+}
+
+// Stat implements fs.StatFS.
+func (o *observedFS) Stat(name string) (fs.FileInfo, error) {
+	start := time.Now()
+	fi, err := fs.Stat(o.fsys, name)
+	o.report(OpStat, name, start, err)
+	return fi, err
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (o *observedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	start := time.Now()
+	entries, err := fs.ReadDir(o.fsys, name)
+	o.report(OpReadDir, name, start, err)
+	return entries, err
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (o *observedFS) ReadFile(name string) ([]byte, error) {
+	start := time.Now()
+	data, err := fs.ReadFile(o.fsys, name)
+	o.report(OpReadFile, name, start, err)
+	return data, err
+}
+
+// Sub implements fs.SubFS.
+func (o *observedFS) Sub(dir string) (fs.FS, error) {
+	start := time.Now()
+	sub, err := fs.Sub(o.fsys, dir)
+	o.report(OpSub, dir, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(sub, o.observe), nil
+}
+
+// observedFile reports the operations on an open file.
+type observedFile struct {
+	file fs.File
+	name string
+	fsys *observedFS
+}
+
+func (f *observedFile) Stat() (fs.FileInfo, error) {
+	start := time.Now()
+	fi, err := f.file.Stat()
+	f.fsys.report(OpStat, f.name, start, err)
+	return fi, err
+}
+
+func (f *observedFile) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := f.file.Read(b)
+	f.fsys.report(OpRead, f.name, start, err)
+	return n, err
+}
+
+func (f *observedFile) Close() error {
+	start := time.Now()
+	err := f.file.Close()
+	f.fsys.report(OpClose, f.name, start, err)
+	return err
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (f *observedFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	start := time.Now()
+	d, ok := f.file.(fs.ReadDirFile)
+	if !ok {
+		err := &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not implemented")}
+		f.fsys.report(OpReadDir, f.name, start, err)
+		return nil, err
+	}
+	entries, err := d.ReadDir(n)
+	f.fsys.report(OpReadDir, f.name, start, err)
+	return entries, err
+}
+
+var _ fs.ReadDirFS = &observedFS{}
+var _ fs.ReadFileFS = &observedFS{}
+var _ fs.StatFS = &observedFS{}
+var _ fs.SubFS = &observedFS{}
+var _ fs.ReadDirFile = &observedFile{}
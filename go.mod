@@ -2,7 +2,11 @@ module github.com/myhops/bbfs
 
 go 1.23.0
 
-require github.com/maypok86/otter v1.2.2
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/maypok86/otter v1.2.2
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/dolthub/maphash v0.1.0 // indirect
@@ -0,0 +1,21 @@
+/*
+Package goproxy serves the Go modules in a Bitbucket repository with the
+GOPROXY protocol, see https://go.dev/ref/mod#goproxy-protocol.
+
+The versions of a module are the tags of the repository, v1.2.3 for a module
+in the root and dir/v1.2.3 for a module in the directory dir, as the go
+command expects them. The module zips are made from the archive api of the
+server.
+
+	h := goproxy.NewHandler(client, "PRJ", "lib", []goproxy.Module{
+		{Path: "example.com/lib"},
+		{Path: "example.com/lib/tools", Dir: "tools"},
+	})
+	http.ListenAndServe(":8080", h)
+
+	GOPROXY=http://localhost:8080 GONOSUMDB=example.com go get example.com/lib@v1.2.3
+
+The modules are private to the checksum database, set GONOSUMDB or GOPRIVATE
+for them.
+*/
+package goproxy
@@ -0,0 +1,48 @@
+package goproxy
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// escapePath returns the module path or version as it appears in the urls of
+// the protocol: upper case letters are replaced by an exclamation mark followed
+// by the lower case letter.
+func escapePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescapePath reverses escapePath.
+func unescapePath(s string) (string, error) {
+	var b strings.Builder
+	bang := false
+	for _, r := range s {
+		switch {
+		case bang:
+			if r < 'a' || r > 'z' {
+				return "", errors.New("invalid escape in " + s)
+			}
+			b.WriteRune(unicode.ToUpper(r))
+			bang = false
+		case r == '!':
+			bang = true
+		case unicode.IsUpper(r):
+			return "", errors.New("unescaped upper case letter in " + s)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if bang {
+		return "", errors.New("invalid escape in " + s)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,145 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func newTestProxy(t *testing.T) *httptest.Server {
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"go.mod":         {Data: []byte("module example.com/Lib\n")},
+		"lib.go":         {Data: []byte("package lib\n")},
+		"tools/go.mod":   {Data: []byte("module example.com/Lib/tools\n")},
+		"tools/tool.go":  {Data: []byte("package tools\n")},
+		"docs/README.md": {Data: []byte("# lib\n")},
+	})
+	first := repo.Resolve("main")
+	repo.Tag("v1.0.0", first)
+	repo.Tag("tools/v0.1.0", first)
+	second := repo.Commit("main", "second", first.Files)
+	repo.Tag("v1.1.0", second)
+	repo.Tag("v1.2.0-rc.1", second)
+	// Not canonical or the wrong major version.
+	repo.Tag("v1.2", second)
+	repo.Tag("v2.0.0", second)
+	srv.AddRepo("PRJ", "lib", repo)
+
+	h := NewHandler(&server.Client{BaseURL: srv.BaseURL()}, "PRJ", "lib", []Module{
+		{Path: "example.com/Lib"},
+		{Path: "example.com/Lib/tools", Dir: "tools"},
+	})
+	ps := httptest.NewServer(h)
+	t.Cleanup(ps.Close)
+	return ps
+}
+
+func get(t *testing.T, url string) (int, []byte) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	return resp.StatusCode, b
+}
+
+func TestGoProxy(t *testing.T) {
+	ps := newTestProxy(t)
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/example.com/!lib/@v/list", http.StatusOK, "v1.0.0\nv1.1.0\nv1.2.0-rc.1\n"},
+		{"/example.com/!lib/tools/@v/list", http.StatusOK, "v0.1.0\n"},
+		{"/example.com/!lib/@v/v1.0.0.mod", http.StatusOK, "module example.com/Lib\n"},
+		{"/example.com/!lib/tools/@v/v0.1.0.mod", http.StatusOK, "module example.com/Lib/tools\n"},
+		{"/example.com/!lib/@v/v1.1.0.info", http.StatusOK, `{"Version":"v1.1.0","Time":"2024-08-05T10:27:04Z"}` + "\n"},
+		{"/example.com/!lib/@latest", http.StatusOK, `{"Version":"v1.1.0","Time":"2024-08-05T10:27:04Z"}` + "\n"},
+		{"/example.com/!lib/@v/v1.3.0.info", http.StatusNotFound, ""},
+		{"/example.com/!lib/@v/v2.0.0.info", http.StatusNotFound, ""},
+		{"/example.com/!lib/@v/main.info", http.StatusNotFound, ""},
+		{"/example.com/Lib/@v/list", http.StatusNotFound, ""},
+		{"/example.com/other/@v/list", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			status, b := get(t, ps.URL+tt.path)
+			if status != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, status, b)
+			}
+			if tt.want != "" && string(b) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, b)
+			}
+		})
+	}
+}
+
+func TestGoProxyZip(t *testing.T) {
+	ps := newTestProxy(t)
+
+	tests := []struct {
+		path  string
+		files []string
+	}{
+		{"/example.com/!lib/@v/v1.0.0.zip", []string{
+			"example.com/Lib@v1.0.0/docs/README.md",
+			"example.com/Lib@v1.0.0/go.mod",
+			"example.com/Lib@v1.0.0/lib.go",
+		}},
+		{"/example.com/!lib/tools/@v/v0.1.0.zip", []string{
+			"example.com/Lib/tools@v0.1.0/go.mod",
+			"example.com/Lib/tools@v0.1.0/tool.go",
+		}},
+	}
+	for _, tt := range tests {
+		status, b := get(t, ps.URL+tt.path)
+		if status != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d: %s", tt.path, status, b)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.files) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.files, names)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	for _, s := range []string{"example.com/Lib", "github.com/BurntSushi/toml", "v1.0.0-RC.1"} {
+		got, err := unescapePath(escapePath(s))
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != s {
+			t.Errorf("expected %s, got %s", s, got)
+		}
+	}
+	for _, s := range []string{"example.com/Lib", "example.com/!", "example.com/!1"} {
+		if _, err := unescapePath(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/nulllog"
+)
+
+// DefaultMaxZipSize is the default maximum size of an archive, the limit of the go command.
+const DefaultMaxZipSize = 500 << 20
+
+// Module is a Go module in the repository.
+type Module struct {
+	// Path is the module path, e.g. example.com/lib.
+	Path string
+	// Dir is the directory of the module in the repository, empty for the root.
+	Dir string
+}
+
+// tagPrefix returns the prefix of the tags of the module versions.
+func (m *Module) tagPrefix() string {
+	if m.Dir == "" {
+		return ""
+	}
+	return m.Dir + "/"
+}
+
+// pathMajor returns the major version in the module path, 0 without a /vN suffix.
+func (m *Module) pathMajor() int {
+	i := strings.LastIndex(m.Path, "/v")
+	if i < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(m.Path[i+2:])
+	if err != nil || n < 2 {
+		return 0
+	}
+	return n
+}
+
+// version returns the version for a tag of the module, false if the tag is
+// not a canonical semantic version for the major version of the module path.
+func (m *Module) version(tag string) (bbfs.Version, bool) {
+	s, ok := strings.CutPrefix(tag, m.tagPrefix())
+	if !ok || !strings.HasPrefix(s, "v") {
+		return bbfs.Version{}, false
+	}
+	v, err := bbfs.ParseVersion(s)
+	if err != nil || v.Build != "" || "v"+v.String() != s {
+		return bbfs.Version{}, false
+	}
+	if major := m.pathMajor(); major != 0 {
+		return v, v.Major == major
+	}
+	return v, v.Major <= 1
+}
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handler)
+
+// WithLogger sets the logger for failed requests.
+func WithLogger(l *slog.Logger) HandlerOption {
+	return func(h *handler) {
+		h.logger = l
+	}
+}
+
+// WithMaxZipSize sets the maximum size of the archive of a module version.
+// Defaults to DefaultMaxZipSize.
+func WithMaxZipSize(n int64) HandlerOption {
+	return func(h *handler) {
+		h.maxZipSize = n
+	}
+}
+
+// NewHandler returns the handler for the modules in the repository.
+// Mount it at the root, or use http.StripPrefix.
+//
+// It serves the @v/list, @v/<version>.info, .mod and .zip and the @latest
+// endpoints of each module. Files in subdirectories with their own go.mod
+// belong to another module and are left out of the zips.
+func NewHandler(client *server.Client, projectKey, repoSlug string, modules []Module, opts ...HandlerOption) http.Handler {
+	h := &handler{
+		client:     client,
+		projectKey: projectKey,
+		repoSlug:   repoSlug,
+		modules:    modules,
+		maxZipSize: DefaultMaxZipSize,
+		logger:     nulllog.Logger(),
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+type handler struct {
+	client     *server.Client
+	projectKey string
+	repoSlug   string
+	modules    []Module
+	maxZipSize int64
+	logger     *slog.Logger
+}
+
+// errNotFound is returned for unknown modules and versions.
+var errNotFound = errors.New("not found")
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	m, rest := h.module(r.URL.Path)
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var err error
+	switch {
+	case rest == "@latest":
+		err = h.serveLatest(w, r, m)
+	case rest == "@v/list":
+		err = h.serveList(w, r, m)
+	default:
+		err = h.serveVersion(w, r, m, rest)
+	}
+	if err == nil {
+		return
+	}
+	if errors.Is(err, errNotFound) || server.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.logger.Info("request failed", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// module returns the module for the url path and the rest of the path after the module.
+func (h *handler) module(p string) (*Module, string) {
+	for i := range h.modules {
+		m := &h.modules[i]
+		if rest, ok := strings.CutPrefix(p, "/"+escapePath(m.Path)+"/"); ok && strings.HasPrefix(rest, "@") {
+			return m, rest
+		}
+	}
+	return nil, ""
+}
+
+// versions returns the versions of the module in ascending order and the tag of each version.
+func (h *handler) versions(ctx context.Context, m *Module) ([]bbfs.Version, map[bbfs.Version]*server.Tag, error) {
+	cmd := &server.GetTagsCommand{
+		ProjectKey: h.projectKey,
+		RepoSlug:   h.repoSlug,
+		OrderBy:    server.OrderByAlphabetical,
+		FilterText: m.tagPrefix() + "v",
+		Limit:      server.MaxLimit,
+	}
+	var versions []bbfs.Version
+	tags := map[bbfs.Version]*server.Tag{}
+	for {
+		resp, err := h.client.GetTags(ctx, cmd)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range resp.Tags {
+			if v, ok := m.version(t.Name); ok {
+				versions = append(versions, v)
+				tags[v] = t
+			}
+		}
+		if resp.IsLastPage {
+			break
+		}
+		cmd.Start = resp.NextPageStart
+	}
+	slices.SortFunc(versions, bbfs.Version.Compare)
+	return versions, tags, nil
+}
+
+func (h *handler) serveList(w http.ResponseWriter, r *http.Request, m *Module) error {
+	versions, _, err := h.versions(r.Context(), m)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, v := range versions {
+		fmt.Fprintf(&b, "v%s\n", v)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, b.String())
+	return nil
+}
+
+// serveLatest serves the info of the highest release, or the highest pre-release without releases.
+func (h *handler) serveLatest(w http.ResponseWriter, r *http.Request, m *Module) error {
+	versions, tags, err := h.versions(r.Context(), m)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("%s: no versions: %w", m.Path, errNotFound)
+	}
+	latest := versions[len(versions)-1]
+	for _, v := range slices.Backward(versions) {
+		if v.Pre == "" {
+			latest = v
+			break
+		}
+	}
+	return h.writeInfo(w, r, latest, tags[latest])
+}
+
+// serveVersion serves the @v/<version>.info, .mod and .zip endpoints.
+func (h *handler) serveVersion(w http.ResponseWriter, r *http.Request, m *Module, rest string) error {
+	file, ok := strings.CutPrefix(rest, "@v/")
+	if !ok {
+		return errNotFound
+	}
+	ext := path.Ext(file)
+	escaped := strings.TrimSuffix(file, ext)
+	vs, err := unescapePath(escaped)
+	if err != nil {
+		return fmt.Errorf("%s: %w", err.Error(), errNotFound)
+	}
+	v, ok := m.version(m.tagPrefix() + vs)
+	if !ok {
+		return fmt.Errorf("%s@%s: %w", m.Path, vs, errNotFound)
+	}
+	tag := m.tagPrefix() + vs
+
+	switch ext {
+	case ".info":
+		t, err := h.tag(r.Context(), tag)
+		if err != nil {
+			return err
+		}
+		return h.writeInfo(w, r, v, t)
+	case ".mod":
+		return h.serveMod(w, r, m, tag)
+	case ".zip":
+		return h.serveZip(w, r, m, tag, vs)
+	}
+	return errNotFound
+}
+
+// tag returns the tag with the name.
+func (h *handler) tag(ctx context.Context, name string) (*server.Tag, error) {
+	cmd := &server.GetTagsCommand{
+		ProjectKey: h.projectKey,
+		RepoSlug:   h.repoSlug,
+		OrderBy:    server.OrderByAlphabetical,
+		FilterText: name,
+		Limit:      server.MaxLimit,
+	}
+	for {
+		resp, err := h.client.GetTags(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp.Tags {
+			if t.Name == name {
+				return t, nil
+			}
+		}
+		if resp.IsLastPage {
+			return nil, fmt.Errorf("tag %s: %w", name, errNotFound)
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// writeInfo writes the info of the version, the time is the time of the tagged commit.
+func (h *handler) writeInfo(w http.ResponseWriter, r *http.Request, v bbfs.Version, t *server.Tag) error {
+	resp, err := h.client.GetCommits(r.Context(), &server.GetCommitsCommand{
+		ProjectKey: h.projectKey,
+		RepoSlug:   h.repoSlug,
+		CommitID:   t.CommitID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Commits) == 0 {
+		return fmt.Errorf("commit %s of tag %s not found", t.CommitID, t.Name)
+	}
+	info := struct {
+		Version string
+		Time    time.Time
+	}{"v" + v.String(), resp.Commits[0].Timestamp.UTC()}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(info)
+}
+
+// serveMod serves the go.mod of the module, or a minimal one if the module has none.
+func (h *handler) serveMod(w http.ResponseWriter, r *http.Request, m *Module, tag string) error {
+	body, err := h.client.OpenRawFile(r.Context(), &server.OpenRawFileCommand{
+		ProjectKey: h.projectKey,
+		RepoSlug:   h.repoSlug,
+		At:         server.TagRef(tag),
+		FilePath:   path.Join(m.Dir, "go.mod"),
+	})
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if server.IsNotFound(err) {
+		fmt.Fprintf(w, "module %s\n", m.Path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// serveZip serves the module zip, made from the archive of the module directory.
+func (h *handler) serveZip(w http.ResponseWriter, r *http.Request, m *Module, tag, version string) error {
+	body, err := h.client.OpenArchive(r.Context(), &server.OpenArchiveCommand{
+		ProjectKey: h.projectKey,
+		RepoSlug:   h.repoSlug,
+		At:         server.TagRef(tag),
+		Format:     server.ArchiveFormatZip,
+		Path:       m.Dir,
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, h.maxZipSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > h.maxZipSize {
+		return fmt.Errorf("archive of %s is larger than %d bytes", tag, h.maxZipSize)
+	}
+	res, err := moduleZip(data, m, version)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(res))
+	return nil
+}
+
+// moduleZip rewrites the archive of the module directory to a module zip:
+// the files are below module@version/ and files of nested modules are left out.
+func moduleZip(archive []byte, m *Module, version string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+	// Strip the module directory from the names.
+	var files []*zip.File
+	names := map[*zip.File]string{}
+	var nested []string
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		name, ok := strings.CutPrefix(f.Name, m.tagPrefix())
+		if !ok {
+			continue
+		}
+		if dir := path.Dir(name); path.Base(name) == "go.mod" && dir != "." {
+			nested = append(nested, dir+"/")
+		}
+		files = append(files, f)
+		names[f] = name
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	prefix := m.Path + "@" + version + "/"
+	for _, f := range files {
+		name := names[f]
+		if slices.ContainsFunc(nested, func(dir string) bool { return strings.HasPrefix(name, dir) }) {
+			continue
+		}
+		if err := copyZipFile(zw, f, prefix+name); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func copyZipFile(zw *zip.Writer, f *zip.File, name string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: f.Modified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rc)
+	return err
+}
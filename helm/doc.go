@@ -0,0 +1,14 @@
+/*
+Package helm turns a directory of a file system into a Helm chart repository.
+
+Index scans the directory for packaged charts, the .tgz files, and for chart
+sources, the directories with a Chart.yaml. Chart sources are packaged on the
+fly with Package. NewHandler serves the index.yaml and the charts, so a
+directory in a Bitbucket repository is a chart repository:
+
+	fsys := bbfs.NewFS(cfg)
+	http.ListenAndServe(":8080", helm.NewHandler(fsys, "charts"))
+
+	helm repo add internal http://localhost:8080
+*/
+package helm
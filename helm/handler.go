@@ -0,0 +1,123 @@
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/nulllog"
+)
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handler)
+
+// WithLogger sets the logger for failed requests.
+func WithLogger(l *slog.Logger) HandlerOption {
+	return func(h *handler) {
+		h.logger = l
+	}
+}
+
+// NewHandler returns the handler for the chart repository in dir of fsys.
+// It serves /index.yaml and the charts at the urls in the index.
+//
+// The directory is scanned for every request of the index or a chart source,
+// wrap fsys in a caching FS, like the one of bbfs.NewFS, for large repositories.
+func NewHandler(fsys fs.FS, dir string, opts ...HandlerOption) http.Handler {
+	if dir == "" {
+		dir = "."
+	}
+	h := &handler{fsys: fsys, dir: dir, logger: nulllog.Logger()}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+type handler struct {
+	fsys   fs.FS
+	dir    string
+	logger *slog.Logger
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case name == "index.yaml":
+		h.serveIndex(w, r)
+	case strings.HasSuffix(name, ".tgz") && fs.ValidPath(name):
+		h.serveChart(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	idx, err := Index(h.fsys, h.dir)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	data, err := idx.Marshal()
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	http.ServeContent(w, r, "index.yaml", time.Time{}, bytes.NewReader(data))
+}
+
+// serveChart serves the packaged chart, or packages the chart source with the name.
+func (h *handler) serveChart(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := fs.ReadFile(h.fsys, relJoin(h.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = h.packageSource(name)
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// packageSource packages the chart source that is served as name.
+func (h *handler) packageSource(name string) ([]byte, error) {
+	charts, err := scan(h.fsys, h.dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range charts {
+		if c.src != "" && c.version.URLs[0] == name {
+			data, _, err := Package(h.fsys, c.src)
+			return data, err
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	h.logger.Info("request failed", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// relJoin joins the name to dir, the root is ".".
+func relJoin(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
@@ -0,0 +1,129 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"gopkg.in/yaml.v3"
+)
+
+func chartYAML(name, version string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte("apiVersion: v2\nname: " + name + "\nversion: " + version + "\n")}
+}
+
+// testRepo returns a repository with a packaged chart and two chart sources,
+// one with the same version as the packaged chart.
+func testRepo(t *testing.T) fstest.MapFS {
+	src := fstest.MapFS{
+		"app/Chart.yaml":             chartYAML("app", "1.0.0"),
+		"app/templates/service.yaml": {Data: []byte("kind: Service\n")},
+	}
+	pkg, _, err := Package(src, "app")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	return fstest.MapFS{
+		"README.md":                        {Data: []byte("# charts\n")},
+		"charts/packages/app-1.0.0.tgz":    {Data: pkg},
+		"charts/app/Chart.yaml":            chartYAML("app", "1.1.0"),
+		"charts/app/values.yaml":           {Data: []byte("replicas: 1\n")},
+		"charts/app/charts/sub/Chart.yaml": chartYAML("sub", "0.1.0"),
+		"charts/old/Chart.yaml":            chartYAML("app", "1.0.0"),
+	}
+}
+
+func TestIndex(t *testing.T) {
+	fsys := testRepo(t)
+	idx, err := Index(fsys, "charts")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(idx.Entries) != 1 {
+		t.Fatalf("expected only app, got %v", idx.Entries)
+	}
+	versions := idx.Entries["app"]
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+	tests := []struct {
+		version string
+		url     string
+	}{
+		{"1.1.0", "app-1.1.0.tgz"},
+		{"1.0.0", "packages/app-1.0.0.tgz"},
+	}
+	for i, tt := range tests {
+		v := versions[i]
+		if v.Version != tt.version || v.URLs[0] != tt.url {
+			t.Errorf("expected %s at %s, got %s at %v", tt.version, tt.url, v.Version, v.URLs)
+		}
+	}
+	sum := sha256.Sum256(fsys["charts/packages/app-1.0.0.tgz"].Data)
+	if versions[1].Digest != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected digest %s", versions[1].Digest)
+	}
+
+	// The digest of a source does not change.
+	again, err := Index(fsys, "charts")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if again.Entries["app"][0].Digest != versions[0].Digest {
+		t.Errorf("digest of the source changed")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(testRepo(t), "charts"))
+	defer srv.Close()
+
+	get := func(path string) (int, []byte) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		return resp.StatusCode, b
+	}
+
+	status, b := get("/index.yaml")
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d", status)
+	}
+	var idx IndexFile
+	if err := yaml.Unmarshal(b, &idx); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	for _, v := range idx.Entries["app"] {
+		status, b := get("/" + v.URLs[0])
+		if status != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", v.URLs[0], status)
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != v.Digest {
+			t.Errorf("%s: digest does not match the index", v.URLs[0])
+		}
+		md, err := chartMetadata(b)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if md.Version != v.Version {
+			t.Errorf("%s: unexpected version %s", v.URLs[0], md.Version)
+		}
+	}
+
+	for _, p := range []string{"/README.md", "/missing-1.0.0.tgz", "/../charts/app-1.1.0.tgz"} {
+		if status, _ := get(p); status != http.StatusNotFound {
+			t.Errorf("%s: expected not found, got %d", p, status)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs"
+	"gopkg.in/yaml.v3"
+)
+
+// IndexAPIVersion is the apiVersion of the generated index.
+const IndexAPIVersion = "v1"
+
+// Metadata is the content of a Chart.yaml.
+type Metadata struct {
+	APIVersion   string            `yaml:"apiVersion"`
+	Name         string            `yaml:"name"`
+	Version      string            `yaml:"version"`
+	KubeVersion  string            `yaml:"kubeVersion,omitempty"`
+	Description  string            `yaml:"description,omitempty"`
+	Type         string            `yaml:"type,omitempty"`
+	Keywords     []string          `yaml:"keywords,omitempty"`
+	Home         string            `yaml:"home,omitempty"`
+	Sources      []string          `yaml:"sources,omitempty"`
+	Dependencies []*Dependency     `yaml:"dependencies,omitempty"`
+	Maintainers  []*Maintainer     `yaml:"maintainers,omitempty"`
+	Icon         string            `yaml:"icon,omitempty"`
+	AppVersion   string            `yaml:"appVersion,omitempty"`
+	Deprecated   bool              `yaml:"deprecated,omitempty"`
+	Annotations  map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Dependency is a dependency in a Chart.yaml.
+type Dependency struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version,omitempty"`
+	Repository string `yaml:"repository,omitempty"`
+	Condition  string `yaml:"condition,omitempty"`
+	Alias      string `yaml:"alias,omitempty"`
+}
+
+// Maintainer is a maintainer in a Chart.yaml.
+type Maintainer struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email,omitempty"`
+	URL   string `yaml:"url,omitempty"`
+}
+
+// ChartVersion is an entry in the index.
+type ChartVersion struct {
+	Metadata `yaml:",inline"`
+	// URLs are relative to the directory of the index.
+	URLs    []string  `yaml:"urls"`
+	Created time.Time `yaml:"created,omitempty"`
+	Digest  string    `yaml:"digest"`
+}
+
+// IndexFile is the index.yaml of a chart repository.
+type IndexFile struct {
+	APIVersion string                     `yaml:"apiVersion"`
+	Entries    map[string][]*ChartVersion `yaml:"entries"`
+	Generated  time.Time                  `yaml:"generated"`
+}
+
+// Marshal returns the index in yaml.
+func (idx *IndexFile) Marshal() ([]byte, error) {
+	return yaml.Marshal(idx)
+}
+
+// chart is a chart found by scan.
+type chart struct {
+	version *ChartVersion
+	// file is the path of the packaged chart, src the directory of the chart source.
+	file string
+	src  string
+}
+
+// Index scans dir in fsys for charts and returns the index of the repository.
+//
+// A .tgz file is a packaged chart, a directory with a Chart.yaml is a chart source
+// that is served as <name>-<version>.tgz. A packaged chart wins from a source with
+// the same name and version. The versions of a chart are sorted newest first.
+func Index(fsys fs.FS, dir string) (*IndexFile, error) {
+	charts, err := scan(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	idx := &IndexFile{
+		APIVersion: IndexAPIVersion,
+		Entries:    map[string][]*ChartVersion{},
+		Generated:  time.Now().UTC(),
+	}
+	for _, c := range charts {
+		idx.Entries[c.version.Name] = append(idx.Entries[c.version.Name], c.version)
+	}
+	for _, versions := range idx.Entries {
+		slices.SortStableFunc(versions, func(a, b *ChartVersion) int {
+			return -compareVersions(a.Version, b.Version)
+		})
+	}
+	return idx, nil
+}
+
+// scan returns the charts in dir, packaged and source.
+func scan(fsys fs.FS, dir string) ([]*chart, error) {
+	if dir == "" {
+		dir = "."
+	}
+	var res []*chart
+	seen := map[string]bool{}
+	var sources []string
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if _, err := fs.Stat(fsys, path.Join(p, "Chart.yaml")); err == nil {
+				sources = append(sources, p)
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path.Ext(p) != ".tgz" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		md, err := chartMetadata(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		res = append(res, &chart{version: newChartVersion(md, relPath(dir, p), data), file: p})
+		seen[md.Name+"-"+md.Version] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range sources {
+		data, md, err := Package(fsys, src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		name := md.Name + "-" + md.Version
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, &chart{version: newChartVersion(md, name+".tgz", data), src: src})
+	}
+	return res, nil
+}
+
+func newChartVersion(md *Metadata, url string, data []byte) *ChartVersion {
+	sum := sha256.Sum256(data)
+	return &ChartVersion{
+		Metadata: *md,
+		URLs:     []string{url},
+		Digest:   hex.EncodeToString(sum[:]),
+	}
+}
+
+// compareVersions compares chart versions as semantic versions,
+// versions that do not parse are compared as strings.
+func compareVersions(a, b string) int {
+	va, erra := bbfs.ParseVersion(a)
+	vb, errb := bbfs.ParseVersion(b)
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
+
+// relPath returns p relative to dir.
+func relPath(dir, p string) string {
+	if dir == "." {
+		return p
+	}
+	return strings.TrimPrefix(p, dir+"/")
+}
+
+// parseMetadata parses a Chart.yaml.
+func parseMetadata(data []byte) (*Metadata, error) {
+	var md Metadata
+	if err := yaml.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("parsing Chart.yaml: %w", err)
+	}
+	if md.Name == "" || md.Version == "" {
+		return nil, errors.New("Chart.yaml needs a name and a version")
+	}
+	return &md, nil
+}
+
+// chartMetadata returns the metadata of a packaged chart,
+// from the Chart.yaml in the top directory of the archive.
+func chartMetadata(data []byte) (*Metadata, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("no Chart.yaml in the archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+		dir, name, ok := strings.Cut(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if !ok || dir == "" || name != "Chart.yaml" {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		return parseMetadata(b)
+	}
+}
+
+// Package packages the chart source in dir like helm package does,
+// and returns the archive and the metadata of the chart.
+//
+// The archive has the files in lexical order without modification times,
+// so the digest only changes with the content. The .helmignore file is not used.
+func Package(fsys fs.FS, dir string) ([]byte, *Metadata, error) {
+	b, err := fs.ReadFile(fsys, path.Join(dir, "Chart.yaml"))
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := parseMetadata(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	err = fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:     path.Join(md.Name, relPath(dir, p)),
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			ModTime:  time.Unix(0, 0),
+			Typeflag: tar.TypeReg,
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), md, nil
+}
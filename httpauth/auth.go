@@ -0,0 +1,132 @@
+package httpauth
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator for a request
+	// without credentials it understands.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned by an Authenticator for wrong
+	// credentials.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is an authenticated user.
+type Identity struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator checks the credentials of a request.
+type Authenticator interface {
+	// Authenticate returns the user of the request, or ErrNoCredentials
+	// or an error wrapping ErrInvalidCredentials.
+	Authenticate(r *http.Request) (*Identity, error)
+	// Challenge returns the WWW-Authenticate header for a request that
+	// is not authenticated.
+	Challenge() string
+}
+
+// Authenticators tries the authenticators in order until one finds
+// credentials in the request.
+type Authenticators []Authenticator
+
+// Authenticate returns the identity from the first authenticator that does
+// not return ErrNoCredentials.
+func (a Authenticators) Authenticate(r *http.Request) (*Identity, error) {
+	for _, auth := range a {
+		id, err := auth.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// Challenge returns the challenges of the authenticators.
+func (a Authenticators) Challenge() string {
+	res := make([]string, 0, len(a))
+	for _, auth := range a {
+		res = append(res, auth.Challenge())
+	}
+	return strings.Join(res, ", ")
+}
+
+// Rule is the allow list for the paths under a prefix.
+type Rule struct {
+	// Prefix is the start of the paths of the rule, e.g. /docs/.
+	Prefix string
+	// Allow are the user names and the groups, as group:name, that may
+	// access the paths. Empty allows all authenticated users.
+	Allow []string
+}
+
+// allows returns true if the rule allows the user.
+func (r *Rule) allows(id *Identity) bool {
+	if len(r.Allow) == 0 {
+		return true
+	}
+	for _, a := range r.Allow {
+		if group, ok := strings.CutPrefix(a, "group:"); ok {
+			if slices.Contains(id.Groups, group) {
+				return true
+			}
+		} else if a == id.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPath returns the path as the handlers serve it, without dot
+// segments, so "/public/../internal/" matches the rules of "/internal/".
+// A trailing slash is kept.
+func cleanPath(p string) string {
+	res := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && res != "/" {
+		res += "/"
+	}
+	return res
+}
+
+// Handler returns a handler that calls next for authenticated users allowed
+// by the rule with the longest prefix of the path. Paths without a rule are
+// open to all authenticated users.
+//
+// Requests without valid credentials get status 401 with the challenge of
+// auth, users that are not allowed get 403.
+func Handler(next http.Handler, auth Authenticator, rules []Rule) http.Handler {
+	rules = slices.Clone(rules)
+	// The longest prefix first.
+	slices.SortStableFunc(rules, func(a, b Rule) int {
+		return len(b.Prefix) - len(a.Prefix)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", auth.Challenge())
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		p := cleanPath(r.URL.Path)
+		for _, rule := range rules {
+			// The directory itself is below a prefix with a slash too.
+			if !strings.HasPrefix(p, rule.Prefix) && !strings.HasPrefix(p+"/", rule.Prefix) {
+				continue
+			}
+			if !rule.allows(id) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			break
+		}
+		next.ServeHTTP(w, r)
+	})
+}
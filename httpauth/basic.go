@@ -0,0 +1,92 @@
+package httpauth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// BasicAuth authenticates the users of a static list with HTTP basic
+// authentication.
+type BasicAuth struct {
+	// Realm is the realm of the challenge, defaults to bbfs.
+	Realm string
+	// users maps the user names to the password or its hash.
+	users map[string]string
+}
+
+// NewBasicAuth returns the authenticator for the users, a map from the user
+// name to the password. A password of the form sha256:<hex> is the SHA-256
+// hash of the password, use HashPassword to create it.
+func NewBasicAuth(users map[string]string) *BasicAuth {
+	return &BasicAuth{users: users}
+}
+
+// LoadBasicAuth reads the users from a file with user:password lines, see
+// NewBasicAuth for the passwords. Empty lines and lines starting with # are
+// skipped.
+func LoadBasicAuth(path string) (*BasicAuth, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, password, ok := strings.Cut(line, ":")
+		if !ok || user == "" || password == "" {
+			return nil, fmt.Errorf("users %s: line %d: expected user:password", path, n)
+		}
+		users[user] = password
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewBasicAuth(users), nil
+}
+
+// HashPassword returns the hashed form of the password for NewBasicAuth.
+func HashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Authenticate checks the user name and password of the request.
+func (b *BasicAuth) Authenticate(r *http.Request) (*Identity, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	want, found := b.users[user]
+	if !found {
+		// Take the same time as for a known user.
+		want = HashPassword("")
+	}
+	got := password
+	if strings.HasPrefix(want, "sha256:") {
+		got = HashPassword(password)
+	}
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 || !found {
+		return nil, fmt.Errorf("%w: user %s", ErrInvalidCredentials, user)
+	}
+	return &Identity{Name: user}, nil
+}
+
+// Challenge returns the challenge for basic authentication.
+func (b *BasicAuth) Challenge() string {
+	realm := b.Realm
+	if realm == "" {
+		realm = "bbfs"
+	}
+	return fmt.Sprintf("Basic realm=%q", realm)
+}
@@ -0,0 +1,17 @@
+/*
+Package httpauth protects the http handlers that serve repository content,
+like those of the site, helm and proxy packages.
+
+An Authenticator checks the credentials of a request: BasicAuth the user
+names and passwords of a static list, OIDC the bearer tokens of an OpenID
+Connect provider. Handler requires an authenticated user for every request,
+and the users or groups in the allow list of the longest matching Rule:
+
+	users, err := httpauth.LoadBasicAuth("users.txt")
+	...
+	auth := httpauth.Authenticators{users, httpauth.NewOIDC(issuer, audience)}
+	h := httpauth.Handler(site.NewHandler(fsys), auth, []httpauth.Rule{
+		{Prefix: "/internal/", Allow: []string{"group:staff"}},
+	})
+*/
+package httpauth
@@ -0,0 +1,211 @@
+package httpauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBasicAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.txt")
+	os.WriteFile(path, []byte("# users\nalice:secret\nbob:"+HashPassword("hunter2")+"\n"), 0o600)
+	auth, err := LoadBasicAuth(path)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	tests := []struct {
+		user     string
+		password string
+		err      error
+	}{
+		{"alice", "secret", nil},
+		{"bob", "hunter2", nil},
+		{"alice", "wrong", ErrInvalidCredentials},
+		{"bob", HashPassword("hunter2"), ErrInvalidCredentials},
+		{"carol", "", ErrInvalidCredentials},
+		{"", "", ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.user != "" {
+			r.SetBasicAuth(tt.user, tt.password)
+		}
+		id, err := auth.Authenticate(r)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.user, tt.err, err)
+			continue
+		}
+		if err == nil && id.Name != tt.user {
+			t.Errorf("expected %s, got %s", tt.user, id.Name)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	auth := NewBasicAuth(map[string]string{"alice": "a", "bob": "b"})
+	groups := staticGroups{auth, map[string][]string{"bob": {"staff"}}}
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), groups, []Rule{
+		{Prefix: "/internal/", Allow: []string{"group:staff"}},
+		{Prefix: "/internal/alice/", Allow: []string{"alice"}},
+	})
+
+	tests := []struct {
+		user   string
+		path   string
+		status int
+	}{
+		{"", "/", http.StatusUnauthorized},
+		{"alice", "/", http.StatusOK},
+		{"alice", "/internal/doc", http.StatusForbidden},
+		{"bob", "/internal/doc", http.StatusOK},
+		{"alice", "/internal/alice/doc", http.StatusOK},
+		{"bob", "/internal/alice/doc", http.StatusForbidden},
+		{"alice", "/public/../internal/doc", http.StatusForbidden},
+		{"alice", "/internal/./doc", http.StatusForbidden},
+		{"alice", "//internal/doc", http.StatusForbidden},
+		{"alice", "/internal", http.StatusForbidden},
+		{"bob", "/internal/alice/../doc", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.user != "" {
+			r.SetBasicAuth(tt.user, tt.user[:1])
+		}
+		h.ServeHTTP(rec, r)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.user, tt.path, tt.status, rec.Code)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != `Basic realm="bbfs"` {
+			t.Errorf("unexpected challenge %q", rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+// staticGroups adds groups to the identities of an authenticator.
+type staticGroups struct {
+	Authenticator
+	groups map[string][]string
+}
+
+func (s staticGroups) Authenticate(r *http.Request) (*Identity, error) {
+	id, err := s.Authenticator.Authenticate(r)
+	if err == nil {
+		id.Groups = s.groups[id.Name]
+	}
+	return id, err
+}
+
+// testProvider is an OpenID Connect provider with one RSA key.
+type testProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	p := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": p.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *testProvider) token(t *testing.T, kid string, claims map[string]any) string {
+	enc := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := enc(map[string]string{"alg": "RS256", "kid": kid}) + "." + enc(claims)
+	hash := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDC(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.Close()
+	auth := NewOIDC(p.URL, "bbfs")
+
+	now := time.Now().Unix()
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":                p.URL,
+			"aud":                []string{"other", "bbfs"},
+			"exp":                now + 60,
+			"sub":                "123",
+			"preferred_username": "alice",
+			"groups":             []string{"staff"},
+		}
+	}
+	with := func(key string, v any) map[string]any {
+		c := valid()
+		if v == nil {
+			delete(c, key)
+		} else {
+			c[key] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", p.token(t, "k1", valid()), nil},
+		{"single audience", p.token(t, "k1", with("aud", "bbfs")), nil},
+		{"wrong audience", p.token(t, "k1", with("aud", "other")), ErrInvalidCredentials},
+		{"wrong issuer", p.token(t, "k1", with("iss", "https://evil")), ErrInvalidCredentials},
+		{"expired", p.token(t, "k1", with("exp", now-3600)), ErrInvalidCredentials},
+		{"no expiry", p.token(t, "k1", with("exp", nil)), ErrInvalidCredentials},
+		{"not yet valid", p.token(t, "k1", with("nbf", now+3600)), ErrInvalidCredentials},
+		{"unknown key", p.token(t, "k2", valid()), ErrInvalidCredentials},
+		{"tampered", p.token(t, "k1", valid())[:20] + "x" + p.token(t, "k1", valid())[21:], ErrInvalidCredentials},
+		{"no token", "", ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			id, err := auth.Authenticate(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if err == nil && (id.Name != "alice" || len(id.Groups) != 1 || id.Groups[0] != "staff") {
+				t.Errorf("unexpected identity %+v", id)
+			}
+		})
+	}
+}
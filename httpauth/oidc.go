@@ -0,0 +1,329 @@
+package httpauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keysRefreshInterval limits how often OIDC fetches the keys of the
+// provider for tokens signed with an unknown key.
+const keysRefreshInterval = time.Minute
+
+// clockSkew is the time that the expiry and start of a token may be off.
+const clockSkew = time.Minute
+
+// OIDC authenticates the bearer tokens of an OpenID Connect provider.
+// It verifies the RS256 and ES256 signatures with the keys of the provider,
+// the issuer, the audience and the validity period of the token.
+type OIDC struct {
+	// Issuer is the url of the provider, its keys are found with the
+	// discovery document at Issuer/.well-known/openid-configuration.
+	Issuer string
+	// Audience is the client id that the tokens must be issued for.
+	Audience string
+	// NameClaim is the claim with the user name, defaults to
+	// preferred_username, with a fallback to sub.
+	NameClaim string
+	// GroupsClaim is the claim with the groups, defaults to groups.
+	GroupsClaim string
+	// HTTPClient fetches the keys, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	now     func() time.Time
+}
+
+// NewOIDC returns the authenticator for the tokens of the issuer for the audience.
+func NewOIDC(issuer, audience string) *OIDC {
+	return &OIDC{Issuer: strings.TrimSuffix(issuer, "/"), Audience: audience}
+}
+
+// Authenticate verifies the bearer token of the request.
+func (o *OIDC) Authenticate(r *http.Request) (*Identity, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	claims, err := o.verify(r.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	return o.identity(claims), nil
+}
+
+// Challenge returns the challenge for bearer tokens.
+func (o *OIDC) Challenge() string {
+	return "Bearer"
+}
+
+// claims are the claims of a token that OIDC checks.
+type claims struct {
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	// all has all claims, for the name and the groups.
+	all map[string]any
+}
+
+func (o *OIDC) identity(c *claims) *Identity {
+	id := &Identity{}
+	nameClaim := o.NameClaim
+	if nameClaim == "" {
+		nameClaim = "preferred_username"
+	}
+	id.Name, _ = c.all[nameClaim].(string)
+	if id.Name == "" {
+		id.Name, _ = c.all["sub"].(string)
+	}
+	groupsClaim := o.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	groups, _ := c.all[groupsClaim].([]any)
+	for _, g := range groups {
+		if s, ok := g.(string); ok {
+			id.Groups = append(id.Groups, s)
+		}
+	}
+	return id
+}
+
+// verify checks the signature and the claims of the token.
+func (o *OIDC) verify(ctx context.Context, token string) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	key, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	if err := decodeSegment(parts[1], &c.all); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	if c.Issuer != o.Issuer {
+		return nil, fmt.Errorf("issuer %q", c.Issuer)
+	}
+	if !hasAudience(c.Audience, o.Audience) {
+		return nil, fmt.Errorf("audience %s", c.Audience)
+	}
+	now := o.clock()
+	if c.ExpiresAt == nil || now.After(unixTime(*c.ExpiresAt).Add(clockSkew)) {
+		return nil, errors.New("token expired")
+	}
+	if c.NotBefore != nil && now.Add(clockSkew).Before(unixTime(*c.NotBefore)) {
+		return nil, errors.New("token not valid yet")
+	}
+	return &c, nil
+}
+
+func (o *OIDC) clock() time.Time {
+	if o.now != nil {
+		return o.now()
+	}
+	return time.Now()
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(int64(sec), 0)
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// hasAudience returns true if aud, a string or an array of strings, has the audience.
+func hasAudience(aud json.RawMessage, audience string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == audience
+	}
+	var many []string
+	if json.Unmarshal(aud, &many) == nil {
+		for _, a := range many {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func verifySignature(alg string, key crypto.PublicKey, input string, sig []byte) error {
+	hash := sha256.Sum256([]byte(input))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token with a key that is not RSA")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+			return errors.New("bad signature")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("ES256 token with a key that is not P-256")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return errors.New("bad signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+// key returns the key with the id. It fetches the keys of the provider
+// when the id is unknown, at most once per keysRefreshInterval.
+func (o *OIDC) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	if !o.fetched.IsZero() && o.clock().Sub(o.fetched) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	keys, err := o.fetchKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys: %w", err)
+	}
+	o.keys = keys
+	o.fetched = o.clock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (o *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := o.getJSON(ctx, o.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("no jwks_uri in the discovery document")
+	}
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := o.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range jwks.Keys {
+		// Skip the keys that are not for signatures or of other types.
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (o *OIDC) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	hc := o.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jwk is a JSON web key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,21 @@
+/*
+Package hugofs assembles a Hugo site from directories in Bitbucket repositories.
+
+New mounts directories of file systems at targets like content, static or
+layouts, the way module mounts do in the Hugo configuration. Several mounts
+may share a target, the files of earlier mounts shadow those of later ones, so
+a site repository can override the content of a theme repository:
+
+	site := bbfs.NewFS(&bbfs.Config{ProjectKey: "WEB", RepositorySlug: "site", ...})
+	theme := bbfs.NewFS(&bbfs.Config{ProjectKey: "WEB", RepositorySlug: "theme", ...})
+	fsys, err := hugofs.New([]hugofs.Mount{
+		{Source: site, Dir: "content", Target: "content"},
+		{Source: theme, Dir: "layouts", Target: "layouts"},
+		{Source: theme, Dir: "exampleSite/content", Target: "content"},
+	})
+
+Hugo uses the modification times for lastmod and for rebuilds, the file
+system of bbfs does not have them. The files of a bbfs file system get the
+time of the commit of its ref, other file systems keep their own times.
+*/
+package hugofs
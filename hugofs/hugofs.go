@@ -0,0 +1,398 @@
+package hugofs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// Mount mounts a directory of a file system at a target.
+type Mount struct {
+	// Source is the file system, e.g. from bbfs.NewFS.
+	Source fs.FS
+	// Dir is the directory in Source, empty for the root.
+	Dir string
+	// Target is the directory in the mounted file system, e.g. content or static/docs.
+	Target string
+}
+
+// Option is an option for New.
+type Option func(*hugoFS)
+
+// WithModTime sets the modification time of the directories that only exist
+// as the parent of a target, and of the files of sources without times.
+func WithModTime(t time.Time) Option {
+	return func(h *hugoFS) {
+		h.modTime = t
+	}
+}
+
+// New returns the file system with the mounts.
+//
+// The files of earlier mounts shadow those of later mounts, directories are
+// merged. The commit time of a bbfs source is looked up by the first Open.
+func New(mounts []Mount, opts ...Option) (fs.FS, error) {
+	h := &hugoFS{}
+	for _, o := range opts {
+		o(h)
+	}
+	for i, m := range mounts {
+		if m.Source == nil {
+			return nil, fmt.Errorf("mount %d: no source", i)
+		}
+		dir, target := cleanDir(m.Dir), cleanDir(m.Target)
+		if !fs.ValidPath(dir) || !fs.ValidPath(target) {
+			return nil, fmt.Errorf("mount %d: invalid dir %q or target %q", i, m.Dir, m.Target)
+		}
+		sub, err := fs.Sub(m.Source, dir)
+		if err != nil {
+			return nil, fmt.Errorf("mount %d: %w", i, err)
+		}
+		h.mounts = append(h.mounts, &mount{fsys: sub, target: target, source: m.Source})
+	}
+	return h, nil
+}
+
+// cleanDir returns "." for the empty directory and removes the slashes around d.
+func cleanDir(d string) string {
+	d = strings.Trim(d, "/")
+	if d == "" {
+		return "."
+	}
+	return d
+}
+
+// mount is a mount with the sub file system of its directory.
+type mount struct {
+	fsys   fs.FS
+	target string
+	// source is the file system of the mount, its commit time is the mod time.
+	source fs.FS
+
+	once    sync.Once
+	modTime time.Time
+	err     error
+}
+
+// rel returns the name in the mount, false if the name is not below the target.
+func (m *mount) rel(name string) (string, bool) {
+	switch {
+	case m.target == ".":
+		return name, true
+	case name == m.target:
+		return ".", true
+	}
+	rest, ok := strings.CutPrefix(name, m.target+"/")
+	return rest, ok
+}
+
+// parentOf returns the child of name on the path to the target, false if the
+// target is not below name.
+func (m *mount) parentOf(name string) (string, bool) {
+	if m.target == "." || name == m.target {
+		return "", false
+	}
+	rest := m.target
+	if name != "." {
+		var ok bool
+		if rest, ok = strings.CutPrefix(m.target, name+"/"); !ok {
+			return "", false
+		}
+	}
+	child, _, _ := strings.Cut(rest, "/")
+	return child, true
+}
+
+// commitTime returns the time of the commit of a bbfs source, the zero time for other sources.
+func (m *mount) commitTime() (time.Time, error) {
+	m.once.Do(func() {
+		b, ok := m.source.(bbfs.Bitbucket)
+		if !ok {
+			return
+		}
+		resp, err := b.Client().GetCommits(context.Background(), &server.GetCommitsCommand{
+			ProjectKey: b.Project(),
+			RepoSlug:   b.Repo(),
+			CommitID:   b.Ref().String(),
+			Limit:      1,
+		})
+		if err != nil {
+			m.err = err
+			return
+		}
+		if len(resp.Commits) == 0 {
+			m.err = fmt.Errorf("no commit for %s", b.Ref())
+			return
+		}
+		m.modTime = resp.Commits[0].Timestamp
+	})
+	return m.modTime, m.err
+}
+
+// hugoFS implements fs.FS for the mounts.
+type hugoFS struct {
+	mounts  []*mount
+	modTime time.Time
+}
+
+var (
+	_ fs.ReadDirFS = &hugoFS{}
+	_ fs.StatFS    = &hugoFS{}
+)
+
+// modTimeFor returns the mod time for a file with the time t in the mount.
+func (h *hugoFS) modTimeFor(m *mount, t time.Time) (time.Time, error) {
+	ct, err := m.commitTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch {
+	case !ct.IsZero():
+		return ct, nil
+	case !t.IsZero():
+		return t, nil
+	}
+	return h.modTime, nil
+}
+
+// Open opens the file of the first mount that has it.
+func (h *hugoFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, m := range h.mounts {
+		rel, ok := m.rel(name)
+		if !ok {
+			continue
+		}
+		f, err := m.fsys.Open(rel)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, pathError("open", name, err)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, pathError("open", name, err)
+		}
+		t, err := h.modTimeFor(m, fi.ModTime())
+		if err != nil {
+			f.Close()
+			return nil, pathError("open", name, err)
+		}
+		info := &fileInfo{FileInfo: fi, name: path.Base(name), modTime: t}
+		if !fi.IsDir() {
+			return &file{File: f, info: info}, nil
+		}
+		f.Close()
+		// Targets look the same in the listing of their parent.
+		if rel == "." {
+			info = h.dirInfo(name)
+		}
+		return &dir{fsys: h, name: name, info: info}, nil
+	}
+	// Directories that only lead to targets.
+	for _, m := range h.mounts {
+		if _, ok := m.parentOf(name); ok {
+			return &dir{fsys: h, name: name, info: h.dirInfo(name)}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// dirInfo returns the info of a directory that leads to targets.
+func (h *hugoFS) dirInfo(name string) *fileInfo {
+	return &fileInfo{name: path.Base(name), modTime: h.modTime}
+}
+
+// ReadDir returns the merged entries of the directory sorted by name.
+func (h *hugoFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := h.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d, ok := f.(*dir)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return d.ReadDir(-1)
+}
+
+// Stat returns the info of the file of the first mount that has it.
+func (h *hugoFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := h.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// entries returns the merged entries of the directory, sorted by name.
+func (h *hugoFS) entries(name string) ([]fs.DirEntry, error) {
+	seen := map[string]bool{}
+	var res []fs.DirEntry
+	add := func(e fs.DirEntry) {
+		if !seen[e.Name()] {
+			seen[e.Name()] = true
+			res = append(res, e)
+		}
+	}
+	for _, m := range h.mounts {
+		if child, ok := m.parentOf(name); ok {
+			add(h.dirInfo(child))
+			continue
+		}
+		rel, ok := m.rel(name)
+		if !ok {
+			continue
+		}
+		// A file in a later mount is shadowed by the directory.
+		fi, err := fs.Stat(m.fsys, rel)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && !fi.IsDir()) {
+			continue
+		}
+		if err != nil {
+			return nil, pathError("readdir", name, err)
+		}
+		entries, err := fs.ReadDir(m.fsys, rel)
+		if err != nil {
+			return nil, pathError("readdir", name, err)
+		}
+		for _, e := range entries {
+			if seen[e.Name()] {
+				continue
+			}
+			fi, err := e.Info()
+			if err != nil {
+				return nil, pathError("readdir", name, err)
+			}
+			t, err := h.modTimeFor(m, fi.ModTime())
+			if err != nil {
+				return nil, pathError("readdir", name, err)
+			}
+			add(&fileInfo{FileInfo: fi, name: e.Name(), modTime: t})
+		}
+	}
+	slices.SortFunc(res, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return res, nil
+}
+
+// pathError returns a PathError for the name with the cause of err.
+func pathError(op, name string, err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// file is a file of a mount with the mod time of the mount.
+type file struct {
+	fs.File
+	info *fileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// dir is a merged directory.
+type dir struct {
+	fsys *hugoFS
+	name string
+	info *fileInfo
+
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+// ReadDir returns the merged entries of the directory.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.entries(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+	if n <= 0 {
+		res := d.entries
+		d.entries = nil
+		return res, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	res := d.entries[:n]
+	d.entries = d.entries[n:]
+	return res, nil
+}
+
+// fileInfo is the info of a file of a mount with the mod time of the mount,
+// or of a directory that leads to targets if FileInfo is nil.
+type fileInfo struct {
+	fs.FileInfo
+	name    string
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+
+func (fi *fileInfo) Size() int64 {
+	if fi.FileInfo == nil {
+		return 0
+	}
+	return fi.FileInfo.Size()
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.FileInfo == nil {
+		return fs.ModeDir | 0o555
+	}
+	return fi.FileInfo.Mode()
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.Mode().IsDir()
+}
+
+func (fi *fileInfo) Sys() any {
+	if fi.FileInfo == nil {
+		return nil
+	}
+	return fi.FileInfo.Sys()
+}
+
+func (fi *fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
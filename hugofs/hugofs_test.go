@@ -0,0 +1,86 @@
+package hugofs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestHugoFS(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("WEB", "site", fakeserver.NewRepo(fstest.MapFS{
+		"content/_index.md":     {Data: []byte("# home\n")},
+		"content/posts/post.md": {Data: []byte("# post\n")},
+		"hugo.toml":             {Data: []byte("title = 'site'\n")},
+	}))
+	site := bbfs.NewFS(&bbfs.Config{BaseURL: srv.BaseURL(), ProjectKey: "WEB", RepositorySlug: "site"})
+
+	themeTime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	theme := fstest.MapFS{
+		"layouts/_default/single.html":  {Data: []byte("{{ .Content }}\n"), ModTime: themeTime},
+		"exampleSite/content/_index.md": {Data: []byte("# example\n"), ModTime: themeTime},
+		"exampleSite/content/about.md":  {Data: []byte("# about\n"), ModTime: themeTime},
+		"static/logo.svg":               {Data: []byte("<svg/>\n")},
+	}
+
+	dirTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys, err := New([]Mount{
+		{Source: site, Dir: "content", Target: "content"},
+		{Source: theme, Dir: "layouts", Target: "layouts"},
+		{Source: theme, Dir: "exampleSite/content", Target: "content"},
+		{Source: theme, Dir: "static", Target: "static/theme"},
+	}, WithModTime(dirTime))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if err := fstest.TestFS(fsys,
+		"content/_index.md", "content/about.md", "content/posts/post.md",
+		"layouts/_default/single.html", "static/theme/logo.svg"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		modTime time.Time
+	}{
+		// The site shadows the theme.
+		{"content/_index.md", "# home\n", fakeserver.Epoch},
+		{"content/about.md", "# about\n", themeTime},
+		{"layouts/_default/single.html", "{{ .Content }}\n", themeTime},
+		{"static/theme/logo.svg", "<svg/>\n", dirTime},
+		{"static", "", dirTime},
+	}
+	for _, tt := range tests {
+		fi, err := fs.Stat(fsys, tt.name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if !fi.ModTime().Equal(tt.modTime) {
+			t.Errorf("%s: expected mod time %s, got %s", tt.name, tt.modTime, fi.ModTime())
+		}
+		if fi.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, tt.name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != tt.data {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.data, data)
+		}
+	}
+
+	if _, err := fs.Stat(fsys, "hugo.toml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist outside the mounts, got %v", err)
+	}
+	if _, err := New([]Mount{{Source: theme, Target: "../content"}}); err == nil {
+		t.Errorf("expected an error for an invalid target")
+	}
+}
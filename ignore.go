@@ -0,0 +1,149 @@
+package bbfs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// IgnoreFile is the name of the ignore file of a directory in the repository.
+const IgnoreFile = ".bbfsignore"
+
+// Ignore is a list of patterns in the syntax of .gitignore, for Export,
+// WriteTar, WriteZip and the mirror package to skip files, e.g. tests,
+// documentation and fixtures:
+//
+//	# comments and blank lines are ignored
+//	*_test.go
+//	/docs/
+//	testdata/
+//	!testdata/keep.json
+//
+// A pattern with a slash at the start or in the middle matches the path
+// relative to the root of the operation, other patterns match a name at any
+// depth. A pattern with a trailing slash only matches directories, ** matches
+// any number of directories, and ! includes what an earlier pattern ignored.
+// As in git, a file in an ignored directory cannot be included again.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	segs    []string
+	negate  bool
+	dirOnly bool
+	// inside is set for a pattern that ends with /**, which matches
+	// the content of a directory but not the directory.
+	inside bool
+}
+
+// ParseIgnore parses the patterns, one per line.
+func ParseIgnore(data []byte) (*Ignore, error) {
+	ig := &Ignore{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r ignoreRule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if strings.HasSuffix(line, "/**") {
+			r.inside = true
+		}
+		if line == "" {
+			return nil, fmt.Errorf("line %d: empty pattern", n)
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("line %d: bad pattern %q: %w", n, line, err)
+		}
+		if !anchored {
+			line = "**/" + line
+		}
+		r.segs = strings.Split(line, "/")
+		ig.rules = append(ig.rules, r)
+	}
+	return ig, sc.Err()
+}
+
+// ReadIgnore reads the ignore file in the file system, e.g. the IgnoreFile
+// of the directory of an export. A missing file ignores nothing.
+func ReadIgnore(fsys fs.FS, name string) (*Ignore, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Ignore{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ig, err := ParseIgnore(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ig, nil
+}
+
+// LoadIgnore reads a local ignore file.
+func LoadIgnore(name string) (*Ignore, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	ig, err := ParseIgnore(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ig, nil
+}
+
+// Append returns the patterns of ig followed by those of other, which take
+// precedence. Either may be nil.
+func (ig *Ignore) Append(other *Ignore) *Ignore {
+	res := &Ignore{}
+	if ig != nil {
+		res.rules = append(res.rules, ig.rules...)
+	}
+	if other != nil {
+		res.rules = append(res.rules, other.rules...)
+	}
+	return res
+}
+
+// Match returns true if the path, relative to the root of the operation, is
+// ignored. It does not check the parents of the path, the callers skip
+// ignored directories. A nil Ignore ignores nothing.
+func (ig *Ignore) Match(name string, isDir bool) bool {
+	if ig == nil || name == "." || name == "" {
+		return false
+	}
+	segs := strings.Split(name, "/")
+	ignored := false
+	for _, r := range ig.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.inside && len(segs) < len(r.segs) {
+			continue
+		}
+		if matchSegments(r.segs, segs) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,121 @@
+package bbfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIgnore(t *testing.T) {
+	ig, err := ParseIgnore([]byte(`# tests and fixtures
+*_test.go
+testdata/
+!testdata/keep.json
+/docs/
+build/**
+\#notes.txt
+`))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	tests := []struct {
+		name    string
+		isDir   bool
+		ignored bool
+	}{
+		{name: "main.go"},
+		{name: "main_test.go", ignored: true},
+		{name: "pkg/a/a_test.go", ignored: true},
+		{name: "testdata", isDir: true, ignored: true},
+		{name: "pkg/testdata", isDir: true, ignored: true},
+		{name: "testdata", isDir: false},
+		{name: "docs", isDir: true, ignored: true},
+		{name: "pkg/docs", isDir: true},
+		{name: "build", isDir: true},
+		{name: "build/out.bin", ignored: true},
+		{name: "build/x", isDir: true, ignored: true},
+		{name: "#notes.txt", ignored: true},
+		{name: "."},
+	}
+	for _, tt := range tests {
+		if got := ig.Match(tt.name, tt.isDir); got != tt.ignored {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.ignored, got)
+		}
+	}
+
+	// The later patterns take precedence.
+	ig = ig.Append(mustParseIgnore(t, "!main_test.go\n"))
+	if ig.Match("main_test.go", false) || !ig.Match("a_test.go", false) {
+		t.Errorf("expected only main_test.go to be included again")
+	}
+	if (*Ignore)(nil).Match("main.go", false) {
+		t.Errorf("expected a nil Ignore to ignore nothing")
+	}
+	if _, err := ParseIgnore([]byte("[a-\n")); err == nil {
+		t.Errorf("expected an error for a bad pattern")
+	}
+}
+
+func TestExportIgnore(t *testing.T) {
+	files := fstest.MapFS{
+		IgnoreFile:                {Data: []byte("*_test.go\ntestdata/\n")},
+		"main.go":                 {Data: []byte("package main")},
+		"main_test.go":            {Data: []byte("package main")},
+		"pkg/a.go":                {Data: []byte("package pkg")},
+		"pkg/testdata/large.json": {Data: []byte("{}")},
+	}
+	ig, err := ReadIgnore(files, IgnoreFile)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	res, err := Export(context.Background(), files, WithExportIgnore(ig))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var names []string
+	for name := range res {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{IgnoreFile, "main.go", "pkg", "pkg/a.go"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTar(&buf, files, "pkg", WithArchiveIgnore(ig)); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	names = nil
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		names = append(names, hdr.Name)
+	}
+	if !slices.Equal(names, []string{"a.go"}) {
+		t.Errorf("expected [a.go], got %v", names)
+	}
+
+	if ig, err := ReadIgnore(files, "pkg/"+IgnoreFile); err != nil || ig.Match("main_test.go", false) {
+		t.Errorf("expected a missing ignore file to ignore nothing, got %v", err)
+	}
+}
+
+func mustParseIgnore(t *testing.T, s string) *Ignore {
+	t.Helper()
+	ig, err := ParseIgnore([]byte(s))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	return ig
+}
@@ -0,0 +1,58 @@
+// Package buildinfo has the version of bbfs for the User-Agent of the clients.
+package buildinfo
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// modulePath is the path of the bbfs module.
+const modulePath = "github.com/myhops/bbfs"
+
+// version is set at build time with
+//
+//	go build -ldflags "-X github.com/myhops/bbfs/internal/buildinfo.version=v1.2.3"
+//
+// Without it Version takes the version of the module from the build info.
+var version string
+
+// Version returns the version of bbfs, devel for a build that has none,
+// e.g. with go run in the repository.
+func Version() string {
+	if version != "" {
+		return version
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if bi.Main.Path == modulePath {
+		return moduleVersion(bi.Main.Version)
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return moduleVersion(dep.Replace.Version)
+			}
+			return moduleVersion(dep.Version)
+		}
+	}
+	return "devel"
+}
+
+func moduleVersion(v string) string {
+	if v == "" || v == "(devel)" {
+		return "devel"
+	}
+	return v
+}
+
+// UserAgent returns the User-Agent of bbfs, bbfs/VERSION, followed by the
+// identity of the application if not empty, e.g. bbfs/v1.2.3 docs-portal/2.1.
+func UserAgent(app string) string {
+	ua := "bbfs/" + Version()
+	if app = strings.TrimSpace(app); app != "" {
+		ua += " " + app
+	}
+	return ua
+}
@@ -0,0 +1,552 @@
+// Package fakecloud implements a small part of the Bitbucket Cloud 2.0 REST API for tests.
+//
+// The repositories are fakeserver repositories, so the same fixtures serve both apis.
+package fakecloud
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+// ApiPath is the path of the api on the server.
+const ApiPath = "/2.0"
+
+// TokenPath is the path of the OAuth token endpoint on the server.
+const TokenPath = "/site/oauth2/access_token"
+
+// MaxPageLen is the largest page the server returns.
+const MaxPageLen = 100
+
+// Server is a fake Bitbucket Cloud server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	repos    map[string]*fakeserver.Repo
+	username string
+	password string
+	token    string
+	clientID string
+	secret   string
+	tokens   atomic.Int64
+	requests atomic.Int64
+}
+
+// New starts a fake server. Close it after use.
+func New() *Server {
+	s := &Server{
+		repos: map[string]*fakeserver.Repo{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the url of the api.
+func (s *Server) BaseURL() string {
+	return s.URL + ApiPath
+}
+
+// TokenURL returns the url of the OAuth token endpoint.
+func (s *Server) TokenURL() string {
+	return s.URL + TokenPath
+}
+
+// AddRepo adds the repository to the workspace.
+func (s *Server) AddRepo(workspace, slug string, r *fakeserver.Repo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[workspace+"/"+slug] = r
+}
+
+// Update calls f with the server locked, use it to change repositories
+// while the server runs.
+func (s *Server) Update(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+// SetAppPassword makes the server accept basic authentication with the
+// username and app password.
+func (s *Server) SetAppPassword(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username, s.password = username, password
+}
+
+// SetAccessToken makes the server accept the token as bearer token.
+func (s *Server) SetAccessToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// SetOAuthConsumer makes the token endpoint issue access tokens for the
+// client credentials grant of the consumer.
+func (s *Server) SetOAuthConsumer(clientID, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientID, s.secret = clientID, secret
+}
+
+// TokensIssued returns the number of access tokens issued by the token endpoint.
+func (s *Server) TokensIssued() int64 {
+	return s.tokens.Load()
+}
+
+// Requests returns the number of requests handled by the server.
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+// authorized returns true if the server requires no authentication or the
+// request carries valid credentials.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.username == "" && s.token == "" && s.clientID == "" {
+		return true
+	}
+	if u, p, ok := r.BasicAuth(); ok {
+		return s.username != "" && u == s.username && p == s.password
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	if s.token != "" && token == s.token {
+		return true
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(token, "oauth-"))
+	return s.clientID != "" && err == nil && n > 0 && int64(n) <= s.tokens.Load()
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == TokenPath {
+		s.serveToken(w, r)
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if r.URL.Path == ApiPath+"/workspaces" {
+		s.serveWorkspaces(w, r)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, ApiPath+"/repositories/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	// workspace/slug/endpoint/...
+	parts := strings.SplitN(strings.TrimSuffix(rest, "/"), "/", 4)
+	if len(parts) == 1 {
+		s.serveRepos(w, r, parts[0])
+		return
+	}
+	repo, ok := s.repos[parts[0]+"/"+parts[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+	if len(parts) == 2 {
+		writeJSON(w, repoJSON(parts[0], parts[1], repo))
+		return
+	}
+	var tail string
+	if len(parts) == 4 {
+		tail = parts[3]
+	}
+
+	switch parts[2] {
+	case "refs":
+		if tail != "tags" {
+			writeError(w, http.StatusNotFound, "Resource not found")
+			return
+		}
+		s.serveTags(w, r, repo)
+	case "commits":
+		s.serveCommits(w, r, repo, tail)
+	case "commit":
+		c := repo.Resolve(tail)
+		if c == nil || tail == "" {
+			writeError(w, http.StatusNotFound, "Commit not found")
+			return
+		}
+		writeJSON(w, commitJSON(c))
+	case "src":
+		if r.Method == http.MethodPost && tail == "" {
+			s.serveCommitSrc(w, r, repo)
+			return
+		}
+		s.serveSrc(w, r, repo, tail)
+	default:
+		writeError(w, http.StatusNotFound, "Resource not found")
+	}
+}
+
+// serveToken implements the client credentials grant.
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if r.Method != http.MethodPost || !ok || s.clientID == "" || id != s.clientID || secret != s.secret {
+		writeError(w, http.StatusUnauthorized, "invalid client")
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeError(w, http.StatusBadRequest, "unsupported grant type")
+		return
+	}
+	n := s.tokens.Add(1)
+	writeJSON(w, map[string]any{
+		"access_token": "oauth-" + strconv.FormatInt(n, 10),
+		"token_type":   "bearer",
+		"expires_in":   7200,
+	})
+}
+
+func (s *Server) serveWorkspaces(w http.ResponseWriter, r *http.Request) {
+	seen := map[string]bool{}
+	var values []any
+	for _, key := range slices.Sorted(maps.Keys(s.repos)) {
+		workspace, _, _ := strings.Cut(key, "/")
+		if seen[workspace] {
+			continue
+		}
+		seen[workspace] = true
+		values = append(values, map[string]any{
+			"type":       "workspace",
+			"slug":       workspace,
+			"name":       workspace,
+			"is_private": true,
+		})
+	}
+	writePage(w, r, values)
+}
+
+// serveRepos lists the repositories in the workspace, q filters on name.
+func (s *Server) serveRepos(w http.ResponseWriter, r *http.Request, workspace string) {
+	match, ok := parseQuery(r.URL.Query().Get("q"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid query")
+		return
+	}
+	var values []any
+	for _, key := range slices.Sorted(maps.Keys(s.repos)) {
+		ws, slug, _ := strings.Cut(key, "/")
+		if ws != workspace || !match(slug) {
+			continue
+		}
+		values = append(values, repoJSON(ws, slug, s.repos[key]))
+	}
+	writePage(w, r, values)
+}
+
+func repoJSON(workspace, slug string, repo *fakeserver.Repo) map[string]any {
+	return map[string]any{
+		"type":       "repository",
+		"slug":       slug,
+		"name":       slug,
+		"full_name":  workspace + "/" + slug,
+		"is_private": true,
+		"workspace":  map[string]any{"slug": workspace},
+		"mainbranch": map[string]any{"type": "branch", "name": repo.DefaultBranch},
+	}
+}
+
+func (s *Server) serveTags(w http.ResponseWriter, r *http.Request, repo *fakeserver.Repo) {
+	match, ok := parseQuery(r.URL.Query().Get("q"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid query")
+		return
+	}
+	var values []any
+	for _, name := range slices.Sorted(maps.Keys(repo.Tags)) {
+		if !match(name) {
+			continue
+		}
+		values = append(values, map[string]any{
+			"type":   "tag",
+			"name":   name,
+			"target": commitJSON(repo.Resolve(repo.Tags[name])),
+		})
+	}
+	writePage(w, r, values)
+}
+
+// parseQuery supports the queries name = "x" and name ~ "x".
+func parseQuery(q string) (func(string) bool, bool) {
+	if q == "" {
+		return func(string) bool { return true }, true
+	}
+	field, rest, _ := strings.Cut(strings.TrimSpace(q), " ")
+	op, value, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	value, err := strconv.Unquote(strings.TrimSpace(value))
+	if field != "name" || err != nil {
+		return nil, false
+	}
+	switch op {
+	case "=":
+		return func(s string) bool { return s == value }, true
+	case "~":
+		return func(s string) bool { return strings.Contains(strings.ToLower(s), strings.ToLower(value)) }, true
+	}
+	return nil, false
+}
+
+// serveCommits lists the commits reachable from include and not from exclude.
+func (s *Server) serveCommits(w http.ResponseWriter, r *http.Request, repo *fakeserver.Repo, revision string) {
+	if revision == "" {
+		revision = r.URL.Query().Get("include")
+	}
+	until := repo.Resolve(revision)
+	if until == nil {
+		writeError(w, http.StatusNotFound, "Commit not found")
+		return
+	}
+	exclude := map[string]bool{}
+	if ex := r.URL.Query().Get("exclude"); ex != "" {
+		c := repo.Resolve(ex)
+		if c == nil {
+			writeError(w, http.StatusNotFound, "Commit not found")
+			return
+		}
+		for ; c != nil; c = c.Parent {
+			exclude[c.ID] = true
+		}
+	}
+	var values []any
+	for c := until; c != nil; c = c.Parent {
+		if !exclude[c.ID] {
+			values = append(values, commitJSON(c))
+		}
+	}
+	writePage(w, r, values)
+}
+
+func commitJSON(c *fakeserver.Commit) map[string]any {
+	if c == nil {
+		return nil
+	}
+	res := map[string]any{
+		"type":    "commit",
+		"hash":    c.ID,
+		"date":    c.Timestamp.Format(time.RFC3339),
+		"message": c.Message,
+		"author": map[string]any{
+			"type": "author",
+			"raw":  c.Author + " <author@example.com>",
+		},
+	}
+	if c.Parent != nil {
+		res["parents"] = []any{map[string]any{"type": "commit", "hash": c.Parent.ID}}
+	}
+	return res
+}
+
+// serveSrc serves the meta data of a path with format=meta,
+// the listing of a directory or the content of a file.
+// Like on Bitbucket Cloud, the commit must not contain slashes.
+func (s *Server) serveSrc(w http.ResponseWriter, r *http.Request, repo *fakeserver.Repo, p string) {
+	rev, p, _ := strings.Cut(p, "/")
+	c := repo.Resolve(rev)
+	if c == nil || rev == "" {
+		writeError(w, http.StatusNotFound, "Commit not found")
+		return
+	}
+	name := strings.Trim(p, "/")
+	if name == "" {
+		name = "."
+	}
+	fi, err := fs.Stat(c.Files, name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "No such file or directory: "+name)
+		return
+	}
+	if r.URL.Query().Get("format") == "meta" {
+		writeJSON(w, entryJSON(c, name, fi))
+		return
+	}
+	if !fi.IsDir() {
+		data, err := fs.ReadFile(c.Files, name)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", http.DetectContentType(data))
+		http.ServeContent(w, r, "", c.Timestamp, bytes.NewReader(data))
+		return
+	}
+	entries, err := fs.ReadDir(c.Files, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var values []any
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		values = append(values, entryJSON(c, path.Join(name, e.Name()), info))
+	}
+	writePage(w, r, values)
+}
+
+// serveCommitSrc commits the files of the multipart form, like the src
+// endpoint of Bitbucket Cloud. A form file adds or updates the file of its
+// field name, a files field deletes the file. When parents is set and is not
+// the head of the branch the response is 409 Conflict.
+func (s *Server) serveCommitSrc(w http.ResponseWriter, r *http.Request, repo *fakeserver.Repo) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	branch := r.FormValue("branch")
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	head := repo.Resolve("refs/heads/" + branch)
+	if head == nil {
+		writeError(w, http.StatusNotFound, "Branch not found: "+branch)
+		return
+	}
+	if parent := r.FormValue("parents"); parent != "" && !strings.HasPrefix(head.ID, parent) {
+		writeError(w, http.StatusConflict, "The parent is not the head of the branch")
+		return
+	}
+	files := maps.Clone(head.Files)
+	if files == nil {
+		files = fstest.MapFS{}
+	}
+	for _, name := range r.MultipartForm.Value["files"] {
+		if _, ok := files[name]; !ok {
+			writeError(w, http.StatusNotFound, "No such file: "+name)
+			return
+		}
+		delete(files, name)
+	}
+	for name, fhs := range r.MultipartForm.File {
+		f, err := fhs[0].Open()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		files[name] = &fstest.MapFile{Data: data}
+	}
+	message := r.FormValue("message")
+	if message == "" {
+		message = "Edited online with Bitbucket"
+	}
+	c := repo.Commit(branch, message, files)
+	w.Header().Set("Location", s.URL+strings.TrimSuffix(r.URL.Path, "/src")+"/commit/"+c.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func entryJSON(c *fakeserver.Commit, name string, fi fs.FileInfo) map[string]any {
+	if name == "." {
+		name = ""
+	}
+	res := map[string]any{
+		"path":   name,
+		"commit": map[string]any{"type": "commit", "hash": c.ID},
+	}
+	if fi.IsDir() {
+		res["type"] = "commit_directory"
+		return res
+	}
+	res["type"] = "commit_file"
+	res["size"] = fi.Size()
+	res["attributes"] = []string{}
+	if fi.Mode()&0o111 != 0 {
+		res["attributes"] = []string{"executable"}
+	}
+	return res
+}
+
+// writePage writes the page of values selected by the page and pagelen parameters.
+// The page parameter is an opaque token, like the cursors used by the
+// src endpoint of Bitbucket Cloud.
+func writePage(w http.ResponseWriter, r *http.Request, values []any) {
+	pagelen, _ := strconv.Atoi(r.URL.Query().Get("pagelen"))
+	if pagelen <= 0 {
+		pagelen = 10
+	}
+	if pagelen > MaxPageLen {
+		writeError(w, http.StatusBadRequest, "pagelen too large")
+		return
+	}
+	pg := 1
+	if token := r.URL.Query().Get("page"); token != "" {
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		if err == nil {
+			pg, err = strconv.Atoi(string(b))
+		}
+		if err != nil || pg < 1 {
+			writeError(w, http.StatusBadRequest, "invalid page")
+			return
+		}
+	}
+	start := min((pg-1)*pagelen, len(values))
+	end := min(start+pagelen, len(values))
+	res := map[string]any{
+		"size":    len(values),
+		"page":    pg,
+		"pagelen": pagelen,
+		"values":  append([]any{}, values[start:end]...),
+	}
+	if end < len(values) {
+		next := *r.URL
+		next.Scheme = "http"
+		next.Host = r.Host
+		q := next.Query()
+		q.Set("page", pageToken(pg+1))
+		next.RawQuery = q.Encode()
+		res["next"] = next.String()
+	}
+	writeJSON(w, res)
+}
+
+// pageToken returns the token for the page.
+func pageToken(pg int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(pg)))
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":  "error",
+		"error": map[string]any{"message": msg},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,60 @@
+package fakeserver
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// serveArchive returns a zip archive of the files at the ref,
+// limited to the path and with the prefix before the names.
+func (s *Server) serveArchive(w http.ResponseWriter, r *http.Request, repo *Repo) {
+	files, ok := repo.files(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	if f := q.Get("format"); f != "" && f != "zip" {
+		http.Error(w, "only zip archives are supported", http.StatusBadRequest)
+		return
+	}
+	root := fsPath(q.Get("path"))
+	if _, err := fs.Stat(files, root); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err := fs.WalkDir(files, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(files, p)
+		if err != nil {
+			return err
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     q.Get("prefix") + strings.TrimPrefix(p, "./"),
+			Method:   zip.Deflate,
+			Modified: Epoch,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	})
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Write(buf.Bytes())
+}
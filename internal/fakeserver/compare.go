@@ -0,0 +1,139 @@
+package fakeserver
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing/fstest"
+)
+
+// change is a changed file between two commits.
+type change struct {
+	path     string
+	typ      string
+	old, new []byte
+}
+
+// changes returns the files that differ between the commits, sorted by path.
+func changes(from, to fstest.MapFS) []change {
+	var res []change
+	paths := map[string]bool{}
+	for p, f := range from {
+		if !f.Mode.IsDir() {
+			paths[p] = true
+		}
+	}
+	for p, f := range to {
+		if !f.Mode.IsDir() {
+			paths[p] = true
+		}
+	}
+	for _, p := range sortedKeys(paths) {
+		nf, inFrom := from[p]
+		of, inTo := to[p]
+		switch {
+		case inFrom && !inTo:
+			res = append(res, change{path: p, typ: "ADD", new: nf.Data})
+		case !inFrom && inTo:
+			res = append(res, change{path: p, typ: "DELETE", old: of.Data})
+		case !bytes.Equal(nf.Data, of.Data):
+			res = append(res, change{path: p, typ: "MODIFY", old: of.Data, new: nf.Data})
+		}
+	}
+	return res
+}
+
+// serveCompare serves compare/changes, compare/commits and compare/diff/{path}.
+func (s *Server) serveCompare(w http.ResponseWriter, r *http.Request, repo *Repo, tail string) {
+	from := repo.commit(r.URL.Query().Get("from"))
+	to := repo.commit(r.URL.Query().Get("to"))
+	if from == nil || to == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if tail == "commits" {
+		// The commits reachable from from and not from to.
+		exclude := map[string]bool{}
+		for c := to; c != nil; c = c.Parent {
+			exclude[c.ID] = true
+		}
+		var values []any
+		for c := from; c != nil; c = c.Parent {
+			if !exclude[c.ID] {
+				values = append(values, commitJSON(c))
+			}
+		}
+		writePage(w, r, values)
+		return
+	}
+	all := changes(from.Files, to.Files)
+
+	if tail == "changes" {
+		var values []any
+		for _, c := range all {
+			values = append(values, map[string]any{
+				"path":     map[string]any{"toString": c.path},
+				"type":     c.typ,
+				"nodeType": "FILE",
+			})
+		}
+		writePage(w, r, values)
+		return
+	}
+	p, ok := strings.CutPrefix(tail, "diff")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	p = strings.Trim(p, "/")
+	diffs := []any{}
+	for _, c := range all {
+		if p != "" && c.path != p && !strings.HasPrefix(c.path, p+"/") {
+			continue
+		}
+		diffs = append(diffs, diffJSON(c))
+	}
+	writeJSON(w, map[string]any{"diffs": diffs})
+}
+
+// diffJSON returns a diff with a single hunk that replaces all lines.
+func diffJSON(c change) map[string]any {
+	d := map[string]any{}
+	if c.typ != "ADD" {
+		d["source"] = map[string]any{"toString": c.path}
+	}
+	if c.typ != "DELETE" {
+		d["destination"] = map[string]any{"toString": c.path}
+	}
+	if bytes.IndexByte(c.old, 0) >= 0 || bytes.IndexByte(c.new, 0) >= 0 {
+		d["binary"] = true
+		return d
+	}
+	oldLines, newLines := lines(c.old), lines(c.new)
+	var segments []any
+	if len(oldLines) > 0 {
+		segments = append(segments, map[string]any{"type": "REMOVED", "lines": oldLines})
+	}
+	if len(newLines) > 0 {
+		segments = append(segments, map[string]any{"type": "ADDED", "lines": newLines})
+	}
+	d["hunks"] = []any{map[string]any{
+		"sourceLine":      min(1, len(oldLines)),
+		"sourceSpan":      len(oldLines),
+		"destinationLine": min(1, len(newLines)),
+		"destinationSpan": len(newLines),
+		"segments":        segments,
+	}}
+	return d
+}
+
+func lines(data []byte) []any {
+	var res []any
+	if len(data) == 0 {
+		return res
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		res = append(res, map[string]any{"line": l})
+	}
+	return res
+}
@@ -0,0 +1,182 @@
+package fakeserver
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// files returns the file tree at the ref in the request.
+func (r *Repo) files(req *http.Request) (fs.FS, bool) {
+	c := r.commit(req.URL.Query().Get("at"))
+	if c == nil {
+		return nil, false
+	}
+	return c.Files, true
+}
+
+// fsPath converts the path from the url to a path in the file tree.
+func fsPath(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// blobID returns the id of the git blob with the data.
+func blobID(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// serveBrowse lists a directory or returns the lines of a file,
+// a PUT commits a file.
+func (s *Server) serveBrowse(w http.ResponseWriter, r *http.Request, repo *Repo, p string) {
+	if r.Method == http.MethodPut {
+		s.servePut(w, r, repo, p)
+		return
+	}
+	files, ok := repo.files(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	name := fsPath(p)
+	fi, err := fs.Stat(files, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !fi.IsDir() {
+		s.serveLines(w, r, files, name)
+		return
+	}
+
+	entries, err := fs.ReadDir(files, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var values []any
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		typ := "FILE"
+		if e.IsDir() {
+			typ = "DIRECTORY"
+		}
+		v := map[string]any{
+			"path": map[string]any{
+				"components": []string{e.Name()},
+				"name":       e.Name(),
+				"toString":   e.Name(),
+			},
+			"type": typ,
+		}
+		if !e.IsDir() {
+			v["size"] = info.Size()
+			data, err := fs.ReadFile(files, path.Join(name, e.Name()))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			v["contentId"] = blobID(data)
+			if info.Mode()&0o111 != 0 {
+				v["executable"] = true
+			}
+		}
+		values = append(values, v)
+	}
+	writeJSON(w, map[string]any{
+		"path": map[string]any{
+			"components": strings.Split(strings.Trim(p, "/"), "/"),
+			"toString":   strings.Trim(p, "/"),
+		},
+		"children": page(r, values),
+	})
+}
+
+// serveLines returns the file as lines.
+func (s *Server) serveLines(w http.ResponseWriter, r *http.Request, files fs.FS, name string) {
+	data, err := fs.ReadFile(files, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		writeJSON(w, map[string]any{
+			"binary": true,
+			"path": map[string]any{
+				"name":     path.Base(name),
+				"toString": name,
+			},
+		})
+		return
+	}
+	var values []any
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		values = append(values, map[string]any{"text": line})
+	}
+	pg := page(r, values)
+	pg["lines"] = pg["values"]
+	delete(pg, "values")
+	writeJSON(w, pg)
+}
+
+// serveRaw returns the content of a file.
+func (s *Server) serveRaw(w http.ResponseWriter, r *http.Request, repo *Repo, p string) {
+	files, ok := repo.files(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := fs.ReadFile(files, fsPath(p))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	http.ServeContent(w, r, "", Epoch, bytes.NewReader(data))
+}
+
+// serveFiles lists the paths of all files below a directory.
+func (s *Server) serveFiles(w http.ResponseWriter, r *http.Request, repo *Repo, p string) {
+	files, ok := repo.files(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	dir := fsPath(p)
+	if fi, err := fs.Stat(files, dir); err != nil || !fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	var values []any
+	err := fs.WalkDir(files, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := name
+		if dir != "." {
+			rel = strings.TrimPrefix(name, dir+"/")
+		}
+		values = append(values, rel)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writePage(w, r, values)
+}
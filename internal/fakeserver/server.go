@@ -0,0 +1,462 @@
+// Package fakeserver implements a small part of the Bitbucket Server REST API for tests.
+package fakeserver
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing/fstest"
+	"time"
+)
+
+// ApiPath is the path of the api on the server.
+const ApiPath = "/rest/api/latest"
+
+// BranchUtilsPath is the path of the branch utils api on the server.
+const BranchUtilsPath = "/rest/branch-utils/latest"
+
+// Version is the server version reported by the fake server.
+const Version = "9.0.0"
+
+// Epoch is the time of the first commit in a repository.
+var Epoch = time.Date(2024, 8, 5, 9, 27, 4, 0, time.UTC)
+
+// Commit is a commit with the complete file tree.
+type Commit struct {
+	ID        string
+	Message   string
+	Author    string
+	Timestamp time.Time
+	Files     fstest.MapFS
+	Parent    *Commit
+}
+
+// Repo is a repository on the fake server.
+type Repo struct {
+	Commits       []*Commit
+	Branches      map[string]string
+	Tags          map[string]string
+	DefaultBranch string
+	// HideSizes makes the sizes endpoint respond with not found,
+	// as on servers without it.
+	HideSizes bool
+	// PullRequests are the pull requests of the repository, by id - 1.
+	PullRequests []*PullRequest
+}
+
+// NewRepo returns a repository with a single commit on branch main.
+func NewRepo(files fstest.MapFS) *Repo {
+	r := &Repo{
+		Branches:      map[string]string{},
+		Tags:          map[string]string{},
+		DefaultBranch: "main",
+	}
+	r.Commit("main", "initial commit", files)
+	return r
+}
+
+// Commit adds a commit with the files to the branch and returns it.
+func (r *Repo) Commit(branch, message string, files fstest.MapFS) *Commit {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d/%s/%s", len(r.Commits), branch, message)))
+	c := &Commit{
+		ID:        hex.EncodeToString(sum[:]),
+		Message:   message,
+		Author:    "Fake Author",
+		Timestamp: Epoch.Add(time.Duration(len(r.Commits)) * time.Hour),
+		Files:     files,
+		Parent:    r.commit(r.Branches[branch]),
+	}
+	r.Commits = append(r.Commits, c)
+	r.Branches[branch] = c.ID
+	return c
+}
+
+// Tag tags the commit.
+func (r *Repo) Tag(name string, c *Commit) {
+	r.Tags[name] = c.ID
+}
+
+// Resolve returns the commit for a branch, tag or (abbreviated) commit id,
+// an empty ref is the default branch. It returns nil if the ref does not exist.
+func (r *Repo) Resolve(ref string) *Commit {
+	return r.commit(ref)
+}
+
+// commit returns the commit for the ref, an empty ref is the default branch.
+func (r *Repo) commit(ref string) *Commit {
+	if ref == "" {
+		ref = r.DefaultBranch
+	}
+	id := ref
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		id = r.Branches[strings.TrimPrefix(ref, "refs/heads/")]
+	case strings.HasPrefix(ref, "refs/tags/"):
+		id = r.Tags[strings.TrimPrefix(ref, "refs/tags/")]
+	default:
+		if b, ok := r.Branches[ref]; ok {
+			id = b
+		} else if t, ok := r.Tags[ref]; ok {
+			id = t
+		}
+	}
+	for _, c := range r.Commits {
+		if id != "" && strings.HasPrefix(c.ID, id) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Server is a fake Bitbucket server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	repos     map[string]*Repo
+	accessKey string
+	requests  atomic.Int64
+	// disabled are the endpoints, or method and endpoint, that the
+	// server does not have.
+	disabled map[string]bool
+}
+
+// New starts a fake server. Close it after use.
+func New() *Server {
+	s := &Server{
+		repos: map[string]*Repo{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the url of the api.
+func (s *Server) BaseURL() string {
+	return s.URL + ApiPath
+}
+
+// AddRepo adds the repository to the server.
+func (s *Server) AddRepo(project, slug string, r *Repo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[project+"/"+slug] = r
+}
+
+// Update calls f with the server locked, use it to change repositories
+// while the server runs.
+func (s *Server) Update(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+// SetAccessKey makes the server require the key as bearer token.
+// The application properties are served without it.
+func (s *Server) SetAccessKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessKey = key
+}
+
+// Disable makes the server answer the requests with the method to the
+// endpoint, e.g. files or raw, like an older version without it: 405 for the
+// method, 404 for all methods when method is empty.
+func (s *Server) Disable(method, endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disabled == nil {
+		s.disabled = map[string]bool{}
+	}
+	s.disabled[method+" "+endpoint] = true
+}
+
+// Requests returns the number of requests handled by the server.
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == ApiPath+"/application-properties" {
+		writeJSON(w, map[string]any{
+			"version":     Version,
+			"buildNumber": "9000000",
+			"displayName": "Bitbucket",
+		})
+		return
+	}
+	if s.accessKey != "" && r.Header.Get("Authorization") != "Bearer "+s.accessKey {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == ApiPath+"/projects" {
+		s.serveProjects(w, r)
+		return
+	}
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/projects/"); ok {
+		s.serveSizes(w, r, rest)
+		return
+	}
+	if rest, ok := strings.CutPrefix(r.URL.Path, BranchUtilsPath+"/projects/"); ok {
+		s.serveBranchUtils(w, r, rest)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, ApiPath+"/projects/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	// project/repos/slug/endpoint/...
+	parts := strings.SplitN(rest, "/", 5)
+	if len(parts) == 2 && parts[1] == "repos" {
+		s.serveRepos(w, r, parts[0])
+		return
+	}
+	if len(parts) < 3 || parts[1] != "repos" {
+		http.NotFound(w, r)
+		return
+	}
+	repo, ok := s.repos[parts[0]+"/"+parts[2]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 3 {
+		writeJSON(w, map[string]any{
+			"slug":    parts[2],
+			"name":    parts[2],
+			"project": map[string]any{"key": parts[0]},
+		})
+		return
+	}
+	var tail string
+	if len(parts) == 5 {
+		tail = parts[4]
+	}
+
+	if s.disabled[" "+parts[3]] {
+		http.NotFound(w, r)
+		return
+	}
+	if s.disabled[r.Method+" "+parts[3]] {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch parts[3] {
+	case "branches":
+		s.serveBranches(w, r, repo)
+	case "tags":
+		s.serveTags(w, r, repo)
+	case "commits":
+		s.serveCommit(w, r, repo, tail)
+	case "browse":
+		s.serveBrowse(w, r, repo, tail)
+	case "raw":
+		s.serveRaw(w, r, repo, tail)
+	case "files":
+		s.serveFiles(w, r, repo, tail)
+	case "compare":
+		s.serveCompare(w, r, repo, tail)
+	case "archive":
+		s.serveArchive(w, r, repo)
+	case "pull-requests":
+		s.servePullRequests(w, r, repo, tail)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveProjects(w http.ResponseWriter, r *http.Request) {
+	filter := r.URL.Query().Get("name")
+	seen := map[string]bool{}
+	var values []any
+	for _, key := range sortedKeys(s.repos) {
+		project, _, _ := strings.Cut(key, "/")
+		if seen[project] || !strings.Contains(project, filter) {
+			continue
+		}
+		seen[project] = true
+		values = append(values, map[string]any{
+			"key":  project,
+			"name": project,
+			"type": "NORMAL",
+		})
+	}
+	writePage(w, r, values)
+}
+
+func (s *Server) serveRepos(w http.ResponseWriter, r *http.Request, project string) {
+	var values []any
+	for _, key := range sortedKeys(s.repos) {
+		p, slug, _ := strings.Cut(key, "/")
+		if p != project {
+			continue
+		}
+		values = append(values, map[string]any{
+			"slug":    slug,
+			"name":    slug,
+			"project": map[string]any{"key": project},
+		})
+	}
+	writePage(w, r, values)
+}
+
+func (s *Server) serveBranches(w http.ResponseWriter, r *http.Request, repo *Repo) {
+	filter := r.URL.Query().Get("filterText")
+	var values []any
+	for _, name := range sortedKeys(repo.Branches) {
+		if !strings.Contains(name, filter) {
+			continue
+		}
+		values = append(values, map[string]any{
+			"id":           "refs/heads/" + name,
+			"displayId":    name,
+			"type":         "BRANCH",
+			"latestCommit": repo.Branches[name],
+			"isDefault":    name == repo.DefaultBranch,
+		})
+	}
+	writePage(w, r, values)
+}
+
+func (s *Server) serveTags(w http.ResponseWriter, r *http.Request, repo *Repo) {
+	filter := r.URL.Query().Get("filterText")
+	var values []any
+	for _, name := range sortedKeys(repo.Tags) {
+		if !strings.Contains(name, filter) {
+			continue
+		}
+		values = append(values, map[string]any{
+			"id":              "refs/tags/" + name,
+			"displayId":       name,
+			"type":            "TAG",
+			"latestCommit":    repo.Tags[name],
+			"latestChangeset": repo.Tags[name],
+		})
+	}
+	writePage(w, r, values)
+}
+
+func (s *Server) serveCommit(w http.ResponseWriter, r *http.Request, repo *Repo, id string) {
+	if id == "" {
+		s.serveCommits(w, r, repo)
+		return
+	}
+	c := repo.commit(id)
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, commitJSON(c))
+}
+
+// serveCommits lists the commits reachable from until and not from since.
+func (s *Server) serveCommits(w http.ResponseWriter, r *http.Request, repo *Repo) {
+	until := repo.commit(r.URL.Query().Get("until"))
+	if until == nil {
+		http.NotFound(w, r)
+		return
+	}
+	exclude := map[string]bool{}
+	if since := r.URL.Query().Get("since"); since != "" {
+		c := repo.commit(since)
+		if c == nil {
+			http.NotFound(w, r)
+			return
+		}
+		for ; c != nil; c = c.Parent {
+			exclude[c.ID] = true
+		}
+	}
+	var values []any
+	for c := until; c != nil; c = c.Parent {
+		if !exclude[c.ID] {
+			values = append(values, commitJSON(c))
+		}
+	}
+	writePage(w, r, values)
+}
+
+func commitJSON(c *Commit) map[string]any {
+	actor := map[string]any{
+		"name":         c.Author,
+		"emailAddress": "author@example.com",
+	}
+	parents := []any{}
+	if c.Parent != nil {
+		parents = append(parents, map[string]any{"id": c.Parent.ID, "displayId": c.Parent.ID[:11]})
+	}
+	return map[string]any{
+		"id":                 c.ID,
+		"displayId":          c.ID[:11],
+		"parents":            parents,
+		"author":             actor,
+		"authorTimestamp":    c.Timestamp.UnixMilli(),
+		"committer":          actor,
+		"committerTimestamp": c.Timestamp.UnixMilli(),
+		"message":            c.Message,
+	}
+}
+
+// page returns the page of values as selected by the start and limit parameters.
+func page(r *http.Request, values []any) map[string]any {
+	start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 25
+	}
+	start = min(start, len(values))
+	end := min(start+limit, len(values))
+	res := map[string]any{
+		"size":       end - start,
+		"limit":      limit,
+		"start":      start,
+		"isLastPage": end == len(values),
+		"values":     append([]any{}, values[start:end]...),
+	}
+	if end < len(values) {
+		res["nextPageStart"] = end
+	}
+	return res
+}
+
+func writePage(w http.ResponseWriter, r *http.Request, values []any) {
+	writeJSON(w, page(r, values))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// serveSizes returns the size of a repository for a path project/repos/slug/sizes
+// of the web interface. The size is the total size of the files of all commits.
+func (s *Server) serveSizes(w http.ResponseWriter, r *http.Request, p string) {
+	parts := strings.Split(p, "/")
+	if len(parts) != 4 || parts[1] != "repos" || parts[3] != "sizes" {
+		http.NotFound(w, r)
+		return
+	}
+	repo, ok := s.repos[parts[0]+"/"+parts[2]]
+	if !ok || repo.HideSizes {
+		http.NotFound(w, r)
+		return
+	}
+	var size int64
+	for _, c := range repo.Commits {
+		for _, f := range c.Files {
+			size += int64(len(f.Data))
+		}
+	}
+	writeJSON(w, map[string]any{"repository": size, "attachments": 0})
+}
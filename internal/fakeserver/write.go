@@ -0,0 +1,265 @@
+package fakeserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing/fstest"
+)
+
+// servePut commits the content of the multipart form on the branch, like
+// the browse endpoint of the server.
+//
+// A new file must not have a sourceCommitId, an existing one must have it
+// and must not have changed on the branch since that commit, otherwise the
+// response is 409 Conflict. A missing branch is created from sourceBranch.
+func (s *Server) servePut(w http.ResponseWriter, r *http.Request, repo *Repo, p string) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, _, err := r.FormFile("content")
+	if err != nil {
+		http.Error(w, "content is missing", http.StatusBadRequest)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	branch := r.FormValue("branch")
+	if branch == "" {
+		http.Error(w, "branch is missing", http.StatusBadRequest)
+		return
+	}
+	head := repo.commit("refs/heads/" + branch)
+	if head == nil {
+		if src := r.FormValue("sourceBranch"); src != "" {
+			head = repo.commit("refs/heads/" + src)
+		}
+		if head == nil {
+			http.NotFound(w, r)
+			return
+		}
+		repo.Branches[branch] = head.ID
+	}
+	name := fsPath(p)
+	current, exists := head.Files[name]
+	switch source := r.FormValue("sourceCommitId"); {
+	case exists && source == "":
+		http.Error(w, "the file already exists", http.StatusConflict)
+		return
+	case exists:
+		c := repo.commit(source)
+		if c == nil {
+			http.Error(w, "unknown sourceCommitId", http.StatusBadRequest)
+			return
+		}
+		if old, ok := c.Files[name]; !ok || !bytes.Equal(old.Data, current.Data) {
+			http.Error(w, "the file was changed since the sourceCommitId", http.StatusConflict)
+			return
+		}
+	}
+	if exists && bytes.Equal(current.Data, data) {
+		http.Error(w, "the content is unchanged", http.StatusConflict)
+		return
+	}
+	if _, err := fs.Stat(head.Files, name); err == nil && !exists {
+		http.Error(w, "the path is a directory", http.StatusConflict)
+		return
+	}
+	files := maps.Clone(head.Files)
+	if files == nil {
+		files = fstest.MapFS{}
+	}
+	files[name] = &fstest.MapFile{Data: data}
+	message := r.FormValue("message")
+	if message == "" {
+		message = "Edit " + name
+	}
+	writeJSON(w, commitJSON(repo.Commit(branch, message, files)))
+}
+
+// PullRequest is a pull request of a repository on the fake server.
+type PullRequest struct {
+	ID    int
+	Title string
+	// From and To are the names of the branches.
+	From    string
+	To      string
+	Version int
+	// State is OPEN, DECLINED or MERGED.
+	State string
+}
+
+func (p *PullRequest) json(mergeCommit string) map[string]any {
+	res := map[string]any{
+		"id":      p.ID,
+		"version": p.Version,
+		"state":   p.State,
+		"title":   p.Title,
+		"fromRef": map[string]any{"id": "refs/heads/" + p.From, "displayId": p.From},
+		"toRef":   map[string]any{"id": "refs/heads/" + p.To, "displayId": p.To},
+	}
+	if mergeCommit != "" {
+		res["properties"] = map[string]any{"mergeCommit": map[string]any{"id": mergeCommit}}
+	}
+	return res
+}
+
+// servePullRequests creates a pull request, or merges it for
+// pull-requests/{id}/merge. The merge fails with 409 Conflict when a file
+// changed on both branches since their common ancestor.
+func (s *Server) servePullRequests(w http.ResponseWriter, r *http.Request, repo *Repo, tail string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if tail == "" {
+		var body struct {
+			Title   string `json:"title"`
+			FromRef struct {
+				ID string `json:"id"`
+			} `json:"fromRef"`
+			ToRef struct {
+				ID string `json:"id"`
+			} `json:"toRef"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from := strings.TrimPrefix(body.FromRef.ID, "refs/heads/")
+		to := strings.TrimPrefix(body.ToRef.ID, "refs/heads/")
+		if _, ok := repo.Branches[from]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if _, ok := repo.Branches[to]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		pr := &PullRequest{ID: len(repo.PullRequests) + 1, Title: body.Title, From: from, To: to, State: "OPEN"}
+		repo.PullRequests = append(repo.PullRequests, pr)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(pr.json(""))
+		return
+	}
+
+	idStr, ok := strings.CutSuffix(tail, "/merge")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil || id < 1 || id > len(repo.PullRequests) {
+		http.NotFound(w, r)
+		return
+	}
+	pr := repo.PullRequests[id-1]
+	if v := r.URL.Query().Get("version"); v != strconv.Itoa(pr.Version) || pr.State != "OPEN" {
+		http.Error(w, "the pull request is out of date", http.StatusConflict)
+		return
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	files, ok := merge(repo.commit(repo.Branches[pr.From]), repo.commit(repo.Branches[pr.To]))
+	if !ok {
+		http.Error(w, "the pull request has conflicts", http.StatusConflict)
+		return
+	}
+	message := body.Message
+	if message == "" {
+		message = fmt.Sprintf("Merge pull request #%d", pr.ID)
+	}
+	c := repo.Commit(pr.To, message, files)
+	pr.State = "MERGED"
+	pr.Version++
+	writeJSON(w, pr.json(c.ID))
+}
+
+// merge returns the files of to with the changes of from since their
+// common ancestor, false when a file changed on both.
+func merge(from, to *Commit) (fstest.MapFS, bool) {
+	base := from
+	for base != nil && !isAncestor(base, to) {
+		base = base.Parent
+	}
+	if base == nil {
+		return nil, false
+	}
+	theirs := map[string]bool{}
+	for _, ch := range changes(to.Files, base.Files) {
+		theirs[ch.path] = true
+	}
+	files := maps.Clone(to.Files)
+	if files == nil {
+		files = fstest.MapFS{}
+	}
+	for _, ch := range changes(from.Files, base.Files) {
+		if theirs[ch.path] {
+			return nil, false
+		}
+		if ch.typ == "DELETE" {
+			delete(files, ch.path)
+			continue
+		}
+		files[ch.path] = from.Files[ch.path]
+	}
+	return files, true
+}
+
+// isAncestor reports whether a is b or an ancestor of b.
+func isAncestor(a, b *Commit) bool {
+	for c := b; c != nil; c = c.Parent {
+		if c == a {
+			return true
+		}
+	}
+	return false
+}
+
+// serveBranchUtils deletes the branch of a path project/repos/slug/branches
+// of the branch utils api. The open pull requests from it are declined.
+func (s *Server) serveBranchUtils(w http.ResponseWriter, r *http.Request, p string) {
+	parts := strings.Split(p, "/")
+	if len(parts) != 4 || parts[1] != "repos" || parts[3] != "branches" {
+		http.NotFound(w, r)
+		return
+	}
+	repo, ok := s.repos[parts[0]+"/"+parts[2]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimPrefix(body.Name, "refs/heads/")
+	if _, ok := repo.Branches[name]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	delete(repo.Branches, name)
+	for _, pr := range repo.PullRequests {
+		if pr.From == name && pr.State == "OPEN" {
+			pr.State = "DECLINED"
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
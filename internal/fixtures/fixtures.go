@@ -0,0 +1,36 @@
+// Package fixtures holds responses of the Bitbucket APIs for the parser tests.
+//
+// The responses have the shape of those of the servers, with made up names,
+// ids and dates. They describe the same repository in every version:
+//
+//	server/7/  Bitbucket Server 7.21
+//	server/8/  Bitbucket Data Center 8.19
+//	cloud/2.0/ Bitbucket Cloud, api 2.0
+//
+// The repository has a README.md and a docs directory on the branch main,
+// two commits and the tag v1.0.0 on the last commit. Add a directory when
+// a version changes the shape of a response, and a row to the tests of the
+// parsers.
+package fixtures
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed server cloud
+var files embed.FS
+
+// Server lists the directories of the Bitbucket Server versions.
+var Server = []string{"server/7", "server/8"}
+
+// Read returns the content of the fixture, e.g. server/8/tags.json.
+// It panics when the fixture does not exist.
+func Read(name string) []byte {
+	data, err := fs.ReadFile(files, name)
+	if err != nil {
+		panic(fmt.Sprintf("fixtures: %s", err))
+	}
+	return data
+}
@@ -0,0 +1,73 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// InvalidateChanges drops the cached responses at the ref for the paths
+// that changed between the commits from and to, found with the compare
+// api, and returns the changed paths. Use the ref as the FS values of the
+// client use it, e.g. "main" or "refs/heads/main".
+//
+// It keeps the rest of the cache, unlike ClearCache after a push.
+func InvalidateChanges(ctx context.Context, client *server.Client, project, repo string, ref Ref, from, to string) ([]string, error) {
+	cmd := &server.GetChangesCommand{
+		ProjectKey: project,
+		RepoSlug:   repo,
+		From:       server.CommitRef(to),
+		To:         server.CommitRef(from),
+		Limit:      server.MaxLimit,
+	}
+	var paths []string
+	for {
+		resp, err := client.GetChanges(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range resp.Changes {
+			paths = append(paths, ch.Path)
+			if ch.SrcPath != "" {
+				paths = append(paths, ch.SrcPath)
+			}
+		}
+		if resp.IsLastPage || len(resp.Changes) == 0 {
+			break
+		}
+		cmd.Start = resp.NextPageStart
+	}
+	client.InvalidatePaths(project, repo, ref, paths)
+	return paths, nil
+}
+
+// Prefetch reads the files at the paths in the repository, to have them in
+// the cache of the FS before they are needed. Paths outside the root of an
+// FS from this package and files that do not exist, e.g. deleted by the
+// change, are skipped.
+func Prefetch(fsys fs.FS, paths []string) error {
+	root := ""
+	if b, ok := fsys.(Bitbucket); ok {
+		root = strings.Trim(b.Root(), "/")
+	}
+	var errs []error
+	for _, p := range paths {
+		name := strings.Trim(p, "/")
+		if root != "" {
+			var ok bool
+			if name, ok = strings.CutPrefix(name, root+"/"); !ok {
+				continue
+			}
+		}
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		if _, err := fs.ReadFile(fsys, name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestWatcherChangedPathsOnly(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"a.txt":     {Data: []byte("a1")},
+		"dir/b.txt": {Data: []byte("b1")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: "main"})
+	fsys := r.FS("")
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		if _, err := fs.ReadFile(fsys, name); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+
+	w := &Watcher{
+		Client:           r.Client(),
+		ProjectKey:       "PRJ",
+		RepoSlug:         "repo",
+		Ref:              "main",
+		Interval:         10 * time.Millisecond,
+		ChangedPathsOnly: true,
+		Prefetch:         fsys,
+	}
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.Update(func() {
+			repo.Commit("main", "second", fstest.MapFS{
+				"a.txt":     {Data: []byte("a1")},
+				"dir/b.txt": {Data: []byte("b2")},
+			})
+		})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stop := errors.New("stop")
+	if err := w.Watch(ctx, func(RefEvent) error { return stop }); err != stop {
+		t.Fatalf("expected the error from the callback, got %v", err)
+	}
+
+	// The unchanged file stays in the cache and the changed file is prefetched.
+	before := srv.Requests()
+	for name, want := range map[string]string{"a.txt": "a1", "dir/b.txt": "b2"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != want {
+			t.Errorf("%s: expected %q, got %q", name, want, data)
+		}
+	}
+	if n := srv.Requests() - before; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+
+	// The listing of the directory of the changed file is dropped.
+	if r.Client().FilesCached(ctx, fsys.(*bbFS).listCommand("dir")) {
+		t.Errorf("expected the listing of dir to be dropped")
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"docs/a.md": {Data: []byte("a")},
+		"src/b.go":  {Data: []byte("b")},
+	}))
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", Root: "docs"})
+
+	// Paths outside the root and deleted files are skipped.
+	if err := Prefetch(fsys, []string{"docs/a.md", "src/b.go", "docs/deleted.md"}); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	before := srv.Requests()
+	if _, err := fs.ReadFile(fsys, "a.md"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if n := srv.Requests() - before; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
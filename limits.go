@@ -0,0 +1,160 @@
+package bbfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrLimitExceeded is matched by a LimitError with errors.Is.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits of a LimitError.
+const (
+	LimitDepth    = "depth"
+	LimitEntries  = "entries"
+	LimitDownload = "download"
+)
+
+// LimitError is returned when a path is deeper than the maximum depth,
+// a listing brings the number of entries over the maximum, a read brings
+// the downloaded bytes over Config.MaxDownloadBytes, an Export exceeds
+// the maximum size, a file read by ReadJSON or ReadYAML is too large or
+// Repo.CheckSize finds a repository that is too large.
+type LimitError struct {
+	// Limit is LimitDepth, LimitEntries, LimitDownload, LimitSize or LimitRepoSize.
+	Limit string
+	Max   int64
+	// Path is the path in the repository where the limit was exceeded,
+	// for LimitSize the path in the exported FS and for LimitRepoSize
+	// the project and slug of the repository.
+	Path string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded at %s", e.Limit, e.Max, e.Path)
+}
+
+// Is returns true for ErrLimitExceeded.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// WithMaxDepth limits the depth of the paths the FS exposes,
+// the files in the root are at depth 1. Deeper paths fail with a LimitError.
+func WithMaxDepth(depth int) Option {
+	return func(f *bbFS) {
+		f.walkLimits().maxDepth = depth
+	}
+}
+
+// WithMaxEntries limits the total number of directory entries the FS
+// returns, the FS values returned by Sub count against the same total.
+// The listing that exceeds the maximum fails with a LimitError.
+//
+// Use Repo.FS to get a fresh FS, with a new count, for each walk.
+func WithMaxEntries(n int64) Option {
+	return func(f *bbFS) {
+		f.walkLimits().maxEntries = n
+	}
+}
+
+// walkLimits returns the limits of the FS, creating them if needed.
+func (b *bbFS) walkLimits() *walkLimits {
+	if b.limits == nil {
+		b.limits = &walkLimits{base: b.root}
+	}
+	return b.limits
+}
+
+// walkLimits are the limits on the paths, entries and bytes an FS exposes.
+type walkLimits struct {
+	// base is the directory the depth is relative to.
+	base        string
+	maxDepth    int
+	maxEntries  int64
+	maxDownload int64
+}
+
+// depth returns the depth of the path relative to the base.
+func (l *walkLimits) depth(fullPath string) int {
+	rel := fullPath
+	if l.base != "" {
+		rel = strings.TrimPrefix(strings.TrimPrefix(fullPath, l.base), "/")
+	}
+	if rel == "" || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// checkDepth returns a LimitError if the path is too deep.
+func (l *walkLimits) checkDepth(fullPath string) error {
+	if l == nil || l.maxDepth <= 0 || l.depth(fullPath) <= l.maxDepth {
+		return nil
+	}
+	return &LimitError{Limit: LimitDepth, Max: int64(l.maxDepth), Path: fullPath}
+}
+
+// checkListing returns a LimitError if the entries of dir are too deep.
+func (l *walkLimits) checkListing(dir string) error {
+	if l == nil || l.maxDepth <= 0 || l.depth(dir)+1 <= l.maxDepth {
+		return nil
+	}
+	return &LimitError{Limit: LimitDepth, Max: int64(l.maxDepth), Path: dir}
+}
+
+// addEntries counts n entries listed in dir and returns a LimitError if
+// the total exceeds the maximum.
+func (l *walkLimits) addEntries(count *atomic.Int64, dir string, n int) error {
+	if l == nil || l.maxEntries <= 0 || count == nil {
+		return nil
+	}
+	if count.Add(int64(n)) > l.maxEntries {
+		return &LimitError{Limit: LimitEntries, Max: l.maxEntries, Path: dir}
+	}
+	return nil
+}
+
+// limitsDownload returns true if the downloaded bytes are limited.
+func (l *walkLimits) limitsDownload() bool {
+	return l != nil && l.maxDownload > 0
+}
+
+// checkDownload returns a LimitError if downloading n more bytes of the
+// file brings the count over the maximum.
+func (l *walkLimits) checkDownload(count *atomic.Int64, fullPath string, n int64) error {
+	if l == nil || l.maxDownload <= 0 || count == nil {
+		return nil
+	}
+	if count.Load()+n > l.maxDownload {
+		return &LimitError{Limit: LimitDownload, Max: l.maxDownload, Path: fullPath}
+	}
+	return nil
+}
+
+// addDownload counts n bytes downloaded for the file and returns a
+// LimitError if the total exceeds the maximum.
+func (l *walkLimits) addDownload(count *atomic.Int64, fullPath string, n int) error {
+	if count == nil {
+		return nil
+	}
+	total := count.Add(int64(n))
+	if l == nil || l.maxDownload <= 0 || total <= l.maxDownload {
+		return nil
+	}
+	return &LimitError{Limit: LimitDownload, Max: l.maxDownload, Path: fullPath}
+}
+
+// DownloadedBytes returns the bytes of file content read through an FS
+// of this package and the FS values returned by its Sub.
+// It returns 0 for other file systems.
+func DownloadedBytes(fsys fs.FS) int64 {
+	b, ok := fsys.(*bbFS)
+	if !ok || b.downloaded == nil {
+		return 0
+	}
+	return b.downloaded.Load()
+}
@@ -0,0 +1,104 @@
+package bbfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestWalkLimits(t *testing.T) {
+	files := fstest.MapFS{
+		"a.txt":           {Data: []byte("a")},
+		"b.txt":           {Data: []byte("b")},
+		"dir/c.txt":       {Data: []byte("c")},
+		"dir/sub/d.txt":   {Data: []byte("d")},
+		"dir/sub/e/f.txt": {Data: []byte("f")},
+	}
+	tests := []struct {
+		name  string
+		opts  []Option
+		limit string
+		path  string
+	}{
+		{"depth", []Option{WithMaxDepth(2)}, LimitDepth, "dir/sub"},
+		{"entries", []Option{WithMaxEntries(4)}, LimitEntries, "dir"},
+		{"no limit", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newFakeFS(t, files, tt.opts...)
+			err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+				return err
+			})
+			if tt.limit == "" {
+				if err != nil {
+					t.Fatalf("error: %s", err.Error())
+				}
+				return
+			}
+			var le *LimitError
+			if !errors.As(err, &le) || !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected a LimitError, got %v", err)
+			}
+			if le.Limit != tt.limit || le.Path != tt.path {
+				t.Errorf("expected %s limit at %s, got %s at %s", tt.limit, tt.path, le.Limit, le.Path)
+			}
+		})
+	}
+
+	fsys := newFakeFS(t, files, WithMaxDepth(2))
+	if _, err := fs.Stat(fsys, "dir/sub/d.txt"); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestDownloadLimit(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(fstest.MapFS{
+		"small.txt": {Data: []byte("12345")},
+		"large.bin": {Data: make([]byte, 100)},
+	}))
+	repo := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", MaxDownloadBytes: 50})
+
+	fsys := repo.FS("")
+	for range 2 {
+		if _, err := fs.ReadFile(fsys, "small.txt"); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+	if n := DownloadedBytes(fsys); n != 10 {
+		t.Errorf("expected 10 bytes downloaded, got %d", n)
+	}
+
+	// The large file is refused before it is downloaded, its size is in
+	// the listing read for the size of small.txt.
+	before := srv.Requests()
+	_, err := fs.ReadFile(fsys, "large.bin")
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != LimitDownload || le.Max != 50 || le.Path != "large.bin" {
+		t.Fatalf("expected a download LimitError, got %v", err)
+	}
+	if n := srv.Requests() - before; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+	if n := DownloadedBytes(fsys); n != 10 {
+		t.Errorf("expected 10 bytes downloaded, got %d", n)
+	}
+
+	// Each FS has its own count.
+	if n := DownloadedBytes(repo.FS("")); n != 0 {
+		t.Errorf("expected 0 bytes for a new FS, got %d", n)
+	}
+	if n := DownloadedBytes(fstest.MapFS{}); n != 0 {
+		t.Errorf("expected 0 bytes for another FS, got %d", n)
+	}
+
+	cfg := &Config{Host: "host", ProjectKey: "PRJ", RepositorySlug: "repo", MaxDownloadBytes: -1}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("expected an error for a negative MaxDownloadBytes")
+	}
+}
@@ -0,0 +1,153 @@
+package bbfs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the configuration as stored in a file.
+type fileConfig struct {
+	Host           string `json:"host" yaml:"host" toml:"host"`
+	BaseURL        string `json:"baseURL" yaml:"baseURL" toml:"baseURL"`
+	ApiVersion     string `json:"apiVersion" yaml:"apiVersion" toml:"apiVersion"`
+	ProjectKey     string `json:"projectKey" yaml:"projectKey" toml:"projectKey"`
+	RepositorySlug string `json:"repositorySlug" yaml:"repositorySlug" toml:"repositorySlug"`
+	Root           string `json:"root" yaml:"root" toml:"root"`
+	AccessKey      string `json:"accessKey" yaml:"accessKey" toml:"accessKey"`
+	// AccessKeyFile is a file with the access key,
+	// relative paths are relative to the config file.
+	AccessKeyFile string `json:"accessKeyFile" yaml:"accessKeyFile" toml:"accessKeyFile"`
+	// AccessKeySecret is a reference to the access key in a secret store.
+	AccessKeySecret string `json:"accessKeySecret" yaml:"accessKeySecret" toml:"accessKeySecret"`
+	At              string `json:"at" yaml:"at" toml:"at"`
+}
+
+// LoadConfig reads the configuration from a JSON, YAML, TOML or dotenv file
+// and validates it. The format follows from the extension: .json, .yaml,
+// .yml, .toml or .env.
+//
+// The keys are the Config fields in lower camel case, e.g. projectKey,
+// a dotenv file uses the names of ConfigFromEnv with the BBFS_ prefix.
+// ${VAR} and $VAR in values are replaced by environment variables.
+// The access key can be read from the file in accessKeyFile, or
+// BBFS_ACCESS_KEY_FILE, instead, or referenced in a secret store with
+// accessKeySecret, see package secrets.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc fileConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&fc)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&fc)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), &fc)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys %v", md.Undecoded())
+		}
+	case ".env":
+		fc, err = parseDotenv(data)
+	default:
+		return nil, fmt.Errorf("config %s: unsupported format %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	for _, s := range []*string{&fc.Host, &fc.BaseURL, &fc.ApiVersion, &fc.ProjectKey,
+		&fc.RepositorySlug, &fc.Root, &fc.AccessKey, &fc.AccessKeyFile, &fc.AccessKeySecret, &fc.At} {
+		*s = os.ExpandEnv(*s)
+	}
+
+	if fc.AccessKeyFile != "" {
+		if fc.AccessKey != "" {
+			return nil, fmt.Errorf("config %s: accessKey and accessKeyFile are both set", path)
+		}
+		keyFile := fc.AccessKeyFile
+		if !filepath.IsAbs(keyFile) {
+			keyFile = filepath.Join(filepath.Dir(path), keyFile)
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: reading access key: %w", path, err)
+		}
+		fc.AccessKey = strings.TrimSpace(string(key))
+	}
+
+	cfg := &Config{
+		Host:            fc.Host,
+		BaseURL:         fc.BaseURL,
+		ApiVersion:      fc.ApiVersion,
+		ProjectKey:      fc.ProjectKey,
+		RepositorySlug:  fc.RepositorySlug,
+		Root:            fc.Root,
+		AccessKey:       fc.AccessKey,
+		AccessKeySecret: fc.AccessKeySecret,
+		At:              Ref(fc.At),
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// parseDotenv parses KEY=VALUE lines with the names of ConfigFromEnv.
+// Empty lines and lines starting with # are skipped, values may be quoted.
+func parseDotenv(data []byte) (fileConfig, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fileConfig{}, fmt.Errorf("line %d: missing =", n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return fileConfig{}, err
+	}
+
+	cfg := ConfigFromEnvFunc(DefaultEnvPrefix, func(key string) string { return vars[key] })
+	fc := fileConfig{
+		Host:            cfg.Host,
+		BaseURL:         cfg.BaseURL,
+		ApiVersion:      cfg.ApiVersion,
+		ProjectKey:      cfg.ProjectKey,
+		RepositorySlug:  cfg.RepositorySlug,
+		Root:            cfg.Root,
+		AccessKey:       cfg.AccessKey,
+		AccessKeyFile:   vars[DefaultEnvPrefix+"ACCESS_KEY_FILE"],
+		AccessKeySecret: cfg.AccessKeySecret,
+		At:              cfg.At.String(),
+	}
+	if fc == (fileConfig{}) {
+		return fc, errors.New("no " + DefaultEnvPrefix + " variables")
+	}
+	return fc, nil
+}
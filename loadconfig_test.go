@@ -0,0 +1,100 @@
+package bbfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_BBFS_PROJECT", "PRJ")
+	want := Config{
+		BaseURL:        "https://bitbucket.example.com/rest/api/latest",
+		ProjectKey:     "PRJ",
+		RepositorySlug: "repo",
+		AccessKey:      "secret",
+		At:             BranchRef("main"),
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.json", `{
+			"baseURL": "https://bitbucket.example.com/rest/api/latest",
+			"projectKey": "${TEST_BBFS_PROJECT}",
+			"repositorySlug": "repo",
+			"accessKeyFile": "key.txt",
+			"at": "refs/heads/main"
+		}`},
+		{"config.yaml", `
+baseURL: https://bitbucket.example.com/rest/api/latest
+projectKey: ${TEST_BBFS_PROJECT}
+repositorySlug: repo
+accessKeyFile: key.txt
+at: refs/heads/main
+`},
+		{"config.toml", `
+baseURL = "https://bitbucket.example.com/rest/api/latest"
+projectKey = "${TEST_BBFS_PROJECT}"
+repositorySlug = "repo"
+accessKeyFile = "key.txt"
+at = "refs/heads/main"
+`},
+		{"config.env", `
+# bbfs
+BBFS_BASE_URL=https://bitbucket.example.com/rest/api/latest
+export BBFS_PROJECT_KEY="$TEST_BBFS_PROJECT"
+BBFS_REPO_SLUG='repo'
+BBFS_ACCESS_KEY_FILE=key.txt
+BBFS_AT=refs/heads/main
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "key.txt"), []byte("secret\n"), 0o600); err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			p := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(p, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			cfg, err := LoadConfig(p)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			if *cfg != want {
+				t.Errorf("expected %+v, got %+v", want, *cfg)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown.json", `{"projectKey": "PRJ", "repo": "x"}`, "unknown field"},
+		{"unknown.toml", `repo = "x"`, "unknown keys"},
+		{"invalid.yaml", "projectKey: PRJ\n", "RepositorySlug is missing"},
+		{"both.json", `{"accessKey": "a", "accessKeyFile": "b"}`, "both set"},
+		{"secret.yaml", "host: h\nprojectKey: PRJ\nrepositorySlug: r\naccessKeySecret: bogus\n", "want scheme:value"},
+		{"config.ini", ``, "unsupported format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(p, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			_, err := LoadConfig(p)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package bbfs
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader reads a configuration file of a repository into a T and reloads
+// it when the ref moves to another commit, by polling with Watch or on the
+// webhook of Handler. Each value is read at a commit, the commit is its
+// version.
+//
+// A failed reload keeps the current value.
+type Loader[T any] struct {
+	Repo *Repo
+	// Ref is the branch or tag to follow, defaults to the ref of the Repo.
+	Ref Ref
+	// Path is the name of the file in the FS of the Repo.
+	Path string
+	// Decode reads the file into the value, defaults to ReadJSON for
+	// .json files and ReadYAML for other files.
+	Decode func(fsys fs.FS, name string, v any, opts ...DecodeOption) error
+	// DecodeOptions are passed to Decode.
+	DecodeOptions []DecodeOption
+	// Interval is the time between the polls of Watch, defaults to
+	// DefaultWatchInterval.
+	Interval time.Duration
+	// OnChange is called with each new value and its commit, one at a time.
+	// It must not call Load.
+	OnChange func(value T, commit string)
+	// OnError is called with the errors of reloads after the first load.
+	// When nil, Watch returns the error.
+	OnError func(error)
+
+	mu      sync.Mutex
+	current atomic.Pointer[loaded[T]]
+}
+
+// loaded is a value read at a commit.
+type loaded[T any] struct {
+	value  T
+	commit string
+}
+
+// Value returns the current value and its commit, the zero value and ""
+// before the first load.
+func (l *Loader[T]) Value() (T, string) {
+	if c := l.current.Load(); c != nil {
+		return c.value, c.commit
+	}
+	var zero T
+	return zero, ""
+}
+
+// Load reads the file at the commit the ref points to now.
+func (l *Loader[T]) Load(ctx context.Context) (T, string, error) {
+	b := &l.Repo.base
+	if b.err != nil {
+		var zero T
+		return zero, "", b.err
+	}
+	commit, err := b.resolve(b.withBudget(ctx), l.ref())
+	if err != nil {
+		var zero T
+		return zero, "", err
+	}
+	return l.loadCommit(commit)
+}
+
+// Watch loads the file and polls the ref until the context is done,
+// reloading the file each time the ref moves. The first load must succeed.
+func (l *Loader[T]) Watch(ctx context.Context) error {
+	if _, _, err := l.Load(ctx); err != nil {
+		return err
+	}
+	interval := l.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		_, _, err := l.reload(ctx)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if l.OnError == nil {
+			return err
+		}
+		l.OnError(err)
+	}
+}
+
+// Handler returns the handler for the refs changed webhook of the server,
+// see WebhookHandler. It reloads the file at the new commit of the ref
+// before it responds, errors go to OnError.
+func (l *Loader[T]) Handler(secret string) http.Handler {
+	b := &l.Repo.base
+	return WebhookHandler(b.projectKey, b.repoSlug, l.ref(), secret, func(ev RefEvent) {
+		if ev.To == "" {
+			// The ref is deleted, keep the value.
+			return
+		}
+		if _, _, err := l.loadCommit(ev.To); err != nil && l.OnError != nil {
+			l.OnError(err)
+		}
+	})
+}
+
+// reload reads the file when the ref points to another commit than the
+// current value, without clearing the cache.
+func (l *Loader[T]) reload(ctx context.Context) (T, string, error) {
+	b := &l.Repo.base
+	commit, err := currentCommit(b.withBudget(ctx), b.client, b.projectKey, b.repoSlug, l.ref())
+	if err != nil {
+		var zero T
+		return zero, "", err
+	}
+	return l.loadCommit(commit)
+}
+
+func (l *Loader[T]) ref() Ref {
+	if l.Ref != "" {
+		return l.Ref
+	}
+	return l.Repo.base.at
+}
+
+// loadCommit reads the file at the commit, unless it is the commit of the
+// current value, and calls OnChange with the new value.
+func (l *Loader[T]) loadCommit(commit string) (T, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c := l.current.Load(); c != nil && c.commit == commit {
+		return c.value, c.commit, nil
+	}
+	decode := l.Decode
+	if decode == nil {
+		decode = ReadYAML
+		if path.Ext(l.Path) == ".json" {
+			decode = ReadJSON
+		}
+	}
+	var v T
+	if err := decode(l.Repo.FS(CommitRef(commit)), l.Path, &v, l.DecodeOptions...); err != nil {
+		var zero T
+		return zero, "", err
+	}
+	l.current.Store(&loaded[T]{value: v, commit: commit})
+	if l.OnChange != nil {
+		l.OnChange(v, commit)
+	}
+	return v, commit, nil
+}
@@ -0,0 +1,117 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestLoaderWatch(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"app.yaml": {Data: []byte("name: first\n")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	changes := make(chan string, 10)
+	errs := make(chan error, 10)
+	l := &Loader[decodeTestConfig]{
+		Repo:     NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: "main"}),
+		Path:     "app.yaml",
+		Interval: 10 * time.Millisecond,
+		OnChange: func(v decodeTestConfig, commit string) {
+			changes <- v.Name + "@" + commit
+		},
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- l.Watch(ctx) }()
+
+	first := repo.Commits[0].ID
+	if got := <-changes; got != "first@"+first {
+		t.Fatalf("unexpected first change %s", got)
+	}
+
+	// A broken file keeps the value.
+	srv.Update(func() {
+		repo.Commit("main", "broken", fstest.MapFS{"app.yaml": {Data: []byte("name: [\n")}})
+	})
+	var de *DecodeError
+	if err := <-errs; !errors.As(err, &de) {
+		t.Fatalf("expected a DecodeError, got %v", err)
+	}
+	if v, commit := l.Value(); v.Name != "first" || commit != first {
+		t.Errorf("expected the first value, got %+v at %s", v, commit)
+	}
+
+	var second string
+	srv.Update(func() {
+		second = repo.Commit("main", "second", fstest.MapFS{"app.yaml": {Data: []byte("name: second\n")}}).ID
+	})
+	select {
+	case got := <-changes:
+		if got != "second@"+second {
+			t.Errorf("unexpected second change %s", got)
+		}
+	case <-ctx.Done():
+		t.Fatalf("no change after the second commit")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLoaderHandler(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"app.json": {Data: []byte(`{"name": "first"}`)}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	var changes []string
+	l := &Loader[decodeTestConfig]{
+		Repo: NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: "main"}),
+		Path: "app.json",
+		OnChange: func(v decodeTestConfig, commit string) {
+			changes = append(changes, v.Name)
+		},
+	}
+	if _, _, err := l.Load(context.Background()); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	second := repo.Commit("main", "second", fstest.MapFS{"app.json": {Data: []byte(`{"name": "second"}`)}}).ID
+
+	h := l.Handler("")
+	for range 2 {
+		payload := `{
+			"eventKey": "repo:refs_changed",
+			"repository": {"slug": "repo", "project": {"key": "PRJ"}},
+			"changes": [{"ref": {"id": "refs/heads/main", "displayId": "main"}, "fromHash": "aaa", "toHash": "` + second + `"}]
+		}`
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+	}
+	// The second event is for the commit of the value.
+	if strings.Join(changes, ",") != "first,second" {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	if v, commit := l.Value(); v.Name != "second" || commit != second {
+		t.Errorf("expected the second value, got %+v at %s", v, commit)
+	}
+}
@@ -0,0 +1,40 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirBucket is a bucket in a local directory, the keys are the paths
+// of the files in the directory.
+type DirBucket string
+
+// Get returns the content of the file.
+func (d DirBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(d.path(key))
+}
+
+// Put writes the file and its directories.
+func (d DirBucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	p := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+// Delete deletes the file.
+func (d DirBucket) Delete(ctx context.Context, key string) error {
+	err := os.Remove(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (d DirBucket) path(key string) string {
+	return filepath.Join(string(d), filepath.FromSlash(key))
+}
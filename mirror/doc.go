@@ -0,0 +1,22 @@
+/*
+Package mirror copies a directory of a file system to an object store bucket,
+for CDN-backed distribution of content managed in Bitbucket.
+
+Sync uploads the files that changed since the last sync and deletes the
+objects of removed files. It keeps the git blob ids of the files in a
+manifest object in the bucket, so unchanged files are neither downloaded
+nor uploaded. The FS of bbfs knows the blob ids from the directory listings,
+other file systems are read to compute them.
+
+	fsys := bbfs.NewFS(cfg)
+	bucket := mirror.NewS3("www-example", "eu-west-1")
+	res, err := mirror.Sync(ctx, fsys, "site", bucket, mirror.WithPrefix("docs"), mirror.WithDelete())
+
+S3 talks to any bucket with the S3 API and AWS signature version 4.
+NewGCS uses it for Google Cloud Storage with HMAC keys, and DirBucket
+mirrors to a local directory.
+
+Only Sync writes the bucket, a sync that fails halfway is repaired by the
+next one, which compares with the manifest of the last complete sync.
+*/
+package mirror
@@ -0,0 +1,282 @@
+package mirror
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/myhops/bbfs"
+)
+
+// ManifestName is the name of the object with the blob ids of the last sync,
+// next to the mirrored objects.
+const ManifestName = ".bbfs-mirror.json"
+
+// DefaultConcurrency is the number of uploads that Sync runs at the same time.
+const DefaultConcurrency = 4
+
+// Bucket is an object store bucket.
+type Bucket interface {
+	// Get returns the content of the object, an error that wraps
+	// fs.ErrNotExist when it does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put writes the object.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete deletes the object, it is not an error if it does not exist.
+	Delete(ctx context.Context, key string) error
+}
+
+// Option is an option for Sync.
+type Option func(*config)
+
+type config struct {
+	prefix      string
+	delete      bool
+	dryRun      bool
+	concurrency int
+	ignore      *bbfs.Ignore
+}
+
+// WithPrefix puts the objects under the prefix, e.g. docs for docs/index.html.
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = strings.Trim(prefix, "/")
+	}
+}
+
+// WithDelete deletes the objects of the files that were removed since the
+// last sync. Objects that Sync did not write are never deleted.
+func WithDelete() Option {
+	return func(c *config) {
+		c.delete = true
+	}
+}
+
+// WithDryRun reports the changes without writing the bucket.
+func WithDryRun() Option {
+	return func(c *config) {
+		c.dryRun = true
+	}
+}
+
+// WithConcurrency sets the number of uploads at the same time,
+// defaults to DefaultConcurrency.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithIgnore skips the files and directories that the patterns, relative
+// to the root, ignore. Ignored files that an earlier sync uploaded count
+// as removed, WithDelete deletes their objects.
+func WithIgnore(ig *bbfs.Ignore) Option {
+	return func(c *config) {
+		c.ignore = ig
+	}
+}
+
+// Result reports the changes of a sync, the paths are relative to the root.
+type Result struct {
+	Uploaded  []string
+	Deleted   []string
+	Unchanged int
+}
+
+// manifest is the content of the manifest object.
+type manifest struct {
+	// Files are the blob ids by path.
+	Files map[string]string `json:"files"`
+}
+
+// Sync copies the files in the root directory of the file system to the
+// bucket. It uploads the files with another blob id than in the manifest of
+// the last sync, then writes the new manifest.
+func Sync(ctx context.Context, fsys fs.FS, root string, b Bucket, opts ...Option) (*Result, error) {
+	cfg := &config{concurrency: DefaultConcurrency}
+	for _, o := range opts {
+		o(cfg)
+	}
+	if root == "" {
+		root = "."
+	}
+	files, err := blobIDs(ctx, fsys, root, cfg.ignore)
+	if err != nil {
+		return nil, err
+	}
+	old, err := readManifest(ctx, b, cfg.key(ManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	for _, name := range sortedKeys(files) {
+		if old.Files[name] == files[name] {
+			res.Unchanged++
+			continue
+		}
+		res.Uploaded = append(res.Uploaded, name)
+	}
+	for _, name := range sortedKeys(old.Files) {
+		if _, ok := files[name]; !ok {
+			res.Deleted = append(res.Deleted, name)
+		}
+	}
+	if !cfg.delete {
+		// Keep the removed files in the manifest, a later sync with
+		// WithDelete deletes them.
+		for _, name := range res.Deleted {
+			files[name] = old.Files[name]
+		}
+		res.Deleted = nil
+	}
+	if cfg.dryRun {
+		return res, nil
+	}
+
+	if err := cfg.upload(ctx, fsys, root, b, res.Uploaded); err != nil {
+		return nil, err
+	}
+	for _, name := range res.Deleted {
+		if err := b.Delete(ctx, cfg.key(name)); err != nil {
+			return nil, fmt.Errorf("deleting %s: %w", name, err)
+		}
+	}
+	data, err := json.MarshalIndent(&manifest{Files: files}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Put(ctx, cfg.key(ManifestName), data, "application/json"); err != nil {
+		return nil, fmt.Errorf("writing the manifest: %w", err)
+	}
+	return res, nil
+}
+
+// key returns the key of the object for the path.
+func (c *config) key(name string) string {
+	if c.prefix == "" {
+		return name
+	}
+	return c.prefix + "/" + name
+}
+
+// upload uploads the files, concurrency at a time. It stops at the first error.
+func (c *config) upload(ctx context.Context, fsys fs.FS, root string, b Bucket, names []string) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	sem := make(chan struct{}, max(c.concurrency, 1))
+	var wg sync.WaitGroup
+	for _, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			data, err := fs.ReadFile(fsys, path.Join(root, name))
+			if err == nil {
+				err = b.Put(ctx, c.key(name), data, contentType(name))
+			}
+			if err != nil {
+				cancel(fmt.Errorf("uploading %s: %w", name, err))
+			}
+		}()
+	}
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// blobIDs returns the blob ids of the files in root by their path relative
+// to root. It reads the files whose file info has no content id.
+func blobIDs(ctx context.Context, fsys fs.FS, root string, ignore *bbfs.Ignore) (map[string]string, error) {
+	res := map[string]string{}
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		if root == "." {
+			rel = name
+		}
+		if ignore.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || rel == ManifestName {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if c, ok := fi.(interface{ ContentID() string }); ok && c.ContentID() != "" {
+			res[rel] = c.ContentID()
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		res[rel] = blobID(data)
+		return nil
+	})
+	return res, err
+}
+
+func readManifest(ctx context.Context, b Bucket, key string) (*manifest, error) {
+	data, err := b.Get(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", key, err)
+	}
+	return &m, nil
+}
+
+// blobID returns the id of the git blob with the data.
+func blobID(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func contentType(name string) string {
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,178 @@
+package bbfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// Newline is the line end that WithNewlines converts text files to.
+type Newline int
+
+const (
+	// NewlineKeep keeps the line ends of the files.
+	NewlineKeep Newline = iota
+	// NewlineLF converts CRLF to LF.
+	NewlineLF
+	// NewlineCRLF converts LF to CRLF.
+	NewlineCRLF
+)
+
+// sniffLen is the number of bytes checked for a NUL byte to tell binary
+// files from text files, as git does.
+const sniffLen = 8000
+
+// WithNewlines converts the line ends of text files when they are read.
+// Files with a NUL byte in the first 8000 bytes are binary and kept as is.
+//
+// The size in the info of a file is the size in the repository, not the
+// size after the conversion.
+func WithNewlines(nl Newline) Option {
+	return func(f *bbFS) {
+		f.newlines = nl
+	}
+}
+
+// IsBinary reports whether the file is binary. For an FS from this package
+// it asks the server, which flags the files it does not show as text.
+// For other file systems it checks the start of the file for a NUL byte.
+func IsBinary(fsys fs.FS, name string) (bool, error) {
+	if b, ok := fsys.(interface {
+		isBinary(name string) (bool, error)
+	}); ok {
+		return b.isBinary(name)
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, sniffLen))
+	if err != nil {
+		return false, err
+	}
+	return bytes.IndexByte(head, 0) >= 0, nil
+}
+
+// isBinary asks the server if the file is binary.
+func (b *bbFS) isBinary(name string) (bool, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return false, &fs.PathError{Op: "isbinary", Path: name, Err: fs.ErrInvalid}
+	}
+	if b.err != nil {
+		return false, &fs.PathError{Op: "isbinary", Path: name, Err: b.err}
+	}
+	if err := b.checkRef(); err != nil {
+		return false, &fs.PathError{Op: "isbinary", Path: name, Err: b.repoError(err)}
+	}
+	if err := b.checkSignature(); err != nil {
+		return false, &fs.PathError{Op: "isbinary", Path: name, Err: err}
+	}
+	fullPath := path.Join(b.root, name)
+	if !b.filter.visible(fullPath, false) {
+		return false, &fs.PathError{Op: "isbinary", Path: name, Err: fs.ErrNotExist}
+	}
+	resp, err := b.client.GetFileLines(b.requestContext(), &server.GetFileLinesCommand{
+		FilePath:   fullPath,
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		At:         b.at,
+		Limit:      1,
+	})
+	if server.IsNotFound(err) {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return false, &fs.PathError{Op: "isbinary", Path: name, Err: err}
+	}
+	return resp.Binary, nil
+}
+
+// readFunc is a function that implements io.Reader.
+type readFunc func([]byte) (int, error)
+
+func (f readFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
+
+// newlineReader converts the line ends of a text file.
+type newlineReader struct {
+	src io.Reader
+	nl  Newline
+	buf []byte
+
+	// head collects the start of the file until it is known to be text or binary.
+	head    []byte
+	sniffed bool
+	binary  bool
+	// cr is true when the last byte converted was a CR.
+	cr  bool
+	out []byte
+	err error
+}
+
+func newNewlineReader(src io.Reader, nl Newline) *newlineReader {
+	return &newlineReader{src: src, nl: nl}
+}
+
+func (r *newlineReader) Read(b []byte) (int, error) {
+	if r.buf == nil {
+		r.buf = make([]byte, 32*1024)
+	}
+	for len(r.out) == 0 && r.err == nil {
+		buf := r.buf
+		n, err := r.src.Read(buf)
+		r.err = err
+		if !r.sniffed {
+			r.head = append(r.head, buf[:n]...)
+			if len(r.head) < sniffLen && err == nil {
+				continue
+			}
+			r.sniffed = true
+			r.binary = bytes.IndexByte(r.head, 0) >= 0
+			r.convert(r.head)
+			r.head = nil
+		} else {
+			r.convert(buf[:n])
+		}
+		if r.err != nil && r.cr && r.nl == NewlineLF {
+			// A CR at the end of the file is not a line end.
+			r.out = append(r.out, '\r')
+			r.cr = false
+		}
+	}
+	n := copy(b, r.out)
+	r.out = r.out[n:]
+	if len(r.out) > 0 {
+		return n, nil
+	}
+	return n, r.err
+}
+
+// convert appends the converted data to out.
+func (r *newlineReader) convert(data []byte) {
+	if r.binary {
+		r.out = append(r.out, data...)
+		return
+	}
+	for _, c := range data {
+		switch r.nl {
+		case NewlineLF:
+			if r.cr && c != '\n' {
+				r.out = append(r.out, '\r')
+			}
+			if c != '\r' {
+				r.out = append(r.out, c)
+			}
+		case NewlineCRLF:
+			if c == '\n' && !r.cr {
+				r.out = append(r.out, '\r')
+			}
+			r.out = append(r.out, c)
+		}
+		r.cr = c == '\r'
+	}
+}
@@ -0,0 +1,95 @@
+package bbfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestNewlineReader(t *testing.T) {
+	long := strings.Repeat("x", sniffLen)
+	tests := []struct {
+		name string
+		nl   Newline
+		in   string
+		want string
+	}{
+		{"lf from crlf", NewlineLF, "a\r\nb\r\n", "a\nb\n"},
+		{"lf keeps lf", NewlineLF, "a\nb", "a\nb"},
+		{"lf keeps lone cr", NewlineLF, "a\rb\r", "a\rb\r"},
+		{"crlf from lf", NewlineCRLF, "a\nb\n", "a\r\nb\r\n"},
+		{"crlf keeps crlf", NewlineCRLF, "a\r\nb\n", "a\r\nb\r\n"},
+		{"empty", NewlineLF, "", ""},
+		{"binary", NewlineLF, "a\r\n\x00b\r\n", "a\r\n\x00b\r\n"},
+		{"nul after the sniffed bytes", NewlineLF, long + "\r\n\x00", long + "\n\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Read one byte at a time to split the CRLF pairs.
+			r := newNewlineReader(iotest.OneByteReader(strings.NewReader(tt.in)), tt.nl)
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewlinesAndBinary(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	files := fstest.MapFS{
+		"dos.txt":   {Data: []byte("one\r\ntwo\r\n")},
+		"image.bin": {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00")},
+		"dir/a.txt": {Data: []byte("a\n")},
+	}
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(files))
+	cfg := &Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"}
+
+	fsys := NewFS(cfg, WithNewlines(NewlineLF))
+	data, err := fs.ReadFile(fsys, "dos.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "one\ntwo\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+	data, err = fs.ReadFile(fsys, "image.bin")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != string(files["image.bin"].Data) {
+		t.Errorf("binary file changed to %q", data)
+	}
+
+	for _, fsys := range []fs.FS{NewFS(cfg), files} {
+		for name, want := range map[string]bool{"dos.txt": false, "image.bin": true} {
+			got, err := IsBinary(fsys, name)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			if got != want {
+				t.Errorf("%s: expected binary %v, got %v", name, want, got)
+			}
+		}
+	}
+	if _, err := IsBinary(NewFS(cfg), "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist, got %v", err)
+	}
+	if _, err := IsBinary(NewFS(cfg), "dir"); !errors.Is(err, server.ErrIsDirectory) {
+		t.Errorf("expected ErrIsDirectory, got %v", err)
+	}
+	if _, err := IsBinary(FileFS(cfg, "dos.txt"), "image.bin"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist outside the FileFS, got %v", err)
+	}
+}
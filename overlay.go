@@ -0,0 +1,203 @@
+package bbfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Overlay returns the union of the file systems: a file in a layer shadows
+// the file with the same name in earlier layers and in the base, the last
+// layer is on top. Directories are merged, a directory in a layer hides a
+// file with the same name below it and the other way around.
+//
+// Use it to override the configuration in a base repository with the
+// configuration of an environment:
+//
+//	fsys := bbfs.Overlay(base, env)
+func Overlay(base fs.FS, layers ...fs.FS) fs.FS {
+	o := &overlayFS{}
+	// Keep the top layer first.
+	for _, l := range slices.Backward(layers) {
+		o.layers = append(o.layers, l)
+	}
+	o.layers = append(o.layers, base)
+	return o
+}
+
+// overlayFS implements fs.FS for Overlay, the layers are in top-down order.
+type overlayFS struct {
+	layers []fs.FS
+}
+
+var (
+	_ fs.ReadDirFS  = &overlayFS{}
+	_ fs.ReadFileFS = &overlayFS{}
+	_ fs.StatFS     = &overlayFS{}
+)
+
+// Open opens the file of the top layer that has it.
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for i, l := range o.layers {
+		f, err := l.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if hidesBelow(l, name) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return f, nil
+		}
+		f.Close()
+		return &overlayDir{fsys: o, name: name, info: fi, top: i}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the merged entries of the directory sorted by name.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := o.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d, ok := f.(*overlayDir)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return d.ReadDir(-1)
+}
+
+// ReadFile reads the file of the top layer that has it.
+func (o *overlayFS) ReadFile(name string) ([]byte, error) {
+	f, err := o.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, ok := f.(*overlayDir); ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return io.ReadAll(f)
+}
+
+// Stat returns the info of the file of the top layer that has it.
+func (o *overlayFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := o.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// entries returns the merged entries of the directory in the layers from top down,
+// until a layer has a file with the name of the directory.
+func (o *overlayFS) entries(name string, top int) ([]fs.DirEntry, error) {
+	seen := map[string]bool{}
+	var res []fs.DirEntry
+	for _, l := range o.layers[top:] {
+		fi, err := fs.Stat(l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if hidesBelow(l, name) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			break
+		}
+		entries, err := fs.ReadDir(l, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				res = append(res, e)
+			}
+		}
+	}
+	slices.SortFunc(res, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return res, nil
+}
+
+// hidesBelow returns true if the nearest parent of name in the layer is a file,
+// it hides the name in the layers below.
+func hidesBelow(layer fs.FS, name string) bool {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		fi, err := fs.Stat(layer, dir)
+		if err == nil {
+			return !fi.IsDir()
+		}
+	}
+	return false
+}
+
+// overlayDir is a merged directory.
+type overlayDir struct {
+	fsys *overlayFS
+	name string
+	info fs.FileInfo
+	// top is the index of the top layer with the directory.
+	top int
+
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *overlayDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *overlayDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *overlayDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the merged entries of the directory.
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.entries(d.name, d.top)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+	if n <= 0 {
+		res := d.entries
+		d.entries = nil
+		return res, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	res := d.entries[:n]
+	d.entries = d.entries[n:]
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package bbfs
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestOverlay(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "base", fakeserver.NewRepo(fstest.MapFS{
+		"app.yaml":          {Data: []byte("replicas: 1\n")},
+		"config/db.yaml":    {Data: []byte("host: localhost\n")},
+		"config/cache.yaml": {Data: []byte("size: 10\n")},
+		"certs":             {Data: []byte("none\n")},
+		"scripts/run.sh":    {Data: []byte("#!/bin/sh\n")},
+	}))
+	base := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "base"})
+
+	env := fstest.MapFS{
+		"config/db.yaml": {Data: []byte("host: db.prod\n")},
+		"certs/ca.pem":   {Data: []byte("pem\n")},
+		"scripts":        {Data: []byte("disabled\n")},
+	}
+	local := fstest.MapFS{
+		"app.yaml": {Data: []byte("replicas: 3\n")},
+	}
+	fsys := Overlay(base, env, local)
+
+	if err := fstest.TestFS(fsys, "app.yaml", "config/db.yaml", "config/cache.yaml", "certs/ca.pem", "scripts"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"app.yaml", "replicas: 3\n"},
+		{"config/db.yaml", "host: db.prod\n"},
+		{"config/cache.yaml", "size: 10\n"},
+		{"certs/ca.pem", "pem\n"},
+		{"scripts", "disabled\n"},
+	}
+	for _, tt := range tests {
+		data, err := fs.ReadFile(fsys, tt.name)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, data)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"app.yaml", "certs", "config", "scripts"}; !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	// The file in the base does not show through the directory of the layer.
+	if _, err := fs.Stat(fsys, "scripts/run.sh"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+/*
+Package proxy serves a file system to clients that do not hold the
+Bitbucket credentials.
+
+NewHandler exposes an fs.FS, typically from bbfs.NewFS, as the service
+bbfs.proxy.v1.FSService defined in proxy.proto. NewFS is the fs.FS on the
+other side, it calls the service over http.
+
+The service uses the unary JSON encoding of the Connect protocol, so any
+Connect client can call it with the messages from proxy.proto:
+
+	curl -H 'Content-Type: application/json' -d '{"name":"README.md"}' \
+		http://localhost:8080/bbfs.proxy.v1.FSService/Open
+*/
+package proxy
@@ -0,0 +1,258 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// FS is a file system served by a handler from NewHandler.
+type FS struct {
+	baseURL string
+	client  *http.Client
+	header  http.Header
+}
+
+// FSOption is an option for NewFS.
+type FSOption func(*FS)
+
+// WithHTTPClient sets the http client, defaults to http.DefaultClient.
+func WithHTTPClient(c *http.Client) FSOption {
+	return func(f *FS) {
+		f.client = c
+	}
+}
+
+// WithHeader adds a header to the requests, e.g. for the authentication at the proxy.
+func WithHeader(key, value string) FSOption {
+	return func(f *FS) {
+		f.header.Add(key, value)
+	}
+}
+
+// NewFS returns the file system served at the base url of the service.
+func NewFS(baseURL string, opts ...FSOption) *FS {
+	f := &FS{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  http.DefaultClient,
+		header:  http.Header{},
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+var (
+	_ fs.ReadDirFS  = &FS{}
+	_ fs.ReadFileFS = &FS{}
+	_ fs.StatFS     = &FS{}
+	_ Tagger        = &FS{}
+)
+
+// call calls the procedure with the request and decodes the response into res.
+func (f *FS) call(ctx context.Context, procedure string, req, res any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, f.baseURL+"/"+ServiceName+"/"+procedure, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range f.header {
+		hr.Header[k] = v
+	}
+	hr.Header.Set("Content-Type", "application/json")
+	hr.Header.Set("Connect-Protocol-Version", "1")
+	resp, err := f.client.Do(hr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorJSON
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Code == "" {
+			return fmt.Errorf("%s failed: %s", procedure, resp.Status)
+		}
+		return &Error{Code: e.Code, Message: e.Message}
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+// pathError wraps err in a PathError, unless it is one.
+func pathError(op, name string, err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// Open opens the file, the content of a file is read on the first Read.
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	var res openResponse
+	if err := f.call(context.Background(), ProcedureOpen, &nameRequest{Name: name}, &res); err != nil {
+		return nil, pathError("open", name, err)
+	}
+	if res.Info == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("missing info in response")}
+	}
+	return &file{fsys: f, name: name, fi: newFileInfo(res.Info)}, nil
+}
+
+// ReadDir returns the entries of the directory sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var res readDirResponse
+	if err := f.call(context.Background(), ProcedureReadDir, &nameRequest{Name: name}, &res); err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	entries := make([]fs.DirEntry, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		entries = append(entries, newFileInfo(e))
+	}
+	return entries, nil
+}
+
+// ReadFile returns the content of the file.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	var res readFileResponse
+	if err := f.call(context.Background(), ProcedureReadFile, &nameRequest{Name: name}, &res); err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	return res.Data, nil
+}
+
+// Stat returns the info of the file.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return file.Stat()
+}
+
+// Tags returns the tags of the repository.
+func (f *FS) Tags(ctx context.Context) ([]*server.Tag, error) {
+	var res tagsResponse
+	if err := f.call(ctx, ProcedureTags, struct{}{}, &res); err != nil {
+		return nil, err
+	}
+	tags := make([]*server.Tag, 0, len(res.Tags))
+	for _, t := range res.Tags {
+		tags = append(tags, &server.Tag{Name: t.Name, CommitID: t.CommitID})
+	}
+	return tags, nil
+}
+
+// file implements fs.File and fs.ReadDirFile.
+type file struct {
+	fsys *FS
+	name string
+	fi   *fileInfo
+
+	data    *bytes.Reader
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+// Read reads the file, the first call gets the content with ReadFile.
+func (f *file) Read(b []byte) (int, error) {
+	if f.fi.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	if f.data == nil {
+		data, err := f.fsys.ReadFile(f.name)
+		if err != nil {
+			return 0, err
+		}
+		f.data = bytes.NewReader(data)
+	}
+	return f.data.Read(b)
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+// ReadDir returns the entries of the directory, the first call gets them with ReadDir.
+func (f *file) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.fi.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.read {
+		entries, err := f.fsys.ReadDir(f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+		f.read = true
+	}
+	if n <= 0 {
+		res := f.entries
+		f.entries = nil
+		return res, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	res := f.entries[:n]
+	f.entries = f.entries[n:]
+	return res, nil
+}
+
+// fileInfo implements fs.FileInfo and fs.DirEntry.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func newFileInfo(fi *fileInfoJSON) *fileInfo {
+	res := &fileInfo{
+		name: fi.Name,
+		size: fi.Size,
+		mode: fs.FileMode(fi.Mode),
+	}
+	if fi.IsDir {
+		res.mode |= fs.ModeDir
+	}
+	if fi.ModTime != "" {
+		res.modTime, _ = time.Parse(time.RFC3339Nano, fi.ModTime)
+	}
+	return res
+}
+
+func (fi *fileInfo) Name() string               { return fi.name }
+func (fi *fileInfo) Size() int64                { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode          { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time         { return fi.modTime }
+func (fi *fileInfo) IsDir() bool                { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() any                   { return nil }
+func (fi *fileInfo) Type() fs.FileMode          { return fi.mode.Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/nulllog"
+)
+
+// DefaultMaxFileSize is the default maximum size of a file returned by ReadFile.
+const DefaultMaxFileSize = 32 * 1024 * 1024
+
+// maxRequestSize limits the size of the request messages.
+const maxRequestSize = 64 * 1024
+
+// Tagger is implemented by file systems that know the tags of their repository,
+// like the FS returned by bbfs.NewFS.
+type Tagger interface {
+	Tags(ctx context.Context) ([]*server.Tag, error)
+}
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handler)
+
+// WithMaxFileSize sets the maximum size of a file returned by ReadFile,
+// larger files fail with ErrTooLarge. Defaults to DefaultMaxFileSize.
+func WithMaxFileSize(n int64) HandlerOption {
+	return func(h *handler) {
+		h.maxFileSize = n
+	}
+}
+
+// WithLogger sets the logger for failed calls.
+func WithLogger(l *slog.Logger) HandlerOption {
+	return func(h *handler) {
+		h.logger = l
+	}
+}
+
+// NewHandler returns the handler for the service backed by fsys.
+// Mount it at the root, or use http.StripPrefix.
+//
+// Tags fails with unimplemented if fsys does not implement Tagger.
+func NewHandler(fsys fs.FS, opts ...HandlerOption) http.Handler {
+	h := &handler{
+		fsys:        fsys,
+		maxFileSize: DefaultMaxFileSize,
+		logger:      nulllog.Logger(),
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+type handler struct {
+	fsys        fs.FS
+	maxFileSize int64
+	logger      *slog.Logger
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	procedure, ok := strings.CutPrefix(r.URL.Path, "/"+ServiceName+"/")
+	if !ok {
+		writeError(w, &Error{Code: codeNotFound, Message: "unknown service"})
+		return
+	}
+	switch procedure {
+	case ProcedureOpen, ProcedureReadDir, ProcedureReadFile, ProcedureTags:
+	default:
+		writeError(w, &Error{Code: codeNotFound, Message: "unknown procedure " + procedure})
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+		w.Header().Set("Accept-Post", "application/json")
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var req nameRequest
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err == nil && len(body) > 0 {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeError(w, &Error{Code: codeInvalidArgument, Message: err.Error()})
+		return
+	}
+
+	if procedure != ProcedureTags && !fs.ValidPath(req.Name) {
+		writeError(w, &Error{Code: codeInvalidArgument, Message: "invalid name " + req.Name})
+		return
+	}
+
+	var res any
+	switch procedure {
+	case ProcedureOpen:
+		res, err = h.open(req.Name)
+	case ProcedureReadDir:
+		res, err = h.readDir(req.Name)
+	case ProcedureReadFile:
+		res, err = h.readFile(req.Name)
+	case ProcedureTags:
+		res, err = h.tags(r.Context())
+	}
+	if err != nil {
+		h.logger.Info("call failed", slog.String("procedure", procedure), slog.String("name", req.Name), slog.String("error", err.Error()))
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *handler) open(name string) (*openResponse, error) {
+	fi, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return &openResponse{Info: newFileInfoJSON(fi)}, nil
+}
+
+func (h *handler) readDir(name string) (*readDirResponse, error) {
+	entries, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	res := &readDirResponse{Entries: []*fileInfoJSON{}}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		res.Entries = append(res.Entries, newFileInfoJSON(fi))
+	}
+	return res, nil
+}
+
+func (h *handler) readFile(name string) (*readFileResponse, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	if fi.Size() > h.maxFileSize {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: ErrTooLarge}
+	}
+	data, err := io.ReadAll(io.LimitReader(f, h.maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > h.maxFileSize {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: ErrTooLarge}
+	}
+	return &readFileResponse{Data: data}, nil
+}
+
+func (h *handler) tags(ctx context.Context) (*tagsResponse, error) {
+	t, ok := h.fsys.(Tagger)
+	if !ok {
+		return nil, &Error{Code: codeUnimplemented, Message: "the file system has no tags"}
+	}
+	tags, err := t.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &tagsResponse{Tags: []*tagJSON{}}
+	for _, tag := range tags {
+		res.Tags = append(res.Tags, &tagJSON{Name: tag.Name, CommitID: tag.CommitID})
+	}
+	return res, nil
+}
+
+// errorCode returns the Connect error code for err.
+func errorCode(err error) string {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e.Code
+	case errors.Is(err, fs.ErrNotExist):
+		return codeNotFound
+	case errors.Is(err, fs.ErrInvalid):
+		return codeInvalidArgument
+	case errors.Is(err, fs.ErrPermission), server.IsUnauthorized(err):
+		return codePermissionDenied
+	case errors.Is(err, ErrTooLarge):
+		return codeResourceExhausted
+	case errors.Is(err, server.ErrCircuitOpen):
+		return codeUnavailable
+	}
+	return codeInternal
+}
+
+// writeError writes the error response, internal errors do not show the message.
+func writeError(w http.ResponseWriter, err error) {
+	res := errorJSON{Code: errorCode(err)}
+	if res.Code != codeInternal {
+		var e *Error
+		if errors.As(err, &e) {
+			res.Message = e.Message
+		} else {
+			res.Message = err.Error()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(codeStatus[res.Code])
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// ServiceName is the full name of the service in proxy.proto.
+const ServiceName = "bbfs.proxy.v1.FSService"
+
+// Procedures of the service, the path of the url is "/" + ServiceName + "/" + procedure.
+const (
+	ProcedureOpen     = "Open"
+	ProcedureReadDir  = "ReadDir"
+	ProcedureReadFile = "ReadFile"
+	ProcedureTags     = "Tags"
+)
+
+// The messages use the protobuf JSON names of the fields in proxy.proto.
+
+type fileInfoJSON struct {
+	Name string `json:"name"`
+	Size int64  `json:"size,omitempty"`
+	Mode uint32 `json:"mode,omitempty"`
+	// ModTime is in RFC 3339 format, empty for the zero time.
+	ModTime string `json:"modTime,omitempty"`
+	IsDir   bool   `json:"isDir,omitempty"`
+}
+
+type nameRequest struct {
+	Name string `json:"name"`
+}
+
+type openResponse struct {
+	Info *fileInfoJSON `json:"info"`
+}
+
+type readDirResponse struct {
+	Entries []*fileInfoJSON `json:"entries"`
+}
+
+type readFileResponse struct {
+	Data []byte `json:"data"`
+}
+
+type tagJSON struct {
+	Name     string `json:"name"`
+	CommitID string `json:"commitId,omitempty"`
+}
+
+type tagsResponse struct {
+	Tags []*tagJSON `json:"tags"`
+}
+
+func newFileInfoJSON(fi fs.FileInfo) *fileInfoJSON {
+	res := &fileInfoJSON{
+		Name:  fi.Name(),
+		Size:  fi.Size(),
+		Mode:  uint32(fi.Mode()),
+		IsDir: fi.IsDir(),
+	}
+	if t := fi.ModTime(); !t.IsZero() {
+		res.ModTime = t.UTC().Format(time.RFC3339Nano)
+	}
+	return res
+}
+
+// Connect error codes used by the service.
+const (
+	codeInvalidArgument   = "invalid_argument"
+	codeNotFound          = "not_found"
+	codePermissionDenied  = "permission_denied"
+	codeResourceExhausted = "resource_exhausted"
+	codeUnimplemented     = "unimplemented"
+	codeInternal          = "internal"
+	codeUnavailable       = "unavailable"
+)
+
+// codeStatus maps the error codes to http status codes as the Connect protocol does.
+var codeStatus = map[string]int{
+	codeInvalidArgument:   http.StatusBadRequest,
+	codeNotFound:          http.StatusNotFound,
+	codePermissionDenied:  http.StatusForbidden,
+	codeResourceExhausted: http.StatusTooManyRequests,
+	codeUnimplemented:     http.StatusNotImplemented,
+	codeInternal:          http.StatusInternalServerError,
+	codeUnavailable:       http.StatusServiceUnavailable,
+}
+
+// errorJSON is the body of an error response.
+type errorJSON struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// ErrTooLarge is returned by ReadFile for files larger than the maximum size of the handler.
+var ErrTooLarge = errors.New("file too large")
+
+// Error is an error returned by the service.
+type Error struct {
+	// Code is the Connect error code, e.g. not_found.
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap returns the fs error for the code, so errors.Is(err, fs.ErrNotExist) works.
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case codeNotFound:
+		return fs.ErrNotExist
+	case codeInvalidArgument:
+		return fs.ErrInvalid
+	case codePermissionDenied:
+		return fs.ErrPermission
+	case codeResourceExhausted:
+		return ErrTooLarge
+	case codeUnimplemented:
+		return errors.ErrUnsupported
+	}
+	return nil
+}
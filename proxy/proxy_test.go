@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestProxy(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"README.md":      {Data: []byte("# readme\n")},
+		"conf/app.yaml":  {Data: []byte("name: app\n")},
+		"docs/a/b/c.txt": {Data: []byte("c\n")},
+	})
+	repo.Tag("v1.0.0", repo.Commits[0])
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	srv.SetAccessKey("secret")
+
+	backend := bbfs.NewFS(&bbfs.Config{
+		BaseURL:        srv.BaseURL(),
+		ProjectKey:     "PRJ",
+		RepositorySlug: "repo",
+		AccessKey:      "secret",
+	})
+	ps := httptest.NewServer(NewHandler(backend))
+	defer ps.Close()
+
+	// The client does not know the access key.
+	fsys := NewFS(ps.URL)
+	if err := fstest.TestFS(fsys, "README.md", "conf/app.yaml", "docs/a/b/c.txt"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	tags, err := fsys.Tags(context.Background())
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if len(tags) != 1 || tags[0].Name != "v1.0.0" || tags[0].CommitID != repo.Commits[0].ID {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	tests := []struct {
+		name string
+		want error
+	}{
+		{"missing.txt", fs.ErrNotExist},
+		{"conf", fs.ErrInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := fs.ReadFile(fsys, tt.name); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	ps := httptest.NewServer(NewHandler(fstest.MapFS{
+		"big.bin": {Data: bytes.Repeat([]byte{1}, 2048)},
+	}, WithMaxFileSize(1024)))
+	defer ps.Close()
+
+	fsys := NewFS(ps.URL)
+	if _, err := fs.ReadFile(fsys, "big.bin"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+	fi, err := fsys.Stat("big.bin")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if fi.Size() != 2048 {
+		t.Errorf("expected size 2048, got %d", fi.Size())
+	}
+}
+
+func TestProxyWithoutTags(t *testing.T) {
+	ps := httptest.NewServer(NewHandler(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+	defer ps.Close()
+
+	fsys := NewFS(ps.URL)
+	if _, err := fsys.Tags(context.Background()); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	data, err := fsys.ReadFile("a.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "a" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestHandlerProtocol(t *testing.T) {
+	h := NewHandler(fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/Open", "application/json", `{"name":"a.txt"}`, http.StatusOK, `"name":"a.txt"`},
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/ReadFile", "application/json", `{"name":"a.txt"}`, http.StatusOK, `{"data":"YQ=="}`},
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/Open", "application/json", `{"name":"/a.txt"}`, http.StatusBadRequest, `"code":"invalid_argument"`},
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/Open", "application/json", `{"name":"b.txt"}`, http.StatusNotFound, `"code":"not_found"`},
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/Write", "application/json", `{}`, http.StatusNotFound, `"code":"not_found"`},
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/Open", "application/json", `{"name":`, http.StatusBadRequest, `"code":"invalid_argument"`},
+		{http.MethodPost, "/bbfs.proxy.v1.FSService/Open", "application/proto", ``, http.StatusUnsupportedMediaType, ``},
+		{http.MethodGet, "/bbfs.proxy.v1.FSService/Open", "", ``, http.StatusMethodNotAllowed, ``},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.path, tt.body, tt.status, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), tt.response) {
+			t.Errorf("%s %s: expected %s in %s", tt.path, tt.body, tt.response, rec.Body.String())
+		}
+	}
+}
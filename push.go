@@ -0,0 +1,72 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// PushDir commits the differences between the local directory and the
+// directory repoPath, relative to the root of the repository, on the branch
+// in a single commit with the message, see server.CommitBuilder. Files that
+// are only in the repository are deleted, which Bitbucket Data Center does
+// not support: PushDir then fails with server.ErrDeleteUnsupported without
+// committing anything.
+//
+// It returns the commit, nil when the directories do not differ.
+func (r *Repo) PushDir(ctx context.Context, localDir, repoPath, branch, message string) (*server.Commit, error) {
+	b := &r.base
+	if b.err != nil {
+		return nil, b.err
+	}
+	if branch == "" {
+		return nil, errors.New("branch is missing")
+	}
+	if !fs.ValidPath(repoPath) {
+		return nil, &fs.PathError{Op: "push", Path: repoPath, Err: fs.ErrInvalid}
+	}
+	fi, err := os.Stat(localDir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", localDir)
+	}
+	remote, err := fs.Sub(r.FS(BranchRef(branch)), repoPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// The directory is new.
+		remote = fstest.MapFS{}
+	case err != nil:
+		return nil, err
+	}
+	local := os.DirFS(localDir)
+
+	repo := &server.BitbucketRepo{Client: b.client, ProjectKey: b.projectKey, RepoSlug: b.repoSlug}
+	cb := repo.NewCommitBuilder(branch, message)
+	for e, err := range DiffFS(remote, local) {
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := path.Join(b.root, repoPath, e.Path)
+		if e.Kind == DiffRemoved {
+			cb.Delete(name)
+			continue
+		}
+		data, err := fs.ReadFile(local, e.Path)
+		if err != nil {
+			return nil, err
+		}
+		cb.Put(name, data)
+	}
+	return cb.Commit(b.withBudget(ctx))
+}
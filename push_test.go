@@ -0,0 +1,88 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestPushDir(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{
+		"README.md":          {Data: []byte("# readme\n")},
+		"deploy/app.yaml":    {Data: []byte("replicas: 1\n")},
+		"deploy/keep.yaml":   {Data: []byte("keep\n")},
+		"deploy/conf/a.yaml": {Data: []byte("a: 1\n")},
+	})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.yaml":    "replicas: 3\n",
+		"keep.yaml":   "keep\n",
+		"conf/a.yaml": "a: 1\n",
+		"conf/b.yaml": "b: 1\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+	}
+
+	commit, err := r.PushDir(ctx, dir, "deploy", "main", "render")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	head := repo.Resolve("main")
+	if commit == nil || commit.ID != head.ID {
+		t.Fatalf("unexpected commit %v", commit)
+	}
+	for name, want := range files {
+		if got := string(head.Files["deploy/"+name].Data); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+	if string(head.Files["README.md"].Data) != "# readme\n" {
+		t.Errorf("README.md changed")
+	}
+
+	// Nothing to push.
+	commit, err = r.PushDir(ctx, dir, "deploy", "main", "render")
+	if err != nil || commit != nil {
+		t.Errorf("expected no commit, got %v, %v", commit, err)
+	}
+
+	// A new directory.
+	commit, err = r.PushDir(ctx, filepath.Join(dir, "conf"), "other/conf", "main", "copy conf")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if commit == nil || string(repo.Resolve("main").Files["other/conf/b.yaml"].Data) != "b: 1\n" {
+		t.Errorf("conf is not pushed")
+	}
+
+	// Data Center has no delete.
+	if err := os.Remove(filepath.Join(dir, "keep.yaml")); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	head = repo.Resolve("main")
+	if _, err := r.PushDir(ctx, dir, "deploy", "main", "remove"); !errors.Is(err, server.ErrDeleteUnsupported) {
+		t.Errorf("expected ErrDeleteUnsupported, got %v", err)
+	}
+	if repo.Resolve("main") != head {
+		t.Errorf("the branch moved")
+	}
+}
@@ -0,0 +1,110 @@
+package bbfs
+
+import (
+	"context"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// Types of RefInfo.
+const (
+	RefTypeBranch = "BRANCH"
+	RefTypeTag    = "TAG"
+)
+
+// RefInfo describes a branch or tag.
+type RefInfo struct {
+	// Ref is the fully qualified ref, use it with Repo.FS.
+	Ref Ref
+	// Name is the display name.
+	Name string
+	// Type is RefTypeBranch or RefTypeTag.
+	Type string
+	// CommitID is the commit the ref points to.
+	CommitID string
+	// IsDefault is true for the default branch.
+	IsDefault bool
+}
+
+// ListRefs returns the branches followed by the tags of the repository,
+// both in alphabetical order.
+func (r *Repo) ListRefs(ctx context.Context) ([]*RefInfo, error) {
+	b := &r.base
+	if b.err != nil {
+		return nil, b.err
+	}
+	ctx = b.withBudget(ctx)
+
+	branches, err := b.listBranches(ctx, server.OrderByAlphabetical)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := b.listTags(ctx, server.OrderByAlphabetical)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*RefInfo, 0, len(branches)+len(tags))
+	for _, br := range branches {
+		res = append(res, &RefInfo{
+			Ref:       BranchRef(br.Name),
+			Name:      br.Name,
+			Type:      RefTypeBranch,
+			CommitID:  br.CommitID,
+			IsDefault: br.IsDefault,
+		})
+	}
+	for _, t := range tags {
+		res = append(res, &RefInfo{
+			Ref:      TagRef(t.Name),
+			Name:     t.Name,
+			Type:     RefTypeTag,
+			CommitID: t.CommitID,
+		})
+	}
+	return res, nil
+}
+
+// listBranches returns all branches of the repository.
+func (b *bbFS) listBranches(ctx context.Context, orderBy server.OrderBy) ([]*server.Branch, error) {
+	cmd := &server.GetBranchesCommand{
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		OrderBy:    orderBy,
+		Limit:      server.MaxLimit,
+	}
+	var res []*server.Branch
+	for {
+		resp, err := b.client.GetBranches(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, resp.Branches...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// listTags returns all tags of the repository.
+func (b *bbFS) listTags(ctx context.Context, orderBy server.OrderBy) ([]*server.Tag, error) {
+	cmd := &server.GetTagsCommand{
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		OrderBy:    orderBy,
+		Limit:      server.MaxLimit,
+	}
+	var res []*server.Tag
+	for {
+		resp, err := b.client.GetTags(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, resp.Tags...)
+		if resp.IsLastPage {
+			return res, nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
@@ -0,0 +1,38 @@
+package bbfs
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestListRefs(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"a": {Data: []byte("a")}})
+	first := repo.Commits[0]
+	second := repo.Commit("feature/x", "second", fstest.MapFS{"b": {Data: []byte("b")}})
+	repo.Tag("v1.0.0", first)
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+	refs, err := r.ListRefs(context.Background())
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	want := []RefInfo{
+		{Ref: "refs/heads/feature/x", Name: "feature/x", Type: RefTypeBranch, CommitID: second.ID},
+		{Ref: "refs/heads/main", Name: "main", Type: RefTypeBranch, CommitID: first.ID, IsDefault: true},
+		{Ref: "refs/tags/v1.0.0", Name: "v1.0.0", Type: RefTypeTag, CommitID: first.ID},
+	}
+	if len(refs) != len(want) {
+		t.Fatalf("expected %d refs, got %d", len(want), len(refs))
+	}
+	for i, ref := range refs {
+		if *ref != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], *ref)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package bbfs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RepoError is the error of a request of an FS of this package, with the
+// repository and ref it was for. It is the Err of the fs.PathError that
+// Open, ReadDir, Stat and Read return when a request fails, so services
+// that read many repositories can tell which one failed:
+//
+//	var re *bbfs.RepoError
+//	if errors.As(err, &re) {
+//		log.Printf("%s/%s at %s: %v", re.Project, re.Repo, re.Ref, re.Err)
+//	}
+type RepoError struct {
+	Project string
+	Repo    string
+	// Ref is the ref of the FS, empty for the default branch.
+	Ref Ref
+	// Commit is the commit of the ref: the ref itself for an FS at a full
+	// commit id, and for a branch or tag the commit it resolved to when the
+	// FS first used it. A branch can have moved on since. It is empty for
+	// the default branch and before the ref is resolved.
+	Commit string
+	Err    error
+}
+
+func (e *RepoError) Error() string {
+	at := e.Ref.String()
+	if at == "" {
+		at = "the default branch"
+	}
+	if e.Commit != "" && e.Commit != e.Ref.String() {
+		at += " (" + e.Commit + ")"
+	}
+	return fmt.Sprintf("%s/%s at %s: %s", e.Project, e.Repo, at, e.Err)
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// repoError returns err in a RepoError with the repository and ref of the
+// FS, nil for nil. An error that has a RepoError is returned as is.
+func (b *bbFS) repoError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var re *RepoError
+	if errors.As(err, &re) {
+		return err
+	}
+	res := &RepoError{Project: b.projectKey, Repo: b.repoSlug, Ref: b.at, Err: err}
+	if isFullCommitID(b.at) {
+		res.Commit = b.at.String()
+	} else {
+		res.Commit = b.resolvedCommit()
+	}
+	return res
+}
@@ -0,0 +1,85 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// LimitRepoSize is the limit of a LimitError from Repo.CheckSize.
+const LimitRepoSize = "repository size"
+
+// ErrSizeUnknown is returned by Repo.CheckSize when the server does not
+// report the size of the repository.
+var ErrSizeUnknown = errors.New("repository size unknown")
+
+// RepoInfo describes a repository.
+type RepoInfo struct {
+	ProjectKey string
+	Slug       string
+	Name       string
+	Public     bool
+	// Size is the size of the git repository in bytes,
+	// -1 when the server does not report it.
+	Size int64
+	// AttachmentsSize is the size of the attachments in bytes,
+	// -1 when the server does not report it.
+	AttachmentsSize int64
+}
+
+// Info returns the metadata of the repository, with its size where the
+// server reports it.
+func (r *Repo) Info(ctx context.Context) (*RepoInfo, error) {
+	b := &r.base
+	if b.err != nil {
+		return nil, b.err
+	}
+	ctx = b.withBudget(ctx)
+
+	repo, err := b.client.GetRepo(ctx, &server.GetRepoCommand{
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := &RepoInfo{
+		ProjectKey:      repo.ProjectKey,
+		Slug:            repo.Slug,
+		Name:            repo.Name,
+		Public:          repo.Public,
+		Size:            -1,
+		AttachmentsSize: -1,
+	}
+	size, err := b.client.GetRepoSize(ctx, &server.GetRepoSizeCommand{
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+	})
+	if server.IsNotFound(err) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Size = size.Repository
+	res.AttachmentsSize = size.Attachments
+	return res, nil
+}
+
+// CheckSize returns a LimitError when the git repository is larger than
+// maxBytes, for tools that refuse to copy large repositories. It returns
+// ErrSizeUnknown when the server does not report the size.
+func (r *Repo) CheckSize(ctx context.Context, maxBytes int64) error {
+	info, err := r.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if info.Size < 0 {
+		return ErrSizeUnknown
+	}
+	if info.Size > maxBytes {
+		return &LimitError{Limit: LimitRepoSize, Max: maxBytes, Path: info.ProjectKey + "/" + info.Slug}
+	}
+	return nil
+}
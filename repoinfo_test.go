@@ -0,0 +1,56 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestRepoInfo(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{"a.txt": {Data: make([]byte, 100)}})
+	srv.AddRepo("PRJ", "repo", repo)
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"})
+	ctx := context.Background()
+
+	info, err := r.Info(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if info.ProjectKey != "PRJ" || info.Slug != "repo" || info.Size != 100 || info.AttachmentsSize != 0 {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	if err := r.CheckSize(ctx, 100); err != nil {
+		t.Errorf("expected no error at the maximum, got %v", err)
+	}
+	err = r.CheckSize(ctx, 99)
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != LimitRepoSize || le.Max != 99 || le.Path != "PRJ/repo" {
+		t.Errorf("expected a repository size LimitError, got %v", err)
+	}
+
+	// The size is not cached.
+	srv.Update(func() {
+		repo.Commit("main", "more", fstest.MapFS{"b.txt": {Data: make([]byte, 50)}})
+	})
+	if info, err := r.Info(ctx); err != nil || info.Size != 150 {
+		t.Errorf("expected size 150, got %+v, %v", info, err)
+	}
+
+	srv.Update(func() { repo.HideSizes = true })
+	info, err = r.Info(ctx)
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if info.Size != -1 || info.AttachmentsSize != -1 {
+		t.Errorf("expected unknown sizes, got %+v", info)
+	}
+	if err := r.CheckSize(ctx, 1000); !errors.Is(err, ErrSizeUnknown) {
+		t.Errorf("expected ErrSizeUnknown, got %v", err)
+	}
+}
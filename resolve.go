@@ -0,0 +1,254 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+var (
+	// ErrRefNotFound is returned when a branch, tag or commit does not exist.
+	ErrRefNotFound = errors.New("ref not found")
+	// ErrEmptyRepository is returned for a ref of a repository without
+	// commits, with ErrRefNotFound.
+	ErrEmptyRepository = errors.New("repository is empty")
+)
+
+// RefNotFoundError is the error for a branch, tag or commit that does not
+// exist. It is ErrRefNotFound for errors.Is.
+type RefNotFoundError struct {
+	Ref Ref
+}
+
+func (e *RefNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRefNotFound, e.Ref)
+}
+
+// Is returns true for ErrRefNotFound.
+func (e *RefNotFoundError) Is(target error) bool {
+	return target == ErrRefNotFound
+}
+
+// isFullCommitID returns true if ref is a complete commit id.
+func isFullCommitID(ref Ref) bool {
+	return len(ref) == 40 && ref.IsCommit()
+}
+
+// ResolveRef returns the commit id for a branch, tag or (abbreviated) commit.
+//
+// Fully qualified refs are looked up as branch or tag.
+// Short names are tried as branch, then as tag and finally as commit.
+// The error is a RefNotFoundError when the ref does not exist.
+func ResolveRef(ctx context.Context, client *server.Client, project, repo string, ref Ref) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("%w: empty ref", ErrRefNotFound)
+	}
+	if isFullCommitID(ref) {
+		return ref.String(), nil
+	}
+
+	lookups := []func() (string, error){
+		func() (string, error) { return findBranch(ctx, client, project, repo, ref) },
+		func() (string, error) { return findTag(ctx, client, project, repo, ref) },
+		func() (string, error) { return findCommit(ctx, client, project, repo, ref) },
+	}
+	switch {
+	case ref.IsBranch():
+		lookups = lookups[:1]
+	case ref.IsTag():
+		lookups = lookups[1:2]
+	}
+
+	for _, lookup := range lookups {
+		id, err := lookup()
+		if err != nil {
+			return "", err
+		}
+		if id != "" {
+			return id, nil
+		}
+	}
+	return "", &RefNotFoundError{Ref: ref}
+}
+
+// currentCommit returns the commit the ref points to now, bypassing the
+// cache of the client. The error is a RefNotFoundError when the ref does not exist.
+func currentCommit(ctx context.Context, client *server.Client, project, repo string, ref Ref) (string, error) {
+	id, err := client.CurrentCommit(ctx, project, repo, ref)
+	if server.IsNotFound(err) {
+		return "", &RefNotFoundError{Ref: ref}
+	}
+	return id, err
+}
+
+// resolve returns the commit id for the ref, the head of the default
+// branch for an empty ref.
+func (b *bbFS) resolve(ctx context.Context, ref Ref) (string, error) {
+	if ref != "" {
+		return ResolveRef(ctx, b.client, b.projectKey, b.repoSlug, ref)
+	}
+	id, err := findCommit(ctx, b.client, b.projectKey, b.repoSlug, "")
+	if err == nil && id == "" {
+		err = fmt.Errorf("%w: no default branch", ErrRefNotFound)
+	}
+	return id, err
+}
+
+// refCheck checks once that the ref of an FS exists.
+type refCheck struct {
+	mu   sync.Mutex
+	done bool
+	err  error
+	// commit is the commit the ref resolved to.
+	commit string
+}
+
+// resolvedCommit returns the commit the ref of the FS resolved to, or
+// "" when it is not resolved yet.
+func (b *bbFS) resolvedCommit() string {
+	c := b.refCheck
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commit
+}
+
+// checkRef resolves the ref of the FS on first use, so an FS at a branch or
+// tag that does not exist fails with a RefNotFoundError instead of the not
+// found errors of the files. The default branch and full commit ids are not
+// checked. The result is kept, except for errors of the requests.
+func (b *bbFS) checkRef() error {
+	c := b.refCheck
+	if c == nil || b.at == "" || isFullCommitID(b.at) {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return c.err
+	}
+	ctx := b.requestContext()
+	id, err := ResolveRef(ctx, b.client, b.projectKey, b.repoSlug, b.at)
+	if err != nil && !errors.Is(err, ErrRefNotFound) {
+		return err
+	}
+	if err != nil {
+		if empty, eerr := b.isEmpty(ctx); eerr == nil && empty {
+			err = fmt.Errorf("%w, %w", ErrEmptyRepository, err)
+		}
+	}
+	c.done, c.err, c.commit = true, err, id
+	return err
+}
+
+// rootNotFound explains err, the not found error of the listing of the
+// root of the FS. In an empty repository the default branch has no files,
+// it returns nil then. For another ref of an empty repository or a ref that
+// does not exist the error wraps ErrEmptyRepository or ErrRefNotFound,
+// otherwise it is err: the root of a Sub does not exist at the ref.
+func (b *bbFS) rootNotFound(ctx context.Context, err error) error {
+	empty, eerr := b.isEmpty(ctx)
+	switch {
+	case eerr != nil:
+		return err
+	case empty && b.at == "":
+		return nil
+	case empty:
+		return fmt.Errorf("%w, %w", ErrEmptyRepository, &RefNotFoundError{Ref: b.at})
+	}
+	if _, rerr := b.resolve(ctx, b.at); errors.Is(rerr, ErrRefNotFound) {
+		return rerr
+	}
+	return err
+}
+
+// isEmpty reports whether the repository has no branches, and so no commits.
+func (b *bbFS) isEmpty(ctx context.Context) (bool, error) {
+	resp, err := b.client.GetBranches(ctx, &server.GetBranchesCommand{
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		Limit:      1,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Branches) == 0, nil
+}
+
+// findBranch returns the commit id for the branch or "" if not found.
+// The filter matches substrings, so it reads all pages of matches.
+func findBranch(ctx context.Context, client *server.Client, project, repo string, ref Ref) (string, error) {
+	cmd := &server.GetBranchesCommand{
+		ProjectKey: project,
+		RepoSlug:   repo,
+		FilterText: ref.Name(),
+		Limit:      100,
+	}
+	for {
+		resp, err := client.GetBranches(ctx, cmd)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range resp.Branches {
+			if b.Ref == server.BranchRef(ref.Name()) {
+				return b.CommitID, nil
+			}
+		}
+		if resp.IsLastPage {
+			return "", nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// findTag returns the commit id for the tag or "" if not found.
+// The filter matches substrings, so it reads all pages of matches.
+func findTag(ctx context.Context, client *server.Client, project, repo string, ref Ref) (string, error) {
+	cmd := &server.GetTagsCommand{
+		ProjectKey: project,
+		RepoSlug:   repo,
+		FilterText: ref.Name(),
+		Limit:      100,
+	}
+	for {
+		resp, err := client.GetTags(ctx, cmd)
+		if err != nil {
+			return "", err
+		}
+		for _, t := range resp.Tags {
+			if t.Name == ref.Name() {
+				return t.CommitID, nil
+			}
+		}
+		if resp.IsLastPage {
+			return "", nil
+		}
+		cmd.Start = resp.NextPageStart
+	}
+}
+
+// findCommit returns the commit id for the ref or "" if not found.
+func findCommit(ctx context.Context, client *server.Client, project, repo string, ref Ref) (string, error) {
+	// Use until, a ref with slashes can not be used as path element.
+	resp, err := client.GetCommits(ctx, &server.GetCommitsCommand{
+		ProjectKey: project,
+		RepoSlug:   repo,
+		Until:      ref,
+		Limit:      1,
+	})
+	if server.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Commits) == 0 {
+		return "", nil
+	}
+	return resp.Commits[0].ID, nil
+}
@@ -0,0 +1,129 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestResolveRef(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	repo := fakeserver.NewRepo(fstest.MapFS{"README.md": {Data: []byte("readme")}})
+	first := repo.Commits[0]
+	repo.Tag("comp/1.0.0", first)
+	second := repo.Commit("develop", "second commit", fstest.MapFS{})
+	srv.AddRepo("PRJ", "repo", repo)
+
+	client := &server.Client{BaseURL: srv.BaseURL()}
+	tests := []struct {
+		ref  Ref
+		want string
+	}{
+		{ref: "main", want: first.ID},
+		{ref: BranchRef("develop"), want: second.ID},
+		{ref: TagRef("comp/1.0.0"), want: first.ID},
+		{ref: "comp/1.0.0", want: first.ID},
+		{ref: Ref(second.ID[:10]), want: second.ID},
+		{ref: CommitRef(first.ID), want: first.ID},
+	}
+	for _, tt := range tests {
+		got, err := ResolveRef(context.Background(), client, "PRJ", "repo", tt.ref)
+		if err != nil {
+			t.Fatalf("error resolving %s: %s", tt.ref, err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.ref, tt.want, got)
+		}
+	}
+
+	for _, ref := range []Ref{"nothere", BranchRef("comp/1.0.0"), TagRef("main")} {
+		_, err := ResolveRef(context.Background(), client, "PRJ", "repo", ref)
+		if !errors.Is(err, ErrRefNotFound) {
+			t.Errorf("%s: expected ErrRefNotFound, got %v", ref, err)
+		}
+	}
+}
+
+func TestResolveRefManyMatches(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	// More than a page of refs match the filter before the exact ref.
+	repo := fakeserver.NewRepo(fstest.MapFS{"README.md": {Data: []byte("readme")}})
+	first := repo.Commits[0]
+	for i := range 150 {
+		repo.Branches[fmt.Sprintf("feature/main-%03d", i)] = first.ID
+		repo.Tag(fmt.Sprintf("old/v1-%03d", i), first)
+	}
+	repo.Tag("v1", first)
+	srv.AddRepo("PRJ", "repo", repo)
+
+	client := &server.Client{BaseURL: srv.BaseURL()}
+	for _, ref := range []Ref{BranchRef("main"), TagRef("v1"), "v1"} {
+		got, err := ResolveRef(context.Background(), client, "PRJ", "repo", ref)
+		if err != nil {
+			t.Fatalf("error resolving %s: %s", ref, err.Error())
+		}
+		if got != first.ID {
+			t.Errorf("%s: expected %s, got %s", ref, first.ID, got)
+		}
+	}
+	fsys := NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: "refs/tags/v1"})
+	if _, err := fs.ReadFile(fsys, "README.md"); err != nil {
+		t.Errorf("error: %s", err.Error())
+	}
+}
+
+func TestMissingAt(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{"docs/README.md": {Data: []byte("readme")}})
+	repo.Tag("v1", repo.Commits[0])
+	srv.AddRepo("PRJ", "repo", repo)
+	newFS := func(ref Ref) fs.FS {
+		return NewFS(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: ref})
+	}
+
+	for _, ref := range []Ref{"v1", BranchRef("main"), ""} {
+		if _, err := fs.ReadFile(newFS(ref), "docs/README.md"); err != nil {
+			t.Errorf("%s: error: %s", ref, err.Error())
+		}
+	}
+
+	fsys := newFS(TagRef("v2"))
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"open", func() error { _, err := fsys.Open("docs/README.md"); return err }},
+		{"stat", func() error { _, err := fs.Stat(fsys, "docs"); return err }},
+		{"readdir", func() error { _, err := fs.ReadDir(fsys, "."); return err }},
+		{"walk", func() error {
+			return fs.WalkDir(fsys, ".", func(_ string, _ fs.DirEntry, err error) error { return err })
+		}},
+	}
+	for _, tt := range calls {
+		err := tt.call()
+		var re *RefNotFoundError
+		if !errors.As(err, &re) || re.Ref != TagRef("v2") || !errors.Is(err, ErrRefNotFound) {
+			t.Errorf("%s: expected a RefNotFoundError for v2, got %v", tt.name, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "refs/tags/v2") {
+			t.Errorf("%s: expected the ref in %q", tt.name, err)
+		}
+		// The ref is resolved once.
+		n := srv.Requests()
+		if err := tt.call(); err == nil || srv.Requests() != n {
+			t.Errorf("%s: expected the kept error without requests, got %v", tt.name, err)
+		}
+	}
+}
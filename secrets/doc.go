@@ -0,0 +1,19 @@
+/*
+Package secrets reads access keys from secret stores, so they do not have
+to be in configuration files.
+
+A secret is referenced by a string with the provider as scheme:
+
+	env:BITBUCKET_TOKEN            the environment variable
+	file:/run/secrets/bitbucket    the content of the file
+	exec:pass show bitbucket       the output of the command
+	vault:secret/data/bbfs#token   the field of a Vault secret
+
+Vault is reached at VAULT_ADDR with the token in VAULT_TOKEN, and
+VAULT_NAMESPACE if set. Both the KV version 1 and 2 engines are supported.
+
+TokenSource turns a provider into a token source for the client, which reads
+the secret again when it expires. The Config of bbfs and the bbclient command
+take a reference in AccessKeySecret and -access-key-secret.
+*/
+package secrets
@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// DefaultTTL is the time a TokenSource uses a secret before reading it again,
+// unless the provider reports the expiry.
+const DefaultTTL = 5 * time.Minute
+
+// Provider returns a secret, e.g. an access token.
+type Provider interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// ProviderFunc is a function that implements Provider.
+type ProviderFunc func(ctx context.Context) (string, error)
+
+// Secret calls f.
+func (f ProviderFunc) Secret(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// ErrEmpty is returned when a provider finds an empty secret.
+var ErrEmpty = errors.New("empty secret")
+
+// Parse returns the provider for the reference, see the package documentation.
+func Parse(ref string) (Provider, error) {
+	scheme, value, ok := strings.Cut(ref, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("secret reference %q: want scheme:value", ref)
+	}
+	switch scheme {
+	case "env":
+		return Env(value), nil
+	case "file":
+		return File(value), nil
+	case "exec":
+		args := strings.Fields(value)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("secret reference %q: missing command", ref)
+		}
+		return Exec(args[0], args[1:]...), nil
+	case "vault":
+		path, field, ok := strings.Cut(value, "#")
+		if !ok || path == "" || field == "" {
+			return nil, fmt.Errorf("secret reference %q: want vault:path#field", ref)
+		}
+		return VaultFromEnv(path, field), nil
+	}
+	return nil, fmt.Errorf("secret reference %q: unknown provider %q", ref, scheme)
+}
+
+// Resolve returns the secret for the reference.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	p, err := Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return p.Secret(ctx)
+}
+
+// Env returns the provider for the environment variable.
+func Env(name string) Provider {
+	return ProviderFunc(func(ctx context.Context) (string, error) {
+		v := os.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("environment variable %s: %w", name, ErrEmpty)
+		}
+		return v, nil
+	})
+}
+
+// File returns the provider for the content of the file,
+// without leading and trailing white space.
+func File(path string) Provider {
+	return ProviderFunc(func(ctx context.Context) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		v := strings.TrimSpace(string(data))
+		if v == "" {
+			return "", fmt.Errorf("file %s: %w", path, ErrEmpty)
+		}
+		return v, nil
+	})
+}
+
+// Exec returns the provider for the output of the command,
+// without leading and trailing white space.
+func Exec(name string, args ...string) Provider {
+	return ProviderFunc(func(ctx context.Context) (string, error) {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("command %s: %w: %s", name, err, msg)
+			}
+			return "", fmt.Errorf("command %s: %w", name, err)
+		}
+		v := strings.TrimSpace(string(out))
+		if v == "" {
+			return "", fmt.Errorf("command %s: %w", name, ErrEmpty)
+		}
+		return v, nil
+	})
+}
+
+// expirer is implemented by providers that know when the secret expires.
+type expirer interface {
+	secretWithExpiry(ctx context.Context) (string, time.Time, error)
+}
+
+// TokenSource returns a token source for the secret of p. The secret is read
+// again after ttl, DefaultTTL when zero, or when the provider reports an
+// earlier expiry, like Vault does for the lease of a dynamic secret.
+func TokenSource(p Provider, ttl time.Duration) server.TokenSource {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return server.NewRefreshingTokenSource(func(ctx context.Context) (*server.AccessToken, error) {
+		expiry := time.Now().Add(ttl)
+		var (
+			v   string
+			err error
+		)
+		if e, ok := p.(expirer); ok {
+			var until time.Time
+			v, until, err = e.secretWithExpiry(ctx)
+			if !until.IsZero() && until.Before(expiry) {
+				expiry = until
+			}
+		} else {
+			v, err = p.Secret(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &server.AccessToken{Value: server.SecretString(v), Expiry: expiry}, nil
+	})
+}
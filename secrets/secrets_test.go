@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("BBFS_TEST_TOKEN", "from-env")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+
+	tests := []struct {
+		ref  string
+		want string
+		err  bool
+	}{
+		{"env:BBFS_TEST_TOKEN", "from-env", false},
+		{"env:BBFS_TEST_UNSET", "", true},
+		{"file:" + file, "from-file", false},
+		{"file:" + empty, "", true},
+		{"exec:echo from-exec", "from-exec", false},
+		{"exec:false", "", true},
+		{"exec: ", "", true},
+		{"vault:secret/data/bbfs", "", true},
+		{"bogus:value", "", true},
+		{"no-scheme", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(context.Background(), tt.ref)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.ref, tt.want, got)
+		}
+	}
+}
+
+func TestVault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/bbfs":
+			w.Write([]byte(`{"data":{"data":{"token":"kv2"},"metadata":{"version":1}}}`))
+		case "/v1/kv/bbfs":
+			w.Write([]byte(`{"lease_duration":60,"data":{"token":"kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("VAULT_NAMESPACE", "team")
+	tests := []struct {
+		ref  string
+		want string
+		err  string
+	}{
+		{"vault:secret/data/bbfs#token", "kv2", ""},
+		{"vault:kv/bbfs#token", "kv1", ""},
+		{"vault:kv/bbfs#password", "", "no field password"},
+		{"vault:secret/data/missing#token", "", "404"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(context.Background(), tt.ref)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error with %q, got %v", tt.ref, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.ref, tt.want, got)
+		}
+	}
+
+	v := VaultFromEnv("secret/data/bbfs", "token")
+	v.Token = "wrong"
+	if _, err := v.Secret(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected permission denied, got %v", err)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	calls := 0
+	p := ProviderFunc(func(ctx context.Context) (string, error) {
+		calls++
+		if calls > 1 {
+			return "", errors.New("unavailable")
+		}
+		return "token", nil
+	})
+	ts := TokenSource(p, 0)
+	for range 2 {
+		got, err := ts.Token(context.Background())
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		if got != "token" {
+			t.Errorf("expected token, got %q", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the secret to be read once, got %d", calls)
+	}
+}
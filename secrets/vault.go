@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault reads a field of a secret from HashiCorp Vault.
+type Vault struct {
+	// Addr is the url of Vault, e.g. https://vault.example.com:8200.
+	Addr string
+	// Token is the Vault token.
+	Token string
+	// Namespace is the Vault Enterprise namespace, if any.
+	Namespace string
+	// Path is the path of the secret, for the KV version 2 engine
+	// with the data segment, e.g. secret/data/bbfs.
+	Path string
+	// Field is the field in the secret.
+	Field string
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// VaultFromEnv returns the provider for the field of the secret at path,
+// with the address, token and namespace from VAULT_ADDR, VAULT_TOKEN
+// and VAULT_NAMESPACE.
+func VaultFromEnv(path, field string) *Vault {
+	return &Vault{
+		Addr:      os.Getenv("VAULT_ADDR"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		Path:      path,
+		Field:     field,
+	}
+}
+
+// vaultResponse is the response for a secret. The KV version 2 engine
+// nests the fields in data.data.
+type vaultResponse struct {
+	LeaseDuration int            `json:"lease_duration"`
+	Data          map[string]any `json:"data"`
+	Errors        []string       `json:"errors"`
+}
+
+// Secret returns the field of the secret.
+func (v *Vault) Secret(ctx context.Context) (string, error) {
+	s, _, err := v.secretWithExpiry(ctx)
+	return s, err
+}
+
+func (v *Vault) secretWithExpiry(ctx context.Context) (string, time.Time, error) {
+	if v.Addr == "" || v.Token == "" {
+		return "", time.Time{}, fmt.Errorf("vault %s: address or token missing", v.Path)
+	}
+	u, err := url.JoinPath(v.Addr, "v1", v.Path)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("vault %s: %w", v.Path, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	hc := v.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("vault %s: %w", v.Path, err)
+	}
+	defer resp.Body.Close()
+
+	var res vaultResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&res); err != nil && resp.StatusCode == http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("vault %s: %w", v.Path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(res.Errors) > 0 {
+			return "", time.Time{}, fmt.Errorf("vault %s: %s: %s", v.Path, resp.Status, strings.Join(res.Errors, ", "))
+		}
+		return "", time.Time{}, fmt.Errorf("vault %s: %s", v.Path, resp.Status)
+	}
+
+	data := res.Data
+	if _, ok := data[v.Field]; !ok {
+		if nested, ok := data["data"].(map[string]any); ok {
+			data = nested
+		}
+	}
+	s, ok := data[v.Field].(string)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("vault %s: no field %s", v.Path, v.Field)
+	}
+	if s == "" {
+		return "", time.Time{}, fmt.Errorf("vault %s#%s: %w", v.Path, v.Field, ErrEmpty)
+	}
+	var expiry time.Time
+	if res.LeaseDuration > 0 {
+		expiry = start.Add(time.Duration(res.LeaseDuration) * time.Second)
+	}
+	return s, expiry, nil
+}
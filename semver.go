@@ -0,0 +1,236 @@
+package bbfs
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, see https://semver.org.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the pre-release, e.g. rc.1, empty for a release.
+	Pre string
+	// Build is the build metadata, it is ignored in comparisons.
+	Build string
+}
+
+// ParseVersion parses a semantic version with an optional v prefix.
+// Minor and patch may be left out, 1.2 is 1.2.0.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	rest := strings.TrimPrefix(s, "v")
+	rest, v.Build, _ = strings.Cut(rest, "+")
+	rest, v.Pre, _ = strings.Cut(rest, "-")
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 || rest == "" {
+		return Version{}, fmt.Errorf("version %q is invalid", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (len(p) > 1 && p[0] == '0') {
+			return Version{}, fmt.Errorf("version %q is invalid", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// String returns the version without v prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v is lower, equal or higher than w.
+func (v Version) Compare(w Version) int {
+	if c := cmp.Compare(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	return comparePre(v.Pre, w.Pre)
+}
+
+// comparePre compares pre-releases, a release is higher than a pre-release.
+func comparePre(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			// Numeric identifiers are lower than alphanumeric ones.
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// Constraint selects versions, see ParseConstraint.
+type Constraint struct {
+	// ranges are alternatives, a version must match all bounds of one range.
+	ranges [][]bound
+}
+
+type bound struct {
+	op string
+	v  Version
+}
+
+func (b bound) check(v Version) bool {
+	c := v.Compare(b.v)
+	switch b.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// ParseConstraint parses a constraint on versions.
+//
+// A constraint is a list of comparisons separated by commas or spaces that
+// must all hold, alternatives are separated by ||. The comparisons are
+// =, !=, >, >=, < and <=, followed by a version, and the shorthands
+// ^1.2.3 (>=1.2.3 <2.0.0), ~1.2.3 (>=1.2.3 <1.3.0) and 1.2.x or 1.2 (>=1.2.0 <1.3.0).
+// An empty constraint and * match all versions.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, alt := range strings.Split(s, "||") {
+		var r []bound
+		for _, f := range strings.FieldsFunc(alt, func(r rune) bool { return r == ',' || r == ' ' }) {
+			bounds, err := parseComparison(f)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("constraint %q is invalid: %w", s, err)
+			}
+			r = append(r, bounds...)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+// parseComparison returns the bounds for a single comparison.
+func parseComparison(s string) ([]bound, error) {
+	if s == "*" || s == "x" {
+		return nil, nil
+	}
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(s, op); ok {
+			v, err := ParseVersion(rest)
+			if err != nil {
+				return nil, err
+			}
+			return []bound{{op, v}}, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(s, "^"); ok {
+		v, err := ParseVersion(rest)
+		if err != nil {
+			return nil, err
+		}
+		upper := Version{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && v.Minor == 0:
+			upper = Version{Patch: v.Patch + 1}
+		case v.Major == 0:
+			upper = Version{Minor: v.Minor + 1}
+		}
+		return []bound{{">=", v}, {"<", upper}}, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "~"); ok {
+		v, err := ParseVersion(rest)
+		if err != nil {
+			return nil, err
+		}
+		return []bound{{">=", v}, {"<", Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+
+	// A partial version matches all versions with the same prefix.
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	for len(parts) > 0 && (parts[len(parts)-1] == "x" || parts[len(parts)-1] == "*") {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 3 {
+		v, err := ParseVersion(s)
+		if err != nil {
+			return nil, err
+		}
+		return []bound{{"=", v}}, nil
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	v, err := ParseVersion(strings.Join(parts, "."))
+	if err != nil {
+		return nil, err
+	}
+	if v.Pre != "" {
+		return nil, fmt.Errorf("partial version %q with pre-release", s)
+	}
+	upper := Version{Major: v.Major + 1}
+	if len(parts) == 2 {
+		upper = Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return []bound{{">=", v}, {"<", upper}}, nil
+}
+
+// Check returns true if the version meets the constraint.
+func (c Constraint) Check(v Version) bool {
+	if len(c.ranges) == 0 {
+		return true
+	}
+	for _, r := range c.ranges {
+		ok := true
+		for _, b := range r {
+			if !b.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
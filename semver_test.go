@@ -0,0 +1,142 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"v1.2.3-rc.1+build.5", "1.2.3-rc.1+build.5", true},
+		{"1.2", "1.2.0", true},
+		{"1", "1.0.0", true},
+		{"01.2.3", "", false},
+		{"1.2.3.4", "", false},
+		{"1.a.3", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: unexpected error %v", tt.in, err)
+			continue
+		}
+		if tt.ok && v.String() != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.in, tt.want, v)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// In ascending order, from the semver specification.
+	versions := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 1; i < len(versions); i++ {
+		a, _ := ParseVersion(versions[i-1])
+		b, _ := ParseVersion(versions[i])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"", []string{"0.0.1", "9.9.9"}, nil},
+		{">=1.2.0, <2.0.0", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"1.2.x", []string{"1.2.0", "1.2.7"}, []string{"1.3.0", "1.1.0"}},
+		{"1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"<1.0.0 || >=3.0.0", []string{"0.9.0", "3.1.0"}, []string{"1.0.0", "2.9.9"}},
+		{"!=1.2.3 >1.2.0", []string{"1.2.4"}, []string{"1.2.3", "1.2.0"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		for _, s := range tt.match {
+			v, _ := ParseVersion(s)
+			if !c.Check(v) {
+				t.Errorf("%q: expected %s to match", tt.constraint, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			v, _ := ParseVersion(s)
+			if c.Check(v) {
+				t.Errorf("%q: expected %s not to match", tt.constraint, s)
+			}
+		}
+	}
+	if _, err := ParseConstraint(">=abc"); err == nil {
+		t.Errorf("expected an error for an invalid constraint")
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	fsys := newTagsFS([]string{
+		"comp/1.2.0", "comp/1.10.0", "comp/2.0.0-rc.1", "comp/not-a-version",
+		"other/9.0.0", "v1.5.0", "v2.1.0",
+	})
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"comp/^1", "comp/1.10.0"},
+		{"comp/", "comp/1.10.0"},
+		{"comp/~1.2", "comp/1.2.0"},
+		{"^1", "v1.5.0"},
+		{"", "v2.1.0"},
+	}
+	for _, tt := range tests {
+		tag, err := LatestTag(context.Background(), fsys, tt.constraint)
+		if err != nil {
+			t.Fatalf("%q: error: %s", tt.constraint, err.Error())
+		}
+		if tag.Name != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.constraint, tt.want, tag.Name)
+		}
+	}
+	if _, err := LatestTag(context.Background(), fsys, "comp/>=3"); !errors.Is(err, ErrNoMatchingTag) {
+		t.Errorf("expected ErrNoMatchingTag, got %v", err)
+	}
+	if _, err := LatestTag(context.Background(), fstest.MapFS{}, "^1"); err == nil {
+		t.Errorf("expected an error for an FS without tags")
+	}
+}
+
+// tagsFS is an FS with tags, the bbfstest package can not be used here.
+type tagsFS struct {
+	fstest.MapFS
+	tags []*server.Tag
+}
+
+func (f *tagsFS) Tags(ctx context.Context) ([]*server.Tag, error) {
+	return f.tags, nil
+}
+
+func newTagsFS(names []string) *tagsFS {
+	f := &tagsFS{}
+	for _, n := range names {
+		f.tags = append(f.tags, &server.Tag{Name: n, Type: server.TagTypeTag})
+	}
+	return f
+}
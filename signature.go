@@ -0,0 +1,148 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// ErrSignatureRejected is matched by a SignatureError with errors.Is.
+var ErrSignatureRejected = errors.New("signature rejected")
+
+// Signature formats.
+const (
+	SignatureGPG = "gpg"
+	SignatureSSH = "ssh"
+)
+
+// Signature is the signature of the commit or tag of a ref.
+type Signature struct {
+	Ref      Ref
+	CommitID string
+	// Format is SignatureGPG or SignatureSSH, empty if the ref is not signed.
+	Format string
+	// Signer identifies the key, e.g. its fingerprint or the email of its owner.
+	Signer string
+	// Verified is true if the source checked the signature against the key.
+	Verified bool
+}
+
+// SignatureSource returns the signature of the commit of a ref.
+//
+// The REST api of Bitbucket Server does not return signatures on all
+// versions, implement it for the signature data available, e.g. from a
+// signing service or the attestations of the build.
+type SignatureSource interface {
+	Signature(ctx context.Context, client *server.Client, project, repo string, ref Ref, commitID string) (*Signature, error)
+}
+
+// SignatureSourceFunc is a function that implements SignatureSource.
+type SignatureSourceFunc func(ctx context.Context, client *server.Client, project, repo string, ref Ref, commitID string) (*Signature, error)
+
+// Signature calls f.
+func (f SignatureSourceFunc) Signature(ctx context.Context, client *server.Client, project, repo string, ref Ref, commitID string) (*Signature, error) {
+	return f(ctx, client, project, repo, ref, commitID)
+}
+
+// SignaturePolicy decides if the FS serves the files of the signed ref,
+// an error rejects the ref.
+type SignaturePolicy func(ctx context.Context, sig *Signature) error
+
+// RequireSigned returns the policy that accepts verified signatures,
+// by one of the signers if any are given.
+func RequireSigned(signers ...string) SignaturePolicy {
+	return func(ctx context.Context, sig *Signature) error {
+		switch {
+		case sig.Format == "":
+			return errors.New("not signed")
+		case !sig.Verified:
+			return errors.New("signature not verified")
+		case len(signers) > 0 && !slices.Contains(signers, sig.Signer):
+			return fmt.Errorf("signer %s not allowed", sig.Signer)
+		}
+		return nil
+	}
+}
+
+// SignatureError is returned when the policy rejects the signature of the ref.
+type SignatureError struct {
+	Ref      Ref
+	CommitID string
+	Err      error
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("signature of %s (%s) rejected: %s", e.Ref, e.CommitID, e.Err.Error())
+}
+
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true for ErrSignatureRejected.
+func (e *SignatureError) Is(target error) bool {
+	return target == ErrSignatureRejected
+}
+
+// WithSignatureVerification makes the FS check the signature of its ref before
+// it serves files. The first Open resolves the ref, gets the signature from the
+// source and passes it to the policy. Open fails with a SignatureError when the
+// policy rejects it.
+//
+// The check is done once per FS. A branch may move after the check, use it
+// with tags or with Repo.FS and the commit of the ref.
+func WithSignatureVerification(source SignatureSource, policy SignaturePolicy) Option {
+	return func(f *bbFS) {
+		f.signature = &signatureCheck{source: source, policy: policy}
+	}
+}
+
+// signatureCheck checks the signature of the ref of an FS once.
+type signatureCheck struct {
+	source SignatureSource
+	policy SignaturePolicy
+
+	once sync.Once
+	err  error
+}
+
+// fresh returns an unchecked copy for another FS.
+func (s *signatureCheck) fresh() *signatureCheck {
+	return &signatureCheck{source: s.source, policy: s.policy}
+}
+
+// checkSignature returns the result of the signature check of the ref, nil without check.
+func (b *bbFS) checkSignature() error {
+	s := b.signature
+	if s == nil {
+		return nil
+	}
+	s.once.Do(func() {
+		ctx := b.requestContext()
+		var id string
+		var err error
+		if b.at == "" {
+			// The head of the default branch.
+			id, err = findCommit(ctx, b.client, b.projectKey, b.repoSlug, "")
+		} else {
+			id, err = ResolveRef(ctx, b.client, b.projectKey, b.repoSlug, b.at)
+		}
+		if err != nil {
+			s.err = err
+			return
+		}
+		sig, err := s.source.Signature(ctx, b.client, b.projectKey, b.repoSlug, b.at, id)
+		if err != nil {
+			s.err = fmt.Errorf("getting signature of %s: %w", b.at, err)
+			return
+		}
+		if err := s.policy(ctx, sig); err != nil {
+			s.err = &SignatureError{Ref: b.at, CommitID: id, Err: err}
+		}
+	})
+	return s.err
+}
@@ -0,0 +1,75 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestSignatureVerification(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	repo := fakeserver.NewRepo(fstest.MapFS{"app.yaml": {Data: []byte("replicas: 1\n")}})
+	signed := repo.Resolve("main")
+	repo.Tag("v1.0.0", signed)
+	unsigned := repo.Commit("main", "unsigned", signed.Files)
+	repo.Tag("v1.1.0", unsigned)
+	srv.AddRepo("PRJ", "repo", repo)
+
+	calls := 0
+	source := SignatureSourceFunc(func(ctx context.Context, client *server.Client, project, repo string, ref Ref, commitID string) (*Signature, error) {
+		calls++
+		sig := &Signature{Ref: ref, CommitID: commitID}
+		if commitID == signed.ID {
+			sig.Format = SignatureGPG
+			sig.Signer = "release@example.com"
+			sig.Verified = true
+		}
+		return sig, nil
+	})
+
+	r := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"},
+		WithSignatureVerification(source, RequireSigned("release@example.com")))
+
+	tests := []struct {
+		ref Ref
+		ok  bool
+	}{
+		{TagRef("v1.0.0"), true},
+		{TagRef("v1.1.0"), false},
+		// The default branch is at the unsigned commit.
+		{"", false},
+	}
+	for _, tt := range tests {
+		calls = 0
+		fsys := r.FS(tt.ref)
+		for range 2 {
+			_, err := fs.ReadFile(fsys, "app.yaml")
+			var se *SignatureError
+			switch {
+			case tt.ok && err != nil:
+				t.Fatalf("%s: error: %s", tt.ref, err.Error())
+			case !tt.ok && (!errors.Is(err, ErrSignatureRejected) || !errors.As(err, &se) || se.CommitID != unsigned.ID):
+				t.Errorf("%s: expected a SignatureError, got %v", tt.ref, err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("%s: expected one signature lookup, got %d", tt.ref, calls)
+		}
+	}
+
+	fsys := r.FS(TagRef("v1.0.0"))
+	other := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"},
+		WithSignatureVerification(source, RequireSigned("someone@example.com"))).FS(TagRef("v1.0.0"))
+	if _, err := fs.Stat(fsys, "app.yaml"); err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if _, err := fs.Stat(other, "app.yaml"); !errors.Is(err, ErrSignatureRejected) {
+		t.Errorf("expected a rejected signer, got %v", err)
+	}
+}
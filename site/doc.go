@@ -0,0 +1,17 @@
+/*
+Package site serves a file system as a static web site.
+
+Unlike http.FileServerFS it renders directory listings only when asked, with
+the sizes of the files, serves a custom page for missing files and sets the
+Cache-Control header. Content at a commit never changes, so it gets a
+separate, typically long, Cache-Control:
+
+	fsys := bbfs.NewFS(cfg)
+	h := site.NewHandler(fsys,
+		site.WithDirectoryIndex(),
+		site.WithNotFoundPage("404.html"),
+		site.WithCacheControl("public, max-age=60", "public, max-age=31536000, immutable"),
+	)
+	http.ListenAndServe(":8080", h)
+*/
+package site
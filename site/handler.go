@@ -0,0 +1,278 @@
+package site
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/nulllog"
+)
+
+// IndexFile is the file served for a directory.
+const IndexFile = "index.html"
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handler)
+
+// WithLogger sets the logger for failed requests.
+func WithLogger(l *slog.Logger) HandlerOption {
+	return func(h *handler) {
+		h.logger = l
+	}
+}
+
+// WithDirectoryIndex lists the entries of directories without an
+// index.html, with their sizes. Without it they are not found.
+func WithDirectoryIndex() HandlerOption {
+	return func(h *handler) {
+		h.dirIndex = true
+	}
+}
+
+// WithNotFoundPage serves the file with the name in the file system, with
+// status 404, for missing files.
+func WithNotFoundPage(name string) HandlerOption {
+	return func(h *handler) {
+		h.notFound = name
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of the responses.
+// The file system is at a commit when it has a Ref method, like the FS of
+// bbfs, that returns a full commit id. Its content never changes, it gets
+// atCommit, other file systems get moving. Empty values do not set the header.
+func WithCacheControl(moving, atCommit string) HandlerOption {
+	return func(h *handler) {
+		h.cacheControl = moving
+		h.commitCacheControl = atCommit
+	}
+}
+
+// NewHandler returns the handler that serves the files in fsys.
+//
+// A directory is served by its index.html, a request for a directory
+// without a trailing slash is redirected to the url with one.
+func NewHandler(fsys fs.FS, opts ...HandlerOption) http.Handler {
+	h := &handler{fsys: fsys, logger: nulllog.Logger()}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+type handler struct {
+	fsys               fs.FS
+	logger             *slog.Logger
+	dirIndex           bool
+	notFound           string
+	cacheControl       string
+	commitCacheControl string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	fi, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !fi.IsDir() {
+		h.serveFile(w, r, name, fi)
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		u := *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	index := path.Join(name, IndexFile)
+	fi, err = fs.Stat(h.fsys, index)
+	switch {
+	case err == nil && !fi.IsDir():
+		h.serveFile(w, r, index, fi)
+	case h.dirIndex && (err == nil || errors.Is(err, fs.ErrNotExist)):
+		h.serveIndex(w, r, name)
+	case err == nil:
+		h.fail(w, r, fs.ErrNotExist)
+	default:
+		h.fail(w, r, err)
+	}
+}
+
+// serveFile serves the file with its blob id as ETag and its modification
+// time, when known, as Last-Modified. A file info with a ContentID method,
+// like those of bbfs, which Stat takes from the listing of the directory,
+// has the id without reading the file, so a request with a matching
+// If-None-Match does not read it.
+func (h *handler) serveFile(w http.ResponseWriter, r *http.Request, name string, fi fs.FileInfo) {
+	var etag string
+	if c, ok := fi.(interface{ ContentID() string }); ok && c.ContentID() != "" {
+		etag = `"` + c.ContentID() + `"`
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			h.setCacheControl(w)
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	data, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if etag == "" {
+		etag = `"` + blobID(data) + `"`
+	}
+	h.setCacheControl(w)
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, fi.ModTime(), bytes.NewReader(data))
+}
+
+// blobID returns the id of the git blob with the data, the id that
+// Bitbucket reports as content id.
+func blobID(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// etagMatch returns true if the If-None-Match header matches the etag.
+func etagMatch(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// entry is an entry of a directory listing.
+type entry struct {
+	Name    string
+	Size    int64
+	IsDir   bool
+	ModTime time.Time
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Dir}}</title></head>
+<body>
+<h1>Index of {{.Dir}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{- if ne .Dir "/"}}
+<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{- end}}
+{{- range .Entries}}
+<tr><td><a href="{{.Name}}{{if .IsDir}}/{{end}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{if not .IsDir}}{{.Size}}{{end}}</td><td>{{if not .ModTime.IsZero}}{{.ModTime.UTC.Format "2006-01-02 15:04:05"}}{{end}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+func (h *handler) serveIndex(w http.ResponseWriter, r *http.Request, name string) {
+	des, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	entries := make([]entry, 0, len(des))
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		entries = append(entries, entry{Name: de.Name(), Size: fi.Size(), IsDir: de.IsDir(), ModTime: fi.ModTime()})
+	}
+	dir := "/"
+	if name != "." {
+		dir = "/" + name + "/"
+	}
+	var buf bytes.Buffer
+	if err := indexTemplate.Execute(&buf, map[string]any{"Dir": dir, "Entries": entries}); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.setCacheControl(w)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("ETag", `"`+blobID(buf.Bytes())+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+// setCacheControl sets the Cache-Control header for the ref of the file system.
+func (h *handler) setCacheControl(w http.ResponseWriter) {
+	cc := h.cacheControl
+	if atCommit(h.fsys) {
+		cc = h.commitCacheControl
+	}
+	if cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+}
+
+// atCommit returns true if the file system is at a full commit id.
+func atCommit(fsys fs.FS) bool {
+	r, ok := fsys.(interface{ Ref() server.Ref })
+	if !ok {
+		return false
+	}
+	ref := r.Ref()
+	return len(ref) == 40 && ref.IsCommit()
+}
+
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		h.serveNotFound(w, r)
+		return
+	}
+	h.logger.Info("request failed", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// serveNotFound serves the not found page, or a plain text message without one.
+func (h *handler) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	if h.notFound == "" {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := fs.ReadFile(h.fsys, h.notFound)
+	if err != nil {
+		h.logger.Info("not found page failed", slog.String("page", h.notFound), slog.String("error", err.Error()))
+		http.NotFound(w, r)
+		return
+	}
+	ct := mime.TypeByExtension(path.Ext(h.notFound))
+	if ct == "" {
+		ct = http.DetectContentType(data)
+	}
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
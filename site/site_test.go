@@ -0,0 +1,155 @@
+package site
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs"
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func testSite() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>")},
+		"404.html":        {Data: []byte("<h1>gone</h1>")},
+		"docs/guide.md":   {Data: []byte("# guide\n")},
+		"docs/img/a.png":  {Data: []byte("png")},
+		"blog/index.html": {Data: []byte("<h1>blog</h1>")},
+	}
+}
+
+// commitFS is a file system at a ref.
+type commitFS struct {
+	fstest.MapFS
+	ref server.Ref
+}
+
+func (f commitFS) Ref() server.Ref { return f.ref }
+
+func get(t *testing.T, h http.Handler, path string) (*http.Response, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	resp := rec.Result()
+	b, _ := io.ReadAll(resp.Body)
+	return resp, string(b)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []HandlerOption
+		path   string
+		status int
+		want   string
+	}{
+		{"index", nil, "/", http.StatusOK, "<h1>home</h1>"},
+		{"file", nil, "/docs/guide.md", http.StatusOK, "# guide\n"},
+		{"dir index.html", nil, "/blog/", http.StatusOK, "<h1>blog</h1>"},
+		{"redirect", nil, "/blog", http.StatusMovedPermanently, ""},
+		{"no listing", nil, "/docs/", http.StatusNotFound, "404 page not found"},
+		{"listing", []HandlerOption{WithDirectoryIndex()}, "/docs/", http.StatusOK, `<a href="guide.md">guide.md</a></td><td>8</td>`},
+		{"listing dir", []HandlerOption{WithDirectoryIndex()}, "/docs/", http.StatusOK, `<a href="img/">img/</a>`},
+		{"missing", nil, "/nope", http.StatusNotFound, "404 page not found"},
+		{"not found page", []HandlerOption{WithNotFoundPage("404.html")}, "/nope", http.StatusNotFound, "<h1>gone</h1>"},
+		{"missing not found page", []HandlerOption{WithNotFoundPage("missing.html")}, "/nope", http.StatusNotFound, "404 page not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(testSite(), tt.opts...)
+			resp, body := get(t, h, tt.path)
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("expected %q in %q", tt.want, body)
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	commit := server.CommitRef(strings.Repeat("a", 40))
+	tests := []struct {
+		name string
+		ref  server.Ref
+		path string
+		want string
+	}{
+		{"branch", server.BranchRef("main"), "/", "max-age=60"},
+		{"commit", commit, "/", "immutable"},
+		{"abbreviated commit", server.CommitRef("aaaaaaa"), "/", "max-age=60"},
+		{"not found", commit, "/nope", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(commitFS{testSite(), tt.ref}, WithCacheControl("max-age=60", "immutable"))
+			resp, _ := get(t, h, tt.path)
+			if got := resp.Header.Get("Cache-Control"); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	h := NewHandler(testSite())
+	resp, _ := get(t, h, "/docs/guide.md")
+	etag := resp.Header.Get("ETag")
+	if want := `"` + blobID([]byte("# guide\n")) + `"`; etag != want {
+		t.Fatalf("expected ETag %s, got %s", want, etag)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/docs/guide.md", nil)
+		req.Header.Set("If-None-Match", tt.ifNoneMatch)
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.ifNoneMatch, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestETagContentID(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "site", fakeserver.NewRepo(testSite()))
+	fsys := bbfs.NewFS(&bbfs.Config{
+		BaseURL:        srv.BaseURL(),
+		ProjectKey:     "PRJ",
+		RepositorySlug: "site",
+	})
+	h := NewHandler(fsys)
+
+	// A cold request reads the listing of the directory, which has the
+	// content ids, and not the file. The next request uses the cached listing.
+	for _, want := range []int64{1, 0} {
+		before := srv.Requests()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/docs/guide.md", nil)
+		req.Header.Set("If-None-Match", `"`+blobID([]byte("# guide\n"))+`"`)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("expected status 304, got %d", rec.Code)
+		}
+		if n := srv.Requests() - before; n != want {
+			t.Errorf("expected %d requests, got %d", want, n)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package bbfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"strings"
+)
+
+// Snapshot is the file system of a repository at a commit. Two snapshots
+// with the same commit have the same files, so a deployment can skip its
+// work when the snapshot did not change.
+type Snapshot struct {
+	// FS is the file system at the commit.
+	FS fs.FS
+	// BaseURL is the url of the server.
+	BaseURL    string
+	ProjectKey string
+	RepoSlug   string
+	// Root is the directory in the repository that is the root of FS.
+	Root string
+	// Commit is the full commit id.
+	Commit string
+}
+
+// Snapshot resolves the ref, the default ref when empty, and returns the
+// snapshot at its commit.
+func (r *Repo) Snapshot(ctx context.Context, ref Ref) (*Snapshot, error) {
+	b := &r.base
+	if b.err != nil {
+		return nil, b.err
+	}
+	if ref == "" {
+		ref = b.at
+	}
+	commit, err := b.resolve(b.withBudget(ctx), ref)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		FS:         r.FS(CommitRef(commit)),
+		BaseURL:    strings.TrimSuffix(b.client.BaseURL, "/"),
+		ProjectKey: b.projectKey,
+		RepoSlug:   b.repoSlug,
+		Root:       b.root,
+		Commit:     commit,
+	}, nil
+}
+
+// Equal returns true if both snapshots are of the same directory of the
+// same repository at the same commit. The options of the FS, like filters,
+// are not compared.
+func (s *Snapshot) Equal(o *Snapshot) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	return s.key() == o.key()
+}
+
+// Hash returns a hex encoded SHA-256 of what Equal compares, to store
+// with the result of a deployment and compare with the next snapshot.
+func (s *Snapshot) Hash() string {
+	sum := sha256.Sum256([]byte(s.key()))
+	return hex.EncodeToString(sum[:])
+}
+
+// key returns the identity of the snapshot, fields separated by a NUL.
+func (s *Snapshot) key() string {
+	return strings.Join([]string{
+		strings.TrimSuffix(s.BaseURL, "/"),
+		s.ProjectKey,
+		s.RepoSlug,
+		strings.Trim(s.Root, "/"),
+		s.Commit,
+	}, "\x00")
+}
@@ -0,0 +1,75 @@
+package bbfs
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestSnapshot(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"version.txt": {Data: []byte("1")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+	cfg := &Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", At: "main"}
+	r := NewRepo(cfg)
+	ctx := context.Background()
+
+	first, err := r.Snapshot(ctx, "")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if first.Commit != repo.Commits[0].ID {
+		t.Errorf("expected commit %s, got %s", repo.Commits[0].ID, first.Commit)
+	}
+	same, err := NewRepo(cfg).Snapshot(ctx, BranchRef("main"))
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if !first.Equal(same) || first.Hash() != same.Hash() {
+		t.Errorf("expected equal snapshots")
+	}
+
+	srv.Update(func() {
+		repo.Commit("main", "second", fstest.MapFS{"version.txt": {Data: []byte("2")}})
+	})
+	r.Client().ClearCache()
+	second, err := r.Snapshot(ctx, "")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if first.Equal(second) || first.Hash() == second.Hash() {
+		t.Errorf("expected different snapshots after a commit")
+	}
+
+	// The snapshot keeps the files of its commit.
+	data, err := fs.ReadFile(first.FS, "version.txt")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if string(data) != "1" {
+		t.Errorf("expected the first version, got %q", data)
+	}
+
+	sub, err := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo", Root: "dir"}).Snapshot(ctx, "main")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if sub.Equal(second) {
+		t.Errorf("expected snapshots of other roots to differ")
+	}
+	def, err := NewRepo(&Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"}).Snapshot(ctx, "")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	if !def.Equal(second) {
+		t.Errorf("expected the snapshot of the default branch to equal that of main")
+	}
+	var none *Snapshot
+	if none.Equal(first) || !none.Equal(nil) {
+		t.Errorf("unexpected Equal for nil snapshots")
+	}
+}
@@ -0,0 +1,122 @@
+package bbfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// SubtreeStats describe the files below a directory. ReadDir of an FS with
+// WithSubtreeStats puts them in the Sys of the info of directory entries:
+//
+//	stats, ok := info.Sys().(*bbfs.SubtreeStats)
+//
+// The files endpoint the stats come from does not report sizes, a walk
+// is needed for the total size of a subtree.
+type SubtreeStats struct {
+	// Files is the number of files in the directory and its subdirectories.
+	Files int64
+	// Dirs is the number of subdirectories with files, at any depth.
+	Dirs int64
+}
+
+// WithSubtreeStats makes ReadDir of the FS add SubtreeStats to the entries
+// of directories, with one listing of all files below the directory read.
+// Paths hidden by a filter are not counted.
+func WithSubtreeStats() Option {
+	return func(f *bbFS) {
+		f.subtreeStats = true
+	}
+}
+
+// addSubtreeStats sets the SubtreeStats of the directory entries of the
+// directory at fullPath.
+func (b *bbFS) addSubtreeStats(fullPath string, entries []fs.DirEntry) error {
+	stats := map[string]*SubtreeStats{}
+	for _, e := range entries {
+		if e.IsDir() {
+			s := &SubtreeStats{}
+			stats[e.Name()] = s
+			e.(*bbFile).fi.sys = s
+		}
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+	dir := fullPath
+	if dir == "." {
+		dir = ""
+	}
+	paths, err := b.subtreePaths(dir)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, p := range paths {
+		child, rest, ok := strings.Cut(p, "/")
+		s := stats[child]
+		if !ok || s == nil || !b.filter.visible(path.Join(dir, p), false) {
+			continue
+		}
+		s.Files++
+		for d := path.Dir(rest); d != "."; d = path.Dir(d) {
+			key := child + "/" + d
+			if seen[key] {
+				break
+			}
+			seen[key] = true
+			s.Dirs++
+		}
+	}
+	return nil
+}
+
+// subtreePaths returns the paths of the files below dir, relative to dir.
+// On a server without the files endpoint it walks the listings.
+func (b *bbFS) subtreePaths(dir string) ([]string, error) {
+	if b.client.Supports(server.FeatureFilePaths) {
+		paths, err := b.client.GetAllFilePaths(b.requestContext(), &server.GetFilePathsCommand{
+			FilePath:   dir,
+			ProjectKey: b.projectKey,
+			RepoSlug:   b.repoSlug,
+			At:         b.at,
+			Limit:      1000,
+		})
+		if !server.IsNotFound(err) {
+			return paths, err
+		}
+		// The directory was listed, so the endpoint is missing.
+		b.client.SetSupported(server.FeatureFilePaths, false)
+	}
+
+	var paths []string
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		iter, err := b.client.GetFilesIterator(b.requestContext(), b.listCommand(path.Join(dir, rel)))
+		if err != nil {
+			return err
+		}
+		for f := range iter.Files() {
+			p := path.Join(rel, f.Name)
+			if f.Type == "DIRECTORY" {
+				if err := walk(p); err != nil {
+					return err
+				}
+				continue
+			}
+			paths = append(paths, p)
+		}
+		if err := iter.Err(); !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
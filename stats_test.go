@@ -0,0 +1,103 @@
+package bbfs
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestSubtreeStats(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	files := fstest.MapFS{
+		"README.md":             {Data: []byte("readme")},
+		"docs/index.md":         {Data: []byte("index")},
+		"docs/guide/a.md":       {Data: []byte("a")},
+		"docs/guide/b.md":       {Data: []byte("b")},
+		"docs/api/v1/spec.yaml": {Data: []byte("spec")},
+		"src/main.go":           {Data: []byte("package main")},
+		"src/main.log":          {Data: []byte("log")},
+	}
+	srv.AddRepo("PRJ", "repo", fakeserver.NewRepo(files))
+	cfg := &Config{BaseURL: srv.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"}
+
+	cases := []struct {
+		name string
+		dir  string
+		opts []Option
+		want map[string]SubtreeStats
+	}{
+		{name: "root", dir: ".", want: map[string]SubtreeStats{
+			"docs": {Files: 4, Dirs: 3},
+			"src":  {Files: 2},
+		}},
+		{name: "subdirectory", dir: "docs", want: map[string]SubtreeStats{
+			"guide": {Files: 2},
+			"api":   {Files: 1, Dirs: 1},
+		}},
+		{name: "filtered", dir: ".", opts: []Option{WithExclude("*.log")}, want: map[string]SubtreeStats{
+			"docs": {Files: 4, Dirs: 3},
+			"src":  {Files: 1},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := NewFS(cfg, append(tc.opts, WithSubtreeStats())...)
+			entries, err := fs.ReadDir(fsys, tc.dir)
+			if err != nil {
+				t.Fatalf("error: %s", err.Error())
+			}
+			for _, e := range entries {
+				fi, err := e.Info()
+				if err != nil {
+					t.Fatalf("error: %s", err.Error())
+				}
+				stats, ok := fi.Sys().(*SubtreeStats)
+				if !e.IsDir() {
+					if ok {
+						t.Errorf("unexpected stats for file %s", e.Name())
+					}
+					continue
+				}
+				if !ok || *stats != tc.want[e.Name()] {
+					t.Errorf("%s: expected %+v, got %+v", e.Name(), tc.want[e.Name()], stats)
+				}
+			}
+		})
+	}
+
+	entries, err := fs.ReadDir(NewFS(cfg), ".")
+	if err != nil {
+		t.Fatalf("error: %s", err.Error())
+	}
+	for _, e := range entries {
+		if fi, _ := e.Info(); fi.Sys() != nil {
+			t.Errorf("unexpected stats without the option for %s", e.Name())
+		}
+	}
+
+	// A server without the files endpoint gets the stats from the listings.
+	old := fakeserver.New()
+	defer old.Close()
+	old.Disable("", "files")
+	old.AddRepo("PRJ", "repo", fakeserver.NewRepo(files))
+	repo := NewRepo(&Config{BaseURL: old.BaseURL(), ProjectKey: "PRJ", RepositorySlug: "repo"}, WithSubtreeStats())
+	for range 2 {
+		entries, err = fs.ReadDir(repo.FS(""), ".")
+		if err != nil {
+			t.Fatalf("error: %s", err.Error())
+		}
+		for _, e := range entries {
+			fi, _ := e.Info()
+			if stats, ok := fi.Sys().(*SubtreeStats); e.Name() == "docs" && (!ok || *stats != (SubtreeStats{Files: 4, Dirs: 3})) {
+				t.Errorf("docs: unexpected stats %+v", stats)
+			}
+		}
+	}
+	if repo.Client().Supports(server.FeatureFilePaths) {
+		t.Errorf("expected the files endpoint to be recorded as missing")
+	}
+}
@@ -0,0 +1,106 @@
+package bbfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// ErrNoMatchingTag is returned when no tag meets the constraint.
+var ErrNoMatchingTag = errors.New("no matching tag")
+
+// TagLister is implemented by the FS values of this package and the mock in bbfstest.
+type TagLister interface {
+	Tags(ctx context.Context) ([]*server.Tag, error)
+}
+
+// TagFilter selects the tags that are versions meeting a constraint.
+type TagFilter struct {
+	// Prefix is the part of the tag before the version, e.g. "component/".
+	// Tags without the prefix are skipped.
+	Prefix string
+	// Constraint is parsed with ParseConstraint, empty matches all versions.
+	Constraint string
+	// AllowPrerelease includes pre-release versions.
+	AllowPrerelease bool
+}
+
+// TaggedVersion is a tag with its parsed version.
+type TaggedVersion struct {
+	Tag     *server.Tag
+	Version Version
+}
+
+// Filter returns the tags that meet the constraint, lowest version first.
+// Tags that are not versions are skipped.
+func (f *TagFilter) Filter(tags []*server.Tag) ([]TaggedVersion, error) {
+	c, err := ParseConstraint(f.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	var res []TaggedVersion
+	for _, t := range tags {
+		s, ok := strings.CutPrefix(t.Name, f.Prefix)
+		if !ok {
+			continue
+		}
+		v, err := ParseVersion(s)
+		if err != nil {
+			continue
+		}
+		if v.Pre != "" && !f.AllowPrerelease {
+			continue
+		}
+		if c.Check(v) {
+			res = append(res, TaggedVersion{Tag: t, Version: v})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b TaggedVersion) int {
+		return a.Version.Compare(b.Version)
+	})
+	return res, nil
+}
+
+// Latest returns the tag with the highest version that meets the constraint.
+// The error wraps ErrNoMatchingTag when there is none.
+func (f *TagFilter) Latest(tags []*server.Tag) (TaggedVersion, error) {
+	res, err := f.Filter(tags)
+	if err != nil {
+		return TaggedVersion{}, err
+	}
+	if len(res) == 0 {
+		return TaggedVersion{}, fmt.Errorf("%w: %s%s", ErrNoMatchingTag, f.Prefix, f.Constraint)
+	}
+	return res[len(res)-1], nil
+}
+
+// LatestTag returns the tag of the repository of fsys with the highest
+// version that meets the constraint. fsys must implement TagLister.
+//
+// A prefix of the tags can be put before the constraint, up to the last
+// slash: "component/^1.2" selects the latest tag component/1.x.y from 1.2.0.
+func LatestTag(ctx context.Context, fsys fs.FS, constraint string) (*server.Tag, error) {
+	lister, ok := fsys.(TagLister)
+	if !ok {
+		return nil, fmt.Errorf("%T does not list tags: %w", fsys, ErrNotImplementedYet)
+	}
+	var prefix string
+	if i := strings.LastIndex(constraint, "/"); i >= 0 {
+		prefix, constraint = constraint[:i+1], constraint[i+1:]
+	}
+	tags, err := lister.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f := &TagFilter{Prefix: prefix, Constraint: constraint}
+	tv, err := f.Latest(tags)
+	if err != nil {
+		return nil, err
+	}
+	return tv.Tag, nil
+}
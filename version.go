@@ -0,0 +1,15 @@
+package bbfs
+
+import "github.com/myhops/bbfs/internal/buildinfo"
+
+// BuildVersion returns the version of bbfs that the clients send in the
+// User-Agent header, bbfs/VERSION. It is the version of the module in the
+// build info, or the version set at build time with
+//
+//	go build -ldflags "-X github.com/myhops/bbfs/internal/buildinfo.version=v1.2.3"
+//
+// and devel when neither is known. It is not called Version, that is the
+// type of a semantic version.
+func BuildVersion() string {
+	return buildinfo.Version()
+}
@@ -0,0 +1,221 @@
+package bbfs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+)
+
+// DefaultWatchInterval is the poll interval of a Watcher without Interval.
+const DefaultWatchInterval = time.Minute
+
+// RefEvent reports that a ref moved to another commit.
+type RefEvent struct {
+	Ref Ref
+	// From is the previous commit, empty for a new ref.
+	From string
+	// To is the new commit, empty for a deleted ref.
+	To   string
+	Time time.Time
+}
+
+// Watcher polls a ref and reports when it points to another commit.
+type Watcher struct {
+	// Client is used for the polls, which bypass its cache.
+	// Its cache is cleared when the ref moves.
+	Client     *server.Client
+	ProjectKey string
+	RepoSlug   string
+	Ref        Ref
+	// Interval is the time between polls, defaults to DefaultWatchInterval.
+	Interval time.Duration
+	// OnError is called with errors from polls after the first.
+	// When nil, Watch returns the error.
+	OnError func(error)
+	// ChangedPathsOnly drops only the cached responses for the paths that
+	// changed when the ref moves, see InvalidateChanges, instead of
+	// clearing the cache. The cache is cleared when the compare fails.
+	ChangedPathsOnly bool
+	// Prefetch is an FS at the ref whose changed files are read again
+	// after they are dropped with ChangedPathsOnly, see Prefetch.
+	// Its errors go to OnError.
+	Prefetch fs.FS
+}
+
+// Watch calls fn each time the ref moves until the context is done or fn
+// returns an error. The first poll must succeed, it sets the commit to
+// compare with.
+func (w *Watcher) Watch(ctx context.Context, fn func(RefEvent) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	last, err := currentCommit(ctx, w.Client, w.ProjectKey, w.RepoSlug, w.Ref)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		id, err := currentCommit(ctx, w.Client, w.ProjectKey, w.RepoSlug, w.Ref)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError == nil {
+				return err
+			}
+			w.OnError(err)
+			continue
+		}
+		if id == last {
+			continue
+		}
+		w.invalidate(ctx, last, id)
+		ev := RefEvent{Ref: w.Ref, From: last, To: id, Time: time.Now()}
+		last = id
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// invalidate drops the responses in the cache that changed with the move
+// of the ref.
+func (w *Watcher) invalidate(ctx context.Context, from, to string) {
+	if !w.ChangedPathsOnly {
+		w.Client.ClearCache()
+		return
+	}
+	paths, err := InvalidateChanges(ctx, w.Client, w.ProjectKey, w.RepoSlug, w.Ref, from, to)
+	if err != nil {
+		w.Client.ClearCache()
+		return
+	}
+	if w.Prefetch == nil {
+		return
+	}
+	if err := Prefetch(w.Prefetch, paths); err != nil && w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+// WebhookHandler returns a handler for the refs changed webhook of the server.
+// It calls fn for each change to ref in the repository.
+//
+// When secret is not empty, requests must be signed with it in the
+// X-Hub-Signature header.
+func WebhookHandler(project, repo string, ref Ref, secret string, fn func(RefEvent)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if secret != "" && !validSignature(body, secret, r.Header.Get("X-Hub-Signature")) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		// The server sends a ping when the webhook is tested.
+		if r.Header.Get("X-Event-Key") == "diagnostics:ping" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		events, err := parseRefsChanged(body, project, repo, ref)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, ev := range events {
+			fn(ev)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// validSignature checks the sha256 hmac of the body.
+func validSignature(body []byte, secret, signature string) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// parseRefsChanged returns the events for the ref in a repo:refs_changed payload.
+func parseRefsChanged(body []byte, project, repo string, ref Ref) ([]RefEvent, error) {
+	var payload struct {
+		EventKey   string    `json:"eventKey"`
+		Date       time.Time `json:"date"`
+		Repository struct {
+			Slug    string `json:"slug"`
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+		} `json:"repository"`
+		Changes []struct {
+			Ref struct {
+				ID        string `json:"id"`
+				DisplayID string `json:"displayId"`
+			} `json:"ref"`
+			FromHash string `json:"fromHash"`
+			ToHash   string `json:"toHash"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.EventKey != "repo:refs_changed" {
+		return nil, errors.New("unsupported event " + payload.EventKey)
+	}
+	if payload.Repository.Project.Key != project || payload.Repository.Slug != repo {
+		return nil, nil
+	}
+	var res []RefEvent
+	for _, c := range payload.Changes {
+		if c.Ref.ID != ref.String() && c.Ref.DisplayID != ref.String() {
+			continue
+		}
+		res = append(res, RefEvent{
+			Ref:  Ref(c.Ref.ID),
+			From: zeroHashToEmpty(c.FromHash),
+			To:   zeroHashToEmpty(c.ToHash),
+			Time: payload.Date,
+		})
+	}
+	return res, nil
+}
+
+// zeroHashToEmpty returns "" for the all zero hash the server uses for
+// created and deleted refs.
+func zeroHashToEmpty(id string) string {
+	if strings.Trim(id, "0") == "" {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,103 @@
+package bbfs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/myhops/bbfs/bbclient/server"
+	"github.com/myhops/bbfs/internal/fakeserver"
+)
+
+func TestWatcher(t *testing.T) {
+	repo := fakeserver.NewRepo(fstest.MapFS{"a": {Data: []byte("a")}})
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRepo("PRJ", "repo", repo)
+
+	w := &Watcher{
+		Client:     &server.Client{BaseURL: srv.BaseURL()},
+		ProjectKey: "PRJ",
+		RepoSlug:   "repo",
+		Ref:        "main",
+		Interval:   10 * time.Millisecond,
+	}
+	first := repo.Commits[0].ID
+	second := make(chan string, 1)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.Update(func() {
+			second <- repo.Commit("main", "second", fstest.MapFS{"b": {Data: []byte("b")}}).ID
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stop := errors.New("stop")
+	err := w.Watch(ctx, func(ev RefEvent) error {
+		if want := <-second; ev.From != first || ev.To != want {
+			t.Errorf("unexpected event %+v, expected %s -> %s", ev, first, want)
+		}
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("expected the error from the callback, got %v", err)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	const payload = `{
+		"eventKey": "repo:refs_changed",
+		"date": "2024-08-05T09:27:04Z",
+		"repository": {"slug": "repo", "project": {"key": "PRJ"}},
+		"changes": [
+			{"ref": {"id": "refs/heads/main", "displayId": "main"}, "fromHash": "aaa", "toHash": "bbb"},
+			{"ref": {"id": "refs/heads/other", "displayId": "other"}, "fromHash": "ccc", "toHash": "ddd"}
+		]
+	}`
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(payload))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		status    int
+		events    int
+	}{
+		{"signed", sign("secret"), http.StatusNoContent, 1},
+		{"bad signature", sign("other"), http.StatusUnauthorized, 0},
+		{"unsigned", "", http.StatusUnauthorized, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []RefEvent
+			h := WebhookHandler("PRJ", "repo", "main", "secret", func(ev RefEvent) {
+				events = append(events, ev)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+			req.Header.Set("X-Hub-Signature", tt.signature)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if len(events) != tt.events {
+				t.Fatalf("expected %d events, got %d", tt.events, len(events))
+			}
+			if tt.events > 0 && (events[0].Ref != "refs/heads/main" || events[0].From != "aaa" || events[0].To != "bbb") {
+				t.Errorf("unexpected event %+v", events[0])
+			}
+		})
+	}
+}